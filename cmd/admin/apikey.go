@@ -0,0 +1,134 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/auth"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/config"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/repository"
+	repo "github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/repository/mongodb"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/tenant"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// runAPIKey manages API keys: apikey create|list|revoke
+func runAPIKey(ctx context.Context, cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: apikey create -name <name> | apikey list | apikey revoke -id <key id>")
+	}
+
+	db, closeDB, err := connect(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	keys := repo.NewAPIKeyRepository(db)
+
+	switch args[0] {
+	case "create":
+		return createAPIKey(ctx, keys, args[1:])
+	case "list":
+		return listAPIKeys(ctx, keys)
+	case "revoke":
+		return revokeAPIKey(ctx, keys, args[1:])
+	default:
+		return fmt.Errorf("unknown apikey command: %s", args[0])
+	}
+}
+
+// createAPIKey generates a new API key and prints it once
+func createAPIKey(ctx context.Context, keys repository.APIKeyRepository, args []string) error {
+	flags := flag.NewFlagSet("apikey create", flag.ContinueOnError)
+	name := flags.String("name", "", "Name describing who uses the key")
+	tenantID := flags.String("tenant", "", "Tenant the key is bound to; unbound keys may pick a tenant per request")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *name == "" {
+		return errors.New("-name is required")
+	}
+
+	if *tenantID != "" && !tenant.IsValidID(*tenantID) {
+		return fmt.Errorf("invalid tenant ID %q", *tenantID)
+	}
+
+	key, plaintext, err := auth.GenerateAPIKey(*name)
+	if err != nil {
+		return err
+	}
+	key.TenantID = *tenantID
+
+	if err := keys.Create(ctx, key); err != nil {
+		return err
+	}
+
+	fmt.Printf("Created API key %s (%s)\n", key.ID.Hex(), key.Name)
+	fmt.Println("Store this key now; it cannot be shown again:")
+	fmt.Println(plaintext)
+	return nil
+}
+
+// listAPIKeys prints all API keys without their secrets
+func listAPIKeys(ctx context.Context, keys repository.APIKeyRepository) error {
+	all, err := keys.FindAll(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tTENANT\tCREATED\tREVOKED")
+	for _, key := range all {
+		revoked := "-"
+		if key.RevokedAt != nil {
+			revoked = key.RevokedAt.Format(time.RFC3339)
+		}
+		tenantID := "-"
+		if key.TenantID != "" {
+			tenantID = key.TenantID
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", key.ID.Hex(), key.Name, tenantID, key.CreatedAt.Format(time.RFC3339), revoked)
+	}
+	return w.Flush()
+}
+
+// revokeAPIKey revokes an API key so it can no longer authenticate
+func revokeAPIKey(ctx context.Context, keys repository.APIKeyRepository, args []string) error {
+	flags := flag.NewFlagSet("apikey revoke", flag.ContinueOnError)
+	idHex := flags.String("id", "", "ID of the key to revoke")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	id, err := primitive.ObjectIDFromHex(*idHex)
+	if err != nil {
+		return fmt.Errorf("invalid key ID %q", *idHex)
+	}
+
+	if err := keys.Revoke(ctx, id, time.Now()); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return fmt.Errorf("no active API key with ID %s", *idHex)
+		}
+		return err
+	}
+
+	fmt.Printf("Revoked API key %s\n", *idHex)
+	return nil
+}
@@ -0,0 +1,74 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"sort"
+
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/config"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/tenant"
+)
+
+// runBackfillTenant assigns chats, messages, folders and invites without a tenant to one
+func runBackfillTenant(ctx context.Context, cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("backfill-tenant", flag.ContinueOnError)
+	tenantID := flags.String("tenant", tenant.DefaultID, "Tenant that receives the existing documents")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if !tenant.IsValidID(*tenantID) {
+		return fmt.Errorf("invalid tenant ID %q", *tenantID)
+	}
+
+	db, closeDB, err := connect(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	updated, err := db.BackfillTenant(ctx, *tenantID)
+
+	collections := make([]string, 0, len(updated))
+	for collection := range updated {
+		collections = append(collections, collection)
+	}
+	sort.Strings(collections)
+
+	for _, collection := range collections {
+		fmt.Printf("Assigned %d %s to tenant %s\n", updated[collection], collection, *tenantID)
+	}
+	return err
+}
+
+// runBackfillTitles stores the lowercased title of chats created before title prefix filters used it
+func runBackfillTitles(ctx context.Context, cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("backfill-titles", flag.ContinueOnError)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	db, closeDB, err := connect(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	updated, err := db.BackfillTitleLower(ctx)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Stored the lowercased title of %d chats\n", updated)
+	return nil
+}
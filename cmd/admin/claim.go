@@ -0,0 +1,59 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/config"
+	repo "github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/repository/mongodb"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/tenant"
+)
+
+// runClaim assigns chats and folders created before ownership existed to a subject
+func runClaim(ctx context.Context, cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("claim", flag.ContinueOnError)
+	subject := flags.String("subject", "", "Subject (API key ID or JWT sub) that becomes the owner")
+	tenantID := flags.String("tenant", tenant.DefaultID, "Tenant whose chats and folders are claimed")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *subject == "" {
+		return errors.New("-subject is required")
+	}
+
+	ctx, err := withTenant(ctx, *tenantID)
+	if err != nil {
+		return err
+	}
+
+	db, closeDB, err := connect(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	chats, err := repo.NewChatRepository(db).ClaimUnowned(ctx, *subject)
+	if err != nil {
+		return err
+	}
+
+	folders, err := repo.NewFolderRepository(db).ClaimUnowned(ctx, *subject)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Assigned %d chats and %d folders to %s\n", chats, folders, *subject)
+	return nil
+}
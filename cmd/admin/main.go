@@ -0,0 +1,154 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/config"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/db/mongodb"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/tenant"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/pkg/buildinfo"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/pkg/logger"
+)
+
+// command is an admin subcommand
+type command struct {
+	name        string
+	description string
+	run         func(ctx context.Context, cfg *config.Config, args []string) error
+}
+
+// commands lists all available admin subcommands
+var commands = []command{
+	{
+		name:        "reconcile",
+		description: "Recompute chat message counts and last message times from the messages collection",
+		run:         runReconcile,
+	},
+	{
+		name:        "apikey",
+		description: "Create, list or revoke API keys (apikey create -name <name> | list | revoke -id <id>)",
+		run:         runAPIKey,
+	},
+	{
+		name:        "claim",
+		description: "Make a subject the owner of all chats and folders that have no owner",
+		run:         runClaim,
+	},
+	{
+		name:        "backfill-tenant",
+		description: "Assign documents created before tenancy existed to a tenant",
+		run:         runBackfillTenant,
+	},
+	{
+		name:        "backfill-titles",
+		description: "Store the lowercased title that title prefix filters match on chats created before it existed",
+		run:         runBackfillTitles,
+	},
+	{
+		name:        "usage",
+		description: "Print a tenant's daily completions and token usage (usage -tenant <id> -days <n>)",
+		run:         runUsage,
+	},
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	if os.Args[1] == "-version" || os.Args[1] == "--version" {
+		fmt.Println("go-sse-ai-chat admin", buildinfo.Get())
+		return
+	}
+
+	cmd := findCommand(os.Args[1])
+	if cmd == nil {
+		fmt.Fprintf(os.Stderr, "Unknown command: %s\n\n", os.Args[1])
+		usage()
+		os.Exit(2)
+	}
+
+	// Load configuration
+	cfg, err := config.Load(config.Options{})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Initialize logger
+	logger.Initialize(cfg.LogLevel, cfg.Server.Environment == "development")
+	defer logger.Sync()
+
+	// Stop long-running commands on interrupt
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if err := cmd.run(ctx, cfg, os.Args[2:]); err != nil && !errors.Is(err, flag.ErrHelp) {
+		fmt.Fprintf(os.Stderr, "%s failed: %v\n", cmd.name, err)
+		os.Exit(1)
+	}
+}
+
+// findCommand looks up a subcommand by name
+func findCommand(name string) *command {
+	for i := range commands {
+		if commands[i].name == name {
+			return &commands[i]
+		}
+	}
+	return nil
+}
+
+// usage prints the list of available subcommands
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: admin <command> [flags]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-12s %s\n", cmd.name, cmd.description)
+	}
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Run 'admin --version' to print version information.")
+}
+
+// withTenant scopes ctx to the tenant named by a -tenant flag
+func withTenant(ctx context.Context, id string) (context.Context, error) {
+	if !tenant.IsValidID(id) {
+		return nil, fmt.Errorf("invalid tenant ID %q", id)
+	}
+	return tenant.WithTenant(ctx, id), nil
+}
+
+// connect opens the database connection used by admin commands
+func connect(cfg *config.Config) (*mongodb.DBConnection, func(), error) {
+	db, err := mongodb.New(&cfg.MongoDB)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	closeDB := func() {
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.MongoDB.Timeout)
+		defer cancel()
+		if err := db.Disconnect(ctx); err != nil {
+			logger.Warnf("Failed to disconnect from MongoDB: %v", err)
+		}
+	}
+
+	return db, closeDB, nil
+}
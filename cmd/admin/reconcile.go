@@ -0,0 +1,50 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/config"
+	repo "github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/repository/mongodb"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/services"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/tenant"
+)
+
+// runReconcile recomputes message_count and last_message_at for every chat of a tenant
+func runReconcile(ctx context.Context, cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("reconcile", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "Report drifted chats without updating them")
+	tenantID := flags.String("tenant", tenant.DefaultID, "Tenant whose chats are reconciled")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	ctx, err := withTenant(ctx, *tenantID)
+	if err != nil {
+		return err
+	}
+
+	db, closeDB, err := connect(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	maintenance := services.NewMaintenanceService(repo.NewChatRepository(db), repo.NewMessageRepository(db))
+
+	report, err := maintenance.ReconcileMessageStats(ctx, *dryRun)
+	if report != nil {
+		fmt.Printf("Scanned %d chats, %d drifted, %d fixed\n", report.Scanned, report.Drifted, report.Fixed)
+	}
+	return err
+}
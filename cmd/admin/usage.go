@@ -0,0 +1,62 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/config"
+	repo "github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/repository/mongodb"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/tenant"
+)
+
+// runUsage prints a tenant's daily completion and token usage
+func runUsage(ctx context.Context, cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("usage", flag.ContinueOnError)
+	tenantID := flags.String("tenant", tenant.DefaultID, "Tenant whose usage is printed")
+	days := flags.Int("days", 7, "Number of days to print, ending today (UTC)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *days < 1 {
+		return errors.New("-days must be at least 1")
+	}
+
+	ctx, err := withTenant(ctx, *tenantID)
+	if err != nil {
+		return err
+	}
+
+	db, closeDB, err := connect(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	usage, err := repo.NewUsageRepository(db).FindByDays(ctx, today.AddDate(0, 0, 1-*days), today)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "DAY\tCOMPLETIONS\tPROMPT TOKENS\tCOMPLETION TOKENS\tTOTAL TOKENS\tUNSTORED MESSAGES")
+	for _, day := range usage {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t%d\n", day.Day.Format(time.DateOnly), day.Completions, day.PromptTokens, day.CompletionTokens, day.TotalTokens(), day.Messages)
+	}
+	return w.Flush()
+}
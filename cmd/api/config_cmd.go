@@ -0,0 +1,48 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/config"
+)
+
+// configFlags registers the flags that select configuration sources
+func configFlags(fs *flag.FlagSet) *config.Options {
+	opts := &config.Options{Overrides: config.Overrides{}}
+	fs.StringVar(&opts.File, "config", "", "YAML or TOML configuration file (default $CONFIG_FILE)")
+	fs.Var(config.Overrides(opts.Overrides), "set", "Override a configuration key, as KEY=value (repeatable)")
+	return opts
+}
+
+// runConfig implements the "config" subcommand
+func runConfig(args []string) error {
+	if len(args) == 0 || args[0] != "print" {
+		return fmt.Errorf("usage: api config print [-show-secrets] [-config file] [-set KEY=value]")
+	}
+
+	fs := flag.NewFlagSet("config print", flag.ContinueOnError)
+	showSecrets := fs.Bool("show-secrets", false, "Print secrets such as API keys and passwords instead of hiding them")
+	fs.Bool("redacted", true, "Deprecated: secrets are hidden unless -show-secrets is set")
+	opts := configFlags(fs)
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	cfg, err := config.Load(*opts)
+	if err != nil {
+		return err
+	}
+
+	return cfg.Print(os.Stdout, *showSecrets)
+}
@@ -0,0 +1,175 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package main
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/handlers"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/models"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/services"
+	apperrors "github.com/yasin-yalcin-dev/go-sse-ai-chat/pkg/errors"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/pkg/grpcjson"
+	chatv1 "github.com/yasin-yalcin-dev/go-sse-ai-chat/pkg/proto/chat/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+)
+
+// protoPath is the schema of the gRPC API, relative to this package
+var protoPath = filepath.Join("..", "..", "docs", "proto", "chat", "v1", "chat.proto")
+
+// TestGRPCDescriptor fails when a gRPC method is served without being
+// described in the committed .proto file, or the other way around, for
+// example when the stubs were not regenerated after changing it
+func TestGRPCDescriptor(t *testing.T) {
+	data, err := os.ReadFile(protoPath)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", protoPath, err)
+	}
+
+	described := make(map[string]bool)
+	service := ""
+	for _, match := range regexp.MustCompile(`(?m)^service (\w+)|^\s*rpc (\w+)\(`).FindAllSubmatch(data, -1) {
+		if len(match[1]) > 0 {
+			service = "chat.v1." + string(match[1])
+			continue
+		}
+		described["/"+service+"/"+string(match[2])] = true
+	}
+
+	server := grpc.NewServer()
+	handlers.RegisterGRPCServices(server, nil)
+
+	served := make(map[string]bool)
+	for name, info := range server.GetServiceInfo() {
+		for _, method := range info.Methods {
+			served["/"+name+"/"+method.Name] = true
+		}
+	}
+
+	for method := range served {
+		if !described[method] {
+			t.Errorf("%s is not described in %s", method, protoPath)
+		}
+	}
+	for method := range described {
+		if !served[method] {
+			t.Errorf("%s is described in %s but not served", method, protoPath)
+		}
+	}
+}
+
+// oneChat is a ChatService that only finds the chat it holds
+type oneChat struct {
+	services.ChatService
+	chat *models.Chat
+}
+
+func (s oneChat) GetChatByID(_ context.Context, id string) (*models.Chat, error) {
+	if id != s.chat.ID.Hex() {
+		return nil, apperrors.NewNotFoundError("Chat not found", nil)
+	}
+	return s.chat, nil
+}
+
+// dialGRPC serves the gRPC API with chatService on an in-memory listener
+// and returns a connection to it
+func dialGRPC(t *testing.T, chatService services.ChatService) *grpc.ClientConn {
+	t.Helper()
+
+	listener := bufconn.Listen(1 << 16)
+	server := grpc.NewServer()
+	handlers.RegisterGRPCServices(server, handlers.NewGRPCHandler(nil, chatService, nil, nil))
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// TestGRPCCodecs checks that generated stubs can call the API with the
+// default protobuf codec as well as with the JSON codec
+func TestGRPCCodecs(t *testing.T) {
+	chat := models.NewChat("Plans")
+	chat.MessageCount = 3
+	client := chatv1.NewChatServiceClient(dialGRPC(t, oneChat{chat: chat}))
+
+	codecs := []struct {
+		name string
+		opts []grpc.CallOption
+	}{
+		{name: "proto"},
+		{name: grpcjson.Name, opts: []grpc.CallOption{grpc.CallContentSubtype(grpcjson.Name)}},
+	}
+
+	want := &chatv1.Chat{
+		Id:           chat.ID.Hex(),
+		Title:        "Plans",
+		MessageCount: 3,
+	}
+	for _, codec := range codecs {
+		t.Run(codec.name, func(t *testing.T) {
+			got, err := client.GetChat(context.Background(), &chatv1.ChatIDRequest{Id: chat.ID.Hex()}, codec.opts...)
+			if err != nil {
+				t.Fatalf("GetChat() error = %v", err)
+			}
+
+			got.CreatedAt, got.UpdatedAt = "", ""
+			if !proto.Equal(got, want) {
+				t.Errorf("GetChat() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+// rawJSON sends and receives JSON documents as they are, as a client
+// without generated code does
+type rawJSON struct{}
+
+func (rawJSON) Marshal(v interface{}) ([]byte, error)      { return []byte(v.(string)), nil }
+func (rawJSON) Unmarshal(data []byte, v interface{}) error { *v.(*string) = string(data); return nil }
+func (rawJSON) Name() string                               { return grpcjson.Name }
+
+// TestGRPCJSONFieldNames checks that JSON calls use the field names of the
+// .proto file, so that they match the REST API
+func TestGRPCJSONFieldNames(t *testing.T) {
+	chat := models.NewChat("Plans")
+	chat.MessageCount = 3
+	conn := dialGRPC(t, oneChat{chat: chat})
+
+	var reply string
+	request := `{"id": "` + chat.ID.Hex() + `", "unknown_field": true}`
+	if err := conn.Invoke(context.Background(), chatv1.ChatService_GetChat_FullMethodName, request, &reply, grpc.ForceCodec(rawJSON{})); err != nil {
+		t.Fatalf("Invoke() error = %v", err)
+	}
+
+	for _, field := range []string{`"title":"Plans"`, `"message_count":3`, `"created_at":`} {
+		if !strings.Contains(strings.ReplaceAll(reply, " ", ""), field) {
+			t.Errorf("reply %s does not contain %s", reply, field)
+		}
+	}
+}
@@ -11,21 +11,46 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/config"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/tracing"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/pkg/buildinfo"
 	"github.com/yasin-yalcin-dev/go-sse-ai-chat/pkg/logger"
+	"google.golang.org/grpc"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "config" {
+		if err := runConfig(os.Args[2:]); err != nil {
+			if err != flag.ErrHelp {
+				fmt.Fprintln(os.Stderr, err)
+			}
+			os.Exit(1)
+		}
+		return
+	}
+
+	showVersion := flag.Bool("version", false, "Print version information and exit")
+	configOpts := configFlags(flag.CommandLine)
+	flag.Parse()
+
+	if *showVersion {
+		fmt.Println("go-sse-ai-chat", buildinfo.Get())
+		return
+	}
+
 	// Load configuration
-	cfg, err := config.Load()
+	cfg, err := config.Load(*configOpts)
 	if err != nil {
-		fmt.Printf("Failed to load configuration: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
 		os.Exit(1)
 	}
 
@@ -33,10 +58,27 @@ func main() {
 	logger.Initialize(cfg.LogLevel, cfg.Server.Environment == "development")
 	defer logger.Sync()
 
-	logger.Info("Starting server...")
+	logger.Infof("Starting server %s", buildinfo.Get())
+
+	// Initialize tracing
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
+	if err != nil {
+		logger.Fatalf("Failed to initialize tracing: %v", err)
+	}
+
+	// Apply reloadable settings on SIGHUP or when the config file changes
+	reloader := config.NewReloader(cfg, *configOpts)
+	reloader.OnReload(func(cfg *config.Config) {
+		logger.SetLevel(cfg.LogLevel)
+	})
 
 	// Setup router with Gin
-	router := setupRouter(cfg)
+	router, app := setupRouter(reloader)
+
+	// Start watching once every component has subscribed to reloads
+	watchCtx, stopWatching := context.WithCancel(context.Background())
+	defer stopWatching()
+	go reloader.Watch(watchCtx)
 
 	// Configure HTTP server with improved settings
 	server := &http.Server{
@@ -56,20 +98,128 @@ func main() {
 		}
 	}()
 
+	// Start the gRPC server next to it
+	if app.grpc != nil {
+		listener, err := net.Listen("tcp", fmt.Sprintf(":%d", cfg.GRPC.Port))
+		if err != nil {
+			logger.Fatalf("Failed to start gRPC server: %v", err)
+		}
+
+		go func() {
+			logger.Infof("gRPC server listening on port %d", cfg.GRPC.Port)
+			if err := app.grpc.Serve(listener); err != nil {
+				logger.Fatalf("Failed to start gRPC server: %v", err)
+			}
+		}()
+	}
+
+	// Serve metrics on their own port
+	if app.metrics != nil {
+		go func() {
+			logger.Infof("Metrics listening on port %d", cfg.Metrics.Port)
+			if err := app.metrics.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				logger.Fatalf("Failed to start metrics server: %v", err)
+			}
+		}()
+	}
+
 	// Wait for interrupt signal to gracefully shutdown
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 	logger.Info("Shutting down server...")
 
+	// Fail readiness first so load balancers stop sending new requests
+	app.readiness.Drain()
+	if cfg.Health.ShutdownDelay > 0 {
+		logger.Infof("Waiting %v for load balancers to observe readiness", cfg.Health.ShutdownDelay)
+		time.Sleep(cfg.Health.ShutdownDelay)
+	}
+
 	// Create a deadline for server shutdown
 	ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
 	defer cancel()
 
-	// Attempt graceful shutdown
+	drain(ctx, server, app, cfg)
+
+	// Flush buffered spans
+	if err := shutdownTracing(ctx); err != nil {
+		logger.Errorf("Failed to flush traces: %v", err)
+	}
+
+	logger.Info("Server exited")
+}
+
+// drain shuts the server down without cutting off streams: it asks SSE
+// clients to reconnect elsewhere, waits for in-flight generations, and only
+// then closes connections and the database
+func drain(ctx context.Context, server *http.Server, app *components, cfg *config.Config) {
+	// Tell SSE clients to reconnect, spread out by jitter
+	app.broker.Drain(cfg.SSE.ReconnectRetry, cfg.SSE.ReconnectJitter)
+
+	// Let generations finish, or checkpoint once the drain timeout passes
+	generationCtx, cancelGenerations := context.WithTimeout(ctx, cfg.SSE.DrainTimeout)
+	defer cancelGenerations()
+	if active := app.generations.Active(); active > 0 {
+		logger.Infof("Waiting up to %v for %d in-flight generations", cfg.SSE.DrainTimeout, active)
+	}
+	if canceled := app.generations.Drain(generationCtx); canceled > 0 {
+		logger.Warnf("Interrupted %d generations that did not finish in time", canceled)
+	}
+
+	// Stop taking jobs; running ones are returned to the queue
+	app.stopJobs()
+	select {
+	case <-app.jobs.Done():
+	case <-ctx.Done():
+	}
+
+	// Stop the generation sweeper, so that it does not write while the database disconnects
+	app.stopSweeper()
+	select {
+	case <-app.sweeperDone:
+	case <-ctx.Done():
+	}
+
+	// Close the remaining streams, so that the server does not wait on them
+	app.stopBroker()
+	select {
+	case <-app.broker.Done():
+	case <-ctx.Done():
+	}
+
 	if err := server.Shutdown(ctx); err != nil {
 		logger.Errorf("Server forced to shutdown: %v", err)
 	}
 
-	logger.Info("Server exited")
+	if app.grpc != nil {
+		stopGRPC(ctx, app.grpc)
+	}
+
+	if app.metrics != nil {
+		if err := app.metrics.Shutdown(ctx); err != nil {
+			logger.Errorf("Metrics server forced to shutdown: %v", err)
+		}
+	}
+
+	if err := app.db.Disconnect(ctx); err != nil {
+		logger.Errorf("Failed to disconnect from MongoDB: %v", err)
+	}
+}
+
+// stopGRPC stops the gRPC server once its in-flight calls finished, or
+// cancels them when ctx is done first
+func stopGRPC(ctx context.Context, server *grpc.Server) {
+	stopped := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		logger.Errorf("gRPC server forced to stop: %v", ctx.Err())
+		server.Stop()
+	}
 }
@@ -0,0 +1,64 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/handlers"
+)
+
+var updateOpenAPI = flag.Bool("update", false, "regenerate docs/openapi.json")
+
+// openAPIPath is the committed OpenAPI document, relative to this package
+var openAPIPath = filepath.Join("..", "..", "docs", "openapi.json")
+
+// TestOpenAPIDocument fails when a route or DTO changes without the committed
+// OpenAPI document. Run it with -update to regenerate the document.
+func TestOpenAPIDocument(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// Method values of nil handlers are never called; every optional route is on
+	router := gin.New()
+	registerRoutes(router, routeTable{streamTokens: true})
+
+	doc, err := handlers.NewOpenAPIDocument(router.Routes())
+	if err != nil {
+		t.Fatalf("Failed to generate the OpenAPI document: %v", err)
+	}
+
+	got, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		t.Fatalf("Failed to encode the OpenAPI document: %v", err)
+	}
+	got = append(got, '\n')
+
+	if *updateOpenAPI {
+		if err := os.WriteFile(openAPIPath, got, 0o644); err != nil {
+			t.Fatalf("Failed to write %s: %v", openAPIPath, err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(openAPIPath)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", openAPIPath, err)
+	}
+
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s is out of date; regenerate it with go test ./cmd/api -run TestOpenAPIDocument -update", openAPIPath)
+	}
+}
@@ -11,25 +11,74 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
+	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/ai"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/auth"
 	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/config"
 	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/db/mongodb"
 	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/handlers"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/health"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/jobs"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/metrics"
 	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/middleware"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/ratelimit"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/repository"
 	repo "github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/repository/mongodb"
 	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/services"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/shutdown"
 	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/sse"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/tenant"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/tracing"
+	_ "github.com/yasin-yalcin-dev/go-sse-ai-chat/pkg/grpcjson" // Registers the optional JSON codec of the gRPC API
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/pkg/logger"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"google.golang.org/grpc"
 )
 
-// setupRouter configures the Gin router with routes and middleware
-func setupRouter(cfg *config.Config) *gin.Engine {
+// components are the parts of the server that shutdown stops, in order
+type components struct {
+	readiness   *health.Registry // Fails once the server drains
+	broker      *sse.Broker      // Asks clients to reconnect, then closes them
+	stopBroker  context.CancelFunc
+	generations *shutdown.Tracker // In-flight generations shutdown waits for
+	jobs        *jobs.Queue       // Returns running jobs to the queue once stopped
+	stopJobs    context.CancelFunc
+	stopSweeper context.CancelFunc // Stops marking orphaned generations as interrupted
+	sweeperDone <-chan struct{}
+	grpc        *grpc.Server // Nil when the gRPC API is disabled
+	metrics     *http.Server // Nil when metrics are disabled
+	db          *mongodb.DBConnection
+}
+
+// setupRouter configures the Gin router with routes and middleware.
+// Components with reloadable settings subscribe to reloader.
+// It also returns the components shutdown has to stop.
+func setupRouter(reloader *config.Reloader) (*gin.Engine, *components) {
+	cfg := reloader.Current()
+
 	// Create default gin router with Logger and Recovery middleware
 	router := gin.Default()
 
+	// Only believe X-Forwarded-For from known proxies, since the client IP
+	// keys rate limits and stream slots
+	if err := router.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		log.Fatalf("Failed to set trusted proxies: %v", err)
+	}
+
+	// Start a server span per request, continuing the caller's trace if any
+	router.Use(otelgin.Middleware(cfg.Tracing.ServiceName, otelgin.WithGinFilter(func(c *gin.Context) bool {
+		return !strings.HasPrefix(c.FullPath(), "/system/")
+	})))
+
 	// Add custom middleware
-	router.Use(middleware.CORSMiddleware(cfg.Server.AllowedOrigins))
+	router.Use(middleware.RequestIDMiddleware())
+	router.Use(middleware.LogFieldsMiddleware(requestLogFields))
+	router.Use(middleware.CORSMiddleware(cfg.Server.AllowedOrigins, cfg.Tenancy.Header, middleware.RequestIDHeader, handlers.ChatIDHeader))
 	router.Use(middleware.LoggerMiddleware())
 	router.Use(middleware.ErrorHandlerMiddleware())
 	// Initialize database connection
@@ -37,69 +86,475 @@ func setupRouter(cfg *config.Config) *gin.Engine {
 	if err != nil {
 		log.Fatalf("Failed to connect to MongoDB: %v", err)
 	}
-	// Initialize handlers
-	systemHandler := handlers.NewSystemHandler(cfg)
-
 	// Initialize repositories
 	chatRepo := repo.NewChatRepository(db)
 	messageRepo := repo.NewMessageRepository(db)
+	folderRepo := repo.NewFolderRepository(db)
+	apiKeyRepo := repo.NewAPIKeyRepository(db)
+	inviteRepo := repo.NewInviteRepository(db)
+	generationRepo := repo.NewGenerationRepository(db)
+	jobRepo := repo.NewJobRepository(db)
+	webhookRepo := repo.NewWebhookRepository(db)
+	deliveryRepo := repo.NewWebhookDeliveryRepository(db)
+	usageRepo := repo.NewUsageRepository(db)
 
-	// Initialize services
-	chatService := services.NewChatService(chatRepo, messageRepo)
-	messageService := services.NewMessageService(messageRepo, chatRepo)
+	// Initialize authentication
+	authenticator := setupAuthenticator(cfg, apiKeyRepo)
+	authMiddleware, streamAuthMiddleware, streamTokens := setupAuth(cfg, authenticator)
+	admins := middleware.NewAdmins(cfg.Auth.AdminSubjects)
+
+	// Resolve the tenant of each request once it is authenticated
+	tenantMiddleware := middleware.TenantMiddleware(cfg.Tenancy.Header, admins)
+	authMiddleware = append(authMiddleware, tenantMiddleware)
+	streamAuthMiddleware = append(streamAuthMiddleware, tenantMiddleware)
+	tenants := setupTenancy(cfg)
+	reloader.OnReload(func(cfg *config.Config) {
+		tenants.SetDefaultAIProvider(cfg.AIProvider)
+	})
+
+	// Rate limit requests once the principal and tenant are known. The
+	// stream limit counts the streams of both the HTTP and the gRPC API.
+	var rules *ratelimit.RuleSet
+	var streamLimiter *ratelimit.ConcurrencyLimiter
+	if cfg.RateLimit.Enabled {
+		rules = ratelimit.NewRuleSet(cfg.RateLimit)
+		reloader.OnReload(func(cfg *config.Config) {
+			rules.Update(cfg.RateLimit)
+		})
+		streamLimiter = ratelimit.NewConcurrencyLimiter(cfg.RateLimit.MaxStreamsPerPrincipal)
+
+		rateLimit := middleware.RateLimitMiddleware(rules, rateLimitClass)
+		authMiddleware = append(authMiddleware, rateLimit)
+		streamAuthMiddleware = append(streamAuthMiddleware, rateLimit, middleware.StreamLimitMiddleware(streamLimiter))
+	}
 
 	// Initialize SSE broker
 	broker := sse.NewBroker(cfg.SSE.MaxClients, cfg.SSE.KeepaliveInterval)
+	reloader.OnReload(func(cfg *config.Config) {
+		broker.Configure(cfg.SSE.MaxClients, cfg.SSE.KeepaliveInterval)
+	})
+
+	// Start broker in a goroutine; shutdown stops it once clients were drained
+	brokerCtx, stopBroker := context.WithCancel(context.Background())
+	go broker.Start(brokerCtx)
 
-	// Start broker in a goroutine
-	go broker.Start(context.Background())
+	// Health checks
+	liveness, readiness := setupHealth(cfg, db, broker, tenants)
+	systemHandler := handlers.NewSystemHandler(cfg, liveness, readiness)
+
+	// Prometheus metrics, served on a listener of their own so that they are
+	// not reachable by API clients
+	var metricsServer *http.Server
+	if cfg.Metrics.Enabled {
+		metrics.RegisterSSEClients(broker.GetClientsPerTenant)
+		mux := http.NewServeMux()
+		mux.Handle(cfg.Metrics.Path, metrics.Handler())
+		metricsServer = &http.Server{
+			Addr:              fmt.Sprintf(":%d", cfg.Metrics.Port),
+			Handler:           mux,
+			ReadHeaderTimeout: cfg.Server.ReadTimeout,
+		}
+	}
+
+	// Background jobs; shutdown stops the worker after generations drained
+	queue := jobs.NewQueue(jobRepo, cfg.Jobs)
+
+	// Events go to SSE clients and to the webhooks subscribed to them
+	publisher := services.NewWebhookPublisher(broker, webhookRepo, chatRepo, queue, cfg.Webhooks.MaxAttempts)
+	queue.Register(services.JobWebhookDelivery, services.NewWebhookDeliveryHandler(webhookRepo, deliveryRepo, cfg.Webhooks))
+
+	// Initialize services
+	chatService := services.NewChatService(chatRepo, messageRepo, folderRepo, db, publisher, tenants)
+	messageService := services.NewMessageService(messageRepo, chatRepo, usageRepo, db, publisher, tenants)
+	folderService := services.NewFolderService(folderRepo, chatRepo, publisher)
+	trashService := services.NewTrashService(chatRepo, messageRepo, db)
+	shareService := services.NewShareService(chatRepo, inviteRepo, publisher)
+	webhookService := services.NewWebhookService(webhookRepo, deliveryRepo)
+
+	// AI providers read each tenant's settings per request, so reloads apply at once
+	aiProviders := ai.NewProviders(tenants.AIProvider, tracing.NewHTTPClient(&http.Client{}))
+
+	// Generations register with the tracker, so that shutdown waits for them,
+	// and interrupted ones are continued with the tenant's AI provider
+	generationTracker := shutdown.NewTracker()
+	generationService := services.NewGenerationService(generationRepo, chatRepo, messageRepo, db, publisher, generationTracker, services.NewAIGenerator(messageRepo, aiProviders), cfg.Generation.CheckpointInterval)
+	completionService := services.NewCompletionService(aiProviders, messageService, generationService, messageRepo, usageRepo, tenants, generationTracker)
+
+	// Purge expired trash on a schedule
+	if cfg.Trash.RetentionDays > 0 {
+		queue.Register(services.JobTrashPurge, services.NewTrashPurgeHandler(trashService, cfg.Trash.Retention()))
+		if err := queue.Schedule("trash-purge", fmt.Sprintf("@every %s", cfg.Trash.PurgeInterval), services.JobTrashPurge, nil); err != nil {
+			log.Fatalf("Failed to schedule trash purge: %v", err)
+		}
+	}
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	go queue.Run(jobsCtx)
+
+	// Mark generations whose process died as interrupted; shutdown stops the
+	// sweeper before the database disconnects
+	sweeperCtx, stopSweeper := context.WithCancel(context.Background())
+	sweeperDone := make(chan struct{})
+	go func() {
+		defer close(sweeperDone)
+		services.RunGenerationSweeper(sweeperCtx, generationService, cfg.Generation.CheckpointInterval, cfg.Generation.OrphanTimeout)
+	}()
 
 	// Initialize handlers
-	handler := handlers.NewHandler(chatService, messageService)
-	sseHandler := handlers.NewSSEHandler(broker, chatService)
+	handler := handlers.NewHandler(chatService, messageService, folderService, trashService, shareService, generationService, webhookService)
+	sseHandler := handlers.NewSSEHandler(broker, chatService, generationService, streamTokens)
+	webSocketHandler := handlers.NewWebSocketHandler(broker, chatService, messageService, generationService, rules, cfg.SSE.WriteTimeout)
+	pollHandler := handlers.NewPollHandler(broker, chatService, cfg.SSE.PollMaxTimeout)
+	jobHandler := handlers.NewJobHandler(queue)
+	completionHandler := handlers.NewCompletionHandler(completionService, cfg.AIProvider.Timeout)
 
-	apiV1 := router.Group("/api/v1")
+	// gRPC API, served next to the HTTP API by the same services
+	var grpcServer *grpc.Server
+	if cfg.GRPC.Enabled {
+		grpcHandler := handlers.NewGRPCHandler(broker, chatService, messageService, generationService)
+		grpcServer = setupGRPC(cfg, authenticator, admins, rules, streamLimiter, grpcHandler)
+	}
+
+	// API routes
+	openAPIHandler := handlers.NewOpenAPIHandler()
+	var adminMiddleware []gin.HandlerFunc
+	if cfg.Auth.Enabled {
+		adminMiddleware = append(adminMiddleware, middleware.AdminMiddleware(admins))
+	}
+	registerRoutes(router, routeTable{
+		api:          handler,
+		sse:          sseHandler,
+		webSocket:    webSocketHandler,
+		poll:         pollHandler,
+		jobs:         jobHandler,
+		completion:   completionHandler,
+		system:       systemHandler,
+		openAPI:      openAPIHandler,
+		auth:         authMiddleware,
+		streamAuth:   streamAuthMiddleware,
+		admin:        adminMiddleware,
+		streamTokens: streamTokens != nil,
+	})
+
+	// The OpenAPI document describes the routes this server registered
+	if err := openAPIHandler.Build(router.Routes()); err != nil {
+		log.Fatalf("Failed to generate the OpenAPI document: %v", err)
+	}
+
+	// Serve static files
+	router.Static("/static", "./static")
+	router.StaticFile("/", "./static/index.html")
+
+	return router, &components{
+		readiness:   readiness,
+		broker:      broker,
+		stopBroker:  stopBroker,
+		generations: generationTracker,
+		jobs:        queue,
+		stopJobs:    stopJobs,
+		stopSweeper: stopSweeper,
+		sweeperDone: sweeperDone,
+		grpc:        grpcServer,
+		metrics:     metricsServer,
+		db:          db,
+	}
+}
+
+// routeTable holds the handlers and middleware of the API routes
+type routeTable struct {
+	api        *handlers.Handler
+	sse        *handlers.SSEHandler
+	webSocket  *handlers.WebSocketHandler
+	poll       *handlers.PollHandler
+	jobs       *handlers.JobHandler
+	completion *handlers.CompletionHandler
+	system     *handlers.SystemHandler
+	openAPI    *handlers.OpenAPIHandler
+
+	auth       []gin.HandlerFunc // Authenticates, resolves the tenant and rate limits API requests
+	streamAuth []gin.HandlerFunc // Does the same for streams, which also accept stream tokens
+	admin      []gin.HandlerFunc // Restricts admin routes to admins
+
+	streamTokens bool // Whether stream tokens are issued
+}
+
+// registerRoutes registers the API routes. The OpenAPI document is generated
+// from the routes registered here, so every route needs its documentation in
+// the handlers package.
+func registerRoutes(router *gin.Engine, table routeTable) {
+	// Streams stay open while the client listens; keep them out of the latency metric
+	stream := append(gin.HandlersChain{middleware.LongLived()}, table.streamAuth...)
+
+	// SSE streaming route; EventSource cannot set headers, so it also accepts a stream token
+	router.GET("/api/v1/chats/:id/stream", append(stream, table.sse.HandleStream)...)
+
+	// WebSocket route; browsers cannot set headers on it either
+	router.GET("/api/v1/chats/:id/ws", append(stream, table.webSocket.HandleWebSocket)...)
+
+	// Long-polling route, for networks that cut long responses
+	router.GET("/api/v1/chats/:id/events", append(stream, table.poll.HandlePoll)...)
+
+	// OpenAI-compatible completion route, at the path OpenAI SDKs expect
+	router.POST("/v1/chat/completions", append(table.auth, table.completion.CreateChatCompletion)...)
+
+	// OpenAPI document, open so that tools can fetch it before authenticating
+	router.GET("/api/v1/openapi.json", table.openAPI.GetSpec)
+
+	apiV1 := router.Group("/api/v1", table.auth...)
 	{
 		// Chat routes
 		chats := apiV1.Group("/chats")
 		{
-			chats.GET("", handler.ListChats)
-			chats.POST("", handler.CreateChat)
-			chats.GET("/:id", handler.GetChat)
-			chats.PUT("/:id", handler.UpdateChat)
-			chats.DELETE("/:id", handler.DeleteChat)
+			chats.GET("", table.api.ListChats)
+			chats.POST("", table.api.CreateChat)
+			chats.GET("/:id", table.api.GetChat)
+			chats.PUT("/:id", table.api.UpdateChat)
+			chats.DELETE("/:id", table.api.DeleteChat)
+			chats.POST("/:id/archive", table.api.ArchiveChat)
+			chats.POST("/:id/unarchive", table.api.UnarchiveChat)
+			chats.POST("/:id/restore", table.api.RestoreChat)
+
+			// Bulk organization routes
+			chats.POST("/bulk/move", table.api.MoveChats)
+			chats.POST("/bulk/tags", table.api.UpdateChatTags)
+
+			// Sharing routes
+			chats.GET("/:id/members", table.api.ListMembers)
+			chats.PUT("/:id/members/:subject", table.api.UpdateMember)
+			chats.DELETE("/:id/members/:subject", table.api.RemoveMember)
+			chats.GET("/:id/invites", table.api.ListInvites)
+			chats.POST("/:id/invites", table.api.CreateInvite)
+			chats.DELETE("/:id/invites/:invite_id", table.api.RevokeInvite)
 
 			// Message routes (nested under chat)
-			chats.GET("/:id/messages", handler.GetMessages)
-			chats.POST("/:id/messages", handler.CreateMessage)
+			chats.GET("/:id/messages", table.api.GetMessages)
+			chats.POST("/:id/messages", table.api.CreateMessage)
+
+			// Generation routes (nested under chat)
+			chats.GET("/:id/generations", table.api.ListGenerations)
+
+			// SSE stream token route
+			if table.streamTokens {
+				chats.POST("/:id/stream-token", table.sse.IssueStreamToken)
+			}
+		}
 
-			// SSE streaming route
-			chats.GET("/:id/stream", sseHandler.HandleStream)
+		// Folder routes
+		folders := apiV1.Group("/folders")
+		{
+			folders.GET("", table.api.ListFolders)
+			folders.POST("", table.api.CreateFolder)
+			folders.GET("/:id", table.api.GetFolder)
+			folders.PUT("/:id", table.api.UpdateFolder)
+			folders.DELETE("/:id", table.api.DeleteFolder)
 		}
 
 		// Individual message routes
 		messages := apiV1.Group("/messages")
 		{
-			messages.GET("/:id", handler.GetMessage)
-			messages.DELETE("/:id", handler.DeleteMessage)
+			messages.GET("/:id", table.api.GetMessage)
+			messages.DELETE("/:id", table.api.DeleteMessage)
+			messages.POST("/:id/restore", table.api.RestoreMessage)
+		}
+
+		// Individual generation routes
+		generations := apiV1.Group("/generations")
+		{
+			generations.GET("/:id", table.api.GetGeneration)
+			generations.POST("/:id/continue", table.api.ContinueGeneration)
+			generations.POST("/:id/discard", table.api.DiscardGeneration)
+			generations.POST("/:id/cancel", table.api.CancelGeneration)
+		}
+
+		// Webhook routes
+		webhooks := apiV1.Group("/webhooks")
+		{
+			webhooks.GET("", table.api.ListWebhooks)
+			webhooks.POST("", table.api.CreateWebhook)
+			webhooks.GET("/:id", table.api.GetWebhook)
+			webhooks.PUT("/:id", table.api.UpdateWebhook)
+			webhooks.DELETE("/:id", table.api.DeleteWebhook)
+			webhooks.POST("/:id/rotate-secret", table.api.RotateWebhookSecret)
+			webhooks.GET("/:id/deliveries", table.api.ListWebhookDeliveries)
 		}
+
+		// Invite acceptance
+		apiV1.POST("/invites/:code/accept", table.api.AcceptInvite)
+
+		// Trash listing
+		apiV1.GET("/trash", table.api.ListTrash)
 		// SSE stats (for monitoring)
 		sse := apiV1.Group("/sse")
 		{
-			sse.GET("/stats", sseHandler.GetStats)
+			sse.GET("/stats", table.sse.GetStats)
+		}
+
+		// Admin routes, which act across tenants
+		admin := apiV1.Group("/admin", table.admin...)
+		{
+			admin.GET("/jobs", table.jobs.ListJobs)
+			admin.GET("/jobs/:id", table.jobs.GetJob)
+			admin.POST("/jobs/:id/retry", table.jobs.RetryJob)
 		}
 	}
 
 	// System routes (outside of versioned API)
 	systemRoutes := router.Group("/system")
 	{
-		systemRoutes.GET("/health", systemHandler.HealthCheck)
-		systemRoutes.GET("/version", systemHandler.Version)
+		systemRoutes.GET("/health", table.system.HealthCheck)
+		systemRoutes.GET("/live", table.system.Live)
+		systemRoutes.GET("/ready", table.system.Ready)
+		systemRoutes.GET("/version", table.system.Version)
 	}
+}
 
-	// Serve static files
-	router.Static("/static", "./static")
-	router.StaticFile("/", "./static/index.html")
+// setupAuthenticator builds the authenticator checking API keys and, when
+// configured, JWTs. It is nil when authentication is disabled.
+func setupAuthenticator(cfg *config.Config, apiKeyRepo repository.APIKeyRepository) auth.Authenticator {
+	if !cfg.Auth.Enabled {
+		logger.Warn("Authentication is disabled; all API routes are open")
+		return nil
+	}
+
+	authenticators := auth.Chain{auth.NewAPIKeyAuthenticator(apiKeyRepo)}
+
+	if cfg.Auth.JWTSecret != "" || cfg.Auth.JWKSFile != "" {
+		jwtAuthenticator, err := auth.NewJWTAuthenticator(auth.JWTConfig{
+			Secret:   cfg.Auth.JWTSecret,
+			JWKSFile: cfg.Auth.JWKSFile,
+			Issuer:   cfg.Auth.JWTIssuer,
+			Audience: cfg.Auth.JWTAudience,
+		})
+		if err != nil {
+			log.Fatalf("Failed to initialize JWT authentication: %v", err)
+		}
+		authenticators = append(authenticators, jwtAuthenticator)
+	}
+
+	return authenticators
+}
+
+// setupAuth builds the authentication middleware for API and stream routes.
+// Both are empty when authentication is disabled.
+func setupAuth(cfg *config.Config, authenticator auth.Authenticator) ([]gin.HandlerFunc, []gin.HandlerFunc, *auth.StreamTokens) {
+	if authenticator == nil {
+		return nil, nil, nil
+	}
+
+	streamTokens, err := auth.NewStreamTokens(cfg.Auth.StreamTokenSecret, cfg.Auth.StreamTokenTTL)
+	if err != nil {
+		log.Fatalf("Failed to initialize stream tokens: %v", err)
+	}
+
+	return []gin.HandlerFunc{middleware.AuthMiddleware(authenticator)},
+		[]gin.HandlerFunc{middleware.StreamAuthMiddleware(authenticator, streamTokens)},
+		streamTokens
+}
+
+// setupGRPC builds the gRPC server. Calls are authenticated, assigned a
+// tenant and rate limited like HTTP requests; rules and streams are nil when
+// rate limiting is disabled.
+func setupGRPC(cfg *config.Config, authenticator auth.Authenticator, admins middleware.Admins, rules *ratelimit.RuleSet, streams *ratelimit.ConcurrencyLimiter, handler *handlers.GRPCHandler) *grpc.Server {
+	requestUnary, requestStream := middleware.GRPCRequestInterceptors()
+	authUnary, authStream := middleware.GRPCAuthInterceptors(authenticator, cfg.Tenancy.Header, admins)
+	unary := []grpc.UnaryServerInterceptor{requestUnary, authUnary}
+	stream := []grpc.StreamServerInterceptor{requestStream, authStream}
+
+	if rules != nil {
+		rateLimitUnary, rateLimitStream := middleware.GRPCRateLimitInterceptors(rules, grpcRateLimitClass, streams)
+		unary = append(unary, rateLimitUnary)
+		stream = append(stream, rateLimitStream)
+	}
+
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(unary...),
+		grpc.ChainStreamInterceptor(stream...),
+	)
+	handlers.RegisterGRPCServices(server, handler)
+	return server
+}
+
+// rateLimitClass returns the rate limit class of a request
+func rateLimitClass(c *gin.Context) string {
+	switch {
+	case c.FullPath() == "/api/v1/chats/:id/stream", c.FullPath() == "/api/v1/chats/:id/ws", c.FullPath() == "/api/v1/chats/:id/events":
+		return ratelimit.ClassStream
+	case c.Request.Method == http.MethodPost && (c.FullPath() == "/api/v1/chats/:id/messages" || c.FullPath() == "/v1/chat/completions"):
+		return ratelimit.ClassMessages
+	default:
+		return ratelimit.ClassDefault
+	}
+}
+
+// grpcRateLimitClass returns the rate limit class of a gRPC method
+func grpcRateLimitClass(method string) string {
+	switch method {
+	case handlers.GRPCMethodSubscribe:
+		return ratelimit.ClassStream
+	case handlers.GRPCMethodCreateMessage:
+		return ratelimit.ClassMessages
+	default:
+		return ratelimit.ClassDefault
+	}
+}
+
+// setupHealth registers the liveness and readiness checks
+func setupHealth(cfg *config.Config, db *mongodb.DBConnection, broker *sse.Broker, tenants *tenant.Registry) (*health.Registry, *health.Registry) {
+	// A stuck broker loop cannot recover on its own, so it fails liveness
+	brokerCheck := health.PingChecker(broker.Ping, 0)
+	liveness := health.NewRegistry(cfg.Health.CheckTimeout)
+	liveness.Register("sse_broker", brokerCheck, true)
+
+	readiness := health.NewRegistry(cfg.Health.CheckTimeout)
+	readiness.Register("mongodb", health.PingChecker(db.Ping, cfg.Health.DBSlowThreshold), true)
+	readiness.Register("sse_broker", brokerCheck, true)
+	readiness.Register("replay_store", health.CheckerFunc(func(ctx context.Context) health.Result {
+		stats := broker.ReplayStoreStats()
+		result := health.Result{
+			Status: health.StatusUp,
+			Details: map[string]interface{}{
+				"chats":    stats.Chats,
+				"messages": stats.Messages,
+				"bytes":    stats.Bytes,
+			},
+		}
+		if stats.Bytes > cfg.Health.ReplayStoreMaxBytes {
+			result.Status = health.StatusDegraded
+			result.Message = "replay store is above its size limit"
+		}
+		return result
+	}), false)
+
+	// Provider outages degrade the service but do not take it out of rotation
+	if cfg.Health.AIProbeEnabled {
+		client := tracing.NewHTTPClient(&http.Client{Timeout: cfg.Health.CheckTimeout})
+		readiness.Register("ai_provider", health.Cached(health.AIProviderChecker(tenants.DefaultAIProvider, client), cfg.Health.AIProbeTTL), false)
+	}
+
+	return liveness, readiness
+}
+
+// requestLogFields returns the log fields identifying the resource of a request
+func requestLogFields(c *gin.Context) []interface{} {
+	if strings.HasPrefix(c.FullPath(), "/api/v1/chats/:id") {
+		return []interface{}{logger.FieldChatID, c.Param("id")}
+	}
+	return nil
+}
+
+// setupTenancy builds the registry of per-tenant provider settings and quotas
+func setupTenancy(cfg *config.Config) *tenant.Registry {
+	var overrides map[string]tenant.Override
+	if cfg.Tenancy.ConfigFile != "" {
+		var err error
+		overrides, err = tenant.LoadOverrides(cfg.Tenancy.ConfigFile)
+		if err != nil {
+			log.Fatalf("Failed to load tenant configuration: %v", err)
+		}
+		logger.Infof("Loaded overrides for %d tenants", len(overrides))
+	}
 
-	return router
+	return tenant.NewRegistry(cfg.AIProvider, tenant.Quotas{
+		MaxChats:          cfg.Tenancy.MaxChats,
+		MaxMessagesPerDay: cfg.Tenancy.MaxMessagesPerDay,
+	}, overrides)
 }
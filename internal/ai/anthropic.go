@@ -0,0 +1,166 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Messages endpoint of Anthropic and the API version it is called with
+const (
+	anthropicMessagesURL = "https://api.anthropic.com/v1/messages"
+	anthropicVersion     = "2023-06-01"
+)
+
+// anthropicProvider streams completions from the Anthropic messages API
+type anthropicProvider struct {
+	client *http.Client
+	key    string
+	model  string
+	limits limits
+}
+
+// anthropicRequest is the body of a messages request
+type anthropicRequest struct {
+	Model         string             `json:"model"`
+	System        string             `json:"system,omitempty"`
+	Messages      []anthropicMessage `json:"messages"`
+	MaxTokens     int                `json:"max_tokens"`
+	Temperature   *float64           `json:"temperature,omitempty"`
+	TopP          *float64           `json:"top_p,omitempty"`
+	StopSequences []string           `json:"stop_sequences,omitempty"`
+	Stream        bool               `json:"stream"`
+}
+
+// anthropicMessage is a message of a messages request
+type anthropicMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// anthropicEvent is an event of a streamed message. Each event type fills
+// in a different subset of the fields.
+type anthropicEvent struct {
+	Type    string `json:"type"`
+	Message struct {
+		Model string `json:"model"`
+		Usage struct {
+			InputTokens int `json:"input_tokens"`
+		} `json:"usage"`
+	} `json:"message"`
+	Delta struct {
+		Type       string `json:"type"`
+		Text       string `json:"text"`
+		StopReason string `json:"stop_reason"`
+	} `json:"delta"`
+	Usage struct {
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"`
+	Error struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// Name implements Provider
+func (p *anthropicProvider) Name() string {
+	return ProviderAnthropic
+}
+
+// DefaultModel implements Provider
+func (p *anthropicProvider) DefaultModel() string {
+	return p.model
+}
+
+// Stream implements Provider. System messages become the system prompt; a
+// final assistant message is continued rather than answered.
+func (p *anthropicProvider) Stream(ctx context.Context, req Request, emit func(chunk string) error) (*Result, error) {
+	ctx, cancel, maxTokens := p.limits.apply(ctx, req)
+	defer cancel()
+
+	body := anthropicRequest{
+		Model:         req.Model,
+		MaxTokens:     maxTokens,
+		Temperature:   req.Temperature,
+		TopP:          req.TopP,
+		StopSequences: req.Stop,
+		Stream:        true,
+	}
+	if body.Model == "" {
+		body.Model = p.model
+	}
+
+	var system []string
+	for _, message := range req.Messages {
+		if message.Role == RoleSystem {
+			system = append(system, message.Content)
+			continue
+		}
+		body.Messages = append(body.Messages, anthropicMessage{Role: message.Role, Content: message.Content})
+	}
+	body.System = strings.Join(system, "\n\n")
+
+	// The API rejects a final assistant message that ends in whitespace
+	if last := len(body.Messages) - 1; last >= 0 && body.Messages[last].Role == RoleAssistant {
+		body.Messages[last].Content = strings.TrimRight(body.Messages[last].Content, " \t\r\n")
+	}
+
+	header := http.Header{}
+	header.Set("x-api-key", p.key)
+	header.Set("anthropic-version", anthropicVersion)
+
+	resp, err := post(ctx, p.client, ProviderAnthropic, anthropicMessagesURL, header, body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	result := &Result{Model: body.Model, FinishReason: FinishStop}
+	err = readEvents(resp.Body, func(_, data string) error {
+		var event anthropicEvent
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			return fmt.Errorf("invalid stream event from Anthropic: %w", err)
+		}
+
+		switch event.Type {
+		case "message_start":
+			if event.Message.Model != "" {
+				result.Model = event.Message.Model
+			}
+			result.Usage.PromptTokens = event.Message.Usage.InputTokens
+
+		case "content_block_delta":
+			if event.Delta.Type == "text_delta" && event.Delta.Text != "" {
+				return emit(event.Delta.Text)
+			}
+
+		case "message_delta":
+			result.Usage.CompletionTokens = event.Usage.OutputTokens
+			if event.Delta.StopReason == "max_tokens" {
+				result.FinishReason = FinishLength
+			}
+
+		case "message_stop":
+			return errStreamDone
+
+		case "error":
+			return &Error{Provider: ProviderAnthropic, StatusCode: http.StatusBadGateway, Message: event.Error.Message}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
@@ -0,0 +1,150 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// openAIChatURL is the chat completions endpoint of OpenAI
+const openAIChatURL = "https://api.openai.com/v1/chat/completions"
+
+// openAIProvider streams completions from the OpenAI chat completions API
+type openAIProvider struct {
+	client *http.Client
+	key    string
+	model  string
+	limits limits
+}
+
+// openAIRequest is the body of a chat completions request
+type openAIRequest struct {
+	Model               string              `json:"model"`
+	Messages            []openAIMessage     `json:"messages"`
+	MaxCompletionTokens int                 `json:"max_completion_tokens,omitempty"`
+	Temperature         *float64            `json:"temperature,omitempty"`
+	TopP                *float64            `json:"top_p,omitempty"`
+	Stop                []string            `json:"stop,omitempty"`
+	Stream              bool                `json:"stream"`
+	StreamOptions       openAIStreamOptions `json:"stream_options"`
+}
+
+// openAIMessage is a message of a chat completions request
+type openAIMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// openAIStreamOptions asks for the usage of a streamed completion
+type openAIStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+// openAIChunk is an event of a streamed completion
+type openAIChunk struct {
+	Model   string `json:"model"`
+	Choices []struct {
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+		FinishReason *string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// Name implements Provider
+func (p *openAIProvider) Name() string {
+	return ProviderOpenAI
+}
+
+// DefaultModel implements Provider
+func (p *openAIProvider) DefaultModel() string {
+	return p.model
+}
+
+// Stream implements Provider
+func (p *openAIProvider) Stream(ctx context.Context, req Request, emit func(chunk string) error) (*Result, error) {
+	ctx, cancel, maxTokens := p.limits.apply(ctx, req)
+	defer cancel()
+
+	body := openAIRequest{
+		Model:               req.Model,
+		Messages:            make([]openAIMessage, len(req.Messages)),
+		MaxCompletionTokens: maxTokens,
+		Temperature:         req.Temperature,
+		TopP:                req.TopP,
+		Stop:                req.Stop,
+		Stream:              true,
+		StreamOptions:       openAIStreamOptions{IncludeUsage: true},
+	}
+	if body.Model == "" {
+		body.Model = p.model
+	}
+	for i, message := range req.Messages {
+		body.Messages[i] = openAIMessage{Role: message.Role, Content: message.Content}
+	}
+
+	header := http.Header{}
+	header.Set("Authorization", "Bearer "+p.key)
+
+	resp, err := post(ctx, p.client, ProviderOpenAI, openAIChatURL, header, body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	result := &Result{Model: body.Model, FinishReason: FinishStop}
+	err = readEvents(resp.Body, func(_, data string) error {
+		if data == "[DONE]" {
+			return errStreamDone
+		}
+
+		var chunk openAIChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return fmt.Errorf("invalid stream event from OpenAI: %w", err)
+		}
+		if chunk.Error != nil {
+			return &Error{Provider: ProviderOpenAI, StatusCode: http.StatusBadGateway, Message: chunk.Error.Message}
+		}
+
+		if chunk.Model != "" {
+			result.Model = chunk.Model
+		}
+		if chunk.Usage != nil {
+			result.Usage = Usage{PromptTokens: chunk.Usage.PromptTokens, CompletionTokens: chunk.Usage.CompletionTokens}
+		}
+
+		for _, choice := range chunk.Choices {
+			if choice.FinishReason != nil {
+				result.FinishReason = *choice.FinishReason
+			}
+			if choice.Delta.Content != "" {
+				if err := emit(choice.Delta.Content); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
@@ -0,0 +1,185 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package ai
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/config"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/metrics"
+)
+
+// Names of the supported providers
+const (
+	ProviderOpenAI    = "openai"
+	ProviderAnthropic = "anthropic"
+)
+
+// Roles of the messages of a conversation
+const (
+	RoleSystem    = "system"
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+)
+
+// Finish reasons of a completion, as reported by the OpenAI API
+const (
+	FinishStop   = "stop"
+	FinishLength = "length"
+)
+
+// Message is a message of the conversation a completion continues
+type Message struct {
+	Role    string
+	Content string
+}
+
+// Request describes a completion to generate
+type Request struct {
+	Model       string // Empty uses the provider's configured model
+	Messages    []Message
+	MaxTokens   int // Zero or more than the configured limit uses the limit
+	Temperature *float64
+	TopP        *float64
+	Stop        []string
+}
+
+// Usage counts the tokens of a completion
+type Usage struct {
+	PromptTokens     int
+	CompletionTokens int
+}
+
+// TotalTokens returns the number of prompt and completion tokens
+func (u Usage) TotalTokens() int {
+	return u.PromptTokens + u.CompletionTokens
+}
+
+// Result describes a finished completion
+type Result struct {
+	Model        string // Model that produced the completion
+	FinishReason string
+	Usage        Usage
+}
+
+// Provider streams completions from an AI provider
+type Provider interface {
+	// Name returns the name of the provider, such as ProviderOpenAI
+	Name() string
+	// DefaultModel returns the model used by requests that do not name one
+	DefaultModel() string
+	// Stream generates a completion of req, passing each chunk of output to emit as it arrives.
+	// An error returned by emit stops the completion.
+	Stream(ctx context.Context, req Request, emit func(chunk string) error) (*Result, error)
+}
+
+// Error is returned when a provider rejects a request
+type Error struct {
+	Provider   string
+	StatusCode int
+	Message    string
+}
+
+// Error implements the error interface
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s returned status %d: %s", e.Provider, e.StatusCode, e.Message)
+}
+
+// Providers builds the provider of each tenant from its current settings
+type Providers struct {
+	settings func(tenantID string) config.AIProviderConfig
+	client   *http.Client
+}
+
+// NewProviders creates providers that read the settings of a tenant from
+// settings on every call, so that reloaded settings apply to the next request
+func NewProviders(settings func(tenantID string) config.AIProviderConfig, client *http.Client) *Providers {
+	return &Providers{settings: settings, client: client}
+}
+
+// Provider returns the provider of a tenant. name selects a provider other
+// than the tenant's configured one, with the tenant's credentials for it;
+// empty uses the configured one.
+func (p *Providers) Provider(tenantID, name string) (Provider, error) {
+	cfg := p.settings(tenantID)
+	if name != "" {
+		cfg.Provider = name
+	}
+
+	limits := limits{timeout: cfg.Timeout, maxTokens: cfg.MaxTokens}
+
+	switch cfg.Provider {
+	case ProviderOpenAI:
+		if cfg.OpenAIKey == "" {
+			return nil, fmt.Errorf("no OpenAI API key is configured")
+		}
+		return instrumented{&openAIProvider{client: p.client, key: cfg.OpenAIKey, model: cfg.OpenAIModel, limits: limits}}, nil
+
+	case ProviderAnthropic:
+		if cfg.AnthropicKey == "" {
+			return nil, fmt.Errorf("no Anthropic API key is configured")
+		}
+		return instrumented{&anthropicProvider{client: p.client, key: cfg.AnthropicKey, model: cfg.AnthropicModel, limits: limits}}, nil
+
+	default:
+		return nil, fmt.Errorf("unknown AI provider %q", cfg.Provider)
+	}
+}
+
+// limits bounds the requests of a provider
+type limits struct {
+	timeout   time.Duration
+	maxTokens int
+}
+
+// apply bounds ctx by the request timeout and returns the number of tokens req may produce
+func (l limits) apply(ctx context.Context, req Request) (context.Context, context.CancelFunc, int) {
+	maxTokens := req.MaxTokens
+	if l.maxTokens > 0 && (maxTokens <= 0 || maxTokens > l.maxTokens) {
+		maxTokens = l.maxTokens
+	}
+
+	if l.timeout <= 0 {
+		ctx, cancel := context.WithCancel(ctx)
+		return ctx, cancel, maxTokens
+	}
+	ctx, cancel := context.WithTimeout(ctx, l.timeout)
+	return ctx, cancel, maxTokens
+}
+
+// instrumented records the metrics of each completion of a provider
+type instrumented struct {
+	Provider
+}
+
+// Stream implements Provider
+func (p instrumented) Stream(ctx context.Context, req Request, emit func(chunk string) error) (*Result, error) {
+	start := time.Now()
+	var ttft time.Duration
+
+	result, err := p.Provider.Stream(ctx, req, func(chunk string) error {
+		if ttft == 0 {
+			ttft = time.Since(start)
+		}
+		return emit(chunk)
+	})
+
+	outputTokens := 0
+	if result != nil {
+		outputTokens = result.Usage.CompletionTokens
+		metrics.AddAITokens(p.Name(), result.Usage.PromptTokens, result.Usage.CompletionTokens)
+	}
+	metrics.ObserveAIGeneration(p.Name(), ttft, time.Since(start), outputTokens, err)
+
+	return result, err
+}
@@ -0,0 +1,139 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package ai
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Limits on reading provider responses
+const (
+	maxEventSize     = 1 << 20  // Largest Server-Sent Event of a stream
+	maxErrorBodySize = 64 << 10 // Largest error body read for its message
+)
+
+// errStreamDone stops reading a stream once the provider marked its end
+var errStreamDone = errors.New("stream done")
+
+// post sends a JSON request to a provider and returns the response of a
+// successful one. Rejected requests return an *Error.
+func post(ctx context.Context, client *http.Client, provider, url string, header http.Header, body interface{}) (*http.Response, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header = header
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		return nil, &Error{Provider: provider, StatusCode: resp.StatusCode, Message: errorMessage(resp.Body)}
+	}
+
+	return resp, nil
+}
+
+// errorMessage extracts the message of an error response. Both providers
+// report errors as {"error": {"message": ...}}.
+func errorMessage(body io.Reader) string {
+	data, _ := io.ReadAll(io.LimitReader(body, maxErrorBodySize))
+
+	var response struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(data, &response); err == nil && response.Error.Message != "" {
+		return response.Error.Message
+	}
+
+	return strings.TrimSpace(string(data))
+}
+
+// readEvents passes the event name and data of each Server-Sent Event in body
+// to handle. Reading stops when handle returns an error; errStreamDone ends
+// the stream successfully. A stream that ends before errStreamDone was cut
+// off and returns io.ErrUnexpectedEOF.
+func readEvents(body io.Reader, handle func(event, data string) error) error {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64<<10), maxEventSize)
+
+	var event string
+	var data strings.Builder
+
+	dispatch := func() error {
+		defer func() {
+			event = ""
+			data.Reset()
+		}()
+		if data.Len() == 0 {
+			return nil
+		}
+		return handle(event, data.String())
+	}
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			if err := dispatch(); err != nil {
+				return doneOrError(err)
+			}
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			event = value
+		case "data":
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(value)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	// The last event may lack its terminating blank line
+	if err := dispatch(); err != nil {
+		return doneOrError(err)
+	}
+
+	return io.ErrUnexpectedEOF
+}
+
+// doneOrError returns nil for errStreamDone and err otherwise
+func doneOrError(err error) error {
+	if errors.Is(err, errStreamDone) {
+		return nil
+	}
+	return err
+}
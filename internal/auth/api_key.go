@@ -0,0 +1,96 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/models"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// apiKeyPrefix marks API key credentials; keys look like "ak_<key id>_<secret>"
+const apiKeyPrefix = "ak_"
+
+// apiKeySecretBytes is the amount of randomness in an API key secret
+const apiKeySecretBytes = 32
+
+// GenerateAPIKey creates a new API key record and returns it along with the
+// plaintext key. The plaintext is not stored and cannot be recovered later.
+func GenerateAPIKey(name string) (*models.APIKey, string, error) {
+	secret := make([]byte, apiKeySecretBytes)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, "", err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(secret)
+
+	key := models.NewAPIKey(name, hashSecret(encoded))
+	return key, apiKeyPrefix + key.ID.Hex() + "_" + encoded, nil
+}
+
+// hashSecret returns the stored representation of an API key secret
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// APIKeyAuthenticator authenticates hashed API keys stored in the database
+type APIKeyAuthenticator struct {
+	repo repository.APIKeyRepository
+}
+
+// NewAPIKeyAuthenticator creates a new API key authenticator
+func NewAPIKeyAuthenticator(repo repository.APIKeyRepository) *APIKeyAuthenticator {
+	return &APIKeyAuthenticator{repo: repo}
+}
+
+// Authenticate implements Authenticator
+func (a *APIKeyAuthenticator) Authenticate(ctx context.Context, credential string) (*Principal, error) {
+	if !strings.HasPrefix(credential, apiKeyPrefix) {
+		return nil, ErrUnsupportedCredential
+	}
+
+	idHex, secret, ok := strings.Cut(strings.TrimPrefix(credential, apiKeyPrefix), "_")
+	if !ok {
+		return nil, ErrInvalidCredential
+	}
+
+	id, err := primitive.ObjectIDFromHex(idHex)
+	if err != nil {
+		return nil, ErrInvalidCredential
+	}
+
+	key, err := a.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if key == nil || key.IsRevoked() {
+		return nil, ErrInvalidCredential
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashSecret(secret)), []byte(key.SecretHash)) != 1 {
+		return nil, ErrInvalidCredential
+	}
+
+	return &Principal{
+		Subject:  key.ID.Hex(),
+		Name:     key.Name,
+		Method:   MethodAPIKey,
+		TenantID: key.TenantID,
+	}, nil
+}
@@ -0,0 +1,111 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// keyStore is an in-memory APIKeyRepository
+type keyStore map[primitive.ObjectID]*models.APIKey
+
+func (s keyStore) Create(_ context.Context, key *models.APIKey) error {
+	s[key.ID] = key
+	return nil
+}
+
+func (s keyStore) FindByID(_ context.Context, id primitive.ObjectID) (*models.APIKey, error) {
+	return s[id], nil
+}
+
+func (s keyStore) FindAll(context.Context) ([]*models.APIKey, error) {
+	return nil, nil
+}
+
+func (s keyStore) Revoke(_ context.Context, id primitive.ObjectID, revokedAt time.Time) error {
+	s[id].RevokedAt = &revokedAt
+	return nil
+}
+
+// TestAPIKeyAuthenticate checks that only stored, unrevoked keys with the right secret are accepted
+func TestAPIKeyAuthenticate(t *testing.T) {
+	store := keyStore{}
+	ctx := context.Background()
+
+	newKey := func(name, tenantID string) (*models.APIKey, string) {
+		key, plaintext, err := GenerateAPIKey(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		key.TenantID = tenantID
+		_ = store.Create(ctx, key)
+		return key, plaintext
+	}
+
+	active, activePlaintext := newKey("ci", "acme")
+	revoked, revokedPlaintext := newKey("old", "")
+	_ = store.Revoke(ctx, revoked.ID, time.Now())
+
+	// A well-formed key that was never stored
+	_, unknownPlaintext, err := GenerateAPIKey("unknown")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wrongSecret := apiKeyPrefix + active.ID.Hex() + "_" + strings.Repeat("A", 43)
+
+	tests := []struct {
+		name    string
+		key     string
+		wantErr error
+	}{
+		{name: "valid", key: activePlaintext},
+		{name: "revoked", key: revokedPlaintext, wantErr: ErrInvalidCredential},
+		{name: "unknown", key: unknownPlaintext, wantErr: ErrInvalidCredential},
+		{name: "wrong secret", key: wrongSecret, wantErr: ErrInvalidCredential},
+		{name: "secret of another key", key: apiKeyPrefix + active.ID.Hex() + "_" + secretOf(revokedPlaintext), wantErr: ErrInvalidCredential},
+		{name: "without secret", key: apiKeyPrefix + active.ID.Hex(), wantErr: ErrInvalidCredential},
+		{name: "bad key ID", key: apiKeyPrefix + "nothex_secret", wantErr: ErrInvalidCredential},
+		{name: "not an API key", key: "header.payload.signature", wantErr: ErrUnsupportedCredential},
+	}
+
+	authenticator := NewAPIKeyAuthenticator(store)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			principal, err := authenticator.Authenticate(ctx, tt.key)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("Authenticate() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Authenticate() error = %v", err)
+			}
+			want := Principal{Subject: active.ID.Hex(), Name: "ci", Method: MethodAPIKey, TenantID: "acme"}
+			if *principal != want {
+				t.Errorf("Authenticate() = %+v, want %+v", *principal, want)
+			}
+		})
+	}
+}
+
+// secretOf returns the secret part of a plaintext API key
+func secretOf(plaintext string) string {
+	return strings.SplitN(plaintext, "_", 3)[2]
+}
@@ -0,0 +1,74 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package auth
+
+import (
+	"context"
+	"errors"
+)
+
+// Authentication methods
+const (
+	MethodAPIKey      = "api_key"
+	MethodJWT         = "jwt"
+	MethodStreamToken = "stream_token"
+)
+
+var (
+	// ErrUnsupportedCredential is returned when an authenticator does not handle
+	// the presented credential format, so the next authenticator should be tried
+	ErrUnsupportedCredential = errors.New("unsupported credential")
+
+	// ErrInvalidCredential is returned when a credential is malformed, expired,
+	// revoked or fails verification
+	ErrInvalidCredential = errors.New("invalid credential")
+)
+
+// Principal is the authenticated caller of a request
+type Principal struct {
+	Subject  string // Stable caller identifier: API key ID or JWT subject
+	Name     string // Human-readable name, if known
+	Method   string // How the caller authenticated
+	TenantID string // Tenant the credential is bound to; empty when it may act in any tenant
+}
+
+// Authenticator verifies a bearer credential and returns its principal
+type Authenticator interface {
+	Authenticate(ctx context.Context, credential string) (*Principal, error)
+}
+
+// Chain tries each authenticator in turn until one accepts the credential format
+type Chain []Authenticator
+
+// Authenticate implements Authenticator
+func (c Chain) Authenticate(ctx context.Context, credential string) (*Principal, error) {
+	for _, authenticator := range c {
+		principal, err := authenticator.Authenticate(ctx, credential)
+		if errors.Is(err, ErrUnsupportedCredential) {
+			continue
+		}
+		return principal, err
+	}
+	return nil, ErrUnsupportedCredential
+}
+
+// principalKey is the context key for the authenticated principal
+type principalKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying the principal
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFromContext returns the principal carried by ctx, if any
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(*Principal)
+	return principal, ok && principal != nil
+}
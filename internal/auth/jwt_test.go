@@ -0,0 +1,318 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// testSecret signs the HS256 tokens of the tests
+const testSecret = "test-secret"
+
+// testClaims returns valid access token claims for alice
+func testClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"sub": "alice",
+		"iss": "https://issuer.example",
+		"aud": "chat-api",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+}
+
+// with returns a copy of claims with the given claims replaced; nil values remove them
+func with(claims jwt.MapClaims, changes jwt.MapClaims) jwt.MapClaims {
+	out := jwt.MapClaims{}
+	for key, value := range claims {
+		out[key] = value
+	}
+	for key, value := range changes {
+		if value == nil {
+			delete(out, key)
+			continue
+		}
+		out[key] = value
+	}
+	return out
+}
+
+// signHS signs claims with a shared secret
+func signHS(t *testing.T, method jwt.SigningMethod, secret string, claims jwt.MapClaims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(method, claims).SignedString([]byte(secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+// signRS signs claims with an RSA key, naming it by kid
+func signRS(t *testing.T, key *rsa.PrivateKey, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+// TestJWTAuthenticate checks the signing methods, times and claims access tokens must have
+func TestJWTAuthenticate(t *testing.T) {
+	authenticator, err := NewJWTAuthenticator(JWTConfig{
+		Secret:   testSecret,
+		Issuer:   "https://issuer.example",
+		Audience: "chat-api",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rsaKey := newRSAKey(t)
+	now := time.Now()
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{
+			name:  "valid",
+			token: signHS(t, jwt.SigningMethodHS256, testSecret, testClaims()),
+		},
+		{
+			name:    "wrong secret",
+			token:   signHS(t, jwt.SigningMethodHS256, "other-secret", testClaims()),
+			wantErr: ErrInvalidCredential,
+		},
+		{
+			name:    "HS384 instead of HS256",
+			token:   signHS(t, jwt.SigningMethodHS384, testSecret, testClaims()),
+			wantErr: ErrInvalidCredential,
+		},
+		{
+			name:    "RS256 without a key set",
+			token:   signRS(t, rsaKey, "", testClaims()),
+			wantErr: ErrInvalidCredential,
+		},
+		{
+			name: "unsigned",
+			token: func() string {
+				token, err := jwt.NewWithClaims(jwt.SigningMethodNone, testClaims()).SignedString(jwt.UnsafeAllowNoneSignatureType)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return token
+			}(),
+			wantErr: ErrInvalidCredential,
+		},
+		{
+			name:    "expired",
+			token:   signHS(t, jwt.SigningMethodHS256, testSecret, with(testClaims(), jwt.MapClaims{"exp": now.Add(-time.Minute).Unix()})),
+			wantErr: ErrInvalidCredential,
+		},
+		{
+			name:  "expired within leeway",
+			token: signHS(t, jwt.SigningMethodHS256, testSecret, with(testClaims(), jwt.MapClaims{"exp": now.Add(-jwtLeeway / 2).Unix()})),
+		},
+		{
+			name:    "not valid yet",
+			token:   signHS(t, jwt.SigningMethodHS256, testSecret, with(testClaims(), jwt.MapClaims{"nbf": now.Add(time.Minute).Unix()})),
+			wantErr: ErrInvalidCredential,
+		},
+		{
+			name:  "not valid yet within leeway",
+			token: signHS(t, jwt.SigningMethodHS256, testSecret, with(testClaims(), jwt.MapClaims{"nbf": now.Add(jwtLeeway / 2).Unix()})),
+		},
+		{
+			name:    "without expiry",
+			token:   signHS(t, jwt.SigningMethodHS256, testSecret, with(testClaims(), jwt.MapClaims{"exp": nil})),
+			wantErr: ErrInvalidCredential,
+		},
+		{
+			name:    "wrong issuer",
+			token:   signHS(t, jwt.SigningMethodHS256, testSecret, with(testClaims(), jwt.MapClaims{"iss": "https://evil.example"})),
+			wantErr: ErrInvalidCredential,
+		},
+		{
+			name:    "wrong audience",
+			token:   signHS(t, jwt.SigningMethodHS256, testSecret, with(testClaims(), jwt.MapClaims{"aud": "other-api"})),
+			wantErr: ErrInvalidCredential,
+		},
+		{
+			name:    "without audience",
+			token:   signHS(t, jwt.SigningMethodHS256, testSecret, with(testClaims(), jwt.MapClaims{"aud": nil})),
+			wantErr: ErrInvalidCredential,
+		},
+		{
+			name:    "stream audience next to the configured one",
+			token:   signHS(t, jwt.SigningMethodHS256, testSecret, with(testClaims(), jwt.MapClaims{"aud": []string{"chat-api", streamTokenAudience}})),
+			wantErr: ErrInvalidCredential,
+		},
+		{
+			name:    "without subject",
+			token:   signHS(t, jwt.SigningMethodHS256, testSecret, with(testClaims(), jwt.MapClaims{"sub": nil})),
+			wantErr: ErrInvalidCredential,
+		},
+		{
+			name:    "not a JWT",
+			token:   "ak_123_secret",
+			wantErr: ErrUnsupportedCredential,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			principal, err := authenticator.Authenticate(context.Background(), tt.token)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("Authenticate() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Authenticate() error = %v", err)
+			}
+			if principal.Subject != "alice" || principal.Method != MethodJWT {
+				t.Errorf("Authenticate() = %+v, want subject alice authenticated by %s", principal, MethodJWT)
+			}
+		})
+	}
+}
+
+// TestJWTRejectsStreamTokens checks that a stream token is not accepted as an
+// access token, even when both are signed with the same secret
+func TestJWTRejectsStreamTokens(t *testing.T) {
+	streamTokens, err := NewStreamTokens(testSecret, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, _, err := streamTokens.Issue(&Principal{Subject: "alice", Method: MethodJWT}, "acme", "chat-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Without a configured audience, any audience but the stream one passes
+	authenticator, err := NewJWTAuthenticator(JWTConfig{Secret: testSecret})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := authenticator.Authenticate(context.Background(), token); !errors.Is(err, ErrInvalidCredential) {
+		t.Errorf("Authenticate() of a stream token error = %v, want %v", err, ErrInvalidCredential)
+	}
+
+	access := signHS(t, jwt.SigningMethodHS256, testSecret, with(testClaims(), jwt.MapClaims{"aud": nil, "iss": nil}))
+	if _, err := authenticator.Authenticate(context.Background(), access); err != nil {
+		t.Errorf("Authenticate() of an access token error = %v", err)
+	}
+}
+
+// TestJWKSKeyRotation checks that tokens of every key in the JWKS file are
+// accepted, and that tokens of a key removed from it are not once reloaded
+func TestJWKSKeyRotation(t *testing.T) {
+	oldKey, newKey := newRSAKey(t), newRSAKey(t)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+
+	oldToken := signRS(t, oldKey, "old", testClaims())
+	newToken := signRS(t, newKey, "new", testClaims())
+
+	// During rotation the key set holds both keys
+	writeJWKS(t, path, map[string]*rsa.PrivateKey{"old": oldKey, "new": newKey})
+	authenticator, err := NewJWTAuthenticator(JWTConfig{JWKSFile: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, token := range map[string]string{"old": oldToken, "new": newToken} {
+		if _, err := authenticator.Authenticate(context.Background(), token); err != nil {
+			t.Errorf("Authenticate() of a token signed by the %s key error = %v", name, err)
+		}
+	}
+
+	unknown := signRS(t, oldKey, "unknown", testClaims())
+	if _, err := authenticator.Authenticate(context.Background(), unknown); !errors.Is(err, ErrInvalidCredential) {
+		t.Errorf("Authenticate() of an unknown key ID error = %v, want %v", err, ErrInvalidCredential)
+	}
+
+	// Without a key ID the key cannot be chosen among several
+	if _, err := authenticator.Authenticate(context.Background(), signRS(t, newKey, "", testClaims())); !errors.Is(err, ErrInvalidCredential) {
+		t.Errorf("Authenticate() without a key ID error = %v, want %v", err, ErrInvalidCredential)
+	}
+
+	// Once the old key is retired, only tokens of the new key are accepted
+	writeJWKS(t, path, map[string]*rsa.PrivateKey{"new": newKey})
+	authenticator, err = NewJWTAuthenticator(JWTConfig{JWKSFile: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := authenticator.Authenticate(context.Background(), oldToken); !errors.Is(err, ErrInvalidCredential) {
+		t.Errorf("Authenticate() of a token signed by a retired key error = %v, want %v", err, ErrInvalidCredential)
+	}
+	if _, err := authenticator.Authenticate(context.Background(), newToken); err != nil {
+		t.Errorf("Authenticate() of a token signed by the new key error = %v", err)
+	}
+	if _, err := authenticator.Authenticate(context.Background(), signRS(t, newKey, "", testClaims())); err != nil {
+		t.Errorf("Authenticate() without a key ID and a single key error = %v", err)
+	}
+}
+
+// newRSAKey generates an RSA signing key
+func newRSAKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// writeJWKS writes the public halves of keys to a JWKS file, keyed by key ID
+func writeJWKS(t *testing.T, path string, keys map[string]*rsa.PrivateKey) {
+	t.Helper()
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	for kid, key := range keys {
+		set.Keys = append(set.Keys, jsonWebKey{
+			Kty: "RSA",
+			Kid: kid,
+			Use: "sig",
+			Alg: "RS256",
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		})
+	}
+
+	data, err := json.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
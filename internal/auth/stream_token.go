@@ -0,0 +1,109 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package auth
+
+import (
+	"crypto/rand"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// streamTokenAudience distinguishes stream tokens from access tokens
+const streamTokenAudience = "stream"
+
+// streamClaims are the claims of a stream token
+type streamClaims struct {
+	jwt.RegisteredClaims
+	ChatID string `json:"chat"`
+	Tenant string `json:"tenant"`
+	Name   string `json:"name,omitempty"`
+	Method string `json:"method"`
+}
+
+// StreamTokens issues and verifies short-lived tokens that authorize a single
+// SSE stream. Browsers' EventSource cannot set headers, so these tokens are
+// passed in the query string instead.
+type StreamTokens struct {
+	secret []byte
+	ttl    time.Duration
+	parser *jwt.Parser
+}
+
+// NewStreamTokens creates a stream token issuer. A random secret is generated
+// when none is given, in which case tokens do not survive a restart.
+func NewStreamTokens(secret string, ttl time.Duration) (*StreamTokens, error) {
+	key := []byte(secret)
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+	}
+
+	return &StreamTokens{
+		secret: key,
+		ttl:    ttl,
+		parser: jwt.NewParser(
+			jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+			jwt.WithAudience(streamTokenAudience),
+			jwt.WithExpirationRequired(),
+		),
+	}, nil
+}
+
+// Issue creates a token that lets the principal stream the given chat of a tenant
+func (s *StreamTokens) Issue(principal *Principal, tenantID, chatID string) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(s.ttl)
+
+	claims := streamClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   principal.Subject,
+			Audience:  jwt.ClaimStrings{streamTokenAudience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+		ChatID: chatID,
+		Tenant: tenantID,
+		Name:   principal.Name,
+		Method: principal.Method,
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.secret)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return token, expiresAt, nil
+}
+
+// Verify checks a stream token for the given chat and returns the principal it was
+// issued to, bound to the tenant the token was issued in
+func (s *StreamTokens) Verify(token, chatID string) (*Principal, error) {
+	var claims streamClaims
+	_, err := s.parser.ParseWithClaims(token, &claims, func(*jwt.Token) (interface{}, error) {
+		return s.secret, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCredential, err)
+	}
+
+	if claims.ChatID != chatID {
+		return nil, fmt.Errorf("%w: token was issued for another chat", ErrInvalidCredential)
+	}
+
+	return &Principal{
+		Subject:  claims.Subject,
+		Name:     claims.Name,
+		Method:   MethodStreamToken,
+		TenantID: claims.Tenant,
+	}, nil
+}
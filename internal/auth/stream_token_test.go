@@ -0,0 +1,83 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package auth
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// TestStreamTokensVerify checks that stream tokens only open the chat they
+// were issued for, until they expire, and that access tokens do not open any
+func TestStreamTokensVerify(t *testing.T) {
+	streamTokens, err := NewStreamTokens(testSecret, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	alice := &Principal{Subject: "alice", Name: "Alice", Method: MethodJWT}
+
+	issue := func(tokens *StreamTokens) string {
+		token, _, err := tokens.Issue(alice, "acme", "chat-1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+
+	expired, err := NewStreamTokens(testSecret, -time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherSecret, err := NewStreamTokens("other-secret", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		chatID  string
+		wantErr bool
+	}{
+		{name: "valid", token: issue(streamTokens), chatID: "chat-1"},
+		{name: "other chat", token: issue(streamTokens), chatID: "chat-2", wantErr: true},
+		{name: "expired", token: issue(expired), chatID: "chat-1", wantErr: true},
+		{name: "other secret", token: issue(otherSecret), chatID: "chat-1", wantErr: true},
+		{
+			name:    "access token",
+			token:   signHS(t, jwt.SigningMethodHS256, testSecret, with(testClaims(), jwt.MapClaims{"chat": "chat-1"})),
+			chatID:  "chat-1",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			principal, err := streamTokens.Verify(tt.token, tt.chatID)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidCredential) {
+					t.Errorf("Verify() error = %v, want %v", err, ErrInvalidCredential)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+			want := Principal{Subject: "alice", Name: "Alice", Method: MethodStreamToken, TenantID: "acme"}
+			if *principal != want {
+				t.Errorf("Verify() = %+v, want %+v", *principal, want)
+			}
+		})
+	}
+}
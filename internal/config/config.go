@@ -11,8 +11,9 @@ package config
 
 import (
 	"fmt"
+	"net"
 	"os"
-	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
@@ -21,10 +22,24 @@ import (
 // Config is the main configuration structure
 type Config struct {
 	Server     ServerConfig
+	GRPC       GRPCConfig
 	MongoDB    MongoDBConfig
 	SSE        SSEConfig
 	LogLevel   string
 	AIProvider AIProviderConfig
+	Generation GenerationConfig
+	Trash      TrashConfig
+	Jobs       JobsConfig
+	Webhooks   WebhooksConfig
+	Auth       AuthConfig
+	Tenancy    TenancyConfig
+	RateLimit  RateLimitConfig
+	Metrics    MetricsConfig
+	Tracing    TracingConfig
+	Health     HealthConfig
+
+	// How each key was resolved, for printing the effective configuration
+	entries []Entry
 }
 
 // ServerConfig contains server configuration
@@ -35,22 +50,36 @@ type ServerConfig struct {
 	WriteTimeout     time.Duration
 	ShutdownTimeout  time.Duration
 	RequestBodyLimit int64
-	TrustedProxies   string
+	TrustedProxies   []string // IPs and CIDRs whose X-Forwarded-For is believed
 	AllowedOrigins   []string
 	DefaultPageSize  int
 	MaxPageSize      int
 }
 
+// GRPCConfig contains gRPC server configuration
+type GRPCConfig struct {
+	Enabled bool
+	Port    int // Port of the gRPC server, which runs alongside the HTTP server
+}
+
 // MongoDBConfig contains MongoDB configuration
 type MongoDBConfig struct {
-	URI                string
-	Database           string
-	Timeout            time.Duration
-	MaxPoolSize        uint64
-	ConnectRetryCount  int
-	ConnectRetryDelay  time.Duration
-	CollectionChats    string
-	CollectionMessages string
+	URI                   string
+	Database              string
+	Timeout               time.Duration
+	MaxPoolSize           uint64
+	ConnectRetryCount     int
+	ConnectRetryDelay     time.Duration
+	CollectionChats       string
+	CollectionMessages    string
+	CollectionFolders     string
+	CollectionAPIKeys     string
+	CollectionInvites     string
+	CollectionGenerations string
+	CollectionJobs        string
+	CollectionWebhooks    string
+	CollectionDeliveries  string
+	CollectionUsage       string
 }
 
 // SSEConfig contains Server-Sent Events configuration
@@ -59,6 +88,107 @@ type SSEConfig struct {
 	KeepaliveInterval time.Duration
 	BufferSize        int
 	WriteTimeout      time.Duration
+	ReconnectRetry    time.Duration // Reconnect delay suggested to clients on shutdown
+	ReconnectJitter   time.Duration // Random delay added to ReconnectRetry, spreading reconnects out
+	DrainTimeout      time.Duration // How long shutdown waits for in-flight generations
+	PollMaxTimeout    time.Duration // Longest a long-polling request waits for events
+}
+
+// GenerationConfig contains settings for tracking AI generations
+type GenerationConfig struct {
+	CheckpointInterval time.Duration // How often partial output is saved
+	OrphanTimeout      time.Duration // How long a running generation may go without a checkpoint before it counts as interrupted
+}
+
+// TrashConfig contains soft-delete retention configuration
+type TrashConfig struct {
+	RetentionDays int           // Days before trashed items are purged; 0 disables purging
+	PurgeInterval time.Duration // How often the purge job runs
+}
+
+// JobsConfig contains background job queue configuration
+type JobsConfig struct {
+	PollInterval       time.Duration  // How often idle workers look for due jobs
+	VisibilityTimeout  time.Duration  // How long a claimed job stays invisible to other workers without a heartbeat
+	MaxAttempts        int            // Attempts before a failing job is dead-lettered, unless the job sets its own
+	BackoffBase        time.Duration  // Delay before the first retry; doubles with every further attempt
+	BackoffMax         time.Duration  // Upper bound of the retry delay
+	Retention          time.Duration  // How long succeeded jobs are kept
+	DefaultConcurrency int            // Jobs of one type run at once per instance, unless listed in Concurrency
+	Concurrency        map[string]int // Per-type overrides of DefaultConcurrency
+}
+
+// WebhooksConfig contains outbound webhook configuration
+type WebhooksConfig struct {
+	Timeout              time.Duration // Timeout of a single delivery request
+	MaxAttempts          int           // Delivery attempts of an event before it is given up
+	DisableAfter         int           // Consecutive failed deliveries after which a webhook is disabled
+	DeliveryRetention    time.Duration // How long the delivery log is kept
+	AllowPrivateNetworks bool          // Whether webhooks may target loopback and private addresses
+}
+
+// AuthConfig contains authentication configuration
+type AuthConfig struct {
+	Enabled           bool
+	JWTSecret         string        // HS256 shared secret; empty disables HS256 tokens
+	JWKSFile          string        // Local JWKS file with RS256 public keys; empty disables RS256 tokens
+	JWTIssuer         string        // Required "iss" claim; empty skips the check
+	JWTAudience       string        // Required "aud" claim; empty skips the check
+	StreamTokenSecret string        // Signs stream query tokens; generated at startup when empty
+	StreamTokenTTL    time.Duration // Lifetime of stream query tokens
+	AdminSubjects     []string      // Principals allowed to use the admin API and act in any tenant
+}
+
+// TenancyConfig contains multi-tenant configuration
+type TenancyConfig struct {
+	Header            string // Request header naming the tenant for principals not bound to one
+	ConfigFile        string // JSON file with per-tenant overrides; empty disables overrides
+	MaxChats          int    // Default chat quota per tenant; 0 means unlimited
+	MaxMessagesPerDay int    // Default daily message quota per tenant; 0 means unlimited
+}
+
+// RateLimitConfig contains rate limiting configuration
+type RateLimitConfig struct {
+	Enabled                bool
+	Default                RateLimitRule // Applies to API routes without a more specific rule
+	Messages               RateLimitRule // Sending messages, which triggers paid generations
+	Stream                 RateLimitRule // Opening SSE streams
+	MaxStreamsPerPrincipal int           // Concurrent SSE streams per principal, or per IP without one; 0 means unlimited
+}
+
+// RateLimitRule sizes the token buckets of a route class; a zero rate disables that bucket
+type RateLimitRule struct {
+	PrincipalPerMinute int
+	PrincipalBurst     int
+	IPPerMinute        int
+	IPBurst            int
+}
+
+// MetricsConfig contains Prometheus metrics configuration
+type MetricsConfig struct {
+	Enabled bool
+	Port    int    // Port of the metrics listener, kept apart from the API
+	Path    string // Path serving metrics in the Prometheus text format
+}
+
+// TracingConfig contains OpenTelemetry tracing configuration
+type TracingConfig struct {
+	Enabled      bool
+	Exporter     string  // "otlp", "stdout" or "file"
+	OTLPEndpoint string  // OTLP/HTTP endpoint URL; empty uses the OTEL_EXPORTER_OTLP_* variables
+	File         string  // Output file of the "file" exporter
+	ServiceName  string  // service.name resource attribute
+	SampleRatio  float64 // Fraction of new traces that are sampled, from 0 to 1
+}
+
+// HealthConfig contains liveness and readiness check configuration
+type HealthConfig struct {
+	CheckTimeout        time.Duration // Time each check gets before it is reported as timed out
+	DBSlowThreshold     time.Duration // MongoDB pings slower than this report degraded
+	AIProbeEnabled      bool          // Whether readiness probes the AI provider
+	AIProbeTTL          time.Duration // How long an AI provider probe result is reused
+	ReplayStoreMaxBytes int           // Replay store size above which readiness reports degraded
+	ShutdownDelay       time.Duration // Time between failing readiness and stopping the server
 }
 
 // AIProviderConfig contains AI provider configuration
@@ -72,145 +202,389 @@ type AIProviderConfig struct {
 	MaxTokens      int
 }
 
-// Load Loads the .env file and environment variables
-func Load() (*Config, error) {
+// Options selects the sources Load reads besides the environment
+type Options struct {
+	File      string            // YAML or TOML file; empty uses the CONFIG_FILE variable
+	Overrides map[string]string // Command-line values, which take precedence over all other sources
+}
+
+// file returns the configuration file to read, if any
+func (o Options) file() string {
+	if o.File != "" {
+		return o.File
+	}
+	return os.Getenv("CONFIG_FILE")
+}
+
+// Load builds the configuration from, in increasing order of precedence,
+// defaults, the configuration file, environment variables (including those
+// of a .env file) and command-line overrides. Every key is named after its
+// environment variable. All invalid values are reported together.
+func Load(opts Options) (*Config, error) {
 	// Load .env file, otherwise use environment variables
 	godotenv.Load()
 
+	l, err := newLoader(opts)
+	if err != nil {
+		return nil, err
+	}
+
 	cfg := &Config{
 		Server: ServerConfig{
-			Environment:      getEnv("ENVIRONMENT", "development"),
-			Port:             getEnvInt("SERVER_PORT", 8080),
-			ReadTimeout:      getEnvDuration("SERVER_READ_TIMEOUT", 10*time.Second),
-			WriteTimeout:     getEnvDuration("SERVER_WRITE_TIMEOUT", 30*time.Second),
-			ShutdownTimeout:  getEnvDuration("SERVER_SHUTDOWN_TIMEOUT", 15*time.Second),
-			RequestBodyLimit: int64(getEnvInt("SERVER_REQUEST_BODY_LIMIT", 1024)) * 1024, // KB -> Bytes
-			TrustedProxies:   getEnv("SERVER_TRUSTED_PROXIES", "127.0.0.1"),
-			AllowedOrigins:   getEnvSlice("SERVER_ALLOWED_ORIGINS", []string{"*"}),
-			DefaultPageSize:  getEnvInt("SERVER_DEFAULT_PAGE_SIZE", 20),
-			MaxPageSize:      getEnvInt("SERVER_MAX_PAGE_SIZE", 100),
+			Environment:      l.string("ENVIRONMENT", "development"),
+			Port:             l.int("SERVER_PORT", 8080),
+			ReadTimeout:      l.duration("SERVER_READ_TIMEOUT", 10*time.Second),
+			WriteTimeout:     l.duration("SERVER_WRITE_TIMEOUT", 30*time.Second),
+			ShutdownTimeout:  l.duration("SERVER_SHUTDOWN_TIMEOUT", 15*time.Second),
+			RequestBodyLimit: int64(l.int("SERVER_REQUEST_BODY_LIMIT", 1024)) * 1024, // KB -> Bytes
+			TrustedProxies:   l.list("SERVER_TRUSTED_PROXIES", []string{"127.0.0.1"}),
+			AllowedOrigins:   l.list("SERVER_ALLOWED_ORIGINS", []string{"*"}),
+			DefaultPageSize:  l.int("SERVER_DEFAULT_PAGE_SIZE", 20),
+			MaxPageSize:      l.int("SERVER_MAX_PAGE_SIZE", 100),
+		},
+		GRPC: GRPCConfig{
+			Enabled: l.bool("GRPC_ENABLED", false),
+			Port:    l.int("GRPC_PORT", 9090),
 		},
 		MongoDB: MongoDBConfig{
-			URI:                getEnv("MONGODB_URI", "mongodb://localhost:27017/sse-chat"),
-			Database:           getEnv("MONGODB_DATABASE", "sse-chat"),
-			Timeout:            getEnvDuration("MONGODB_TIMEOUT", 10*time.Second),
-			MaxPoolSize:        uint64(getEnvInt("MONGODB_MAX_POOL_SIZE", 100)),
-			ConnectRetryCount:  getEnvInt("MONGODB_CONNECT_RETRY_COUNT", 5),
-			ConnectRetryDelay:  getEnvDuration("MONGODB_CONNECT_RETRY_DELAY", 3*time.Second),
-			CollectionChats:    getEnv("MONGODB_COLLECTION_CHATS", "chats"),
-			CollectionMessages: getEnv("MONGODB_COLLECTION_MESSAGES", "messages"),
+			URI:                   l.secret("MONGODB_URI", "mongodb://localhost:27017/sse-chat", redactURI),
+			Database:              l.string("MONGODB_DATABASE", "sse-chat"),
+			Timeout:               l.duration("MONGODB_TIMEOUT", 10*time.Second),
+			MaxPoolSize:           uint64(l.int("MONGODB_MAX_POOL_SIZE", 100)),
+			ConnectRetryCount:     l.int("MONGODB_CONNECT_RETRY_COUNT", 5),
+			ConnectRetryDelay:     l.duration("MONGODB_CONNECT_RETRY_DELAY", 3*time.Second),
+			CollectionChats:       l.string("MONGODB_COLLECTION_CHATS", "chats"),
+			CollectionMessages:    l.string("MONGODB_COLLECTION_MESSAGES", "messages"),
+			CollectionFolders:     l.string("MONGODB_COLLECTION_FOLDERS", "folders"),
+			CollectionAPIKeys:     l.string("MONGODB_COLLECTION_API_KEYS", "api_keys"),
+			CollectionInvites:     l.string("MONGODB_COLLECTION_INVITES", "invites"),
+			CollectionGenerations: l.string("MONGODB_COLLECTION_GENERATIONS", "generations"),
+			CollectionJobs:        l.string("MONGODB_COLLECTION_JOBS", "jobs"),
+			CollectionWebhooks:    l.string("MONGODB_COLLECTION_WEBHOOKS", "webhooks"),
+			CollectionDeliveries:  l.string("MONGODB_COLLECTION_WEBHOOK_DELIVERIES", "webhook_deliveries"),
+			CollectionUsage:       l.string("MONGODB_COLLECTION_USAGE", "usage"),
 		},
 		SSE: SSEConfig{
-			MaxClients:        getEnvInt("SSE_MAX_CLIENTS", 1000),
-			KeepaliveInterval: getEnvDuration("SSE_KEEPALIVE_INTERVAL", 15*time.Second),
-			BufferSize:        getEnvInt("SSE_BUFFER_SIZE", 256),
-			WriteTimeout:      getEnvDuration("SSE_WRITE_TIMEOUT", 5*time.Second),
+			MaxClients:        l.int("SSE_MAX_CLIENTS", 1000),
+			KeepaliveInterval: l.duration("SSE_KEEPALIVE_INTERVAL", 15*time.Second),
+			BufferSize:        l.int("SSE_BUFFER_SIZE", 256),
+			WriteTimeout:      l.duration("SSE_WRITE_TIMEOUT", 5*time.Second),
+			ReconnectRetry:    l.duration("SSE_RECONNECT_RETRY", 2*time.Second),
+			ReconnectJitter:   l.duration("SSE_RECONNECT_JITTER", 3*time.Second),
+			DrainTimeout:      l.duration("SSE_DRAIN_TIMEOUT", 10*time.Second),
+			PollMaxTimeout:    l.duration("SSE_POLL_MAX_TIMEOUT", 60*time.Second),
 		},
-		LogLevel: getEnv("LOG_LEVEL", "info"),
+		LogLevel: l.string("LOG_LEVEL", "info"),
 		AIProvider: AIProviderConfig{
-			Provider:       getEnv("AI_PROVIDER", "openai"),
-			OpenAIKey:      getEnv("OPENAI_API_KEY", ""),
-			OpenAIModel:    getEnv("OPENAI_MODEL", "gpt-4o"),
-			AnthropicKey:   getEnv("ANTHROPIC_API_KEY", ""),
-			AnthropicModel: getEnv("ANTHROPIC_MODEL", "claude-3-opus-20240229"),
-			Timeout:        getEnvDuration("AI_TIMEOUT", 60*time.Second),
-			MaxTokens:      getEnvInt("AI_MAX_TOKENS", 4096),
+			Provider:       l.string("AI_PROVIDER", "openai"),
+			OpenAIKey:      l.secret("OPENAI_API_KEY", "", redactSecret),
+			OpenAIModel:    l.string("OPENAI_MODEL", "gpt-4o"),
+			AnthropicKey:   l.secret("ANTHROPIC_API_KEY", "", redactSecret),
+			AnthropicModel: l.string("ANTHROPIC_MODEL", "claude-3-opus-20240229"),
+			Timeout:        l.duration("AI_TIMEOUT", 60*time.Second),
+			MaxTokens:      l.int("AI_MAX_TOKENS", 4096),
+		},
+		Generation: GenerationConfig{
+			CheckpointInterval: l.duration("GENERATION_CHECKPOINT_INTERVAL", 2*time.Second),
+			OrphanTimeout:      l.duration("GENERATION_ORPHAN_TIMEOUT", 30*time.Second),
+		},
+		Trash: TrashConfig{
+			RetentionDays: l.int("TRASH_RETENTION_DAYS", 30),
+			PurgeInterval: l.duration("TRASH_PURGE_INTERVAL", time.Hour),
+		},
+		Jobs: JobsConfig{
+			PollInterval:       l.duration("JOBS_POLL_INTERVAL", time.Second),
+			VisibilityTimeout:  l.duration("JOBS_VISIBILITY_TIMEOUT", 5*time.Minute),
+			MaxAttempts:        l.int("JOBS_MAX_ATTEMPTS", 5),
+			BackoffBase:        l.duration("JOBS_BACKOFF_BASE", 10*time.Second),
+			BackoffMax:         l.duration("JOBS_BACKOFF_MAX", time.Hour),
+			Retention:          l.duration("JOBS_RETENTION", 7*24*time.Hour),
+			DefaultConcurrency: l.int("JOBS_DEFAULT_CONCURRENCY", 4),
+			Concurrency:        l.limits("JOBS_CONCURRENCY", map[string]int{}),
+		},
+		Webhooks: WebhooksConfig{
+			Timeout:              l.duration("WEBHOOK_TIMEOUT", 10*time.Second),
+			MaxAttempts:          l.int("WEBHOOK_MAX_ATTEMPTS", 8),
+			DisableAfter:         l.int("WEBHOOK_DISABLE_AFTER", 20),
+			DeliveryRetention:    l.duration("WEBHOOK_DELIVERY_RETENTION", 7*24*time.Hour),
+			AllowPrivateNetworks: l.bool("WEBHOOK_ALLOW_PRIVATE_NETWORKS", false),
+		},
+		Auth: AuthConfig{
+			Enabled:           l.bool("AUTH_ENABLED", true),
+			JWTSecret:         l.secret("AUTH_JWT_SECRET", "", redactSecret),
+			JWKSFile:          l.string("AUTH_JWKS_FILE", ""),
+			JWTIssuer:         l.string("AUTH_JWT_ISSUER", ""),
+			JWTAudience:       l.string("AUTH_JWT_AUDIENCE", ""),
+			StreamTokenSecret: l.secret("AUTH_STREAM_TOKEN_SECRET", "", redactSecret),
+			StreamTokenTTL:    l.duration("AUTH_STREAM_TOKEN_TTL", time.Minute),
+			AdminSubjects:     l.list("AUTH_ADMIN_SUBJECTS", nil),
+		},
+		Tenancy: TenancyConfig{
+			Header:            l.string("TENANT_HEADER", "X-Tenant-ID"),
+			ConfigFile:        l.string("TENANT_CONFIG_FILE", ""),
+			MaxChats:          l.int("TENANT_MAX_CHATS", 0),
+			MaxMessagesPerDay: l.int("TENANT_MAX_MESSAGES_PER_DAY", 0),
+		},
+		RateLimit: RateLimitConfig{
+			Enabled:                l.bool("RATE_LIMIT_ENABLED", true),
+			Default:                l.rateLimitRule("RATE_LIMIT_DEFAULT", RateLimitRule{300, 60, 600, 120}),
+			Messages:               l.rateLimitRule("RATE_LIMIT_MESSAGES", RateLimitRule{20, 5, 60, 10}),
+			Stream:                 l.rateLimitRule("RATE_LIMIT_STREAM", RateLimitRule{30, 10, 60, 20}),
+			MaxStreamsPerPrincipal: l.int("RATE_LIMIT_MAX_STREAMS_PER_PRINCIPAL", 10),
+		},
+		Metrics: MetricsConfig{
+			Enabled: l.bool("METRICS_ENABLED", true),
+			Port:    l.int("METRICS_PORT", 9464),
+			Path:    l.string("METRICS_PATH", "/metrics"),
+		},
+		Tracing: TracingConfig{
+			Enabled:      l.bool("TRACING_ENABLED", false),
+			Exporter:     l.string("TRACING_EXPORTER", "otlp"),
+			OTLPEndpoint: l.string("TRACING_OTLP_ENDPOINT", ""),
+			File:         l.string("TRACING_FILE", "traces.jsonl"),
+			ServiceName:  l.string("TRACING_SERVICE_NAME", "go-sse-ai-chat"),
+			SampleRatio:  l.float("TRACING_SAMPLE_RATIO", 1),
+		},
+		Health: HealthConfig{
+			CheckTimeout:        l.duration("HEALTH_CHECK_TIMEOUT", 2*time.Second),
+			DBSlowThreshold:     l.duration("HEALTH_DB_SLOW_THRESHOLD", 500*time.Millisecond),
+			AIProbeEnabled:      l.bool("HEALTH_AI_PROBE_ENABLED", true),
+			AIProbeTTL:          l.duration("HEALTH_AI_PROBE_TTL", time.Minute),
+			ReplayStoreMaxBytes: l.int("HEALTH_REPLAY_STORE_MAX_MB", 64) * 1024 * 1024, // MB -> Bytes
+			ShutdownDelay:       l.duration("HEALTH_SHUTDOWN_DELAY", 0),
 		},
 	}
 
+	cfg.entries = l.entries
+
 	// verify configuration
-	if err := validate(cfg); err != nil {
-		return nil, err
+	l.checkUnknownKeys()
+	errs := append(l.errs, validate(cfg)...)
+	if len(errs) > 0 {
+		return nil, errs
 	}
 
 	return cfg, nil
 }
 
-// validate checks that the configuration is valid
-func validate(cfg *Config) error {
+// validate checks that the configuration is valid and returns every problem found
+func validate(cfg *Config) Errors {
+	var errs Errors
+
 	//Server control
 	if cfg.Server.Port <= 0 || cfg.Server.Port > 65535 {
-		return fmt.Errorf("SERVER_PORT invalid: %d", cfg.Server.Port)
+		errs = append(errs, fmt.Errorf("SERVER_PORT invalid: %d", cfg.Server.Port))
+	}
+
+	for _, proxy := range cfg.Server.TrustedProxies {
+		if !validProxy(proxy) {
+			errs = append(errs, fmt.Errorf("SERVER_TRUSTED_PROXIES entries must be IPs or CIDRs, received: %s", proxy))
+		}
+	}
+
+	// gRPC control
+	if cfg.GRPC.Enabled {
+		if cfg.GRPC.Port <= 0 || cfg.GRPC.Port > 65535 {
+			errs = append(errs, fmt.Errorf("GRPC_PORT invalid: %d", cfg.GRPC.Port))
+		} else if cfg.GRPC.Port == cfg.Server.Port {
+			errs = append(errs, fmt.Errorf("GRPC_PORT must differ from SERVER_PORT: %d", cfg.GRPC.Port))
+		}
 	}
 
 	// MongoDB control
 	if cfg.MongoDB.URI == "" {
-		return fmt.Errorf("MONGODB_URI is required")
+		errs = append(errs, fmt.Errorf("MONGODB_URI is required"))
 	}
 
-	// AI Provider control
-	provider := cfg.AIProvider.Provider
-	if provider != "openai" && provider != "anthropic" {
-		return fmt.Errorf("AI_PROVIDER value must be 'openai' or 'anthropic', received: %s", provider)
+	// SSE control
+	if cfg.SSE.MaxClients <= 0 {
+		errs = append(errs, fmt.Errorf("SSE_MAX_CLIENTS must be positive: %d", cfg.SSE.MaxClients))
 	}
 
-	if provider == "openai" && cfg.AIProvider.OpenAIKey == "" {
-		return fmt.Errorf("OPENAI_API_KEY is required when AI_PROVIDER is set to 'openai'")
+	if cfg.SSE.KeepaliveInterval <= 0 {
+		errs = append(errs, fmt.Errorf("SSE_KEEPALIVE_INTERVAL must be positive: %v", cfg.SSE.KeepaliveInterval))
 	}
 
-	if provider == "anthropic" && cfg.AIProvider.AnthropicKey == "" {
-		return fmt.Errorf("ANTHROPIC_API_KEY is required when AI_PROVIDER is set to 'anthropic'")
+	if cfg.SSE.ReconnectRetry < 0 || cfg.SSE.ReconnectJitter < 0 || cfg.SSE.DrainTimeout < 0 {
+		errs = append(errs, fmt.Errorf("SSE_RECONNECT_RETRY, SSE_RECONNECT_JITTER and SSE_DRAIN_TIMEOUT must not be negative"))
 	}
 
-	return nil
-}
+	if cfg.SSE.PollMaxTimeout <= 0 {
+		errs = append(errs, fmt.Errorf("SSE_POLL_MAX_TIMEOUT must be positive: %v", cfg.SSE.PollMaxTimeout))
+	}
 
-// Auxiliary functions
+	// Generation control
+	if cfg.Generation.CheckpointInterval <= 0 {
+		errs = append(errs, fmt.Errorf("GENERATION_CHECKPOINT_INTERVAL must be positive: %v", cfg.Generation.CheckpointInterval))
+	}
 
-// getEnv takes an environment variable key and a default value, and returns the value of the environment variable or the default value if it is not set
-func getEnv(key, defaultValue string) string {
-	if value, exists := os.LookupEnv(key); exists {
-		return value
+	if cfg.Generation.OrphanTimeout <= 2*cfg.Generation.CheckpointInterval {
+		errs = append(errs, fmt.Errorf("GENERATION_ORPHAN_TIMEOUT must be more than twice GENERATION_CHECKPOINT_INTERVAL: %v", cfg.Generation.OrphanTimeout))
+	}
+
+	// Trash control
+	if cfg.Trash.RetentionDays < 0 {
+		errs = append(errs, fmt.Errorf("TRASH_RETENTION_DAYS must not be negative: %d", cfg.Trash.RetentionDays))
+	}
+
+	if cfg.Trash.RetentionDays > 0 && cfg.Trash.PurgeInterval < time.Second {
+		errs = append(errs, fmt.Errorf("TRASH_PURGE_INTERVAL must be at least 1s: %v", cfg.Trash.PurgeInterval))
+	}
+
+	// Jobs control
+	if cfg.Jobs.PollInterval <= 0 {
+		errs = append(errs, fmt.Errorf("JOBS_POLL_INTERVAL must be positive: %v", cfg.Jobs.PollInterval))
+	}
+
+	if cfg.Jobs.VisibilityTimeout < 10*time.Second {
+		errs = append(errs, fmt.Errorf("JOBS_VISIBILITY_TIMEOUT must be at least 10s: %v", cfg.Jobs.VisibilityTimeout))
+	}
+
+	if cfg.Jobs.MaxAttempts <= 0 {
+		errs = append(errs, fmt.Errorf("JOBS_MAX_ATTEMPTS must be positive: %d", cfg.Jobs.MaxAttempts))
+	}
+
+	if cfg.Jobs.BackoffBase <= 0 || cfg.Jobs.BackoffMax < cfg.Jobs.BackoffBase {
+		errs = append(errs, fmt.Errorf("JOBS_BACKOFF_BASE must be positive and at most JOBS_BACKOFF_MAX: %v, %v", cfg.Jobs.BackoffBase, cfg.Jobs.BackoffMax))
+	}
+
+	if cfg.Jobs.Retention <= 0 {
+		errs = append(errs, fmt.Errorf("JOBS_RETENTION must be positive: %v", cfg.Jobs.Retention))
 	}
-	return defaultValue
-}
 
-// getEnvInt takes an environment variable key and a default value, and returns the value of the environment variable or the default value if it is not set
-func getEnvInt(key string, defaultValue int) int {
-	if value, exists := os.LookupEnv(key); exists {
-		if intValue, err := strconv.Atoi(value); err == nil {
-			return intValue
+	if cfg.Jobs.DefaultConcurrency <= 0 {
+		errs = append(errs, fmt.Errorf("JOBS_DEFAULT_CONCURRENCY must be positive: %d", cfg.Jobs.DefaultConcurrency))
+	}
+
+	for jobType, limit := range cfg.Jobs.Concurrency {
+		if limit <= 0 {
+			errs = append(errs, fmt.Errorf("JOBS_CONCURRENCY for %s must be positive: %d", jobType, limit))
 		}
 	}
-	return defaultValue
-}
 
-// getEnvDuration takes an environment variable key and a default value, and returns the value of the environment variable or the default value if it is not set
-func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
-	if value, exists := os.LookupEnv(key); exists {
-		if duration, err := time.ParseDuration(value); err == nil {
-			return duration
+	// Webhooks control
+	if cfg.Webhooks.Timeout <= 0 {
+		errs = append(errs, fmt.Errorf("WEBHOOK_TIMEOUT must be positive: %v", cfg.Webhooks.Timeout))
+	}
+
+	if cfg.Webhooks.MaxAttempts <= 0 {
+		errs = append(errs, fmt.Errorf("WEBHOOK_MAX_ATTEMPTS must be positive: %d", cfg.Webhooks.MaxAttempts))
+	}
+
+	if cfg.Webhooks.DisableAfter <= 0 {
+		errs = append(errs, fmt.Errorf("WEBHOOK_DISABLE_AFTER must be positive: %d", cfg.Webhooks.DisableAfter))
+	}
+
+	if cfg.Webhooks.DeliveryRetention <= 0 {
+		errs = append(errs, fmt.Errorf("WEBHOOK_DELIVERY_RETENTION must be positive: %v", cfg.Webhooks.DeliveryRetention))
+	}
+
+	// Auth control
+	if cfg.Auth.Enabled && cfg.Auth.StreamTokenTTL <= 0 {
+		errs = append(errs, fmt.Errorf("AUTH_STREAM_TOKEN_TTL must be positive: %v", cfg.Auth.StreamTokenTTL))
+	}
+
+	if cfg.Auth.StreamTokenSecret != "" && cfg.Auth.StreamTokenSecret == cfg.Auth.JWTSecret {
+		errs = append(errs, fmt.Errorf("AUTH_STREAM_TOKEN_SECRET must differ from AUTH_JWT_SECRET"))
+	}
+
+	// Tenancy control
+	if cfg.Tenancy.Header == "" {
+		errs = append(errs, fmt.Errorf("TENANT_HEADER must not be empty"))
+	}
+
+	if cfg.Tenancy.MaxChats < 0 || cfg.Tenancy.MaxMessagesPerDay < 0 {
+		errs = append(errs, fmt.Errorf("TENANT_MAX_CHATS and TENANT_MAX_MESSAGES_PER_DAY must not be negative"))
+	}
+
+	// Rate limit control
+	if cfg.RateLimit.Enabled {
+		rules := []struct {
+			prefix string
+			rule   RateLimitRule
+		}{
+			{"RATE_LIMIT_DEFAULT", cfg.RateLimit.Default},
+			{"RATE_LIMIT_MESSAGES", cfg.RateLimit.Messages},
+			{"RATE_LIMIT_STREAM", cfg.RateLimit.Stream},
+		}
+		for _, r := range rules {
+			prefix, rule := r.prefix, r.rule
+			if rule.PrincipalPerMinute < 0 || rule.IPPerMinute < 0 {
+				errs = append(errs, fmt.Errorf("%s rates must not be negative", prefix))
+			}
+			if (rule.PrincipalPerMinute > 0 && rule.PrincipalBurst <= 0) || (rule.IPPerMinute > 0 && rule.IPBurst <= 0) {
+				errs = append(errs, fmt.Errorf("%s bursts must be positive for enabled buckets", prefix))
+			}
 		}
 	}
-	return defaultValue
-}
 
-// getEnvBool takes an environment variable key and a default value, and returns the value of the environment variable or the default value if it is not set
-func getEnvBool(key string, defaultValue bool) bool {
-	if value, exists := os.LookupEnv(key); exists {
-		if boolValue, err := strconv.ParseBool(value); err == nil {
-			return boolValue
+	// Metrics control
+	if cfg.Metrics.Enabled {
+		if !strings.HasPrefix(cfg.Metrics.Path, "/") {
+			errs = append(errs, fmt.Errorf("METRICS_PATH must start with '/': %s", cfg.Metrics.Path))
+		}
+		if cfg.Metrics.Port <= 0 || cfg.Metrics.Port > 65535 {
+			errs = append(errs, fmt.Errorf("METRICS_PORT invalid: %d", cfg.Metrics.Port))
+		} else if cfg.Metrics.Port == cfg.Server.Port {
+			errs = append(errs, fmt.Errorf("METRICS_PORT must differ from SERVER_PORT: %d", cfg.Metrics.Port))
+		} else if cfg.GRPC.Enabled && cfg.Metrics.Port == cfg.GRPC.Port {
+			errs = append(errs, fmt.Errorf("METRICS_PORT must differ from GRPC_PORT: %d", cfg.Metrics.Port))
 		}
 	}
-	return defaultValue
-}
 
-// getEnvSlice takes an environment variable key and a default value, and returns the value of the environment variable or the default value if it is not set
-func getEnvSlice(key string, defaultValue []string) []string {
-	if value, exists := os.LookupEnv(key); exists && value != "" {
-		// Split comma separated values
-		// TODO: A better parsing algorithm can be added (like CSV)
-		return splitCSV(value)
+	// Tracing control
+	if cfg.Tracing.Enabled {
+		switch cfg.Tracing.Exporter {
+		case "otlp", "stdout":
+		case "file":
+			if cfg.Tracing.File == "" {
+				errs = append(errs, fmt.Errorf("TRACING_FILE is required when TRACING_EXPORTER is set to 'file'"))
+			}
+		default:
+			errs = append(errs, fmt.Errorf("TRACING_EXPORTER value must be 'otlp', 'stdout' or 'file', received: %s", cfg.Tracing.Exporter))
+		}
+
+		if cfg.Tracing.SampleRatio < 0 || cfg.Tracing.SampleRatio > 1 {
+			errs = append(errs, fmt.Errorf("TRACING_SAMPLE_RATIO must be between 0 and 1: %v", cfg.Tracing.SampleRatio))
+		}
+	}
+
+	// Health control
+	if cfg.Health.CheckTimeout <= 0 {
+		errs = append(errs, fmt.Errorf("HEALTH_CHECK_TIMEOUT must be positive: %v", cfg.Health.CheckTimeout))
+	}
+
+	if cfg.Health.ShutdownDelay < 0 {
+		errs = append(errs, fmt.Errorf("HEALTH_SHUTDOWN_DELAY must not be negative: %v", cfg.Health.ShutdownDelay))
+	}
+
+	// AI Provider control
+	provider := cfg.AIProvider.Provider
+	if provider != "openai" && provider != "anthropic" {
+		errs = append(errs, fmt.Errorf("AI_PROVIDER value must be 'openai' or 'anthropic', received: %s", provider))
 	}
-	return defaultValue
-}
 
-// splitCSV splits a string by commas
-func splitCSV(s string) []string {
-	if s == "" {
-		return []string{}
+	if provider == "openai" && cfg.AIProvider.OpenAIKey == "" {
+		errs = append(errs, fmt.Errorf("OPENAI_API_KEY is required when AI_PROVIDER is set to 'openai'"))
 	}
 
-	// TODO: Implement proper CSV splitting
-	return []string{s}
+	if provider == "anthropic" && cfg.AIProvider.AnthropicKey == "" {
+		errs = append(errs, fmt.Errorf("ANTHROPIC_API_KEY is required when AI_PROVIDER is set to 'anthropic'"))
+	}
+
+	return errs
+}
+
+// Retention returns how long trashed items are kept before being purged
+func (c TrashConfig) Retention() time.Duration {
+	return time.Duration(c.RetentionDays) * 24 * time.Hour
+}
+
+// validProxy reports whether proxy is an IP address or a CIDR range
+func validProxy(proxy string) bool {
+	if strings.Contains(proxy, "/") {
+		_, _, err := net.ParseCIDR(proxy)
+		return err == nil
+	}
+	return net.ParseIP(proxy) != nil
 }
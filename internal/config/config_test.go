@@ -0,0 +1,38 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestValidateRejectsSharedStreamTokenSecret checks that stream tokens cannot
+// be signed with the secret of access tokens
+func TestValidateRejectsSharedStreamTokenSecret(t *testing.T) {
+	hasError := func(errs Errors) bool {
+		for _, err := range errs {
+			if strings.Contains(err.Error(), "AUTH_STREAM_TOKEN_SECRET") {
+				return true
+			}
+		}
+		return false
+	}
+
+	cfg := &Config{Auth: AuthConfig{Enabled: true, JWTSecret: "shared", StreamTokenSecret: "shared"}}
+	if !hasError(validate(cfg)) {
+		t.Error("validate() accepts AUTH_STREAM_TOKEN_SECRET equal to AUTH_JWT_SECRET")
+	}
+
+	cfg.Auth.StreamTokenSecret = "other"
+	if hasError(validate(cfg)) {
+		t.Error("validate() rejects distinct AUTH_STREAM_TOKEN_SECRET and AUTH_JWT_SECRET")
+	}
+}
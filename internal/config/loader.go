@@ -0,0 +1,388 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package config
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Names of the configuration sources, from lowest to highest precedence
+const (
+	SourceDefault = "default"
+	SourceFile    = "file"
+	SourceEnv     = "env"
+	SourceFlag    = "flag"
+)
+
+// Entry is a configuration key as it was resolved
+type Entry struct {
+	Key    string
+	Value  string
+	Source string
+	redact func(string) string // Hides secrets in Value; nil for plain values
+}
+
+// source is a layer of configuration values
+type source struct {
+	name   string
+	lookup func(key string) (string, bool)
+	keys   []string // Keys a strict source defines, checked against the known keys
+}
+
+// loader resolves configuration keys across sources and collects every problem it finds
+type loader struct {
+	sources []source // Highest precedence first
+	entries []Entry
+	errs    Errors
+}
+
+// newLoader creates a loader reading command-line overrides, then environment
+// variables, then the configuration file, if any
+func newLoader(opts Options) (*loader, error) {
+	l := &loader{}
+
+	if len(opts.Overrides) > 0 {
+		overrides := make(map[string]string, len(opts.Overrides))
+		for key, value := range opts.Overrides {
+			overrides[normalizeKey(key)] = value
+		}
+		l.sources = append(l.sources, mapSource(SourceFlag, overrides))
+	}
+
+	l.sources = append(l.sources, source{name: SourceEnv, lookup: os.LookupEnv})
+
+	if file := opts.file(); file != "" {
+		values, err := readFile(file)
+		if err != nil {
+			return nil, err
+		}
+		l.sources = append(l.sources, mapSource(SourceFile+" "+file, values))
+	}
+
+	return l, nil
+}
+
+// mapSource creates a strict source from a map of values
+func mapSource(name string, values map[string]string) source {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return source{
+		name: name,
+		lookup: func(key string) (string, bool) {
+			value, ok := values[key]
+			return value, ok
+		},
+		keys: keys,
+	}
+}
+
+// resolve returns the value of key from the first source defining it.
+// Typed keys skip empty values, so that a blank line in a .env file keeps the default.
+func (l *loader) resolve(key string, allowEmpty bool) (string, string, bool) {
+	for _, src := range l.sources {
+		if value, ok := src.lookup(key); ok && (allowEmpty || strings.TrimSpace(value) != "") {
+			return value, src.name, true
+		}
+	}
+	return "", SourceDefault, false
+}
+
+// record remembers how key was resolved
+func (l *loader) record(key, value, source string, redact func(string) string) {
+	l.entries = append(l.entries, Entry{Key: key, Value: value, Source: source, redact: redact})
+}
+
+// invalid records a value that could not be parsed
+func (l *loader) invalid(key, value, source, kind string) {
+	l.errs = append(l.errs, fmt.Errorf("%s: invalid %s %q (from %s)", key, kind, value, source))
+}
+
+// string resolves a string key
+func (l *loader) string(key, defaultValue string) string {
+	value, source, ok := l.resolve(key, true)
+	if !ok {
+		value = defaultValue
+	}
+	l.record(key, value, source, nil)
+	return value
+}
+
+// secret resolves a string key whose value is hidden by redacted output
+func (l *loader) secret(key, defaultValue string, redact func(string) string) string {
+	value, source, ok := l.resolve(key, true)
+	if !ok {
+		value = defaultValue
+	}
+	l.record(key, value, source, redact)
+	return value
+}
+
+// int resolves an integer key
+func (l *loader) int(key string, defaultValue int) int {
+	value, source, ok := l.resolve(key, false)
+	if !ok {
+		l.record(key, strconv.Itoa(defaultValue), source, nil)
+		return defaultValue
+	}
+
+	l.record(key, value, source, nil)
+	intValue, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		l.invalid(key, value, source, "integer")
+		return defaultValue
+	}
+	return intValue
+}
+
+// float resolves a floating point key
+func (l *loader) float(key string, defaultValue float64) float64 {
+	value, source, ok := l.resolve(key, false)
+	if !ok {
+		l.record(key, strconv.FormatFloat(defaultValue, 'g', -1, 64), source, nil)
+		return defaultValue
+	}
+
+	l.record(key, value, source, nil)
+	floatValue, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil {
+		l.invalid(key, value, source, "number")
+		return defaultValue
+	}
+	return floatValue
+}
+
+// bool resolves a boolean key
+func (l *loader) bool(key string, defaultValue bool) bool {
+	value, source, ok := l.resolve(key, false)
+	if !ok {
+		l.record(key, strconv.FormatBool(defaultValue), source, nil)
+		return defaultValue
+	}
+
+	l.record(key, value, source, nil)
+	boolValue, err := strconv.ParseBool(strings.TrimSpace(value))
+	if err != nil {
+		l.invalid(key, value, source, "boolean")
+		return defaultValue
+	}
+	return boolValue
+}
+
+// duration resolves a duration key such as "15s"
+func (l *loader) duration(key string, defaultValue time.Duration) time.Duration {
+	value, source, ok := l.resolve(key, false)
+	if !ok {
+		l.record(key, defaultValue.String(), source, nil)
+		return defaultValue
+	}
+
+	l.record(key, value, source, nil)
+	duration, err := time.ParseDuration(strings.TrimSpace(value))
+	if err != nil {
+		l.invalid(key, value, source, "duration")
+		return defaultValue
+	}
+	return duration
+}
+
+// list resolves a comma separated list key. Items are trimmed, empty items
+// are dropped, and items containing commas can be quoted as in CSV.
+func (l *loader) list(key string, defaultValue []string) []string {
+	value, source, ok := l.resolve(key, false)
+	if !ok {
+		l.record(key, joinList(defaultValue), source, nil)
+		return defaultValue
+	}
+
+	l.record(key, value, source, nil)
+	items, err := splitList(value)
+	if err != nil {
+		l.invalid(key, value, source, "list")
+		return defaultValue
+	}
+	return items
+}
+
+// rateLimitRule resolves the <prefix>_PER_MINUTE, <prefix>_BURST, <prefix>_IP_PER_MINUTE and <prefix>_IP_BURST keys
+func (l *loader) rateLimitRule(prefix string, defaultValue RateLimitRule) RateLimitRule {
+	return RateLimitRule{
+		PrincipalPerMinute: l.int(prefix+"_PER_MINUTE", defaultValue.PrincipalPerMinute),
+		PrincipalBurst:     l.int(prefix+"_BURST", defaultValue.PrincipalBurst),
+		IPPerMinute:        l.int(prefix+"_IP_PER_MINUTE", defaultValue.IPPerMinute),
+		IPBurst:            l.int(prefix+"_IP_BURST", defaultValue.IPBurst),
+	}
+}
+
+// limits resolves a comma separated list of name=limit pairs, such as "a=1,b=2"
+func (l *loader) limits(key string, defaultValue map[string]int) map[string]int {
+	value, source, ok := l.resolve(key, false)
+	if !ok {
+		l.record(key, joinLimits(defaultValue), source, nil)
+		return defaultValue
+	}
+
+	l.record(key, value, source, nil)
+	items, err := splitList(value)
+	if err != nil {
+		l.invalid(key, value, source, "list")
+		return defaultValue
+	}
+
+	limits := make(map[string]int, len(items))
+	for _, item := range items {
+		name, limit, ok := strings.Cut(item, "=")
+		n, err := strconv.Atoi(strings.TrimSpace(limit))
+		if !ok || strings.TrimSpace(name) == "" || err != nil {
+			l.invalid(key, value, source, "list of name=limit pairs")
+			return defaultValue
+		}
+		limits[strings.TrimSpace(name)] = n
+	}
+	return limits
+}
+
+// checkUnknownKeys reports keys of strict sources that no setting reads,
+// which are most likely typos
+func (l *loader) checkUnknownKeys() {
+	known := make(map[string]bool, len(l.entries))
+	for _, entry := range l.entries {
+		known[entry.Key] = true
+	}
+
+	for _, src := range l.sources {
+		for _, key := range src.keys {
+			if !known[key] {
+				l.errs = append(l.errs, fmt.Errorf("%s: unknown key (from %s)", key, src.name))
+			}
+		}
+	}
+}
+
+// splitList parses a comma separated list
+func splitList(s string) ([]string, error) {
+	reader := csv.NewReader(strings.NewReader(s))
+	reader.TrimLeadingSpace = true
+
+	fields, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if field = strings.TrimSpace(field); field != "" {
+			items = append(items, field)
+		}
+	}
+	return items, nil
+}
+
+// joinList formats a list so that splitList reads it back
+func joinList(items []string) string {
+	var builder strings.Builder
+	writer := csv.NewWriter(&builder)
+	writer.Write(items)
+	writer.Flush()
+	return strings.TrimSuffix(builder.String(), "\n")
+}
+
+// joinLimits formats name=limit pairs so that limits reads them back
+func joinLimits(limits map[string]int) string {
+	items := make([]string, 0, len(limits))
+	for name, limit := range limits {
+		items = append(items, fmt.Sprintf("%s=%d", name, limit))
+	}
+	sort.Strings(items)
+	return joinList(items)
+}
+
+// normalizeKey turns "server.port" or "server-port" into "SERVER_PORT"
+func normalizeKey(key string) string {
+	return strings.ToUpper(strings.NewReplacer(".", "_", "-", "_").Replace(strings.TrimSpace(key)))
+}
+
+// readFile reads a YAML or TOML configuration file. Nested tables are
+// flattened into the keys of the environment variables, so that
+// "server: {port: 8080}" sets SERVER_PORT.
+func readFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var tree map[string]interface{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &tree)
+	case ".toml":
+		err = toml.Unmarshal(data, &tree)
+	default:
+		return nil, fmt.Errorf("config file %s must have a .yaml, .yml or .toml extension", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	values := make(map[string]string)
+	if err := flatten("", tree, values); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	return values, nil
+}
+
+// flatten stores the leaves of tree in values under their joined key paths
+func flatten(prefix string, tree map[string]interface{}, values map[string]string) error {
+	for name, node := range tree {
+		key := normalizeKey(name)
+		if prefix != "" {
+			key = prefix + "_" + key
+		}
+
+		if nested, ok := node.(map[string]interface{}); ok {
+			if err := flatten(key, nested, values); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// "server: {port: 1}" and "server_port: 1" name the same key
+		if _, exists := values[key]; exists {
+			return fmt.Errorf("%s is defined more than once", key)
+		}
+
+		switch value := node.(type) {
+		case []interface{}:
+			items := make([]string, len(value))
+			for i, item := range value {
+				items[i] = fmt.Sprint(item)
+			}
+			values[key] = joinList(items)
+		case nil:
+			values[key] = ""
+		default:
+			values[key] = fmt.Sprint(value)
+		}
+	}
+	return nil
+}
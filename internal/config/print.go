@@ -0,0 +1,116 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package config
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+)
+
+// redactedValue replaces secrets in redacted output
+const redactedValue = "<redacted>"
+
+// Errors lists every problem found while loading a configuration
+type Errors []error
+
+// Error formats all problems, one per line
+func (e Errors) Error() string {
+	lines := make([]string, len(e))
+	for i, err := range e {
+		lines[i] = "  - " + err.Error()
+	}
+	return fmt.Sprintf("%d configuration error(s):\n%s", len(e), strings.Join(lines, "\n"))
+}
+
+// Overrides collects KEY=value command-line overrides. It implements flag.Value,
+// so a flag of this type can be repeated.
+type Overrides map[string]string
+
+// String formats the overrides for flag usage output
+func (o Overrides) String() string {
+	pairs := make([]string, 0, len(o))
+	for key, value := range o {
+		pairs = append(pairs, key+"="+value)
+	}
+	return strings.Join(pairs, ",")
+}
+
+// Set parses one KEY=value override
+func (o Overrides) Set(pair string) error {
+	key, value, ok := strings.Cut(pair, "=")
+	if !ok || strings.TrimSpace(key) == "" {
+		return fmt.Errorf("expected KEY=value, got %q", pair)
+	}
+	o[normalizeKey(key)] = value
+	return nil
+}
+
+// Entries returns how each configuration key was resolved, in declaration order
+func (c *Config) Entries() []Entry {
+	return c.entries
+}
+
+// Print writes the effective configuration as KEY=value lines annotated with
+// their source. Secrets are masked unless showSecrets is set.
+func (c *Config) Print(w io.Writer, showSecrets bool) error {
+	lines := make([]string, len(c.entries))
+	width := 0
+	for i, entry := range c.entries {
+		value := entry.Value
+		if !showSecrets && entry.redact != nil {
+			value = entry.redact(value)
+		}
+
+		lines[i] = entry.Key + "=" + quoteValue(value)
+		if len(lines[i]) > width {
+			width = len(lines[i])
+		}
+	}
+
+	for i, entry := range c.entries {
+		if _, err := fmt.Fprintf(w, "%-*s  # %s\n", width, lines[i], entry.Source); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// quoteValue quotes values that a .env parser would otherwise misread
+func quoteValue(value string) string {
+	if value == "" || strings.ContainsAny(value, " \t#\"'") {
+		return fmt.Sprintf("%q", value)
+	}
+	return value
+}
+
+// redactSecret hides a secret, keeping only whether it is set
+func redactSecret(value string) string {
+	if value == "" {
+		return ""
+	}
+	return redactedValue
+}
+
+// redactURI hides the password of a connection URI
+func redactURI(value string) string {
+	parsed, err := url.Parse(value)
+	if err != nil {
+		return redactSecret(value)
+	}
+
+	if _, hasPassword := parsed.User.Password(); hasPassword {
+		parsed.User = url.UserPassword(parsed.User.Username(), redactedValue)
+		return strings.Replace(parsed.String(), url.QueryEscape(redactedValue), redactedValue, 1)
+	}
+	return value
+}
@@ -0,0 +1,46 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package config
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// TestPrintHidesSecretsByDefault checks that secrets are only printed on request
+func TestPrintHidesSecretsByDefault(t *testing.T) {
+	cfg := &Config{entries: []Entry{
+		{Key: "OPENAI_API_KEY", Value: "sk-secret", Source: "env", redact: redactSecret},
+		{Key: "MONGODB_URI", Value: "mongodb://app:hunter2@db:27017/chat", Source: "env", redact: redactURI},
+		{Key: "SERVER_PORT", Value: "8080", Source: "default"},
+	}}
+
+	var out bytes.Buffer
+	if err := cfg.Print(&out, false); err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"sk-secret", "hunter2"} {
+		if strings.Contains(out.String(), secret) {
+			t.Errorf("Print() shows secret %q:\n%s", secret, out.String())
+		}
+	}
+	if !strings.Contains(out.String(), "SERVER_PORT=8080") {
+		t.Errorf("Print() hides plain values:\n%s", out.String())
+	}
+
+	out.Reset()
+	if err := cfg.Print(&out, true); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "sk-secret") {
+		t.Errorf("Print() with showSecrets hides secrets:\n%s", out.String())
+	}
+}
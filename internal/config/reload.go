@@ -0,0 +1,267 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package config
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/pkg/logger"
+)
+
+// reloadDebounce groups the bursts of file events an editor save produces
+const reloadDebounce = 200 * time.Millisecond
+
+// reloadableKeys are the keys a running server applies without a restart
+var reloadableKeys = map[string]bool{
+	"SSE_MAX_CLIENTS":        true,
+	"SSE_KEEPALIVE_INTERVAL": true,
+	"LOG_LEVEL":              true,
+
+	"RATE_LIMIT_DEFAULT_PER_MINUTE":     true,
+	"RATE_LIMIT_DEFAULT_BURST":          true,
+	"RATE_LIMIT_DEFAULT_IP_PER_MINUTE":  true,
+	"RATE_LIMIT_DEFAULT_IP_BURST":       true,
+	"RATE_LIMIT_MESSAGES_PER_MINUTE":    true,
+	"RATE_LIMIT_MESSAGES_BURST":         true,
+	"RATE_LIMIT_MESSAGES_IP_PER_MINUTE": true,
+	"RATE_LIMIT_MESSAGES_IP_BURST":      true,
+	"RATE_LIMIT_STREAM_PER_MINUTE":      true,
+	"RATE_LIMIT_STREAM_BURST":           true,
+	"RATE_LIMIT_STREAM_IP_PER_MINUTE":   true,
+	"RATE_LIMIT_STREAM_IP_BURST":        true,
+
+	"AI_PROVIDER":       true,
+	"OPENAI_API_KEY":    true,
+	"OPENAI_MODEL":      true,
+	"ANTHROPIC_API_KEY": true,
+	"ANTHROPIC_MODEL":   true,
+	"AI_TIMEOUT":        true,
+	"AI_MAX_TOKENS":     true,
+}
+
+// Change is a key whose value differs between two configurations.
+// Secrets are redacted.
+type Change struct {
+	Key    string `json:"key"`
+	Old    string `json:"old"`
+	New    string `json:"new"`
+	Source string `json:"source"`
+}
+
+// Reloader holds the current configuration and replaces its reloadable
+// settings when the configuration is loaded again
+type Reloader struct {
+	opts      Options
+	current   atomic.Pointer[Config]
+	listeners []func(*Config)
+	mutex     sync.Mutex // Serializes reloads and listener registration
+}
+
+// NewReloader creates a reloader starting from cfg, which was loaded with opts
+func NewReloader(cfg *Config, opts Options) *Reloader {
+	r := &Reloader{opts: opts}
+	r.current.Store(cfg)
+	return r
+}
+
+// Current returns the configuration in effect
+func (r *Reloader) Current() *Config {
+	return r.current.Load()
+}
+
+// OnReload registers fn to be called with the new configuration after each
+// reload that changed a reloadable setting
+func (r *Reloader) OnReload(fn func(cfg *Config)) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.listeners = append(r.listeners, fn)
+}
+
+// Reload loads the configuration again and applies the changed reloadable
+// settings. Nothing is applied when the new configuration is invalid.
+// Changes to other keys are returned as pending: they need a restart.
+func (r *Reloader) Reload() (applied, pending []Change, err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	loaded, err := Load(r.opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	current := r.current.Load()
+	for _, change := range diff(current, loaded) {
+		if reloadableKeys[change.Key] {
+			applied = append(applied, change)
+		} else {
+			pending = append(pending, change)
+		}
+	}
+
+	if len(applied) == 0 {
+		return nil, pending, nil
+	}
+
+	next := current.withReloadable(loaded)
+	r.current.Store(next)
+	for _, listener := range r.listeners {
+		listener(next)
+	}
+
+	return applied, pending, nil
+}
+
+// withReloadable returns a copy of c with the reloadable settings of loaded
+func (c *Config) withReloadable(loaded *Config) *Config {
+	next := *c
+	next.SSE.MaxClients = loaded.SSE.MaxClients
+	next.SSE.KeepaliveInterval = loaded.SSE.KeepaliveInterval
+	next.LogLevel = loaded.LogLevel
+	next.RateLimit.Default = loaded.RateLimit.Default
+	next.RateLimit.Messages = loaded.RateLimit.Messages
+	next.RateLimit.Stream = loaded.RateLimit.Stream
+	next.AIProvider = loaded.AIProvider
+
+	next.entries = make([]Entry, len(c.entries))
+	copy(next.entries, c.entries)
+	for i, entry := range next.entries {
+		if !reloadableKeys[entry.Key] {
+			continue
+		}
+		for _, reloaded := range loaded.entries {
+			if reloaded.Key == entry.Key {
+				next.entries[i] = reloaded
+				break
+			}
+		}
+	}
+
+	return &next
+}
+
+// diff returns the keys whose values differ between two configurations
+func diff(from, to *Config) []Change {
+	oldEntries := make(map[string]Entry, len(from.entries))
+	for _, entry := range from.entries {
+		oldEntries[entry.Key] = entry
+	}
+
+	var changes []Change
+	for _, entry := range to.entries {
+		previous, ok := oldEntries[entry.Key]
+		if ok && previous.Value == entry.Value {
+			continue
+		}
+
+		change := Change{Key: entry.Key, Old: previous.Value, New: entry.Value, Source: entry.Source}
+		if entry.redact != nil {
+			change.Old = entry.redact(change.Old)
+			change.New = entry.redact(change.New)
+		}
+		changes = append(changes, change)
+	}
+
+	return changes
+}
+
+// Watch reloads the configuration on SIGHUP and whenever the configuration
+// file changes, until ctx is done
+func (r *Reloader) Watch(ctx context.Context) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+
+	var fileEvents <-chan fsnotify.Event
+	var fileErrors <-chan error
+	file := r.opts.file()
+	if file != "" {
+		watcher, err := watchFile(file)
+		if err != nil {
+			logger.Warnf("Not watching config file %s for changes: %v", file, err)
+		} else {
+			defer watcher.Close()
+			fileEvents, fileErrors = watcher.Events, watcher.Errors
+		}
+	}
+
+	debounce := time.NewTimer(reloadDebounce)
+	debounce.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-hangup:
+			r.reloadAndLog("signal")
+
+		case event := <-fileEvents:
+			if filepath.Clean(event.Name) == filepath.Clean(file) && event.Has(fsnotify.Write|fsnotify.Create|fsnotify.Rename) {
+				debounce.Reset(reloadDebounce)
+			}
+
+		case err := <-fileErrors:
+			logger.Warnf("Config file watcher error: %v", err)
+
+		case <-debounce.C:
+			r.reloadAndLog("file")
+		}
+	}
+}
+
+// watchFile watches the directory of file, so that the file can be replaced
+// by renaming, as editors and configuration management tools do
+func watchFile(file string) (*fsnotify.Watcher, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := watcher.Add(filepath.Dir(file)); err != nil {
+		watcher.Close()
+		return nil, err
+	}
+
+	return watcher, nil
+}
+
+// reloadAndLog reloads the configuration and records the outcome in the audit log
+func (r *Reloader) reloadAndLog(trigger string) {
+	applied, pending, err := r.Reload()
+	if err != nil {
+		logger.Errorf("Config reload triggered by %s failed, keeping the current configuration: %v", trigger, err)
+		return
+	}
+
+	if len(pending) > 0 {
+		keys := make([]string, len(pending))
+		for i, change := range pending {
+			keys[i] = change.Key
+		}
+		logger.Warnf("Config changes to %v require a restart and were not applied", keys)
+	}
+
+	if len(applied) == 0 {
+		logger.Infof("Config reload triggered by %s found no reloadable changes", trigger)
+		return
+	}
+
+	logger.With("event", "config_reloaded", "trigger", trigger, "changes", applied).
+		Infof("Configuration reloaded with %d change(s)", len(applied))
+}
@@ -34,6 +34,46 @@ func (c *DBConnection) Messages() *mongo.Collection {
 	return c.database.Collection(c.cfg.CollectionMessages)
 }
 
+// Folders returns the folders collection
+func (c *DBConnection) Folders() *mongo.Collection {
+	return c.database.Collection(c.cfg.CollectionFolders)
+}
+
+// APIKeys returns the API keys collection
+func (c *DBConnection) APIKeys() *mongo.Collection {
+	return c.database.Collection(c.cfg.CollectionAPIKeys)
+}
+
+// Invites returns the chat invites collection
+func (c *DBConnection) Invites() *mongo.Collection {
+	return c.database.Collection(c.cfg.CollectionInvites)
+}
+
+// Generations returns the AI generations collection
+func (c *DBConnection) Generations() *mongo.Collection {
+	return c.database.Collection(c.cfg.CollectionGenerations)
+}
+
+// Jobs returns the background jobs collection
+func (c *DBConnection) Jobs() *mongo.Collection {
+	return c.database.Collection(c.cfg.CollectionJobs)
+}
+
+// Webhooks returns the webhook subscriptions collection
+func (c *DBConnection) Webhooks() *mongo.Collection {
+	return c.database.Collection(c.cfg.CollectionWebhooks)
+}
+
+// WebhookDeliveries returns the webhook delivery log collection
+func (c *DBConnection) WebhookDeliveries() *mongo.Collection {
+	return c.database.Collection(c.cfg.CollectionDeliveries)
+}
+
+// Usage returns the daily usage counter collection
+func (c *DBConnection) Usage() *mongo.Collection {
+	return c.database.Collection(c.cfg.CollectionUsage)
+}
+
 // Collection returns a MongoDB collection
 func (c *DBConnection) Collection(name string) *mongo.Collection {
 	return c.database.Collection(name)
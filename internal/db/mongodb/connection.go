@@ -25,6 +25,8 @@ type DBConnection struct {
 	client   *mongo.Client
 	database *mongo.Database
 	cfg      *config.MongoDBConfig
+
+	transactions bool // Server supports multi-document transactions
 }
 
 // New creates a new MongoDB connection
@@ -41,6 +43,8 @@ func New(cfg *config.MongoDBConfig) (*DBConnection, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
+	conn.detectTransactionSupport(ctx)
+
 	if err := conn.EnsureIndexes(ctx); err != nil {
 		logger.Warnf("Failed to create indexes: %v", err)
 		// Continue even if index creation fails
@@ -49,6 +53,17 @@ func New(cfg *config.MongoDBConfig) (*DBConnection, error) {
 	return conn, nil
 }
 
+// NewFromClient wraps a connected client, using the database and
+// collections named in cfg. Unlike New it neither checks the server nor
+// creates indexes.
+func NewFromClient(client *mongo.Client, cfg *config.MongoDBConfig) *DBConnection {
+	return &DBConnection{
+		client:   client,
+		database: client.Database(cfg.Database),
+		cfg:      cfg,
+	}
+}
+
 // connect establishes a connection to MongoDB
 func (c *DBConnection) connect() error {
 	// Create MongoDB client options
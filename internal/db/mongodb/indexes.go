@@ -11,6 +11,7 @@ package mongodb
 
 import (
 	"context"
+	"errors"
 
 	"github.com/yasin-yalcin-dev/go-sse-ai-chat/pkg/logger"
 	"go.mongodb.org/mongo-driver/bson"
@@ -18,8 +19,41 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// Indexes that were replaced by their tenant-prefixed versions
+var (
+	legacyChatIndexes = []string{
+		"updated_at_active",
+		"active_archived_updated_at_id",
+		"active_archived_last_message_at_id",
+		"tags",
+		"folder_id_updated_at",
+		"members_subject_updated_at_id",
+		"title_text",
+	}
+	legacyMessageIndexes = []string{
+		"chat_id_created_at",
+		"chat_id_created_at_id",
+		"role_chat_id",
+		"content_text",
+	}
+	legacyFolderIndexes = []string{
+		"owner_id_name",
+	}
+)
+
 // EnsureIndexes creates all required indexes for the collections
 func (c *DBConnection) EnsureIndexes(ctx context.Context) error {
+	// Drop indexes that predate tenancy; a collection allows only one text index
+	if err := dropIndexes(ctx, c.Chats(), legacyChatIndexes); err != nil {
+		return err
+	}
+	if err := dropIndexes(ctx, c.Messages(), legacyMessageIndexes); err != nil {
+		return err
+	}
+	if err := dropIndexes(ctx, c.Folders(), legacyFolderIndexes); err != nil {
+		return err
+	}
+
 	// Create indexes for chats collection
 	if err := c.createChatIndexes(ctx); err != nil {
 		return err
@@ -30,6 +64,36 @@ func (c *DBConnection) EnsureIndexes(ctx context.Context) error {
 		return err
 	}
 
+	// Create indexes for folders collection
+	if err := c.createFolderIndexes(ctx); err != nil {
+		return err
+	}
+
+	// Create indexes for invites collection
+	if err := c.createInviteIndexes(ctx); err != nil {
+		return err
+	}
+
+	// Create indexes for generations collection
+	if err := c.createGenerationIndexes(ctx); err != nil {
+		return err
+	}
+
+	// Create indexes for jobs collection
+	if err := c.createJobIndexes(ctx); err != nil {
+		return err
+	}
+
+	// Create indexes for webhook collections
+	if err := c.createWebhookIndexes(ctx); err != nil {
+		return err
+	}
+
+	// Create indexes for usage collection
+	if err := c.createUsageIndexes(ctx); err != nil {
+		return err
+	}
+
 	logger.Info("All database indexes created successfully")
 	return nil
 }
@@ -40,16 +104,79 @@ func (c *DBConnection) createChatIndexes(ctx context.Context) error {
 	chatIndexes := []mongo.IndexModel{
 		{
 			Keys: bson.D{
+				{Key: "tenant_id", Value: 1},
+				{Key: "updated_at", Value: -1},
+				{Key: "active", Value: 1},
+			},
+			Options: options.Index().SetName("tenant_id_updated_at_active"),
+		},
+		{
+			// Supports keyset pagination of the default chat list
+			Keys: bson.D{
+				{Key: "tenant_id", Value: 1},
+				{Key: "active", Value: 1},
+				{Key: "archived", Value: 1},
 				{Key: "updated_at", Value: -1},
+				{Key: "_id", Value: -1},
+			},
+			Options: options.Index().SetName("tenant_id_active_archived_updated_at_id"),
+		},
+		{
+			Keys: bson.D{
+				{Key: "tenant_id", Value: 1},
 				{Key: "active", Value: 1},
+				{Key: "archived", Value: 1},
+				{Key: "last_message_at", Value: -1},
+				{Key: "_id", Value: -1},
 			},
-			Options: options.Index().SetName("updated_at_active"),
+			Options: options.Index().SetName("tenant_id_active_archived_last_message_at_id"),
 		},
 		{
 			Keys: bson.D{
+				{Key: "tenant_id", Value: 1},
+				{Key: "tags", Value: 1},
+			},
+			Options: options.Index().SetName("tenant_id_tags"),
+		},
+		{
+			Keys: bson.D{
+				{Key: "tenant_id", Value: 1},
+				{Key: "folder_id", Value: 1},
+				{Key: "updated_at", Value: -1},
+			},
+			Options: options.Index().SetName("tenant_id_folder_id_updated_at"),
+		},
+		{
+			// Supports listings scoped to the caller
+			Keys: bson.D{
+				{Key: "tenant_id", Value: 1},
+				{Key: "members.subject", Value: 1},
+				{Key: "updated_at", Value: -1},
+				{Key: "_id", Value: -1},
+			},
+			Options: options.Index().SetName("tenant_id_members_subject_updated_at_id"),
+		},
+		{
+			// Supports trash listing and purging; only trashed chats are indexed
+			Keys: bson.D{
+				{Key: "deleted_at", Value: -1},
+			},
+			Options: options.Index().SetName("deleted_at").SetSparse(true),
+		},
+		{
+			// Supports title prefix filters
+			Keys: bson.D{
+				{Key: "tenant_id", Value: 1},
+				{Key: "title_lower", Value: 1},
+			},
+			Options: options.Index().SetName("tenant_id_title_lower"),
+		},
+		{
+			Keys: bson.D{
+				{Key: "tenant_id", Value: 1},
 				{Key: "title", Value: "text"},
 			},
-			Options: options.Index().SetName("title_text"),
+			Options: options.Index().SetName("tenant_id_title_text"),
 		},
 	}
 
@@ -70,23 +197,51 @@ func (c *DBConnection) createMessageIndexes(ctx context.Context) error {
 	messageIndexes := []mongo.IndexModel{
 		{
 			Keys: bson.D{
+				{Key: "tenant_id", Value: 1},
 				{Key: "chat_id", Value: 1},
 				{Key: "created_at", Value: -1},
 			},
-			Options: options.Index().SetName("chat_id_created_at"),
+			Options: options.Index().SetName("tenant_id_chat_id_created_at"),
 		},
 		{
+			// Supports keyset pagination of message history
 			Keys: bson.D{
+				{Key: "tenant_id", Value: 1},
+				{Key: "chat_id", Value: 1},
+				{Key: "created_at", Value: 1},
+				{Key: "_id", Value: 1},
+			},
+			Options: options.Index().SetName("tenant_id_chat_id_created_at_id"),
+		},
+		{
+			Keys: bson.D{
+				{Key: "tenant_id", Value: 1},
 				{Key: "role", Value: 1},
 				{Key: "chat_id", Value: 1},
 			},
-			Options: options.Index().SetName("role_chat_id"),
+			Options: options.Index().SetName("tenant_id_role_chat_id"),
 		},
 		{
+			// Supports the daily message quota
 			Keys: bson.D{
+				{Key: "tenant_id", Value: 1},
+				{Key: "created_at", Value: -1},
+			},
+			Options: options.Index().SetName("tenant_id_created_at"),
+		},
+		{
+			// Supports trash listing and purging; only trashed messages are indexed
+			Keys: bson.D{
+				{Key: "deleted_at", Value: -1},
+			},
+			Options: options.Index().SetName("deleted_at").SetSparse(true),
+		},
+		{
+			Keys: bson.D{
+				{Key: "tenant_id", Value: 1},
 				{Key: "content", Value: "text"},
 			},
-			Options: options.Index().SetName("content_text"),
+			Options: options.Index().SetName("tenant_id_content_text"),
 		},
 	}
 
@@ -100,3 +255,241 @@ func (c *DBConnection) createMessageIndexes(ctx context.Context) error {
 	logger.Info("Message indexes created successfully")
 	return nil
 }
+
+// createFolderIndexes creates indexes for the folders collection
+func (c *DBConnection) createFolderIndexes(ctx context.Context) error {
+	folderIndexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "tenant_id", Value: 1},
+				{Key: "owner_id", Value: 1},
+				{Key: "name", Value: 1},
+			},
+			Options: options.Index().SetName("tenant_id_owner_id_name"),
+		},
+	}
+
+	_, err := c.Folders().Indexes().CreateMany(ctx, folderIndexes)
+	if err != nil {
+		logger.Errorf("Failed to create folder indexes: %v", err)
+		return err
+	}
+
+	logger.Info("Folder indexes created successfully")
+	return nil
+}
+
+// createInviteIndexes creates indexes for the invites collection
+func (c *DBConnection) createInviteIndexes(ctx context.Context) error {
+	inviteIndexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "code_hash", Value: 1},
+			},
+			Options: options.Index().SetName("code_hash").SetUnique(true),
+		},
+		{
+			Keys: bson.D{
+				{Key: "chat_id", Value: 1},
+				{Key: "created_at", Value: -1},
+			},
+			Options: options.Index().SetName("chat_id_created_at"),
+		},
+		{
+			// Let MongoDB remove invites once they expire
+			Keys: bson.D{
+				{Key: "expires_at", Value: 1},
+			},
+			Options: options.Index().SetName("expires_at_ttl").SetExpireAfterSeconds(0),
+		},
+	}
+
+	_, err := c.Invites().Indexes().CreateMany(ctx, inviteIndexes)
+	if err != nil {
+		logger.Errorf("Failed to create invite indexes: %v", err)
+		return err
+	}
+
+	logger.Info("Invite indexes created successfully")
+	return nil
+}
+
+// createGenerationIndexes creates indexes for the generations collection
+func (c *DBConnection) createGenerationIndexes(ctx context.Context) error {
+	generationIndexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "tenant_id", Value: 1},
+				{Key: "chat_id", Value: 1},
+				{Key: "status", Value: 1},
+				{Key: "started_at", Value: -1},
+			},
+			Options: options.Index().SetName("tenant_id_chat_id_status_started_at"),
+		},
+		{
+			// Supports the sweep for generations that stopped checkpointing
+			Keys: bson.D{
+				{Key: "status", Value: 1},
+				{Key: "updated_at", Value: 1},
+			},
+			Options: options.Index().SetName("status_updated_at"),
+		},
+	}
+
+	_, err := c.Generations().Indexes().CreateMany(ctx, generationIndexes)
+	if err != nil {
+		logger.Errorf("Failed to create generation indexes: %v", err)
+		return err
+	}
+
+	logger.Info("Generation indexes created successfully")
+	return nil
+}
+
+// createJobIndexes creates indexes for the jobs collection
+func (c *DBConnection) createJobIndexes(ctx context.Context) error {
+	jobIndexes := []mongo.IndexModel{
+		{
+			// Supports claiming the next visible job of the types a worker runs
+			Keys: bson.D{
+				{Key: "status", Value: 1},
+				{Key: "type", Value: 1},
+				{Key: "visible_at", Value: 1},
+			},
+			Options: options.Index().SetName("status_type_visible_at"),
+		},
+		{
+			Keys: bson.D{
+				{Key: "status", Value: 1},
+				{Key: "created_at", Value: -1},
+			},
+			Options: options.Index().SetName("status_created_at"),
+		},
+		{
+			// Deduplicates jobs enqueued with a key, such as scheduled runs
+			Keys: bson.D{
+				{Key: "unique_key", Value: 1},
+			},
+			Options: options.Index().SetName("unique_key").SetUnique(true).
+				SetPartialFilterExpression(bson.M{"unique_key": bson.M{"$exists": true}}),
+		},
+		{
+			// Let MongoDB remove succeeded jobs once their retention passes
+			Keys: bson.D{
+				{Key: "expires_at", Value: 1},
+			},
+			Options: options.Index().SetName("expires_at_ttl").SetExpireAfterSeconds(0),
+		},
+	}
+
+	_, err := c.Jobs().Indexes().CreateMany(ctx, jobIndexes)
+	if err != nil {
+		logger.Errorf("Failed to create job indexes: %v", err)
+		return err
+	}
+
+	logger.Info("Job indexes created successfully")
+	return nil
+}
+
+// createWebhookIndexes creates indexes for the webhook and delivery log collections
+func (c *DBConnection) createWebhookIndexes(ctx context.Context) error {
+	webhookIndexes := []mongo.IndexModel{
+		{
+			// Supports finding the webhooks subscribed to an event
+			Keys: bson.D{
+				{Key: "tenant_id", Value: 1},
+				{Key: "active", Value: 1},
+				{Key: "events", Value: 1},
+			},
+			Options: options.Index().SetName("tenant_id_active_events"),
+		},
+		{
+			Keys: bson.D{
+				{Key: "tenant_id", Value: 1},
+				{Key: "owner_id", Value: 1},
+				{Key: "created_at", Value: -1},
+			},
+			Options: options.Index().SetName("tenant_id_owner_id_created_at"),
+		},
+	}
+
+	if _, err := c.Webhooks().Indexes().CreateMany(ctx, webhookIndexes); err != nil {
+		logger.Errorf("Failed to create webhook indexes: %v", err)
+		return err
+	}
+
+	deliveryIndexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "tenant_id", Value: 1},
+				{Key: "webhook_id", Value: 1},
+				{Key: "created_at", Value: -1},
+			},
+			Options: options.Index().SetName("tenant_id_webhook_id_created_at"),
+		},
+		{
+			// Let MongoDB remove deliveries once their retention passes
+			Keys: bson.D{
+				{Key: "expires_at", Value: 1},
+			},
+			Options: options.Index().SetName("expires_at_ttl").SetExpireAfterSeconds(0),
+		},
+	}
+
+	if _, err := c.WebhookDeliveries().Indexes().CreateMany(ctx, deliveryIndexes); err != nil {
+		logger.Errorf("Failed to create webhook delivery indexes: %v", err)
+		return err
+	}
+
+	logger.Info("Webhook indexes created successfully")
+	return nil
+}
+
+// createUsageIndexes creates indexes for the daily usage counter collection
+func (c *DBConnection) createUsageIndexes(ctx context.Context) error {
+	usageIndexes := []mongo.IndexModel{
+		{
+			// One counter per tenant and day
+			Keys: bson.D{
+				{Key: "tenant_id", Value: 1},
+				{Key: "day", Value: 1},
+			},
+			Options: options.Index().SetName("tenant_id_day").SetUnique(true),
+		},
+		{
+			// Let MongoDB remove counters once their day is over
+			Keys: bson.D{
+				{Key: "expires_at", Value: 1},
+			},
+			Options: options.Index().SetName("expires_at_ttl").SetExpireAfterSeconds(0),
+		},
+	}
+
+	if _, err := c.Usage().Indexes().CreateMany(ctx, usageIndexes); err != nil {
+		logger.Errorf("Failed to create usage indexes: %v", err)
+		return err
+	}
+
+	logger.Info("Usage indexes created successfully")
+	return nil
+}
+
+// dropIndexes removes the named indexes from a collection, ignoring ones that do not exist
+func dropIndexes(ctx context.Context, collection *mongo.Collection, names []string) error {
+	for _, name := range names {
+		_, err := collection.Indexes().DropOne(ctx, name)
+		if err == nil {
+			logger.Infof("Dropped legacy index %s.%s", collection.Name(), name)
+			continue
+		}
+
+		var cmdErr mongo.CommandError
+		if errors.As(err, &cmdErr) && (cmdErr.Name == "IndexNotFound" || cmdErr.Name == "NamespaceNotFound") {
+			continue
+		}
+		logger.Errorf("Failed to drop index %s.%s: %v", collection.Name(), name, err)
+		return err
+	}
+	return nil
+}
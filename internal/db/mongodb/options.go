@@ -10,7 +10,12 @@
 package mongodb
 
 import (
+	"context"
+
 	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/config"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/metrics"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/tracing"
+	"go.mongodb.org/mongo-driver/event"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
@@ -19,5 +24,28 @@ func createClientOptions(cfg *config.MongoDBConfig) *options.ClientOptions {
 	return options.Client().
 		ApplyURI(cfg.URI).
 		SetMaxPoolSize(cfg.MaxPoolSize).
-		SetConnectTimeout(cfg.Timeout)
+		SetConnectTimeout(cfg.Timeout).
+		SetMonitor(combineMonitors(metrics.NewCommandMonitor(), tracing.NewCommandMonitor()))
+}
+
+// combineMonitors returns a command monitor that forwards every event to all monitors.
+// The driver accepts a single monitor per client.
+func combineMonitors(monitors ...*event.CommandMonitor) *event.CommandMonitor {
+	return &event.CommandMonitor{
+		Started: func(ctx context.Context, e *event.CommandStartedEvent) {
+			for _, monitor := range monitors {
+				monitor.Started(ctx, e)
+			}
+		},
+		Succeeded: func(ctx context.Context, e *event.CommandSucceededEvent) {
+			for _, monitor := range monitors {
+				monitor.Succeeded(ctx, e)
+			}
+		},
+		Failed: func(ctx context.Context, e *event.CommandFailedEvent) {
+			for _, monitor := range monitors {
+				monitor.Failed(ctx, e)
+			}
+		},
+	}
 }
@@ -0,0 +1,35 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package mongodb
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// BackfillTenant assigns documents created before tenancy existed to the given tenant.
+// It returns the number of updated documents per collection.
+func (c *DBConnection) BackfillTenant(ctx context.Context, tenantID string) (map[string]int64, error) {
+	collections := []*mongo.Collection{c.Chats(), c.Messages(), c.Folders(), c.Invites()}
+	filter := bson.M{"tenant_id": bson.M{"$in": bson.A{nil, ""}}}
+	update := bson.M{"$set": bson.M{"tenant_id": tenantID}}
+
+	updated := make(map[string]int64, len(collections))
+	for _, collection := range collections {
+		result, err := collection.UpdateMany(ctx, filter, update)
+		if err != nil {
+			return updated, err
+		}
+		updated[collection.Name()] = result.ModifiedCount
+	}
+	return updated, nil
+}
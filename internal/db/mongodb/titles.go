@@ -0,0 +1,33 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package mongodb
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// BackfillTitleLower sets the lowercased title of chats created before it was
+// stored, so that title prefix filters match them. It returns the number of
+// updated chats.
+func (c *DBConnection) BackfillTitleLower(ctx context.Context) (int64, error) {
+	filter := bson.M{"title_lower": bson.M{"$exists": false}}
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{"title_lower": bson.M{"$toLower": "$title"}}}},
+	}
+
+	result, err := c.Chats().UpdateMany(ctx, filter, update)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
@@ -0,0 +1,65 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package mongodb
+
+import (
+	"context"
+
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/pkg/logger"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// detectTransactionSupport checks whether the server is a replica set member or
+// a mongos router; standalone servers do not support multi-document transactions
+func (c *DBConnection) detectTransactionSupport(ctx context.Context) {
+	var hello struct {
+		SetName string `bson:"setName"`
+		Msg     string `bson:"msg"`
+	}
+
+	err := c.database.RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello)
+	if err != nil {
+		logger.Warnf("Failed to detect transaction support, running without transactions: %v", err)
+		return
+	}
+
+	c.transactions = hello.SetName != "" || hello.Msg == "isdbgrid"
+	if !c.transactions {
+		logger.Warn("MongoDB is running as a standalone server; multi-document operations will run without transactions")
+	}
+}
+
+// SupportsTransactions reports whether multi-document transactions are available
+func (c *DBConnection) SupportsTransactions() bool {
+	return c.transactions
+}
+
+// WithTransaction runs fn inside a MongoDB transaction. Repository calls made with
+// the context passed to fn take part in the transaction. fn may be retried on
+// transient errors, so it must not have side effects outside the database.
+// On servers without transaction support fn runs directly with ctx.
+func (c *DBConnection) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	// Nested calls join the surrounding transaction
+	if !c.transactions || mongo.SessionFromContext(ctx) != nil {
+		return fn(ctx)
+	}
+
+	session, err := c.client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sc)
+	})
+	return err
+}
@@ -10,12 +10,20 @@
 package handlers
 
 import (
+	"context"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/models"
 	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/models/dto"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/repository"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/services"
 	"github.com/yasin-yalcin-dev/go-sse-ai-chat/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // CreateChat handles POST /api/v1/chats
@@ -32,20 +40,7 @@ func (h *Handler) CreateChat(c *gin.Context) {
 		return
 	}
 
-	// Convert domain model to DTO
-	response := dto.ChatResponse{
-		ID:           chat.ID.Hex(),
-		Title:        chat.Title,
-		CreatedAt:    chat.CreatedAt.Format(time.RFC3339),
-		UpdatedAt:    chat.UpdatedAt.Format(time.RFC3339),
-		MessageCount: chat.MessageCount,
-	}
-
-	if !chat.LastMessageAt.IsZero() {
-		response.LastMessageAt = chat.LastMessageAt.Format(time.RFC3339)
-	}
-
-	respondWithJSON(c, http.StatusCreated, response)
+	respondWithJSON(c, http.StatusCreated, newChatResponse(chat))
 }
 
 // GetChat handles GET /api/v1/chats/:id
@@ -62,59 +57,80 @@ func (h *Handler) GetChat(c *gin.Context) {
 		return
 	}
 
-	// Convert domain model to DTO
-	response := dto.ChatResponse{
-		ID:           chat.ID.Hex(),
-		Title:        chat.Title,
-		CreatedAt:    chat.CreatedAt.Format(time.RFC3339),
-		UpdatedAt:    chat.UpdatedAt.Format(time.RFC3339),
-		MessageCount: chat.MessageCount,
-	}
+	respondWithJSON(c, http.StatusOK, newChatResponse(chat))
+}
 
-	if !chat.LastMessageAt.IsZero() {
-		response.LastMessageAt = chat.LastMessageAt.Format(time.RFC3339)
+// ListChats handles GET /api/v1/chats
+func (h *Handler) ListChats(c *gin.Context) {
+	response, err := listChats(c.Request.Context(), h.chatService, c.Request.URL.Query())
+	if err != nil {
+		respondWithError(c, err)
+		return
 	}
 
 	respondWithJSON(c, http.StatusOK, response)
 }
 
-// ListChats handles GET /api/v1/chats
-func (h *Handler) ListChats(c *gin.Context) {
-	page, pageSize := handlePagination(c, 10, 100)
+// listChats lists a page of chats with the filters, sort order and page or
+// cursor given as query parameters. It is shared by the REST and gRPC APIs.
+func listChats(ctx context.Context, chatService services.ChatService, values url.Values) (*dto.ChatListResponse, error) {
+	page, pageSize := parsePagination(values, 10, 100)
 
-	chats, total, err := h.chatService.ListChats(c.Request.Context(), page, pageSize)
+	opts, err := parseChatListOptions(values)
 	if err != nil {
-		respondWithError(c, err)
-		return
+		return nil, err
 	}
 
-	// Convert domain models to DTOs
-	chatResponses := make([]dto.ChatResponse, len(chats))
-	for i, chat := range chats {
-		chatResponses[i] = dto.ChatResponse{
-			ID:           chat.ID.Hex(),
-			Title:        chat.Title,
-			CreatedAt:    chat.CreatedAt.Format(time.RFC3339),
-			UpdatedAt:    chat.UpdatedAt.Format(time.RFC3339),
-			MessageCount: chat.MessageCount,
+	query, useCursor, err := parseCursor(values, pageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	if !useCursor {
+		chats, total, err := chatService.ListChats(ctx, opts, page, pageSize)
+		if err != nil {
+			return nil, err
 		}
 
-		if !chat.LastMessageAt.IsZero() {
-			chatResponses[i].LastMessageAt = chat.LastMessageAt.Format(time.RFC3339)
+		var first, last *repository.Cursor
+		if len(chats) > 0 {
+			first = chatCursor(chats[0], opts.Sort)
+			last = chatCursor(chats[len(chats)-1], opts.Sort)
 		}
+
+		pages := calculateTotalPages(total, pageSize)
+		return &dto.ChatListResponse{
+			Chats: newChatResponses(chats),
+			Pagination: dto.PaginationInfo{
+				Total:    total,
+				Page:     page,
+				PageSize: pageSize,
+				Pages:    pages,
+			},
+			Cursors: newCursorInfo(pageInfo(page, pages), first, last),
+		}, nil
 	}
 
-	response := dto.ChatListResponse{
-		Chats: chatResponses,
+	chats, info, total, err := chatService.ListChatsByCursor(ctx, opts, query)
+	if err != nil {
+		return nil, err
+	}
+
+	var first, last *repository.Cursor
+	if len(chats) > 0 {
+		first = chatCursor(chats[0], opts.Sort)
+		last = chatCursor(chats[len(chats)-1], opts.Sort)
+	}
+
+	return &dto.ChatListResponse{
+		Chats: newChatResponses(chats),
 		Pagination: dto.PaginationInfo{
 			Total:    total,
-			Page:     page,
-			PageSize: pageSize,
-			Pages:    calculateTotalPages(total, pageSize),
+			PageSize: query.Limit,
+			Pages:    calculateTotalPages(total, query.Limit),
 		},
-	}
-
-	respondWithJSON(c, http.StatusOK, response)
+		Cursors: newCursorInfo(info, first, last),
+	}, nil
 }
 
 // UpdateChat handles PUT /api/v1/chats/:id
@@ -131,26 +147,98 @@ func (h *Handler) UpdateChat(c *gin.Context) {
 		return
 	}
 
-	chat, err := h.chatService.UpdateChat(c.Request.Context(), id, req.Title)
+	chat, err := h.chatService.UpdateChat(c.Request.Context(), id, req.Title, req.Pinned)
 	if err != nil {
 		respondWithError(c, err)
 		return
 	}
 
-	// Convert domain model to DTO
-	response := dto.ChatResponse{
-		ID:           chat.ID.Hex(),
-		Title:        chat.Title,
-		CreatedAt:    chat.CreatedAt.Format(time.RFC3339),
-		UpdatedAt:    chat.UpdatedAt.Format(time.RFC3339),
-		MessageCount: chat.MessageCount,
+	respondWithJSON(c, http.StatusOK, newChatResponse(chat))
+}
+
+// ArchiveChat handles POST /api/v1/chats/:id/archive
+func (h *Handler) ArchiveChat(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		respondWithError(c, errors.NewBadRequestError("Chat ID is required", nil))
+		return
 	}
 
-	if !chat.LastMessageAt.IsZero() {
-		response.LastMessageAt = chat.LastMessageAt.Format(time.RFC3339)
+	chat, err := h.chatService.ArchiveChat(c.Request.Context(), id)
+	if err != nil {
+		respondWithError(c, err)
+		return
 	}
 
-	respondWithJSON(c, http.StatusOK, response)
+	respondWithJSON(c, http.StatusOK, newChatResponse(chat))
+}
+
+// UnarchiveChat handles POST /api/v1/chats/:id/unarchive
+func (h *Handler) UnarchiveChat(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		respondWithError(c, errors.NewBadRequestError("Chat ID is required", nil))
+		return
+	}
+
+	chat, err := h.chatService.UnarchiveChat(c.Request.Context(), id)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	respondWithJSON(c, http.StatusOK, newChatResponse(chat))
+}
+
+// RestoreChat handles POST /api/v1/chats/:id/restore
+func (h *Handler) RestoreChat(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		respondWithError(c, errors.NewBadRequestError("Chat ID is required", nil))
+		return
+	}
+
+	chat, err := h.chatService.RestoreChat(c.Request.Context(), id)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	respondWithJSON(c, http.StatusOK, newChatResponse(chat))
+}
+
+// MoveChats handles POST /api/v1/chats/bulk/move
+func (h *Handler) MoveChats(c *gin.Context) {
+	var req dto.MoveChatsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondWithError(c, errors.NewBadRequestError("Invalid request body", err))
+		return
+	}
+
+	updated, err := h.chatService.MoveChats(c.Request.Context(), req.ChatIDs, req.FolderID)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	respondWithJSON(c, http.StatusOK, dto.BulkUpdateResponse{Updated: updated})
+}
+
+// UpdateChatTags handles POST /api/v1/chats/bulk/tags
+func (h *Handler) UpdateChatTags(c *gin.Context) {
+	var req dto.UpdateChatTagsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondWithError(c, errors.NewBadRequestError("Invalid request body", err))
+		return
+	}
+
+	updated, err := h.chatService.UpdateChatTags(c.Request.Context(), req.ChatIDs, req.Add, req.Remove)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	respondWithJSON(c, http.StatusOK, dto.BulkUpdateResponse{Updated: updated})
 }
 
 // DeleteChat handles DELETE /api/v1/chats/:id
@@ -170,3 +258,153 @@ func (h *Handler) DeleteChat(c *gin.Context) {
 		Message: "Chat deleted successfully",
 	})
 }
+
+// newChatResponse converts a chat domain model to its DTO
+func newChatResponse(chat *models.Chat) dto.ChatResponse {
+	response := dto.ChatResponse{
+		ID:           chat.ID.Hex(),
+		Title:        chat.Title,
+		CreatedAt:    chat.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:    chat.UpdatedAt.Format(time.RFC3339),
+		MessageCount: chat.MessageCount,
+		Archived:     chat.Archived,
+		Pinned:       chat.Pinned,
+		Tags:         chat.Tags,
+		OwnerID:      chat.OwnerID,
+	}
+
+	if !chat.LastMessageAt.IsZero() {
+		response.LastMessageAt = chat.LastMessageAt.Format(time.RFC3339)
+	}
+
+	if chat.ArchivedAt != nil {
+		response.ArchivedAt = chat.ArchivedAt.Format(time.RFC3339)
+	}
+
+	if chat.FolderID != nil {
+		response.FolderID = chat.FolderID.Hex()
+	}
+
+	if chat.DeletedAt != nil {
+		response.DeletedAt = chat.DeletedAt.Format(time.RFC3339)
+	}
+
+	return response
+}
+
+// newChatResponses converts a list of chat domain models to DTOs
+func newChatResponses(chats []*models.Chat) []dto.ChatResponse {
+	responses := make([]dto.ChatResponse, len(chats))
+	for i, chat := range chats {
+		responses[i] = newChatResponse(chat)
+	}
+	return responses
+}
+
+// chatCursor returns the keyset position of a chat in a chat list in the given sort order
+func chatCursor(chat *models.Chat, sort repository.ChatSort) *repository.Cursor {
+	var value interface{}
+	switch sort.Field {
+	case repository.ChatSortCreatedAt:
+		value = chat.CreatedAt
+	case repository.ChatSortLastMessageAt:
+		// Chats without messages have no last_message_at stored
+		if !chat.LastMessageAt.IsZero() {
+			value = chat.LastMessageAt
+		}
+	case repository.ChatSortTitle:
+		value = chat.Title
+	default:
+		value = chat.UpdatedAt
+	}
+
+	return repository.NewCursor(sort.Field, sort.Ascending, value, chat.ID)
+}
+
+// parseChatListOptions extracts chat listing filters and sort order from query parameters.
+// Without explicit filters only active, unarchived chats are listed.
+func parseChatListOptions(values url.Values) (repository.ChatListOptions, error) {
+	var opts repository.ChatListOptions
+	var err error
+
+	active := true
+	opts.Filter.Active = &active
+	if values.Get("active") != "" {
+		if opts.Filter.Active, err = parseBoolFilter(values, "active"); err != nil {
+			return opts, err
+		}
+	}
+
+	archived := false
+	opts.Filter.Archived = &archived
+	if values.Get("archived") != "" {
+		if opts.Filter.Archived, err = parseBoolFilter(values, "archived"); err != nil {
+			return opts, err
+		}
+	}
+
+	if opts.Filter.Pinned, err = parseBoolFilter(values, "pinned"); err != nil {
+		return opts, err
+	}
+
+	opts.Filter.Tag = strings.ToLower(strings.TrimSpace(values.Get("tag")))
+	opts.Filter.TitlePrefix = values.Get("title_prefix")
+
+	switch folderID := values.Get("folder_id"); folderID {
+	case "":
+	case "none":
+		opts.Filter.Unfiled = true
+	default:
+		id, err := primitive.ObjectIDFromHex(folderID)
+		if err != nil {
+			return opts, errors.NewBadRequestError("folder_id must be a folder ID or none", err)
+		}
+		opts.Filter.FolderID = &id
+	}
+
+	if createdAfter := values.Get("created_after"); createdAfter != "" {
+		t, err := time.Parse(time.RFC3339, createdAfter)
+		if err != nil {
+			return opts, errors.NewBadRequestError("created_after must be an RFC 3339 timestamp", err)
+		}
+		opts.Filter.CreatedAfter = &t
+	}
+
+	opts.Sort = repository.DefaultChatSort()
+	if field := values.Get("sort"); field != "" {
+		if !repository.IsValidChatSortField(field) {
+			return opts, errors.NewBadRequestError("sort must be one of updated_at, last_message_at, created_at, title", nil)
+		}
+		opts.Sort.Field = field
+		// Titles read naturally A-Z, timestamps newest first
+		opts.Sort.Ascending = field == repository.ChatSortTitle
+	}
+
+	switch values.Get("order") {
+	case "":
+	case "asc":
+		opts.Sort.Ascending = true
+	case "desc":
+		opts.Sort.Ascending = false
+	default:
+		return opts, errors.NewBadRequestError("order must be asc or desc", nil)
+	}
+
+	return opts, nil
+}
+
+// parseBoolFilter parses an optional boolean query filter.
+// It returns nil when the parameter is absent or set to "any".
+func parseBoolFilter(values url.Values, name string) (*bool, error) {
+	value := values.Get(name)
+	if value == "" || value == "any" {
+		return nil, nil
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil, errors.NewBadRequestError(name+" must be true, false or any", err)
+	}
+
+	return &b, nil
+}
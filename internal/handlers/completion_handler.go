@@ -0,0 +1,252 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/ai"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/middleware"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/models/dto"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/services"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/sse"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/pkg/errors"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/pkg/logger"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ChatIDHeader names the chat to store an exchange of the OpenAI-compatible API in
+const ChatIDHeader = "X-Chat-ID"
+
+// completionWriteGrace is the time to write a completion once the provider timed out
+const completionWriteGrace = 10 * time.Second
+
+// Object types of the OpenAI-compatible API
+const (
+	objectChatCompletion      = "chat.completion"
+	objectChatCompletionChunk = "chat.completion.chunk"
+)
+
+// CompletionHandler handles the OpenAI-compatible chat completion API, so
+// that OpenAI SDKs can use the server as a gateway to the configured provider
+type CompletionHandler struct {
+	completionService services.CompletionService
+	timeout           time.Duration
+}
+
+// NewCompletionHandler creates a new completion handler. timeout is the
+// provider timeout, which responses may take to write.
+func NewCompletionHandler(completionService services.CompletionService, timeout time.Duration) *CompletionHandler {
+	return &CompletionHandler{
+		completionService: completionService,
+		timeout:           timeout,
+	}
+}
+
+// CreateChatCompletion handles POST /v1/chat/completions.
+// The reply is streamed as chat.completion.chunk events when the request asks
+// for it, and returned as a chat.completion otherwise.
+func (h *CompletionHandler) CreateChatCompletion(c *gin.Context) {
+	var body dto.ChatCompletionRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		respondWithError(c, errors.NewBadRequestError("Invalid request body", err))
+		return
+	}
+
+	req, err := newCompletionRequest(&body, c.GetHeader(ChatIDHeader))
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	if req.ChatID != "" {
+		ctx = logger.WithContext(ctx, logger.FieldChatID, req.ChatID)
+	}
+
+	// Replies may take longer than the server's write timeout
+	if h.timeout > 0 {
+		if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Now().Add(h.timeout + completionWriteGrace)); err != nil {
+			logger.FromContext(ctx).Debugf("Failed to extend write deadline of completion: %v", err)
+		}
+	}
+
+	chunk := dto.ChatCompletionChunk{
+		ID:      "chatcmpl-" + primitive.NewObjectID().Hex(),
+		Object:  objectChatCompletionChunk,
+		Created: time.Now().Unix(),
+	}
+
+	if body.Stream {
+		includeUsage := body.StreamOptions != nil && body.StreamOptions.IncludeUsage
+		h.streamCompletion(ctx, c, req, chunk, includeUsage)
+		return
+	}
+
+	result, err := h.completionService.CreateCompletion(ctx, &req, func(string) error { return nil })
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	respondWithJSON(c, http.StatusOK, dto.ChatCompletionResponse{
+		ID:      chunk.ID,
+		Object:  objectChatCompletion,
+		Created: chunk.Created,
+		Model:   result.Model,
+		Choices: []dto.ChatCompletionChoice{{
+			Message:      dto.ChatCompletionReplyMessage{Role: ai.RoleAssistant, Content: result.Content},
+			FinishReason: result.FinishReason,
+		}},
+		Usage: newCompletionUsage(result.Usage),
+	})
+}
+
+// streamCompletion streams a completion as Server-Sent Events, ending with a
+// [DONE] event. The response starts with the first output, so that errors
+// before it are reported with their status code; later errors are sent as
+// an error event.
+func (h *CompletionHandler) streamCompletion(ctx context.Context, c *gin.Context, req services.CompletionRequest, chunk dto.ChatCompletionChunk, includeUsage bool) {
+	var transport *sse.SSETransport
+
+	write := func(data interface{}) error {
+		payload, err := json.Marshal(data)
+		if err != nil {
+			return err
+		}
+		return transport.WriteMessage(&sse.Message{Data: payload})
+	}
+
+	writeDelta := func(delta dto.ChatCompletionDelta, finishReason *string) error {
+		chunk.Choices = []dto.ChatCompletionChunkChoice{{Delta: delta, FinishReason: finishReason}}
+		return write(chunk)
+	}
+
+	open := func() error {
+		if transport != nil {
+			return nil
+		}
+
+		var err error
+		if transport, err = sse.NewSSETransport(c.Writer); err != nil {
+			return err
+		}
+		if err := transport.Open(); err != nil {
+			return err
+		}
+		return writeDelta(dto.ChatCompletionDelta{Role: ai.RoleAssistant}, nil)
+	}
+
+	result, err := h.completionService.CreateCompletion(ctx, &req, func(content string) error {
+		chunk.Model = req.Model
+		if err := open(); err != nil {
+			return err
+		}
+		return writeDelta(dto.ChatCompletionDelta{Content: content}, nil)
+	})
+	if err != nil {
+		if transport == nil {
+			respondWithError(c, err)
+			return
+		}
+
+		logger.FromContext(ctx).Warnf("Completion failed after streaming started: %v", err)
+		details := dto.ErrorDetails{Message: err.Error()}
+		if appErr, ok := errors.AsAppError(err); ok {
+			details.Code = appErr.Code
+		}
+		if err := write(dto.ErrorResponse{Error: details, RequestID: middleware.GetRequestID(c)}); err != nil {
+			logger.FromContext(ctx).Debugf("Failed to send completion error: %v", err)
+		}
+		return
+	}
+
+	// Empty replies still get a response
+	if err := open(); err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	chunk.Model = result.Model
+	if err := writeDelta(dto.ChatCompletionDelta{}, &result.FinishReason); err != nil {
+		logger.FromContext(ctx).Debugf("Failed to finish completion stream: %v", err)
+		return
+	}
+
+	if includeUsage {
+		usage := newCompletionUsage(result.Usage)
+		chunk.Choices = []dto.ChatCompletionChunkChoice{}
+		chunk.Usage = &usage
+		if err := write(chunk); err != nil {
+			logger.FromContext(ctx).Debugf("Failed to send completion usage: %v", err)
+			return
+		}
+	}
+
+	if err := transport.WriteMessage(&sse.Message{Data: json.RawMessage("[DONE]")}); err != nil {
+		logger.FromContext(ctx).Debugf("Failed to end completion stream: %v", err)
+	}
+}
+
+// newCompletionRequest validates a completion request. The exchange is
+// stored in the chat named by chatID, if any, or else by the user field when
+// it holds a chat ID. Clients that cannot set headers name the chat that way;
+// other user values are opaque end-user IDs and store nothing.
+func newCompletionRequest(body *dto.ChatCompletionRequest, chatID string) (services.CompletionRequest, error) {
+	if body.N > 1 {
+		return services.CompletionRequest{}, errors.NewValidationError("Only one choice (n=1) is supported", nil)
+	}
+
+	if chatID == "" && primitive.IsValidObjectID(body.User) {
+		chatID = body.User
+	}
+	if chatID != "" && !primitive.IsValidObjectID(chatID) {
+		return services.CompletionRequest{}, errors.NewValidationError("Invalid chat ID", nil)
+	}
+
+	req := services.CompletionRequest{
+		Request: ai.Request{
+			Model:       body.Model,
+			Messages:    make([]ai.Message, len(body.Messages)),
+			MaxTokens:   body.MaxTokens,
+			Temperature: body.Temperature,
+			TopP:        body.TopP,
+			Stop:        body.Stop,
+		},
+		ChatID: chatID,
+	}
+	if body.MaxCompletionTokens > 0 {
+		req.MaxTokens = body.MaxCompletionTokens
+	}
+
+	for i, message := range body.Messages {
+		role := message.Role
+		if role == "developer" {
+			// Newer OpenAI models call system messages developer messages
+			role = ai.RoleSystem
+		}
+		req.Messages[i] = ai.Message{Role: role, Content: string(message.Content)}
+	}
+
+	return req, nil
+}
+
+// newCompletionUsage converts the token usage of a completion
+func newCompletionUsage(usage ai.Usage) dto.ChatCompletionUsage {
+	return dto.ChatCompletionUsage{
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		TotalTokens:      usage.TotalTokens(),
+	}
+}
@@ -0,0 +1,254 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/ai"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/models/dto"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/services"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/pkg/errors"
+)
+
+const testChatID = "6123456789abcdef01234567"
+
+// fakeCompletions is a CompletionService that emits fixed chunks and then
+// returns err, or a result when err is nil
+type fakeCompletions struct {
+	chunks []string
+	err    error
+	req    *services.CompletionRequest
+}
+
+func (f *fakeCompletions) CreateCompletion(_ context.Context, req *services.CompletionRequest, emit func(chunk string) error) (*services.CompletionResult, error) {
+	f.req = req
+	if req.Model == "" {
+		req.Model = "fake-1"
+	}
+	for _, chunk := range f.chunks {
+		if err := emit(chunk); err != nil {
+			return nil, err
+		}
+	}
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &services.CompletionResult{
+		Result:  ai.Result{Model: req.Model, FinishReason: "stop", Usage: ai.Usage{PromptTokens: 5, CompletionTokens: 2}},
+		Content: strings.Join(f.chunks, ""),
+	}, nil
+}
+
+// postCompletion sends a completion request to a handler backed by service
+func postCompletion(t *testing.T, service services.CompletionService, body string, header http.Header) *httptest.ResponseRecorder {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.POST("/v1/chat/completions", NewCompletionHandler(service, 0).CreateChatCompletion)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	for key, values := range header {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// sseData returns the data of the events of a streamed response
+func sseData(t *testing.T, body string) []string {
+	t.Helper()
+	var data []string
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	for scanner.Scan() {
+		if payload, ok := strings.CutPrefix(scanner.Text(), "data: "); ok {
+			data = append(data, payload)
+		}
+	}
+	return data
+}
+
+func TestNewCompletionRequestChatID(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  string
+		user    string
+		want    string
+		wantErr bool
+	}{
+		{name: "header", header: testChatID, want: testChatID},
+		{name: "user field holding a chat ID", user: testChatID, want: testChatID},
+		{name: "opaque user field", user: "user-1234"},
+		{name: "header takes precedence", header: testChatID, user: "6123456789abcdef0123ffff", want: testChatID},
+		{name: "opaque user field with header", header: testChatID, user: "user-1234", want: testChatID},
+		{name: "invalid header", header: "not-a-chat", wantErr: true},
+		{name: "neither"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := &dto.ChatCompletionRequest{
+				Messages: []dto.ChatCompletionMessage{{Role: ai.RoleUser, Content: "Hi"}},
+				User:     tt.user,
+			}
+			req, err := newCompletionRequest(body, tt.header)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newCompletionRequest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if req.ChatID != tt.want {
+				t.Errorf("newCompletionRequest() chat ID = %q, want %q", req.ChatID, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateChatCompletion(t *testing.T) {
+	service := &fakeCompletions{chunks: []string{"Hel", "lo"}}
+	body := `{"messages": [{"role": "developer", "content": "Be brief"}, {"role": "user", "content": "Hi"}], "user": "` + testChatID + `"}`
+
+	w := postCompletion(t, service, body, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+
+	var resp dto.ChatCompletionResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("response error = %v", err)
+	}
+	if resp.Object != objectChatCompletion || resp.Model != "fake-1" {
+		t.Errorf("response = %s from %q, want %s from %q", resp.Object, resp.Model, objectChatCompletion, "fake-1")
+	}
+	if len(resp.Choices) != 1 || resp.Choices[0].Message.Content != "Hello" {
+		t.Errorf("choices = %+v, want one with %q", resp.Choices, "Hello")
+	}
+	if resp.Usage.TotalTokens != 7 {
+		t.Errorf("total tokens = %d, want 7", resp.Usage.TotalTokens)
+	}
+
+	if service.req.ChatID != testChatID {
+		t.Errorf("chat ID = %q, want the user field %q", service.req.ChatID, testChatID)
+	}
+	if service.req.Messages[0].Role != ai.RoleSystem {
+		t.Errorf("developer message role = %q, want %q", service.req.Messages[0].Role, ai.RoleSystem)
+	}
+}
+
+func TestCreateChatCompletionStream(t *testing.T) {
+	service := &fakeCompletions{chunks: []string{"Hel", "lo"}}
+	body := `{"messages": [{"role": "user", "content": "Hi"}], "stream": true, "stream_options": {"include_usage": true}}`
+
+	w := postCompletion(t, service, body, http.Header{ChatIDHeader: {testChatID}})
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+	if got := w.Header().Get("Content-Type"); !strings.HasPrefix(got, "text/event-stream") {
+		t.Errorf("Content-Type = %q, want text/event-stream", got)
+	}
+
+	data := sseData(t, w.Body.String())
+	if len(data) != 6 || data[len(data)-1] != "[DONE]" {
+		t.Fatalf("events = %q, want role, two deltas, finish, usage and [DONE]", data)
+	}
+
+	chunks := make([]dto.ChatCompletionChunk, len(data)-1)
+	for i, payload := range data[:len(data)-1] {
+		if err := json.Unmarshal([]byte(payload), &chunks[i]); err != nil {
+			t.Fatalf("event %d error = %v", i, err)
+		}
+		if chunks[i].Object != objectChatCompletionChunk || chunks[i].ID != chunks[0].ID {
+			t.Errorf("event %d = %s %s, want %s %s", i, chunks[i].Object, chunks[i].ID, objectChatCompletionChunk, chunks[0].ID)
+		}
+	}
+
+	if chunks[0].Choices[0].Delta.Role != ai.RoleAssistant {
+		t.Errorf("first delta role = %q, want %q", chunks[0].Choices[0].Delta.Role, ai.RoleAssistant)
+	}
+	if got := chunks[1].Choices[0].Delta.Content + chunks[2].Choices[0].Delta.Content; got != "Hello" {
+		t.Errorf("streamed content = %q, want %q", got, "Hello")
+	}
+	if reason := chunks[3].Choices[0].FinishReason; reason == nil || *reason != "stop" {
+		t.Errorf("finish reason = %v, want stop", reason)
+	}
+	if usage := chunks[4].Usage; usage == nil || usage.TotalTokens != 7 || len(chunks[4].Choices) != 0 {
+		t.Errorf("usage chunk = %+v, want 7 tokens and no choices", chunks[4])
+	}
+
+	if service.req.ChatID != testChatID {
+		t.Errorf("chat ID = %q, want the header %q", service.req.ChatID, testChatID)
+	}
+}
+
+func TestCreateChatCompletionStreamErrors(t *testing.T) {
+	failure := errors.NewExternalServiceError("AI provider request failed", nil)
+	body := `{"messages": [{"role": "user", "content": "Hi"}], "stream": true}`
+
+	t.Run("before the first chunk", func(t *testing.T) {
+		w := postCompletion(t, &fakeCompletions{err: failure}, body, nil)
+		if w.Code != failure.GetStatusCode() {
+			t.Fatalf("status = %d, want %d", w.Code, failure.GetStatusCode())
+		}
+
+		var resp dto.ErrorResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("response error = %v: %s", err, w.Body.String())
+		}
+		if resp.Error.Code != failure.Code {
+			t.Errorf("error code = %q, want %q", resp.Error.Code, failure.Code)
+		}
+	})
+
+	t.Run("after the first chunk", func(t *testing.T) {
+		w := postCompletion(t, &fakeCompletions{chunks: []string{"Hel"}, err: failure}, body, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d once streaming started", w.Code, http.StatusOK)
+		}
+
+		data := sseData(t, w.Body.String())
+		if len(data) != 3 {
+			t.Fatalf("events = %q, want role, delta and error", data)
+		}
+
+		var resp dto.ErrorResponse
+		if err := json.Unmarshal([]byte(data[2]), &resp); err != nil {
+			t.Fatalf("error event = %v: %s", err, data[2])
+		}
+		if resp.Error.Code != failure.Code {
+			t.Errorf("error code = %q, want %q", resp.Error.Code, failure.Code)
+		}
+		if strings.Contains(w.Body.String(), "[DONE]") {
+			t.Error("failed stream ended with [DONE]")
+		}
+	})
+}
+
+func TestCreateChatCompletionRejectsInvalidChatID(t *testing.T) {
+	service := &fakeCompletions{}
+	body := `{"messages": [{"role": "user", "content": "Hi"}]}`
+
+	w := postCompletion(t, service, body, http.Header{ChatIDHeader: {"not-a-chat"}})
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	if service.req != nil {
+		t.Error("completion ran despite the invalid chat ID")
+	}
+}
@@ -0,0 +1,123 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/models"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/models/dto"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/pkg/errors"
+)
+
+// ListFolders handles GET /api/v1/folders
+func (h *Handler) ListFolders(c *gin.Context) {
+	folders, err := h.folderService.ListFolders(c.Request.Context())
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	response := dto.FolderListResponse{
+		Folders: make([]dto.FolderResponse, len(folders)),
+	}
+	for i, folder := range folders {
+		response.Folders[i] = newFolderResponse(folder)
+	}
+
+	respondWithJSON(c, http.StatusOK, response)
+}
+
+// CreateFolder handles POST /api/v1/folders
+func (h *Handler) CreateFolder(c *gin.Context) {
+	var req dto.CreateFolderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondWithError(c, errors.NewBadRequestError("Invalid request body", err))
+		return
+	}
+
+	folder, err := h.folderService.CreateFolder(c.Request.Context(), req.Name)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	respondWithJSON(c, http.StatusCreated, newFolderResponse(folder))
+}
+
+// GetFolder handles GET /api/v1/folders/:id
+func (h *Handler) GetFolder(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		respondWithError(c, errors.NewBadRequestError("Folder ID is required", nil))
+		return
+	}
+
+	folder, err := h.folderService.GetFolderByID(c.Request.Context(), id)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	respondWithJSON(c, http.StatusOK, newFolderResponse(folder))
+}
+
+// UpdateFolder handles PUT /api/v1/folders/:id
+func (h *Handler) UpdateFolder(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		respondWithError(c, errors.NewBadRequestError("Folder ID is required", nil))
+		return
+	}
+
+	var req dto.UpdateFolderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondWithError(c, errors.NewBadRequestError("Invalid request body", err))
+		return
+	}
+
+	folder, err := h.folderService.UpdateFolder(c.Request.Context(), id, req.Name)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	respondWithJSON(c, http.StatusOK, newFolderResponse(folder))
+}
+
+// DeleteFolder handles DELETE /api/v1/folders/:id
+func (h *Handler) DeleteFolder(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		respondWithError(c, errors.NewBadRequestError("Folder ID is required", nil))
+		return
+	}
+
+	if err := h.folderService.DeleteFolder(c.Request.Context(), id); err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	respondWithJSON(c, http.StatusOK, dto.SuccessResponse{
+		Message: "Folder deleted successfully",
+	})
+}
+
+// newFolderResponse converts a folder domain model to its DTO
+func newFolderResponse(folder *models.Folder) dto.FolderResponse {
+	return dto.FolderResponse{
+		ID:        folder.ID.Hex(),
+		Name:      folder.Name,
+		CreatedAt: folder.CreatedAt.Format(time.RFC3339),
+		UpdatedAt: folder.UpdatedAt.Format(time.RFC3339),
+	}
+}
@@ -0,0 +1,113 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/models"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/models/dto"
+)
+
+// ListGenerations handles GET /api/v1/chats/:id/generations
+func (h *Handler) ListGenerations(c *gin.Context) {
+	status := models.GenerationStatus(c.Query("status"))
+
+	generations, err := h.generationService.ListGenerations(c.Request.Context(), c.Param("id"), status)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	response := dto.GenerationListResponse{
+		Generations: make([]dto.GenerationResponse, len(generations)),
+	}
+	for i, generation := range generations {
+		response.Generations[i] = h.newGenerationResponse(generation)
+	}
+
+	respondWithJSON(c, http.StatusOK, response)
+}
+
+// GetGeneration handles GET /api/v1/generations/:id
+func (h *Handler) GetGeneration(c *gin.Context) {
+	generation, err := h.generationService.GetGeneration(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	respondWithJSON(c, http.StatusOK, h.newGenerationResponse(generation))
+}
+
+// ContinueGeneration handles POST /api/v1/generations/:id/continue
+func (h *Handler) ContinueGeneration(c *gin.Context) {
+	generation, err := h.generationService.ContinueGeneration(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	// The continuation streams to the chat's subscribers in the background
+	respondWithJSON(c, http.StatusAccepted, h.newGenerationResponse(generation))
+}
+
+// DiscardGeneration handles POST /api/v1/generations/:id/discard
+func (h *Handler) DiscardGeneration(c *gin.Context) {
+	if err := h.generationService.DiscardGeneration(c.Request.Context(), c.Param("id")); err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	respondWithJSON(c, http.StatusOK, dto.SuccessResponse{
+		Message: "Generation discarded successfully",
+	})
+}
+
+// CancelGeneration handles POST /api/v1/generations/:id/cancel
+func (h *Handler) CancelGeneration(c *gin.Context) {
+	if err := h.generationService.CancelGeneration(c.Request.Context(), c.Param("id")); err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	// The generation stops asynchronously; generation_canceled reports when it did
+	respondWithJSON(c, http.StatusAccepted, dto.SuccessResponse{
+		Message: "Generation cancellation requested",
+	})
+}
+
+// newGenerationResponse converts a generation to its API representation
+func (h *Handler) newGenerationResponse(generation *models.Generation) dto.GenerationResponse {
+	response := dto.GenerationResponse{
+		ID:        generation.ID.Hex(),
+		ChatID:    generation.ChatID.Hex(),
+		MessageID: generation.MessageID.Hex(),
+		Status:    string(generation.Status),
+		Provider:  generation.Provider,
+		Model:     generation.Model,
+		Content:   generation.Content,
+		Error:     generation.Error,
+		StartedAt: generation.StartedAt.Format(time.RFC3339),
+		UpdatedAt: generation.UpdatedAt.Format(time.RFC3339),
+		Resumable: generation.Status == models.GenerationInterrupted && h.generationService.CanContinue(),
+	}
+
+	if generation.ResumedFrom != nil {
+		response.ResumedFrom = generation.ResumedFrom.Hex()
+	}
+	if generation.FinishedAt != nil {
+		response.FinishedAt = generation.FinishedAt.Format(time.RFC3339)
+	}
+
+	return response
+}
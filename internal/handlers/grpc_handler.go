@@ -0,0 +1,348 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package handlers
+
+import (
+	"context"
+
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/models"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/models/dto"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/services"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/sse"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/pkg/errors"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/pkg/logger"
+	chatv1 "github.com/yasin-yalcin-dev/go-sse-ai-chat/pkg/proto/chat/v1"
+	"google.golang.org/grpc"
+)
+
+// GRPCHandler serves the chat and message operations over gRPC. It delegates
+// to the same services, validation and conversions as the REST handlers;
+// authentication, tenancy and rate limits are applied by interceptors.
+type GRPCHandler struct {
+	chatv1.UnimplementedChatServiceServer
+	chatv1.UnimplementedMessageServiceServer
+
+	broker            *sse.Broker
+	chatService       services.ChatService
+	messageService    services.MessageService
+	generationService services.GenerationService
+}
+
+// NewGRPCHandler creates a new gRPC handler
+func NewGRPCHandler(broker *sse.Broker, chatService services.ChatService, messageService services.MessageService, generationService services.GenerationService) *GRPCHandler {
+	return &GRPCHandler{
+		broker:            broker,
+		chatService:       chatService,
+		messageService:    messageService,
+		generationService: generationService,
+	}
+}
+
+// CreateChat handles chat.v1.ChatService/CreateChat
+func (h *GRPCHandler) CreateChat(ctx context.Context, req *chatv1.CreateChatRequest) (*chatv1.Chat, error) {
+	body := dto.CreateChatRequest{Title: req.GetTitle()}
+	if err := validateRPCRequest(&body); err != nil {
+		return nil, err
+	}
+
+	chat, err := h.chatService.CreateChat(ctx, body.Title)
+	if err != nil {
+		return nil, err
+	}
+
+	return newChatProto(chat), nil
+}
+
+// GetChat handles chat.v1.ChatService/GetChat
+func (h *GRPCHandler) GetChat(ctx context.Context, req *chatv1.ChatIDRequest) (*chatv1.Chat, error) {
+	id, err := chatIDOf(req)
+	if err != nil {
+		return nil, err
+	}
+
+	chat, err := h.chatService.GetChatByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return newChatProto(chat), nil
+}
+
+// ListChats handles chat.v1.ChatService/ListChats
+func (h *GRPCHandler) ListChats(ctx context.Context, req *chatv1.ListChatsRequest) (*chatv1.ChatList, error) {
+	query := dto.ListChatsRequest{
+		Page:         int(req.GetPage()),
+		PageSize:     int(req.GetPageSize()),
+		Before:       req.GetBefore(),
+		After:        req.GetAfter(),
+		Active:       req.GetActive(),
+		Archived:     req.GetArchived(),
+		Pinned:       req.GetPinned(),
+		Tag:          req.GetTag(),
+		TitlePrefix:  req.GetTitlePrefix(),
+		FolderID:     req.GetFolderId(),
+		CreatedAfter: req.GetCreatedAfter(),
+		Sort:         req.GetSort(),
+		Order:        req.GetOrder(),
+	}
+
+	list, err := listChats(ctx, h.chatService, query.Query())
+	if err != nil {
+		return nil, err
+	}
+
+	return newChatListProto(list), nil
+}
+
+// UpdateChat handles chat.v1.ChatService/UpdateChat
+func (h *GRPCHandler) UpdateChat(ctx context.Context, req *chatv1.UpdateChatRequest) (*chatv1.Chat, error) {
+	body := dto.UpdateChatByIDRequest{
+		ID:                req.GetId(),
+		UpdateChatRequest: dto.UpdateChatRequest{Title: req.GetTitle(), Pinned: req.Pinned},
+	}
+	if err := validateRPCRequest(&body); err != nil {
+		return nil, err
+	}
+
+	chat, err := h.chatService.UpdateChat(ctx, body.ID, body.Title, body.Pinned)
+	if err != nil {
+		return nil, err
+	}
+
+	return newChatProto(chat), nil
+}
+
+// ArchiveChat handles chat.v1.ChatService/ArchiveChat
+func (h *GRPCHandler) ArchiveChat(ctx context.Context, req *chatv1.ChatIDRequest) (*chatv1.Chat, error) {
+	id, err := chatIDOf(req)
+	if err != nil {
+		return nil, err
+	}
+
+	chat, err := h.chatService.ArchiveChat(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return newChatProto(chat), nil
+}
+
+// UnarchiveChat handles chat.v1.ChatService/UnarchiveChat
+func (h *GRPCHandler) UnarchiveChat(ctx context.Context, req *chatv1.ChatIDRequest) (*chatv1.Chat, error) {
+	id, err := chatIDOf(req)
+	if err != nil {
+		return nil, err
+	}
+
+	chat, err := h.chatService.UnarchiveChat(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return newChatProto(chat), nil
+}
+
+// MoveChats handles chat.v1.ChatService/MoveChats
+func (h *GRPCHandler) MoveChats(ctx context.Context, req *chatv1.MoveChatsRequest) (*chatv1.BulkUpdateResponse, error) {
+	body := dto.MoveChatsRequest{ChatIDs: req.GetChatIds(), FolderID: req.GetFolderId()}
+	if err := validateRPCRequest(&body); err != nil {
+		return nil, err
+	}
+
+	updated, err := h.chatService.MoveChats(ctx, body.ChatIDs, body.FolderID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &chatv1.BulkUpdateResponse{Updated: updated}, nil
+}
+
+// UpdateChatTags handles chat.v1.ChatService/UpdateChatTags
+func (h *GRPCHandler) UpdateChatTags(ctx context.Context, req *chatv1.UpdateChatTagsRequest) (*chatv1.BulkUpdateResponse, error) {
+	body := dto.UpdateChatTagsRequest{ChatIDs: req.GetChatIds(), Add: req.GetAdd(), Remove: req.GetRemove()}
+	if err := validateRPCRequest(&body); err != nil {
+		return nil, err
+	}
+
+	updated, err := h.chatService.UpdateChatTags(ctx, body.ChatIDs, body.Add, body.Remove)
+	if err != nil {
+		return nil, err
+	}
+
+	return &chatv1.BulkUpdateResponse{Updated: updated}, nil
+}
+
+// DeleteChat handles chat.v1.ChatService/DeleteChat
+func (h *GRPCHandler) DeleteChat(ctx context.Context, req *chatv1.ChatIDRequest) (*chatv1.SuccessResponse, error) {
+	id, err := chatIDOf(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := h.chatService.DeleteChat(ctx, id); err != nil {
+		return nil, err
+	}
+
+	return &chatv1.SuccessResponse{Message: "Chat deleted successfully"}, nil
+}
+
+// RestoreChat handles chat.v1.ChatService/RestoreChat
+func (h *GRPCHandler) RestoreChat(ctx context.Context, req *chatv1.ChatIDRequest) (*chatv1.Chat, error) {
+	id, err := chatIDOf(req)
+	if err != nil {
+		return nil, err
+	}
+
+	chat, err := h.chatService.RestoreChat(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return newChatProto(chat), nil
+}
+
+// Subscribe handles chat.v1.ChatService/Subscribe. It streams the events of
+// a chat as frames, like a WebSocket, until the client cancels the call or
+// the broker closes the client, for example on shutdown.
+func (h *GRPCHandler) Subscribe(req *chatv1.SubscribeRequest, stream grpc.ServerStreamingServer[chatv1.Frame]) error {
+	body := dto.SubscribeRequest{ChatID: req.GetChatId(), AfterEventID: req.GetAfterEventId()}
+	if err := validateRPCRequest(&body); err != nil {
+		return err
+	}
+
+	// Verify the chat exists and the caller may read it
+	chat, err := h.chatService.GetChatByID(stream.Context(), body.ChatID)
+	if err != nil {
+		return err
+	}
+	if chat == nil {
+		return errors.NewNotFoundError("Chat not found", nil)
+	}
+	chatID := chat.ID.Hex()
+	clientID := newClientID(chatID)
+
+	// Correlate the logs of this stream
+	ctx := logger.WithContext(stream.Context(), logger.FieldChatID, chatID, logger.FieldClientID, clientID)
+	log := logger.FromContext(ctx)
+
+	if body.AfterEventID != "" {
+		log.Infof("gRPC reconnection requested for chat %s, client %s, last event %s", chatID, clientID, body.AfterEventID)
+	} else {
+		log.Infof("New gRPC subscription requested for chat %s, client %s", chatID, clientID)
+	}
+
+	transport := sse.NewGRPCTransport(func(frame *sse.Frame) error {
+		msg, err := newFrameProto(frame)
+		if err != nil {
+			return err
+		}
+		return stream.Send(msg)
+	})
+	client := sse.NewClient(ctx, clientID, chat.TenantID, transport, h.broker)
+	client.LastEventID = body.AfterEventID
+
+	// Offer to continue replies that were cut off while nobody was listening
+	sendInterruptedGenerations(ctx, h.generationService, client, chatID)
+
+	client.Listen()
+
+	log.Debugf("Subscription closed for client %s", clientID)
+	return nil
+}
+
+// CreateMessage handles chat.v1.MessageService/CreateMessage
+func (h *GRPCHandler) CreateMessage(ctx context.Context, req *chatv1.CreateMessageRequest) (*chatv1.Message, error) {
+	body := dto.SendMessageRequest{
+		ChatID: req.GetChatId(),
+		CreateMessageRequest: dto.CreateMessageRequest{
+			Content: req.GetContent(),
+			Role:    models.MessageRole(req.GetRole()),
+			Type:    models.MessageType(req.GetType()),
+		},
+	}
+	if err := validateRPCRequest(&body); err != nil {
+		return nil, err
+	}
+	if err := validateMessageRequest(&body.CreateMessageRequest); err != nil {
+		return nil, err
+	}
+
+	message, err := h.messageService.CreateMessage(ctx, body.ChatID, body.Content, body.Role, body.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	return newMessageProto(message)
+}
+
+// GetMessage handles chat.v1.MessageService/GetMessage
+func (h *GRPCHandler) GetMessage(ctx context.Context, req *chatv1.MessageIDRequest) (*chatv1.Message, error) {
+	id, err := messageIDOf(req)
+	if err != nil {
+		return nil, err
+	}
+
+	message, err := h.messageService.GetMessageByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return newMessageProto(message)
+}
+
+// ListMessages handles chat.v1.MessageService/ListMessages
+func (h *GRPCHandler) ListMessages(ctx context.Context, req *chatv1.ListMessagesRequest) (*chatv1.MessageList, error) {
+	query := dto.ListMessagesRequest{
+		ChatID:   req.GetChatId(),
+		Page:     int(req.GetPage()),
+		PageSize: int(req.GetPageSize()),
+		Before:   req.GetBefore(),
+		After:    req.GetAfter(),
+	}
+	if err := validateRPCRequest(&query); err != nil {
+		return nil, err
+	}
+
+	list, err := listMessages(ctx, h.messageService, query.ChatID, query.Query())
+	if err != nil {
+		return nil, err
+	}
+
+	return newMessageListProto(list)
+}
+
+// DeleteMessage handles chat.v1.MessageService/DeleteMessage
+func (h *GRPCHandler) DeleteMessage(ctx context.Context, req *chatv1.MessageIDRequest) (*chatv1.SuccessResponse, error) {
+	id, err := messageIDOf(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := h.messageService.DeleteMessage(ctx, id); err != nil {
+		return nil, err
+	}
+
+	return &chatv1.SuccessResponse{Message: "Message deleted successfully"}, nil
+}
+
+// RestoreMessage handles chat.v1.MessageService/RestoreMessage
+func (h *GRPCHandler) RestoreMessage(ctx context.Context, req *chatv1.MessageIDRequest) (*chatv1.Message, error) {
+	id, err := messageIDOf(req)
+	if err != nil {
+		return nil, err
+	}
+
+	message, err := h.messageService.RestoreMessage(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return newMessageProto(message)
+}
@@ -0,0 +1,197 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package handlers
+
+import (
+	"encoding/json"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/models"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/models/dto"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/sse"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/pkg/errors"
+	chatv1 "github.com/yasin-yalcin-dev/go-sse-ai-chat/pkg/proto/chat/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// gRPC service names
+const (
+	GRPCChatService    = "chat.v1.ChatService"
+	GRPCMessageService = "chat.v1.MessageService"
+)
+
+// Full names of the gRPC methods that interceptors treat specially
+const (
+	GRPCMethodSubscribe     = "/" + GRPCChatService + "/Subscribe"
+	GRPCMethodCreateMessage = "/" + GRPCMessageService + "/CreateMessage"
+)
+
+// RegisterGRPCServices registers the chat and message services served by h.
+// Their messages are generated from docs/proto/chat/v1/chat.proto; requests
+// are converted into the DTOs of the REST API, so that both are validated
+// alike.
+func RegisterGRPCServices(server grpc.ServiceRegistrar, h *GRPCHandler) {
+	chatv1.RegisterChatServiceServer(server, h)
+	chatv1.RegisterMessageServiceServer(server, h)
+}
+
+// validateRPCRequest checks the binding rules of a request, as gin does for request bodies
+func validateRPCRequest(req interface{}) error {
+	if err := binding.Validator.ValidateStruct(req); err != nil {
+		return errors.NewBadRequestError("Invalid request", err)
+	}
+	return nil
+}
+
+// chatIDOf returns the validated chat ID of a request
+func chatIDOf(req *chatv1.ChatIDRequest) (string, error) {
+	body := dto.ChatIDRequest{ID: req.GetId()}
+	if err := validateRPCRequest(&body); err != nil {
+		return "", err
+	}
+	return body.ID, nil
+}
+
+// messageIDOf returns the validated message ID of a request
+func messageIDOf(req *chatv1.MessageIDRequest) (string, error) {
+	body := dto.MessageIDRequest{ID: req.GetId()}
+	if err := validateRPCRequest(&body); err != nil {
+		return "", err
+	}
+	return body.ID, nil
+}
+
+// newChatProto converts a chat into its gRPC message
+func newChatProto(chat *models.Chat) *chatv1.Chat {
+	response := newChatResponse(chat)
+	return chatProto(&response)
+}
+
+// chatProto converts a chat response into its gRPC message
+func chatProto(chat *dto.ChatResponse) *chatv1.Chat {
+	return &chatv1.Chat{
+		Id:            chat.ID,
+		Title:         chat.Title,
+		CreatedAt:     chat.CreatedAt,
+		UpdatedAt:     chat.UpdatedAt,
+		LastMessageAt: chat.LastMessageAt,
+		MessageCount:  int32(chat.MessageCount),
+		Archived:      chat.Archived,
+		ArchivedAt:    chat.ArchivedAt,
+		Pinned:        chat.Pinned,
+		Tags:          chat.Tags,
+		FolderId:      chat.FolderID,
+		DeletedAt:     chat.DeletedAt,
+		OwnerId:       chat.OwnerID,
+	}
+}
+
+// newChatListProto converts a page of chats into its gRPC message
+func newChatListProto(list *dto.ChatListResponse) *chatv1.ChatList {
+	chats := make([]*chatv1.Chat, len(list.Chats))
+	for i := range list.Chats {
+		chats[i] = chatProto(&list.Chats[i])
+	}
+
+	return &chatv1.ChatList{
+		Chats:      chats,
+		Pagination: paginationProto(list.Pagination),
+		Cursors:    cursorsProto(list.Cursors),
+	}
+}
+
+// newMessageProto converts a message into its gRPC message
+func newMessageProto(message *models.Message) (*chatv1.Message, error) {
+	response := newMessageResponse(message)
+	return messageProto(&response)
+}
+
+// messageProto converts a message response into its gRPC message. Metadata
+// is converted through JSON, as it is in REST responses.
+func messageProto(message *dto.MessageResponse) (*chatv1.Message, error) {
+	msg := &chatv1.Message{
+		Id:        message.ID,
+		ChatId:    message.ChatID,
+		Content:   message.Content,
+		Role:      string(message.Role),
+		Type:      string(message.Type),
+		CreatedAt: message.CreatedAt,
+		DeletedAt: message.DeletedAt,
+	}
+
+	if len(message.Metadata) > 0 {
+		data, err := json.Marshal(message.Metadata)
+		if err != nil {
+			return nil, err
+		}
+		msg.Metadata = &structpb.Struct{}
+		if err := protojson.Unmarshal(data, msg.Metadata); err != nil {
+			return nil, err
+		}
+	}
+
+	return msg, nil
+}
+
+// newMessageListProto converts a page of messages into its gRPC message
+func newMessageListProto(list *dto.MessageListResponse) (*chatv1.MessageList, error) {
+	messages := make([]*chatv1.Message, len(list.Messages))
+	for i := range list.Messages {
+		msg, err := messageProto(&list.Messages[i])
+		if err != nil {
+			return nil, err
+		}
+		messages[i] = msg
+	}
+
+	return &chatv1.MessageList{
+		Messages:   messages,
+		Pagination: paginationProto(list.Pagination),
+		Cursors:    cursorsProto(list.Cursors),
+	}, nil
+}
+
+// paginationProto converts pagination details into their gRPC message
+func paginationProto(pagination dto.PaginationInfo) *chatv1.Pagination {
+	return &chatv1.Pagination{
+		Total:    pagination.Total,
+		Page:     int32(pagination.Page),
+		PageSize: int32(pagination.PageSize),
+		Pages:    pagination.Pages,
+	}
+}
+
+// cursorsProto converts keyset pagination tokens into their gRPC message
+func cursorsProto(cursors *dto.CursorInfo) *chatv1.Cursors {
+	if cursors == nil {
+		return nil
+	}
+	return &chatv1.Cursors{Before: cursors.Before, After: cursors.After}
+}
+
+// newFrameProto converts a frame of the event protocol into its gRPC message
+func newFrameProto(frame *sse.Frame) (*chatv1.Frame, error) {
+	msg := &chatv1.Frame{
+		Id:      frame.ID,
+		Event:   frame.Event,
+		RetryMs: frame.RetryMs,
+	}
+
+	if len(frame.Data) > 0 {
+		msg.Data = &structpb.Value{}
+		if err := protojson.Unmarshal(frame.Data, msg.Data); err != nil {
+			return nil, err
+		}
+	}
+
+	return msg, nil
+}
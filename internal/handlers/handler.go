@@ -11,25 +11,38 @@ package handlers
 
 import (
 	"net/http"
+	"net/url"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/middleware"
 	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/models/dto"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/repository"
 	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/services"
 	"github.com/yasin-yalcin-dev/go-sse-ai-chat/pkg/errors"
 )
 
 // Handler contains services for all handlers
 type Handler struct {
-	chatService    services.ChatService
-	messageService services.MessageService
+	chatService       services.ChatService
+	messageService    services.MessageService
+	folderService     services.FolderService
+	trashService      services.TrashService
+	shareService      services.ShareService
+	generationService services.GenerationService
+	webhookService    services.WebhookService
 }
 
 // NewHandler creates a new handler with all required services
-func NewHandler(chatService services.ChatService, messageService services.MessageService) *Handler {
+func NewHandler(chatService services.ChatService, messageService services.MessageService, folderService services.FolderService, trashService services.TrashService, shareService services.ShareService, generationService services.GenerationService, webhookService services.WebhookService) *Handler {
 	return &Handler{
-		chatService:    chatService,
-		messageService: messageService,
+		chatService:       chatService,
+		messageService:    messageService,
+		folderService:     folderService,
+		trashService:      trashService,
+		shareService:      shareService,
+		generationService: generationService,
+		webhookService:    webhookService,
 	}
 }
 
@@ -43,6 +56,7 @@ func respondWithError(c *gin.Context, err error) {
 				Code:    appErr.Code,
 				Message: appErr.Error(),
 			},
+			RequestID: middleware.GetRequestID(c),
 		}
 
 		// Add details if available
@@ -59,6 +73,7 @@ func respondWithError(c *gin.Context, err error) {
 		Error: dto.ErrorDetails{
 			Message: err.Error(),
 		},
+		RequestID: middleware.GetRequestID(c),
 	})
 }
 
@@ -69,16 +84,21 @@ func respondWithJSON(c *gin.Context, status int, data interface{}) {
 
 // handlePagination extracts pagination parameters from the request
 func handlePagination(c *gin.Context, defaultPageSize, maxPageSize int) (page, pageSize int) {
+	return parsePagination(c.Request.URL.Query(), defaultPageSize, maxPageSize)
+}
+
+// parsePagination extracts pagination parameters from query parameters
+func parsePagination(query url.Values, defaultPageSize, maxPageSize int) (page, pageSize int) {
 	page = 1
 	pageSize = defaultPageSize
 
-	if pageStr := c.Query("page"); pageStr != "" {
+	if pageStr := query.Get("page"); pageStr != "" {
 		if pageInt, err := strconv.Atoi(pageStr); err == nil && pageInt > 0 {
 			page = pageInt
 		}
 	}
 
-	if pageSizeStr := c.Query("page_size"); pageSizeStr != "" {
+	if pageSizeStr := query.Get("page_size"); pageSizeStr != "" {
 		if pageSizeInt, err := strconv.Atoi(pageSizeStr); err == nil && pageSizeInt > 0 {
 			pageSize = pageSizeInt
 		}
@@ -96,3 +116,63 @@ func handlePagination(c *gin.Context, defaultPageSize, maxPageSize int) (page, p
 func calculateTotalPages(total int64, pageSize int) int64 {
 	return (total + int64(pageSize) - 1) / int64(pageSize)
 }
+
+// parseCursor extracts keyset pagination parameters from query parameters.
+// Cursor mode is opt-in: useCursor is true only when before or after is set,
+// otherwise the request is served with page-based pagination.
+func parseCursor(values url.Values, limit int) (query repository.CursorQuery, useCursor bool, err error) {
+	before := values.Get("before")
+	after := values.Get("after")
+
+	if before == "" && after == "" {
+		return query, false, nil
+	}
+
+	if values.Get("page") != "" {
+		return query, false, errors.NewBadRequestError("page cannot be combined with before or after", nil)
+	}
+
+	if before != "" && after != "" {
+		return query, false, errors.NewBadRequestError("Only one of before and after may be set", nil)
+	}
+
+	query.Limit = limit
+
+	if before != "" {
+		if query.Before, err = repository.DecodeCursor(before); err != nil {
+			return query, false, errors.NewBadRequestError("Invalid before cursor", err)
+		}
+	}
+
+	if after != "" {
+		if query.After, err = repository.DecodeCursor(after); err != nil {
+			return query, false, errors.NewBadRequestError("Invalid after cursor", err)
+		}
+	}
+
+	return query, true, nil
+}
+
+// newCursorInfo builds the cursor tokens for a page whose first and last items are given
+func newCursorInfo(info repository.PageInfo, first, last *repository.Cursor) *dto.CursorInfo {
+	cursors := &dto.CursorInfo{}
+
+	if info.HasBefore && first != nil {
+		cursors.Before = first.Encode()
+	}
+
+	if info.HasAfter && last != nil {
+		cursors.After = last.Encode()
+	}
+
+	return cursors
+}
+
+// pageInfo reports whether there are pages before and after an offset page,
+// so that page-based responses can hand out cursors to switch to cursor mode
+func pageInfo(page int, pages int64) repository.PageInfo {
+	return repository.PageInfo{
+		HasBefore: page > 1,
+		HasAfter:  int64(page) < pages,
+	}
+}
@@ -0,0 +1,113 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/jobs"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/models"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/models/dto"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/repository"
+)
+
+// JobHandler handles the admin endpoints of the background job queue
+type JobHandler struct {
+	queue *jobs.Queue
+}
+
+// NewJobHandler creates a new job handler
+func NewJobHandler(queue *jobs.Queue) *JobHandler {
+	return &JobHandler{queue: queue}
+}
+
+// ListJobs handles GET /api/v1/admin/jobs
+func (h *JobHandler) ListJobs(c *gin.Context) {
+	page, pageSize := handlePagination(c, 20, 100)
+
+	filter := repository.JobFilter{
+		Status:   models.JobStatus(c.Query("status")),
+		Type:     c.Query("type"),
+		TenantID: c.Query("tenant_id"),
+	}
+
+	found, total, err := h.queue.ListJobs(c.Request.Context(), filter, page, pageSize)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	response := dto.JobListResponse{
+		Jobs: make([]dto.JobResponse, len(found)),
+		Pagination: dto.PaginationInfo{
+			Total:    total,
+			Page:     page,
+			PageSize: pageSize,
+			Pages:    calculateTotalPages(total, pageSize),
+		},
+	}
+	for i, job := range found {
+		response.Jobs[i] = newJobResponse(job)
+	}
+
+	respondWithJSON(c, http.StatusOK, response)
+}
+
+// GetJob handles GET /api/v1/admin/jobs/:id
+func (h *JobHandler) GetJob(c *gin.Context) {
+	job, err := h.queue.GetJob(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	respondWithJSON(c, http.StatusOK, newJobResponse(job))
+}
+
+// RetryJob handles POST /api/v1/admin/jobs/:id/retry
+func (h *JobHandler) RetryJob(c *gin.Context) {
+	job, err := h.queue.RetryJob(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	respondWithJSON(c, http.StatusOK, newJobResponse(job))
+}
+
+// newJobResponse converts a job to its API representation
+func newJobResponse(job *models.Job) dto.JobResponse {
+	response := dto.JobResponse{
+		ID:          job.ID.Hex(),
+		TenantID:    job.TenantID,
+		Type:        job.Type,
+		Payload:     job.Payload,
+		UniqueKey:   job.UniqueKey,
+		Status:      string(job.Status),
+		Attempts:    job.Attempts,
+		MaxAttempts: job.MaxAttempts,
+		VisibleAt:   job.VisibleAt.Format(time.RFC3339),
+		LockedBy:    job.LockedBy,
+		LastError:   job.LastError,
+		CreatedAt:   job.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:   job.UpdatedAt.Format(time.RFC3339),
+	}
+
+	if job.StartedAt != nil {
+		response.StartedAt = job.StartedAt.Format(time.RFC3339)
+	}
+	if job.FinishedAt != nil {
+		response.FinishedAt = job.FinishedAt.Format(time.RFC3339)
+	}
+
+	return response
+}
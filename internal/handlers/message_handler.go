@@ -10,12 +10,16 @@
 package handlers
 
 import (
+	"context"
 	"net/http"
+	"net/url"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/models"
 	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/models/dto"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/repository"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/services"
 	"github.com/yasin-yalcin-dev/go-sse-ai-chat/pkg/errors"
 )
 
@@ -27,39 +31,75 @@ func (h *Handler) GetMessages(c *gin.Context) {
 		return
 	}
 
-	page, pageSize := handlePagination(c, 20, 100)
-
-	messages, total, err := h.messageService.GetChatMessages(c.Request.Context(), chatID, page, pageSize)
+	response, err := listMessages(c.Request.Context(), h.messageService, chatID, c.Request.URL.Query())
 	if err != nil {
 		respondWithError(c, err)
 		return
 	}
 
-	// Convert domain models to DTOs
-	messageResponses := make([]dto.MessageResponse, len(messages))
-	for i, message := range messages {
-		messageResponses[i] = dto.MessageResponse{
-			ID:        message.ID.Hex(),
-			ChatID:    message.ChatID.Hex(),
-			Content:   message.Content,
-			Role:      message.Role,
-			Type:      message.Type,
-			CreatedAt: message.CreatedAt.Format(time.RFC3339),
-			Metadata:  message.Metadata,
+	respondWithJSON(c, http.StatusOK, response)
+}
+
+// listMessages lists a page of the messages of a chat, with the page or
+// cursor given as query parameters. It is shared by the REST and gRPC APIs.
+func listMessages(ctx context.Context, messageService services.MessageService, chatID string, values url.Values) (*dto.MessageListResponse, error) {
+	page, pageSize := parsePagination(values, 20, 100)
+
+	query, useCursor, err := parseCursor(values, pageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	if !useCursor {
+		messages, total, err := messageService.GetChatMessages(ctx, chatID, page, pageSize)
+		if err != nil {
+			return nil, err
+		}
+
+		// Pages are numbered from the newest messages, so the next page holds
+		// the messages before this one in cursor terms
+		var oldest, newest *repository.Cursor
+		if len(messages) > 0 {
+			oldest = messageCursor(messages[0])
+			newest = messageCursor(messages[len(messages)-1])
 		}
+
+		pages := calculateTotalPages(total, pageSize)
+		info := pageInfo(page, pages)
+		info.HasBefore, info.HasAfter = info.HasAfter, info.HasBefore
+
+		return &dto.MessageListResponse{
+			Messages: newMessageResponses(messages),
+			Pagination: dto.PaginationInfo{
+				Total:    total,
+				Page:     page,
+				PageSize: pageSize,
+				Pages:    pages,
+			},
+			Cursors: newCursorInfo(info, oldest, newest),
+		}, nil
 	}
 
-	response := dto.MessageListResponse{
-		Messages: messageResponses,
+	messages, info, total, err := messageService.GetChatMessagesByCursor(ctx, chatID, query)
+	if err != nil {
+		return nil, err
+	}
+
+	var first, last *repository.Cursor
+	if len(messages) > 0 {
+		first = messageCursor(messages[0])
+		last = messageCursor(messages[len(messages)-1])
+	}
+
+	return &dto.MessageListResponse{
+		Messages: newMessageResponses(messages),
 		Pagination: dto.PaginationInfo{
 			Total:    total,
-			Page:     page,
-			PageSize: pageSize,
-			Pages:    calculateTotalPages(total, pageSize),
+			PageSize: query.Limit,
+			Pages:    calculateTotalPages(total, query.Limit),
 		},
-	}
-
-	respondWithJSON(c, http.StatusOK, response)
+		Cursors: newCursorInfo(info, first, last),
+	}, nil
 }
 
 // CreateMessage handles POST /api/v1/chats/:id/messages
@@ -76,6 +116,33 @@ func (h *Handler) CreateMessage(c *gin.Context) {
 		return
 	}
 
+	if err := validateMessageRequest(&req); err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	message, err := h.messageService.CreateMessage(
+		c.Request.Context(),
+		chatID,
+		req.Content,
+		req.Role,
+		req.Type,
+	)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	respondWithJSON(c, http.StatusCreated, newMessageResponse(message))
+}
+
+// validateMessageRequest applies the defaults of a new message and validates
+// its role and type. It is shared by the REST, WebSocket and gRPC transports.
+func validateMessageRequest(req *dto.CreateMessageRequest) error {
+	if req.Content == "" {
+		return errors.NewValidationError("Message content is required", nil)
+	}
+
 	// Set default values if not provided
 	if req.Role == "" {
 		req.Role = models.RoleUser
@@ -87,57 +154,71 @@ func (h *Handler) CreateMessage(c *gin.Context) {
 
 	// Validate role
 	if req.Role != models.RoleUser && req.Role != models.RoleAssistant && req.Role != models.RoleSystem {
-		respondWithError(c, errors.NewValidationError("Invalid role", nil))
-		return
+		return errors.NewValidationError("Invalid role", nil)
 	}
 
 	// Validate type
 	if req.Type != models.TypeText && req.Type != models.TypeImage && req.Type != models.TypeCode {
-		respondWithError(c, errors.NewValidationError("Invalid message type", nil))
+		return errors.NewValidationError("Invalid message type", nil)
+	}
+
+	return nil
+}
+
+// GetMessage handles GET /api/v1/messages/:id
+func (h *Handler) GetMessage(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		respondWithError(c, errors.NewBadRequestError("Message ID is required", nil))
 		return
 	}
 
-	message, err := h.messageService.CreateMessage(
-		c.Request.Context(),
-		chatID,
-		req.Content,
-		req.Role,
-		req.Type,
-	)
+	message, err := h.messageService.GetMessageByID(c.Request.Context(), id)
 	if err != nil {
 		respondWithError(c, err)
 		return
 	}
 
-	// Convert domain model to DTO
-	response := dto.MessageResponse{
-		ID:        message.ID.Hex(),
-		ChatID:    message.ChatID.Hex(),
-		Content:   message.Content,
-		Role:      message.Role,
-		Type:      message.Type,
-		CreatedAt: message.CreatedAt.Format(time.RFC3339),
-		Metadata:  message.Metadata,
+	respondWithJSON(c, http.StatusOK, newMessageResponse(message))
+}
+
+// DeleteMessage handles DELETE /api/v1/messages/:id
+func (h *Handler) DeleteMessage(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		respondWithError(c, errors.NewBadRequestError("Message ID is required", nil))
+		return
 	}
 
-	respondWithJSON(c, http.StatusCreated, response)
+	if err := h.messageService.DeleteMessage(c.Request.Context(), id); err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	respondWithJSON(c, http.StatusOK, dto.SuccessResponse{
+		Message: "Message deleted successfully",
+	})
 }
 
-// GetMessage handles GET /api/v1/messages/:id
-func (h *Handler) GetMessage(c *gin.Context) {
+// RestoreMessage handles POST /api/v1/messages/:id/restore
+func (h *Handler) RestoreMessage(c *gin.Context) {
 	id := c.Param("id")
 	if id == "" {
 		respondWithError(c, errors.NewBadRequestError("Message ID is required", nil))
 		return
 	}
 
-	message, err := h.messageService.GetMessageByID(c.Request.Context(), id)
+	message, err := h.messageService.RestoreMessage(c.Request.Context(), id)
 	if err != nil {
 		respondWithError(c, err)
 		return
 	}
 
-	// Convert domain model to DTO
+	respondWithJSON(c, http.StatusOK, newMessageResponse(message))
+}
+
+// newMessageResponse converts a message domain model to its DTO
+func newMessageResponse(message *models.Message) dto.MessageResponse {
 	response := dto.MessageResponse{
 		ID:        message.ID.Hex(),
 		ChatID:    message.ChatID.Hex(),
@@ -148,23 +229,23 @@ func (h *Handler) GetMessage(c *gin.Context) {
 		Metadata:  message.Metadata,
 	}
 
-	respondWithJSON(c, http.StatusOK, response)
-}
-
-// DeleteMessage handles DELETE /api/v1/messages/:id
-func (h *Handler) DeleteMessage(c *gin.Context) {
-	id := c.Param("id")
-	if id == "" {
-		respondWithError(c, errors.NewBadRequestError("Message ID is required", nil))
-		return
+	if message.DeletedAt != nil {
+		response.DeletedAt = message.DeletedAt.Format(time.RFC3339)
 	}
 
-	if err := h.messageService.DeleteMessage(c.Request.Context(), id); err != nil {
-		respondWithError(c, err)
-		return
+	return response
+}
+
+// newMessageResponses converts a list of message domain models to DTOs
+func newMessageResponses(messages []*models.Message) []dto.MessageResponse {
+	responses := make([]dto.MessageResponse, len(messages))
+	for i, message := range messages {
+		responses[i] = newMessageResponse(message)
 	}
+	return responses
+}
 
-	respondWithJSON(c, http.StatusOK, dto.SuccessResponse{
-		Message: "Message deleted successfully",
-	})
+// messageCursor returns the keyset position of a message in its chat history
+func messageCursor(message *models.Message) *repository.Cursor {
+	return repository.NewCursor("created_at", true, message.CreatedAt, message.ID)
 }
@@ -0,0 +1,592 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/health"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/models/dto"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/openapi"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/sse"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/pkg/buildinfo"
+)
+
+// Names of the security schemes of the OpenAPI document
+const (
+	securityBearer      = "bearerAuth"
+	securityAPIKey      = "apiKeyAuth"
+	securityStreamToken = "streamToken"
+)
+
+// apiInfo describes the API in the OpenAPI document
+var apiInfo = openapi.Info{
+	Title:       "Go SSE AI Chat API",
+	Version:     "v1",
+	Description: "Chats with AI replies streamed over Server-Sent Events, WebSockets or long polling.",
+}
+
+// Query parameters shared by several operations
+var (
+	pageParams = []openapi.Param{
+		{Name: "page", Type: "integer", Description: "Page number, starting at 1"},
+		{Name: "page_size", Type: "integer", Description: "Number of items per page, at most 100"},
+	}
+	cursorParams = []openapi.Param{
+		{Name: "before", Description: "Cursor of the page before, from cursors.before; switches to cursor pagination and cannot be combined with page"},
+		{Name: "after", Description: "Cursor of the page after, from cursors.after; switches to cursor pagination and cannot be combined with page"},
+	}
+	reconnectParams = []openapi.Param{
+		{Name: "client_id", Description: "Client ID of a reconnecting client, as sent in its earlier events"},
+	}
+)
+
+// chatEvents are the events sent on a chat's SSE stream
+var chatEvents = map[string]interface{}{
+	sse.EventPing:                  sse.PingEvent{},
+	sse.EventControl:               sse.ControlEvent{},
+	sse.EventChatCreated:           sse.ChatEvent{},
+	sse.EventChatUpdated:           sse.ChatUpdatedEvent{},
+	sse.EventChatDeleted:           sse.ChatDeletedEvent{},
+	sse.EventChatRestored:          sse.ChatEvent{},
+	sse.EventMessageCreated:        sse.MessageCreatedEvent{},
+	sse.EventMessageDeleted:        sse.MessageDeletedEvent{},
+	sse.EventMessage:               sse.MessageChunkEvent{},
+	sse.EventComplete:              sse.CompleteEvent{},
+	sse.EventGenerationInterrupted: sse.GenerationInterruptedEvent{},
+	sse.EventGenerationCanceled:    sse.GenerationCanceledEvent{},
+}
+
+// webSocketEvents are the events sent on a chat's WebSocket, which also
+// answers commands
+var webSocketEvents = withEvents(chatEvents, map[string]interface{}{
+	sse.EventAck:   sse.AckEvent{},
+	sse.EventError: sse.ErrorEvent{},
+})
+
+// pollEvents are the events returned by long polling: the stored ones only
+var pollEvents = map[string]interface{}{
+	sse.EventChatCreated:           sse.ChatEvent{},
+	sse.EventChatUpdated:           sse.ChatUpdatedEvent{},
+	sse.EventChatDeleted:           sse.ChatDeletedEvent{},
+	sse.EventChatRestored:          sse.ChatEvent{},
+	sse.EventMessageCreated:        sse.MessageCreatedEvent{},
+	sse.EventMessageDeleted:        sse.MessageDeletedEvent{},
+	sse.EventComplete:              sse.CompleteEvent{},
+	sse.EventGenerationInterrupted: sse.GenerationInterruptedEvent{},
+	sse.EventGenerationCanceled:    sse.GenerationCanceledEvent{},
+}
+
+// streamSecurity lets stream routes authenticate with a stream token
+var streamSecurity = []openapi.SecurityRequirement{{securityStreamToken: {}}}
+
+// operationDocs documents the operation of each handler method, keyed by
+// Type.Method. Every route served by a handler must be documented here.
+var operationDocs = map[string]openapi.Route{
+	// Chats
+	"Handler.ListChats": {
+		Summary: "List chats",
+		Tag:     "Chats",
+		Params: append(append([]openapi.Param{
+			{Name: "active", Enum: []string{"true", "false", "any"}, Description: "Filter by active state; defaults to true"},
+			{Name: "archived", Enum: []string{"true", "false", "any"}, Description: "Filter by archived state; defaults to false"},
+			{Name: "pinned", Enum: []string{"true", "false", "any"}, Description: "Filter by pinned state"},
+			{Name: "tag", Description: "Only list chats with this tag"},
+			{Name: "title_prefix", Description: "Only list chats whose title starts with this prefix"},
+			{Name: "folder_id", Description: "Only list chats of this folder, or unfiled chats with none"},
+			{Name: "created_after", Description: "Only list chats created after this RFC 3339 timestamp"},
+			{Name: "sort", Enum: []string{"updated_at", "last_message_at", "created_at", "title"}, Description: "Field to sort by; defaults to updated_at"},
+			{Name: "order", Enum: []string{"asc", "desc"}, Description: "Sort order; titles default to asc, timestamps to desc"},
+		}, pageParams...), cursorParams...),
+		Response: dto.ChatListResponse{},
+	},
+	"Handler.CreateChat": {
+		Summary:  "Create a chat",
+		Tag:      "Chats",
+		Request:  dto.CreateChatRequest{},
+		Status:   http.StatusCreated,
+		Response: dto.ChatResponse{},
+	},
+	"Handler.GetChat": {
+		Summary:  "Get a chat",
+		Tag:      "Chats",
+		Response: dto.ChatResponse{},
+	},
+	"Handler.UpdateChat": {
+		Summary:  "Update a chat",
+		Tag:      "Chats",
+		Request:  dto.UpdateChatRequest{},
+		Response: dto.ChatResponse{},
+	},
+	"Handler.DeleteChat": {
+		Summary:     "Delete a chat",
+		Description: "Moves the chat and its messages to the trash.",
+		Tag:         "Chats",
+		Response:    dto.SuccessResponse{},
+	},
+	"Handler.ArchiveChat": {
+		Summary:  "Archive a chat",
+		Tag:      "Chats",
+		Response: dto.ChatResponse{},
+	},
+	"Handler.UnarchiveChat": {
+		Summary:  "Unarchive a chat",
+		Tag:      "Chats",
+		Response: dto.ChatResponse{},
+	},
+	"Handler.RestoreChat": {
+		Summary:  "Restore a chat from the trash",
+		Tag:      "Chats",
+		Response: dto.ChatResponse{},
+	},
+	"Handler.MoveChats": {
+		Summary:  "Move chats to a folder",
+		Tag:      "Chats",
+		Request:  dto.MoveChatsRequest{},
+		Response: dto.BulkUpdateResponse{},
+	},
+	"Handler.UpdateChatTags": {
+		Summary:  "Add and remove tags of chats",
+		Tag:      "Chats",
+		Request:  dto.UpdateChatTagsRequest{},
+		Response: dto.BulkUpdateResponse{},
+	},
+
+	// Sharing
+	"Handler.ListMembers": {
+		Summary:  "List the members of a chat",
+		Tag:      "Sharing",
+		Response: dto.ChatMemberListResponse{},
+	},
+	"Handler.UpdateMember": {
+		Summary:  "Change the role of a member of a chat",
+		Tag:      "Sharing",
+		Request:  dto.UpdateMemberRequest{},
+		Response: dto.ChatMemberResponse{},
+	},
+	"Handler.RemoveMember": {
+		Summary:  "Remove a member from a chat",
+		Tag:      "Sharing",
+		Response: dto.SuccessResponse{},
+	},
+	"Handler.ListInvites": {
+		Summary:  "List the invites of a chat",
+		Tag:      "Sharing",
+		Response: dto.InviteListResponse{},
+	},
+	"Handler.CreateInvite": {
+		Summary:     "Create an invite to a chat",
+		Description: "The invite code is only returned here.",
+		Tag:         "Sharing",
+		Request:     dto.CreateInviteRequest{},
+		Status:      http.StatusCreated,
+		Response:    dto.InviteResponse{},
+	},
+	"Handler.RevokeInvite": {
+		Summary:  "Revoke an invite",
+		Tag:      "Sharing",
+		Response: dto.SuccessResponse{},
+	},
+	"Handler.AcceptInvite": {
+		Summary:  "Accept an invite",
+		Tag:      "Sharing",
+		Response: dto.ChatResponse{},
+	},
+
+	// Messages
+	"Handler.GetMessages": {
+		Summary:  "List the messages of a chat",
+		Tag:      "Messages",
+		Params:   append(append([]openapi.Param{}, pageParams...), cursorParams...),
+		Response: dto.MessageListResponse{},
+	},
+	"Handler.CreateMessage": {
+		Summary:     "Send a message",
+		Description: "The AI reply to a user message is streamed to the chat's clients.",
+		Tag:         "Messages",
+		Request:     dto.CreateMessageRequest{},
+		Status:      http.StatusCreated,
+		Response:    dto.MessageResponse{},
+	},
+	"Handler.GetMessage": {
+		Summary:  "Get a message",
+		Tag:      "Messages",
+		Response: dto.MessageResponse{},
+	},
+	"Handler.DeleteMessage": {
+		Summary:  "Delete a message",
+		Tag:      "Messages",
+		Response: dto.SuccessResponse{},
+	},
+	"Handler.RestoreMessage": {
+		Summary:  "Restore a message from the trash",
+		Tag:      "Messages",
+		Response: dto.MessageResponse{},
+	},
+
+	// Generations
+	"Handler.ListGenerations": {
+		Summary: "List the generations of a chat",
+		Tag:     "Generations",
+		Params: []openapi.Param{
+			{Name: "status", Enum: []string{"running", "completed", "failed", "interrupted", "resumed", "discarded", "canceled"}, Description: "Only list generations with this status"},
+		},
+		Response: dto.GenerationListResponse{},
+	},
+	"Handler.GetGeneration": {
+		Summary:  "Get a generation",
+		Tag:      "Generations",
+		Response: dto.GenerationResponse{},
+	},
+	"Handler.ContinueGeneration": {
+		Summary:  "Continue an interrupted generation",
+		Tag:      "Generations",
+		Status:   http.StatusAccepted,
+		Response: dto.GenerationResponse{},
+	},
+	"Handler.DiscardGeneration": {
+		Summary:  "Discard an interrupted generation",
+		Tag:      "Generations",
+		Response: dto.SuccessResponse{},
+	},
+	"Handler.CancelGeneration": {
+		Summary:  "Cancel a running generation",
+		Tag:      "Generations",
+		Status:   http.StatusAccepted,
+		Response: dto.SuccessResponse{},
+	},
+
+	// Folders
+	"Handler.ListFolders": {
+		Summary:  "List folders",
+		Tag:      "Folders",
+		Response: dto.FolderListResponse{},
+	},
+	"Handler.CreateFolder": {
+		Summary:  "Create a folder",
+		Tag:      "Folders",
+		Request:  dto.CreateFolderRequest{},
+		Status:   http.StatusCreated,
+		Response: dto.FolderResponse{},
+	},
+	"Handler.GetFolder": {
+		Summary:  "Get a folder",
+		Tag:      "Folders",
+		Response: dto.FolderResponse{},
+	},
+	"Handler.UpdateFolder": {
+		Summary:  "Rename a folder",
+		Tag:      "Folders",
+		Request:  dto.UpdateFolderRequest{},
+		Response: dto.FolderResponse{},
+	},
+	"Handler.DeleteFolder": {
+		Summary:     "Delete a folder",
+		Description: "The chats of the folder become unfiled.",
+		Tag:         "Folders",
+		Response:    dto.SuccessResponse{},
+	},
+
+	// Webhooks
+	"Handler.ListWebhooks": {
+		Summary:  "List webhooks",
+		Tag:      "Webhooks",
+		Response: dto.WebhookListResponse{},
+	},
+	"Handler.CreateWebhook": {
+		Summary:     "Create a webhook",
+		Description: "The signing secret is only returned here and when it is rotated.",
+		Tag:         "Webhooks",
+		Request:     dto.CreateWebhookRequest{},
+		Status:      http.StatusCreated,
+		Response:    dto.WebhookResponse{},
+	},
+	"Handler.GetWebhook": {
+		Summary:  "Get a webhook",
+		Tag:      "Webhooks",
+		Response: dto.WebhookResponse{},
+	},
+	"Handler.UpdateWebhook": {
+		Summary:  "Update a webhook",
+		Tag:      "Webhooks",
+		Request:  dto.UpdateWebhookRequest{},
+		Response: dto.WebhookResponse{},
+	},
+	"Handler.DeleteWebhook": {
+		Summary:  "Delete a webhook",
+		Tag:      "Webhooks",
+		Response: dto.SuccessResponse{},
+	},
+	"Handler.RotateWebhookSecret": {
+		Summary:  "Rotate the signing secret of a webhook",
+		Tag:      "Webhooks",
+		Response: dto.WebhookResponse{},
+	},
+	"Handler.ListWebhookDeliveries": {
+		Summary:  "List the deliveries of a webhook",
+		Tag:      "Webhooks",
+		Params:   pageParams,
+		Response: dto.WebhookDeliveryListResponse{},
+	},
+
+	// Trash
+	"Handler.ListTrash": {
+		Summary: "List trashed chats or messages",
+		Tag:     "Trash",
+		Params: append([]openapi.Param{
+			{Name: "type", Enum: []string{"chats", "messages"}, Description: "What to list; defaults to chats"},
+		}, pageParams...),
+		Response: dto.TrashListResponse{},
+	},
+
+	// Streaming
+	"SSEHandler.HandleStream": {
+		Summary:     "Stream the events of a chat",
+		Description: "Server-Sent Events. A reconnecting client sends Last-Event-ID to replay the events it missed.",
+		Tag:         "Streaming",
+		Security:    streamSecurity,
+		Params: append([]openapi.Param{
+			{Name: "Last-Event-ID", In: "header", Description: "ID of the last event received before reconnecting"},
+		}, reconnectParams...),
+		Stream: true,
+		Events: chatEvents,
+	},
+	"SSEHandler.IssueStreamToken": {
+		Summary:     "Issue a stream token",
+		Description: "The token authenticates the streams of the chat in the token query parameter, for clients such as EventSource that cannot set headers.",
+		Tag:         "Streaming",
+		Response:    dto.StreamTokenResponse{},
+	},
+	"SSEHandler.GetStats": {
+		Summary:  "Count streaming clients",
+		Tag:      "Streaming",
+		Params:   []openapi.Param{{Name: "chat_id", Description: "Only count the clients of this chat"}},
+		Response: dto.StreamStatsResponse{},
+	},
+	"WebSocketHandler.HandleWebSocket": {
+		Summary:     "Stream the events of a chat over a WebSocket",
+		Description: "Each frame is a JSON object with the event name, its ID and its data. Clients send commands, which are answered with ack or error events.",
+		Tag:         "Streaming",
+		Security:    streamSecurity,
+		Params: append([]openapi.Param{
+			{Name: "last_event_id", Description: "ID of the last event received before reconnecting"},
+		}, reconnectParams...),
+		Status:   http.StatusSwitchingProtocols,
+		Events:   webSocketEvents,
+		Commands: dto.WebSocketCommand{},
+	},
+	"PollHandler.HandlePoll": {
+		Summary:     "Poll the events of a chat",
+		Description: "Returns the events after the cursor, waiting for new ones when there are none yet.",
+		Tag:         "Streaming",
+		Security:    streamSecurity,
+		Params: []openapi.Param{
+			{Name: "after", Description: "Cursor of the last event received"},
+			{Name: "timeout", Description: "How long to wait for events, as a duration such as 25s or a number of seconds"},
+		},
+		Response: dto.EventBatchResponse{},
+		Events:   pollEvents,
+	},
+
+	// OpenAI-compatible API
+	"CompletionHandler.CreateChatCompletion": {
+		Summary:     "Create a chat completion",
+		Description: "OpenAI-compatible. A streamed completion sends chat.completion.chunk objects as unnamed events and ends with [DONE].",
+		Tag:         "OpenAI",
+		Params: []openapi.Param{
+			{Name: ChatIDHeader, In: "header", Description: "ID of the chat to store the exchange in"},
+		},
+		Request:  dto.ChatCompletionRequest{},
+		Response: dto.ChatCompletionResponse{},
+		Stream:   true,
+		Events:   map[string]interface{}{sse.EventMessage: dto.ChatCompletionChunk{}},
+	},
+
+	// Admin
+	"JobHandler.ListJobs": {
+		Summary: "List background jobs",
+		Tag:     "Admin",
+		Params: append([]openapi.Param{
+			{Name: "status", Enum: []string{"pending", "running", "succeeded", "dead"}, Description: "Only list jobs with this status"},
+			{Name: "type", Description: "Only list jobs of this type"},
+			{Name: "tenant_id", Description: "Only list jobs of this tenant"},
+		}, pageParams...),
+		Response: dto.JobListResponse{},
+	},
+	"JobHandler.GetJob": {
+		Summary:  "Get a background job",
+		Tag:      "Admin",
+		Response: dto.JobResponse{},
+	},
+	"JobHandler.RetryJob": {
+		Summary:  "Retry a dead background job",
+		Tag:      "Admin",
+		Response: dto.JobResponse{},
+	},
+
+	// System
+	"SystemHandler.HealthCheck": {
+		Summary:  "Check readiness",
+		Tag:      "System",
+		Public:   true,
+		Response: healthResponse{},
+	},
+	"SystemHandler.Live": {
+		Summary:  "Check liveness",
+		Tag:      "System",
+		Public:   true,
+		Response: healthResponse{},
+	},
+	"SystemHandler.Ready": {
+		Summary:  "Check readiness",
+		Tag:      "System",
+		Public:   true,
+		Response: healthResponse{},
+	},
+	"SystemHandler.Version": {
+		Summary:  "Get the version of the server",
+		Tag:      "System",
+		Public:   true,
+		Response: buildinfo.Info{},
+	},
+	"OpenAPIHandler.GetSpec": {
+		Summary: "Get this OpenAPI document",
+		Tag:     "System",
+		Public:  true,
+	},
+}
+
+// withEvents returns the events of both maps
+func withEvents(events, more map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(events)+len(more))
+	for name, payload := range events {
+		merged[name] = payload
+	}
+	for name, payload := range more {
+		merged[name] = payload
+	}
+	return merged
+}
+
+// NewOpenAPIDocument builds the OpenAPI document of the routes served by the
+// handlers of this package. Other routes, such as static files and metrics,
+// are left out; a handler route without documentation is an error.
+func NewOpenAPIDocument(routes gin.RoutesInfo) (*openapi.Document, error) {
+	generator := openapi.NewGenerator(apiInfo, dto.ErrorResponse{})
+	generator.AddSecurityScheme(securityBearer, openapi.SecurityScheme{
+		Type:        "http",
+		Scheme:      "bearer",
+		Description: "An API key or a JWT",
+	}, true)
+	generator.AddSecurityScheme(securityAPIKey, openapi.SecurityScheme{
+		Type: "apiKey",
+		In:   "header",
+		Name: "X-API-Key",
+	}, true)
+	generator.AddSecurityScheme(securityStreamToken, openapi.SecurityScheme{
+		Type:        "apiKey",
+		In:          "query",
+		Name:        "token",
+		Description: "A stream token issued for the chat",
+	}, false)
+
+	// Types that unmarshal from more than one JSON type
+	generator.Define(dto.MessageContent(""), &openapi.Schema{OneOf: []*openapi.Schema{
+		{Type: "string"},
+		{Type: "array", Items: &openapi.Schema{
+			Type:       "object",
+			Properties: map[string]*openapi.Schema{"type": {Const: "text"}, "text": {Type: "string"}},
+			Required:   []string{"type", "text"},
+		}},
+	}})
+	generator.Define(dto.StopSequences{}, &openapi.Schema{OneOf: []*openapi.Schema{
+		{Type: "string"},
+		{Type: "array", Items: &openapi.Schema{Type: "string"}},
+	}})
+	generator.Define(health.Status(""), &openapi.Schema{Type: "string", Enum: []string{
+		string(health.StatusUp), string(health.StatusDegraded), string(health.StatusDown),
+	}})
+
+	for _, route := range routes {
+		name, ok := handlerMethod(route.Handler)
+		if !ok {
+			continue
+		}
+
+		docs, ok := operationDocs[name]
+		if !ok {
+			return nil, fmt.Errorf("route %s %s is served by %s, which has no OpenAPI documentation", route.Method, route.Path, name)
+		}
+
+		if err := generator.Add(route.Method, route.Path, operationID(name), docs); err != nil {
+			return nil, err
+		}
+	}
+
+	return generator.Document(), nil
+}
+
+// handlerMethod returns the Type.Method name of a handler method of this
+// package from the function name gin reports for a route's handler
+func handlerMethod(funcName string) (string, bool) {
+	prefix := reflect.TypeOf(Handler{}).PkgPath() + ".(*"
+	name, ok := strings.CutPrefix(funcName, prefix)
+	if !ok {
+		return "", false
+	}
+	name = strings.TrimSuffix(name, "-fm")
+	return strings.Replace(name, ").", ".", 1), true
+}
+
+// operationID returns the operation ID of a handler method: its name in lower camel case
+func operationID(handlerMethod string) string {
+	_, method, _ := strings.Cut(handlerMethod, ".")
+	runes := []rune(method)
+	runes[0] = unicode.ToLower(runes[0])
+	return string(runes)
+}
+
+// OpenAPIHandler serves the OpenAPI document of the API
+type OpenAPIHandler struct {
+	spec []byte
+}
+
+// NewOpenAPIHandler creates a new OpenAPI handler. It serves the document
+// once Build was called with the routes of the server.
+func NewOpenAPIHandler() *OpenAPIHandler {
+	return &OpenAPIHandler{}
+}
+
+// Build generates the document of the routes
+func (h *OpenAPIHandler) Build(routes gin.RoutesInfo) error {
+	doc, err := NewOpenAPIDocument(routes)
+	if err != nil {
+		return err
+	}
+
+	spec, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+
+	h.spec = spec
+	return nil
+}
+
+// GetSpec handles GET /api/v1/openapi.json
+func (h *OpenAPIHandler) GetSpec(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json; charset=utf-8", h.spec)
+}
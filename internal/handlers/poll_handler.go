@@ -0,0 +1,140 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/models/dto"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/services"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/sse"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/pkg/errors"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/pkg/logger"
+)
+
+// Long-polling waits
+const (
+	defaultPollTimeout = 25 * time.Second
+	minPollTimeout     = time.Second      // Leaves time for the replay of stored events
+	pollWriteGrace     = 10 * time.Second // Time to write the response once the wait is over
+)
+
+// PollHandler handles long-polling requests, for clients that cannot keep an
+// SSE stream or WebSocket open. Each request subscribes to the broker like a
+// stream does, and returns once events are available.
+type PollHandler struct {
+	broker      *sse.Broker
+	chatService services.ChatService
+	maxTimeout  time.Duration
+}
+
+// NewPollHandler creates a new long-polling handler. Requests wait for events
+// for at most maxTimeout.
+func NewPollHandler(broker *sse.Broker, chatService services.ChatService, maxTimeout time.Duration) *PollHandler {
+	return &PollHandler{
+		broker:      broker,
+		chatService: chatService,
+		maxTimeout:  maxTimeout,
+	}
+}
+
+// HandlePoll handles GET /api/v1/chats/:id/events.
+// It returns the events after the after cursor from the replay store, or
+// waits up to timeout for new ones.
+func (h *PollHandler) HandlePoll(c *gin.Context) {
+	timeout, err := h.pollTimeout(c.Query("timeout"))
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	chat, ok := streamChat(c, h.chatService)
+	if !ok {
+		return
+	}
+	chatID := chat.ID.Hex()
+	after := c.Query("after")
+
+	// Each request is a new client; concurrent polls must not share an ID
+	clientID := newClientID(chatID)
+	ctx := logger.WithContext(c.Request.Context(), logger.FieldChatID, chatID, logger.FieldClientID, clientID)
+
+	// The wait may outlast the server's write timeout
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Now().Add(timeout + pollWriteGrace)); err != nil {
+		logger.FromContext(ctx).Debugf("Failed to extend write deadline of poll: %v", err)
+	}
+
+	// The broker replays the events after the cursor on registration, and
+	// delivers new ones after them, so none are missed in between
+	transport := sse.NewPollTransport()
+	client := sse.NewClient(ctx, clientID, chat.TenantID, transport, h.broker)
+	client.LastEventID = after
+
+	listening := make(chan struct{})
+	go func() {
+		defer close(listening)
+		client.Listen()
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-transport.Ready():
+	case <-timer.C:
+	case <-c.Request.Context().Done():
+	case <-client.Ctx.Done():
+	}
+
+	client.Cancel()
+	<-listening
+
+	events, retry := transport.Batch()
+	response := dto.EventBatchResponse{
+		Events:  make([]dto.EventResponse, len(events)),
+		Cursor:  after,
+		RetryMs: retry.Milliseconds(),
+	}
+	for i, event := range events {
+		response.Events[i] = dto.EventResponse{
+			ID:    event.ID,
+			Event: event.Event,
+			Data:  event.Data,
+		}
+		response.Cursor = event.ID
+	}
+
+	respondWithJSON(c, http.StatusOK, response)
+}
+
+// pollTimeout parses the timeout of a poll, a duration such as 25s or a
+// number of seconds. It is kept between 1s and the configured maximum.
+func (h *PollHandler) pollTimeout(value string) (time.Duration, error) {
+	if value == "" {
+		return min(defaultPollTimeout, h.maxTimeout), nil
+	}
+
+	timeout, err := time.ParseDuration(value)
+	if err != nil {
+		seconds, convErr := strconv.Atoi(value)
+		if convErr != nil {
+			return 0, errors.NewBadRequestError("Invalid timeout", err)
+		}
+		timeout = time.Duration(seconds) * time.Second
+	}
+
+	if timeout < 0 {
+		return 0, errors.NewBadRequestError("Timeout must not be negative", nil)
+	}
+	return min(max(timeout, minPollTimeout), h.maxTimeout), nil
+}
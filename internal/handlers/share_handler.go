@@ -0,0 +1,155 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/models"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/models/dto"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/pkg/errors"
+)
+
+// ListMembers handles GET /api/v1/chats/:id/members
+func (h *Handler) ListMembers(c *gin.Context) {
+	members, err := h.shareService.ListMembers(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	response := dto.ChatMemberListResponse{
+		Members: make([]dto.ChatMemberResponse, len(members)),
+	}
+	for i, member := range members {
+		response.Members[i] = newChatMemberResponse(member)
+	}
+
+	respondWithJSON(c, http.StatusOK, response)
+}
+
+// UpdateMember handles PUT /api/v1/chats/:id/members/:subject
+func (h *Handler) UpdateMember(c *gin.Context) {
+	var req dto.UpdateMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondWithError(c, errors.NewBadRequestError("Invalid request body", err))
+		return
+	}
+
+	member, err := h.shareService.UpdateMemberRole(c.Request.Context(), c.Param("id"), c.Param("subject"), models.ChatRole(req.Role))
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	respondWithJSON(c, http.StatusOK, newChatMemberResponse(*member))
+}
+
+// RemoveMember handles DELETE /api/v1/chats/:id/members/:subject
+func (h *Handler) RemoveMember(c *gin.Context) {
+	if err := h.shareService.RemoveMember(c.Request.Context(), c.Param("id"), c.Param("subject")); err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	respondWithJSON(c, http.StatusOK, dto.SuccessResponse{
+		Message: "Member removed successfully",
+	})
+}
+
+// CreateInvite handles POST /api/v1/chats/:id/invites
+func (h *Handler) CreateInvite(c *gin.Context) {
+	var req dto.CreateInviteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondWithError(c, errors.NewBadRequestError("Invalid request body", err))
+		return
+	}
+
+	if req.ExpiresInHours < 0 {
+		respondWithError(c, errors.NewValidationError("expires_in_hours must not be negative", nil))
+		return
+	}
+
+	ttl := time.Duration(req.ExpiresInHours) * time.Hour
+	invite, code, err := h.shareService.CreateInvite(c.Request.Context(), c.Param("id"), models.ChatRole(req.Role), ttl)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	response := newInviteResponse(invite)
+	response.Code = code
+
+	respondWithJSON(c, http.StatusCreated, response)
+}
+
+// ListInvites handles GET /api/v1/chats/:id/invites
+func (h *Handler) ListInvites(c *gin.Context) {
+	invites, err := h.shareService.ListInvites(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	response := dto.InviteListResponse{
+		Invites: make([]dto.InviteResponse, len(invites)),
+	}
+	for i, invite := range invites {
+		response.Invites[i] = newInviteResponse(invite)
+	}
+
+	respondWithJSON(c, http.StatusOK, response)
+}
+
+// RevokeInvite handles DELETE /api/v1/chats/:id/invites/:invite_id
+func (h *Handler) RevokeInvite(c *gin.Context) {
+	if err := h.shareService.RevokeInvite(c.Request.Context(), c.Param("id"), c.Param("invite_id")); err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	respondWithJSON(c, http.StatusOK, dto.SuccessResponse{
+		Message: "Invite revoked successfully",
+	})
+}
+
+// AcceptInvite handles POST /api/v1/invites/:code/accept
+func (h *Handler) AcceptInvite(c *gin.Context) {
+	chat, err := h.shareService.AcceptInvite(c.Request.Context(), c.Param("code"))
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	respondWithJSON(c, http.StatusOK, newChatResponse(chat))
+}
+
+// newChatMemberResponse converts a chat member to its API representation
+func newChatMemberResponse(member models.ChatMember) dto.ChatMemberResponse {
+	return dto.ChatMemberResponse{
+		Subject: member.Subject,
+		Role:    string(member.Role),
+		AddedAt: member.AddedAt.Format(time.RFC3339),
+	}
+}
+
+// newInviteResponse converts an invite to its API representation
+func newInviteResponse(invite *models.ChatInvite) dto.InviteResponse {
+	return dto.InviteResponse{
+		ID:        invite.ID.Hex(),
+		ChatID:    invite.ChatID.Hex(),
+		Role:      string(invite.Role),
+		CreatedBy: invite.CreatedBy,
+		CreatedAt: invite.CreatedAt.Format(time.RFC3339),
+		ExpiresAt: invite.ExpiresAt.Format(time.RFC3339),
+	}
+}
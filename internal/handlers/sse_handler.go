@@ -10,12 +10,19 @@
 package handlers
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/auth"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/middleware"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/models"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/models/dto"
 	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/services"
 	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/sse"
 	"github.com/yasin-yalcin-dev/go-sse-ai-chat/pkg/errors"
@@ -24,89 +31,211 @@ import (
 
 // SSEHandler handles SSE connections
 type SSEHandler struct {
-	broker      *sse.Broker
-	chatService services.ChatService
+	broker            *sse.Broker
+	chatService       services.ChatService
+	generationService services.GenerationService
+	streamTokens      *auth.StreamTokens
 }
 
-// NewSSEHandler creates a new SSE handler
-func NewSSEHandler(broker *sse.Broker, chatService services.ChatService) *SSEHandler {
+// NewSSEHandler creates a new SSE handler.
+// streamTokens may be nil when authentication is disabled.
+func NewSSEHandler(broker *sse.Broker, chatService services.ChatService, generationService services.GenerationService, streamTokens *auth.StreamTokens) *SSEHandler {
 	return &SSEHandler{
-		broker:      broker,
-		chatService: chatService,
+		broker:            broker,
+		chatService:       chatService,
+		generationService: generationService,
+		streamTokens:      streamTokens,
 	}
 }
 
-// HandleStream handles streaming chat events via SSE
-func (h *SSEHandler) HandleStream(c *gin.Context) {
-	// Get chat ID from URL
+// IssueStreamToken handles POST /api/v1/chats/:id/stream-token.
+// The returned token authorizes GET /api/v1/chats/:id/stream?token=... for clients,
+// such as browser EventSource, that cannot send an Authorization header.
+func (h *SSEHandler) IssueStreamToken(c *gin.Context) {
 	chatID := c.Param("id")
-	if chatID == "" {
-		err := errors.NewBadRequestError("Missing chat ID", nil)
-		c.JSON(err.GetStatusCode(), err.ToResponse())
+
+	principal, ok := middleware.GetPrincipal(c)
+	if !ok || h.streamTokens == nil {
+		respondWithError(c, errors.NewNotFoundError("Stream tokens are not enabled", nil))
 		return
 	}
 
-	// Get client ID from query param (for reconnection) or generate new one
-	clientID := c.Query("client_id")
+	// Only issue tokens for chats the caller can see
+	chat, err := h.chatService.GetChatByID(c.Request.Context(), chatID)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
 
-	// If no client ID or invalid format, generate a new one
-	if clientID == "" || !strings.HasPrefix(clientID, chatID+"_") {
-		clientID = fmt.Sprintf("%s_%s", chatID, uuid.New().String())
+	if chat == nil {
+		respondWithError(c, errors.NewNotFoundError(fmt.Sprintf("Chat %s not found", chatID), nil))
+		return
 	}
 
-	// Verify the chat exists
-	chat, err := h.chatService.GetChatByID(c.Request.Context(), chatID)
+	token, expiresAt, err := h.streamTokens.Issue(principal, chat.TenantID, chat.ID.Hex())
 	if err != nil {
-		logger.Errorf("Error fetching chat %s: %v", chatID, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		respondWithError(c, errors.NewInternalError("Failed to issue stream token", err))
 		return
 	}
 
-	if chat == nil {
-		err := errors.NewNotFoundError(fmt.Sprintf("Chat %s not found", chatID), nil)
-		c.JSON(err.GetStatusCode(), err.ToResponse())
+	respondWithJSON(c, http.StatusOK, dto.StreamTokenResponse{
+		Token:     token,
+		ExpiresAt: expiresAt.Format(time.RFC3339),
+	})
+}
+
+// HandleStream handles streaming chat events via SSE
+func (h *SSEHandler) HandleStream(c *gin.Context) {
+	chat, ok := streamChat(c, h.chatService)
+	if !ok {
+		return
+	}
+	chatID := chat.ID.Hex()
+	clientID := streamClientID(c, chatID)
+
+	transport, err := sse.NewSSETransport(c.Writer)
+	if err != nil {
+		respondWithError(c, errors.NewInternalError("Streaming is not supported", err))
 		return
 	}
 
 	// Get last event ID for replay (if client is reconnecting)
 	lastEventID := c.GetHeader("Last-Event-ID")
-	isReconnection := lastEventID != ""
+
+	// Correlate the logs of this connection
+	ctx := logger.WithContext(c.Request.Context(), logger.FieldChatID, chatID, logger.FieldClientID, clientID)
+	log := logger.FromContext(ctx)
 
 	// Log connection attempt
-	if isReconnection {
-		logger.Infof("SSE reconnection requested for chat %s, client %s, last event %s",
+	if lastEventID != "" {
+		log.Infof("SSE reconnection requested for chat %s, client %s, last event %s",
 			chatID, clientID, lastEventID)
 	} else {
-		logger.Infof("New SSE connection requested for chat %s, client %s", chatID, clientID)
+		log.Infof("New SSE connection requested for chat %s, client %s", chatID, clientID)
 	}
 
-	// Create new client
-	client := sse.NewClient(clientID, c.Writer, h.broker)
+	// Create new client; replay continues after its last event
+	client := sse.NewClient(ctx, clientID, chat.TenantID, transport, h.broker)
+	client.LastEventID = lastEventID
+
+	// Offer to continue replies that were cut off while nobody was listening
+	sendInterruptedGenerations(ctx, h.generationService, client, chatID)
 
-	// Start listening for messages
+	// Close the client when the connection drops
+	stop := context.AfterFunc(c.Request.Context(), client.Cancel)
+	defer stop()
+
+	// Listen for messages until the client disconnects or the broker closes
+	// it, for example on shutdown
 	client.Listen()
+	log.Debugf("Connection closed for client %s", clientID)
+}
 
-	// The connection will be kept open until the client disconnects
-	// or the context is canceled
-	<-c.Request.Context().Done()
-	logger.Debugf("Connection context done for client %s", clientID)
+// streamChat returns the chat a stream connects to, responding with an error
+// unless it exists and the caller may read it. Both the SSE and the WebSocket
+// transports check their chat with it.
+func streamChat(c *gin.Context, chatService services.ChatService) (*models.Chat, bool) {
+	// Get chat ID from URL
+	chatID := c.Param("id")
+	if chatID == "" {
+		err := errors.NewBadRequestError("Missing chat ID", nil)
+		c.JSON(err.GetStatusCode(), err.ToResponse())
+		return nil, false
+	}
+
+	// Verify the chat exists and the caller may read it
+	chat, err := chatService.GetChatByID(c.Request.Context(), chatID)
+	if err != nil {
+		if appErr, ok := errors.AsAppError(err); ok {
+			c.JSON(appErr.GetStatusCode(), appErr.ToResponse())
+			return nil, false
+		}
+		logger.FromContext(c.Request.Context()).Errorf("Error fetching chat %s: %v", chatID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return nil, false
+	}
+
+	if chat == nil {
+		err := errors.NewNotFoundError(fmt.Sprintf("Chat %s not found", chatID), nil)
+		c.JSON(err.GetStatusCode(), err.ToResponse())
+		return nil, false
+	}
+
+	return chat, true
+}
+
+// streamClientID returns the client ID of a stream: the client_id query
+// parameter of a reconnecting client, or else a new one. Client IDs start
+// with the chat ID, which the broker uses to replay the chat's recent events.
+func streamClientID(c *gin.Context, chatID string) string {
+	clientID := c.Query("client_id")
+
+	// If no client ID or invalid format, generate a new one
+	if clientID == "" || !strings.HasPrefix(clientID, chatID+"_") {
+		clientID = newClientID(chatID)
+	}
+	return clientID
+}
+
+// newClientID generates a client ID for a chat
+func newClientID(chatID string) string {
+	return fmt.Sprintf("%s_%s", chatID, uuid.New().String())
 }
 
-// GetStats returns stats about SSE connections
+// sendInterruptedGenerations queues a generation_interrupted event for each
+// interrupted generation of a chat. Failures are logged: the stream works
+// without them.
+func sendInterruptedGenerations(ctx context.Context, generationService services.GenerationService, client *sse.Client, chatID string) {
+	generations, err := generationService.ListGenerations(ctx, chatID, models.GenerationInterrupted)
+	if err != nil {
+		logger.FromContext(ctx).Warnf("Failed to list interrupted generations of chat %s: %v", chatID, err)
+		return
+	}
+
+	resumable := generationService.CanContinue()
+	for _, generation := range generations {
+		data, err := json.Marshal(sse.GenerationInterruptedEvent{
+			GenerationID: generation.ID.Hex(),
+			ChatID:       chatID,
+			Content:      generation.Content,
+			Resumable:    resumable,
+		})
+		if err != nil {
+			logger.FromContext(ctx).Warnf("Failed to encode generation_interrupted event: %v", err)
+			return
+		}
+
+		if err := client.Send(&sse.Message{
+			ID:       generation.ID.Hex(),
+			TenantID: client.TenantID,
+			ChatID:   chatID,
+			Event:    sse.EventGenerationInterrupted,
+			Data:     data,
+		}); err != nil {
+			logger.FromContext(ctx).Warnf("Failed to queue generation_interrupted event: %v", err)
+			return
+		}
+	}
+}
+
+// GetStats returns stats about the SSE connections of the request's tenant
 func (h *SSEHandler) GetStats(c *gin.Context) {
+	tenantID := middleware.GetTenantID(c)
+
 	// Get stats per chat if chat ID is provided
 	chatID := c.Query("chat_id")
 	if chatID != "" {
-		clientCount := h.broker.GetClientsInChat(chatID)
-		c.JSON(http.StatusOK, gin.H{
-			"chat_id": chatID,
-			"clients": clientCount,
+		clientCount := h.broker.GetClientsInChat(tenantID, chatID)
+		respondWithJSON(c, http.StatusOK, dto.StreamStatsResponse{
+			ChatID:  chatID,
+			Clients: &clientCount,
 		})
 		return
 	}
 
 	// Otherwise return total stats
-	c.JSON(http.StatusOK, gin.H{
-		"total_clients": h.broker.GetClientCount(),
+	totalClients := h.broker.GetClientCountInTenant(tenantID)
+	respondWithJSON(c, http.StatusOK, dto.StreamStatsResponse{
+		TotalClients: &totalClients,
 	})
 }
@@ -11,51 +11,69 @@ package handlers
 
 import (
 	"net/http"
-	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/config"
-	"github.com/yasin-yalcin-dev/go-sse-ai-chat/pkg/logger"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/health"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/pkg/buildinfo"
 )
 
 // SystemHandler handles system-related endpoints
 type SystemHandler struct {
-	config *config.Config
+	config    *config.Config
+	liveness  *health.Registry
+	readiness *health.Registry
 }
 
 // NewSystemHandler creates a new system handler
-func NewSystemHandler(cfg *config.Config) *SystemHandler {
+func NewSystemHandler(cfg *config.Config, liveness, readiness *health.Registry) *SystemHandler {
 	return &SystemHandler{
-		config: cfg,
+		config:    cfg,
+		liveness:  liveness,
+		readiness: readiness,
 	}
 }
 
-// HealthCheck handles the health check endpoint
+// Live handles GET /system/live. It fails only when the process itself is
+// broken and should be restarted.
+func (h *SystemHandler) Live(c *gin.Context) {
+	respondWithHealth(c, h.liveness.Check(c.Request.Context()))
+}
+
+// Ready handles GET /system/ready. It fails when the server cannot serve
+// requests, including while it shuts down.
+func (h *SystemHandler) Ready(c *gin.Context) {
+	respondWithHealth(c, h.readiness.Check(c.Request.Context()))
+}
+
+// HealthCheck handles GET /system/health, which reports readiness
 func (h *SystemHandler) HealthCheck(c *gin.Context) {
-	// Create health status response
-	response := gin.H{
-		"status":    "ok",
-		"timestamp": time.Now().Format(time.RFC3339),
-		"services":  make(map[string]string),
-	}
+	h.Ready(c)
+}
 
-	// Add version information
-	response["version"] = "0.1.0"
+// healthResponse is a health report tagged with the running version
+type healthResponse struct {
+	health.Report
+	Version  string `json:"version"`
+	Revision string `json:"revision,omitempty"`
+}
 
-	// TODO: Add status of dependencies (MongoDB, etc.)
-	// For now, just mark them as "unknown"
-	response["services"].(map[string]string)["database"] = "unknown"
-	response["services"].(map[string]string)["ai_provider"] = "unknown"
+// respondWithHealth sends a health report; only a down status fails the probe
+func respondWithHealth(c *gin.Context, report health.Report) {
+	status := http.StatusOK
+	if report.Status == health.StatusDown {
+		status = http.StatusServiceUnavailable
+	}
 
-	logger.Debug("Health check executed")
-	c.JSON(http.StatusOK, response)
+	info := buildinfo.Get()
+	c.JSON(status, healthResponse{
+		Report:   report,
+		Version:  info.Version,
+		Revision: info.ShortRevision(),
+	})
 }
 
-// Version returns the current API version
+// Version returns the build metadata of the running server
 func (h *SystemHandler) Version(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
-		"version":    "0.1.0",
-		"build_time": "2025-04-12T00:00:00Z", // This should be set during build process
-		"go_version": "1.20",
-	})
+	c.JSON(http.StatusOK, buildinfo.Get())
 }
@@ -0,0 +1,61 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/models/dto"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/pkg/errors"
+)
+
+// ListTrash handles GET /api/v1/trash
+func (h *Handler) ListTrash(c *gin.Context) {
+	page, pageSize := handlePagination(c, 20, 100)
+
+	response := dto.TrashListResponse{
+		Type: c.DefaultQuery("type", "chats"),
+	}
+
+	var total int64
+	switch response.Type {
+	case "chats":
+		chats, count, err := h.trashService.ListDeletedChats(c.Request.Context(), page, pageSize)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+		response.Chats = newChatResponses(chats)
+		total = count
+
+	case "messages":
+		messages, count, err := h.trashService.ListDeletedMessages(c.Request.Context(), page, pageSize)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+		response.Messages = newMessageResponses(messages)
+		total = count
+
+	default:
+		respondWithError(c, errors.NewBadRequestError("type must be chats or messages", nil))
+		return
+	}
+
+	response.Pagination = dto.PaginationInfo{
+		Total:    total,
+		Page:     page,
+		PageSize: pageSize,
+		Pages:    calculateTotalPages(total, pageSize),
+	}
+
+	respondWithJSON(c, http.StatusOK, response)
+}
@@ -0,0 +1,179 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/models"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/models/dto"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/services"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/pkg/errors"
+)
+
+// ListWebhooks handles GET /api/v1/webhooks
+func (h *Handler) ListWebhooks(c *gin.Context) {
+	webhooks, err := h.webhookService.ListWebhooks(c.Request.Context())
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	response := dto.WebhookListResponse{
+		Webhooks: make([]dto.WebhookResponse, len(webhooks)),
+	}
+	for i, webhook := range webhooks {
+		response.Webhooks[i] = newWebhookResponse(webhook, false)
+	}
+
+	respondWithJSON(c, http.StatusOK, response)
+}
+
+// CreateWebhook handles POST /api/v1/webhooks
+func (h *Handler) CreateWebhook(c *gin.Context) {
+	var req dto.CreateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondWithError(c, errors.NewBadRequestError("Invalid request body", err))
+		return
+	}
+
+	webhook, err := h.webhookService.CreateWebhook(c.Request.Context(), req.URL, req.Events, req.Description)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	respondWithJSON(c, http.StatusCreated, newWebhookResponse(webhook, true))
+}
+
+// GetWebhook handles GET /api/v1/webhooks/:id
+func (h *Handler) GetWebhook(c *gin.Context) {
+	webhook, err := h.webhookService.GetWebhook(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	respondWithJSON(c, http.StatusOK, newWebhookResponse(webhook, false))
+}
+
+// UpdateWebhook handles PUT /api/v1/webhooks/:id
+func (h *Handler) UpdateWebhook(c *gin.Context) {
+	var req dto.UpdateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondWithError(c, errors.NewBadRequestError("Invalid request body", err))
+		return
+	}
+
+	webhook, err := h.webhookService.UpdateWebhook(c.Request.Context(), c.Param("id"), services.WebhookUpdate{
+		URL:         req.URL,
+		Events:      req.Events,
+		Description: req.Description,
+		Active:      req.Active,
+	})
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	respondWithJSON(c, http.StatusOK, newWebhookResponse(webhook, false))
+}
+
+// RotateWebhookSecret handles POST /api/v1/webhooks/:id/rotate-secret
+func (h *Handler) RotateWebhookSecret(c *gin.Context) {
+	webhook, err := h.webhookService.RotateWebhookSecret(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	respondWithJSON(c, http.StatusOK, newWebhookResponse(webhook, true))
+}
+
+// DeleteWebhook handles DELETE /api/v1/webhooks/:id
+func (h *Handler) DeleteWebhook(c *gin.Context) {
+	if err := h.webhookService.DeleteWebhook(c.Request.Context(), c.Param("id")); err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	respondWithJSON(c, http.StatusOK, dto.SuccessResponse{
+		Message: "Webhook deleted successfully",
+	})
+}
+
+// ListWebhookDeliveries handles GET /api/v1/webhooks/:id/deliveries
+func (h *Handler) ListWebhookDeliveries(c *gin.Context) {
+	page, pageSize := handlePagination(c, 20, 100)
+
+	deliveries, total, err := h.webhookService.ListDeliveries(c.Request.Context(), c.Param("id"), page, pageSize)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	response := dto.WebhookDeliveryListResponse{
+		Deliveries: make([]dto.WebhookDeliveryResponse, len(deliveries)),
+		Pagination: dto.PaginationInfo{
+			Total:    total,
+			Page:     page,
+			PageSize: pageSize,
+			Pages:    calculateTotalPages(total, pageSize),
+		},
+	}
+	for i, delivery := range deliveries {
+		response.Deliveries[i] = dto.WebhookDeliveryResponse{
+			ID:         delivery.ID.Hex(),
+			EventID:    delivery.EventID,
+			Event:      delivery.Event,
+			Attempt:    delivery.Attempt,
+			URL:        delivery.URL,
+			StatusCode: delivery.StatusCode,
+			Error:      delivery.Error,
+			Success:    delivery.Success,
+			DurationMs: delivery.DurationMs,
+			CreatedAt:  delivery.CreatedAt.Format(time.RFC3339),
+		}
+	}
+
+	respondWithJSON(c, http.StatusOK, response)
+}
+
+// newWebhookResponse converts a webhook to its DTO; the secret is only included when withSecret is set
+func newWebhookResponse(webhook *models.Webhook, withSecret bool) dto.WebhookResponse {
+	response := dto.WebhookResponse{
+		ID:                  webhook.ID.Hex(),
+		URL:                 webhook.URL,
+		Events:              webhook.Events,
+		Description:         webhook.Description,
+		Active:              webhook.Active,
+		ConsecutiveFailures: webhook.ConsecutiveFailures,
+		DisabledReason:      webhook.DisabledReason,
+		CreatedAt:           webhook.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:           webhook.UpdatedAt.Format(time.RFC3339),
+	}
+
+	if withSecret {
+		response.Secret = webhook.Secret
+	}
+	if webhook.DisabledAt != nil {
+		response.DisabledAt = webhook.DisabledAt.Format(time.RFC3339)
+	}
+	if webhook.LastDeliveryAt != nil {
+		response.LastDeliveryAt = webhook.LastDeliveryAt.Format(time.RFC3339)
+	}
+	if webhook.LastSuccessAt != nil {
+		response.LastSuccessAt = webhook.LastSuccessAt.Format(time.RFC3339)
+	}
+
+	return response
+}
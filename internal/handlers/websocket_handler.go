@@ -0,0 +1,238 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/auth"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/middleware"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/models/dto"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/ratelimit"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/services"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/sse"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/pkg/errors"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/pkg/logger"
+)
+
+// maxCommandSize bounds the size of a command read from a WebSocket
+const maxCommandSize = 64 * 1024
+
+// WebSocketHandler handles WebSocket connections. A WebSocket receives the
+// same events as an SSE stream, and also accepts commands from the client.
+type WebSocketHandler struct {
+	broker            *sse.Broker
+	chatService       services.ChatService
+	messageService    services.MessageService
+	generationService services.GenerationService
+	rateLimits        *ratelimit.RuleSet
+	upgrader          websocket.Upgrader
+	writeTimeout      time.Duration
+}
+
+// NewWebSocketHandler creates a new WebSocket handler. Messages sent over a
+// WebSocket are charged to the messages class of rateLimits, which may be nil
+// when rate limiting is disabled. Writes taking longer than writeTimeout
+// close the connection.
+func NewWebSocketHandler(broker *sse.Broker, chatService services.ChatService, messageService services.MessageService, generationService services.GenerationService, rateLimits *ratelimit.RuleSet, writeTimeout time.Duration) *WebSocketHandler {
+	return &WebSocketHandler{
+		broker:            broker,
+		chatService:       chatService,
+		messageService:    messageService,
+		generationService: generationService,
+		rateLimits:        rateLimits,
+		upgrader: websocket.Upgrader{
+			// Origins are already checked by the CORS middleware
+			CheckOrigin: func(*http.Request) bool { return true },
+		},
+		writeTimeout: writeTimeout,
+	}
+}
+
+// HandleWebSocket handles GET /api/v1/chats/:id/ws
+func (h *WebSocketHandler) HandleWebSocket(c *gin.Context) {
+	chat, ok := streamChat(c, h.chatService)
+	if !ok {
+		return
+	}
+	chatID := chat.ID.Hex()
+	clientID := streamClientID(c, chatID)
+
+	// Correlate the logs of this connection
+	ctx := logger.WithContext(c.Request.Context(), logger.FieldChatID, chatID, logger.FieldClientID, clientID)
+	log := logger.FromContext(ctx)
+
+	// The upgrader responds with an error itself when the upgrade fails
+	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		log.Warnf("WebSocket upgrade failed for chat %s: %v", chatID, err)
+		return
+	}
+	conn.SetReadLimit(maxCommandSize)
+
+	// Browsers cannot set Last-Event-ID on a WebSocket, so it is a query parameter
+	lastEventID := c.Query("last_event_id")
+	if lastEventID != "" {
+		log.Infof("WebSocket reconnection requested for chat %s, client %s, last event %s",
+			chatID, clientID, lastEventID)
+	} else {
+		log.Infof("New WebSocket connection requested for chat %s, client %s", chatID, clientID)
+	}
+
+	client := sse.NewClient(ctx, clientID, chat.TenantID, sse.NewWebSocketTransport(conn, h.writeTimeout), h.broker)
+	client.LastEventID = lastEventID
+
+	// Offer to continue replies that were cut off while nobody was listening
+	sendInterruptedGenerations(ctx, h.generationService, client, chatID)
+
+	// Read commands until the client disconnects; the closed connection then ends Listen
+	readerDone := make(chan struct{})
+	go func() {
+		defer close(readerDone)
+		h.readCommands(c, conn, client)
+	}()
+
+	// Listen for messages until the client disconnects or the broker closes
+	// it, for example on shutdown
+	client.Listen()
+
+	// The reader uses the request, so it must stop before the handler returns
+	<-readerDone
+	log.Debugf("Connection closed for client %s", clientID)
+}
+
+// readCommands reads and runs the commands of a client until its connection fails or closes
+func (h *WebSocketHandler) readCommands(c *gin.Context, conn *websocket.Conn, client *sse.Client) {
+	defer client.Cancel()
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			if client.Ctx.Err() == nil && !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				logger.FromContext(client.Ctx).Debugf("Failed to read from WebSocket client %s: %v", client.ID, err)
+			}
+			return
+		}
+
+		var command dto.WebSocketCommand
+		if err := json.Unmarshal(data, &command); err != nil {
+			h.replyError(client, command, errors.NewBadRequestError("Invalid command", err))
+			continue
+		}
+
+		result, err := h.runCommand(c, client, command)
+		if err != nil {
+			h.replyError(client, command, err)
+			continue
+		}
+		h.reply(client, sse.EventAck, sse.AckEvent{
+			RequestID: command.RequestID,
+			Type:      command.Type,
+			Result:    result,
+		})
+	}
+}
+
+// runCommand runs a command and returns the result to acknowledge it with
+func (h *WebSocketHandler) runCommand(c *gin.Context, client *sse.Client, command dto.WebSocketCommand) (interface{}, error) {
+	// Commands run with the authenticated request's context, logging as the connection
+	ctx := logger.WithContext(c.Request.Context(), logger.FieldChatID, c.Param("id"), logger.FieldClientID, client.ID)
+
+	switch command.Type {
+	case dto.CommandSendMessage:
+		if err := requireHeaderCredentials(c); err != nil {
+			return nil, err
+		}
+		if command.Message == nil {
+			return nil, errors.NewValidationError("Message is required", nil)
+		}
+		if err := validateMessageRequest(command.Message); err != nil {
+			return nil, err
+		}
+
+		// Messages count against the same limit as those sent over REST
+		if result, limited := middleware.ChargeRateLimit(c, h.rateLimits, ratelimit.ClassMessages); limited && !result.Allowed {
+			return nil, middleware.RateLimitError(result)
+		}
+
+		message, err := h.messageService.CreateMessage(ctx, c.Param("id"), command.Message.Content, command.Message.Role, command.Message.Type)
+		if err != nil {
+			return nil, err
+		}
+		return newMessageResponse(message), nil
+
+	case dto.CommandCancelGeneration:
+		if err := requireHeaderCredentials(c); err != nil {
+			return nil, err
+		}
+		if command.GenerationID == "" {
+			return nil, errors.NewValidationError("Generation ID is required", nil)
+		}
+		if err := h.generationService.CancelGeneration(ctx, command.GenerationID); err != nil {
+			return nil, err
+		}
+		return nil, nil
+
+	default:
+		return nil, errors.NewBadRequestError("Unknown command type", nil)
+	}
+}
+
+// requireHeaderCredentials rejects commands that change a chat on connections
+// opened with a stream token. Stream tokens travel in the URL, where they end
+// up in logs, and only authorize reading the stream.
+func requireHeaderCredentials(c *gin.Context) error {
+	if principal, ok := middleware.GetPrincipal(c); ok && principal.Method == auth.MethodStreamToken {
+		return errors.NewForbiddenError("Stream tokens are read-only; connect with an Authorization or X-API-Key header to send commands", nil)
+	}
+	return nil
+}
+
+// replyError answers a failed command with an error event
+func (h *WebSocketHandler) replyError(client *sse.Client, command dto.WebSocketCommand, err error) {
+	event := sse.ErrorEvent{
+		RequestID: command.RequestID,
+		Type:      command.Type,
+		Status:    http.StatusInternalServerError,
+		Message:   "Internal server error",
+	}
+
+	if appErr, ok := errors.AsAppError(err); ok {
+		event.Status = appErr.GetStatusCode()
+		event.Code = appErr.Code
+		event.Message = appErr.Error()
+	} else {
+		logger.FromContext(client.Ctx).Errorf("WebSocket command %s failed: %v", command.Type, err)
+	}
+
+	h.reply(client, sse.EventError, event)
+}
+
+// reply queues an event for the client that sent a command only
+func (h *WebSocketHandler) reply(client *sse.Client, event string, payload interface{}) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		logger.FromContext(client.Ctx).Errorf("Failed to encode %s event: %v", event, err)
+		return
+	}
+
+	if err := client.Send(&sse.Message{
+		TenantID: client.TenantID,
+		Target:   client.ID,
+		Event:    event,
+		Data:     data,
+	}); err != nil {
+		logger.FromContext(client.Ctx).Debugf("Failed to queue %s event: %v", event, err)
+	}
+}
@@ -0,0 +1,64 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/auth"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/models/dto"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/sse"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/pkg/errors"
+)
+
+// TestWebSocketCommandsRejectStreamTokens checks that connections opened with
+// a stream token cannot send messages or cancel generations, while those
+// authenticated with a header can
+func TestWebSocketCommandsRejectStreamTokens(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	commands := []dto.WebSocketCommand{
+		{Type: dto.CommandSendMessage},
+		{Type: dto.CommandCancelGeneration},
+	}
+	methods := []struct {
+		method    string
+		forbidden bool
+	}{
+		{method: auth.MethodStreamToken, forbidden: true},
+		{method: auth.MethodJWT},
+		{method: auth.MethodAPIKey},
+	}
+
+	handler := &WebSocketHandler{}
+	for _, command := range commands {
+		for _, tt := range methods {
+			t.Run(command.Type+"/"+tt.method, func(t *testing.T) {
+				c, _ := gin.CreateTestContext(httptest.NewRecorder())
+				req := httptest.NewRequest(http.MethodGet, "/api/v1/chats/6123456789abcdef01234567/ws", nil)
+				c.Request = req.WithContext(auth.WithPrincipal(req.Context(), &auth.Principal{Subject: "alice", Method: tt.method}))
+				c.Params = gin.Params{{Key: "id", Value: "6123456789abcdef01234567"}}
+
+				// The command is missing its fields, so callers allowed to send it get a validation error
+				_, err := handler.runCommand(c, &sse.Client{ID: "client-1"}, command)
+				appErr, ok := errors.AsAppError(err)
+				if !ok {
+					t.Fatalf("runCommand() error = %v, want an application error", err)
+				}
+				if forbidden := appErr.GetStatusCode() == http.StatusForbidden; forbidden != tt.forbidden {
+					t.Errorf("runCommand() status = %d, want forbidden %v", appErr.GetStatusCode(), tt.forbidden)
+				}
+			})
+		}
+	}
+}
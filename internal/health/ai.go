@@ -0,0 +1,87 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package health
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/config"
+)
+
+// Model listing endpoints used to probe the AI providers; they are free to call
+// and verify both reachability and credentials
+const (
+	openAIModelsURL    = "https://api.openai.com/v1/models"
+	anthropicModelsURL = "https://api.anthropic.com/v1/models"
+)
+
+// AIProviderChecker probes the default AI provider returned by provider,
+// which may change between checks. Rejected credentials report down;
+// rate limiting and server errors report degraded.
+func AIProviderChecker(provider func() config.AIProviderConfig, client *http.Client) Checker {
+	return CheckerFunc(func(ctx context.Context) Result {
+		cfg := provider()
+		req, err := newProviderRequest(ctx, cfg)
+		if err != nil {
+			return Result{Status: StatusDown, Message: err.Error()}
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return Result{Status: StatusDown, Message: err.Error()}
+		}
+		defer resp.Body.Close()
+		io.Copy(io.Discard, resp.Body)
+
+		details := map[string]interface{}{
+			"provider":    cfg.Provider,
+			"status_code": resp.StatusCode,
+		}
+
+		switch {
+		case resp.StatusCode < 300:
+			return Result{Status: StatusUp, Details: details}
+		case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+			return Result{Status: StatusDown, Message: "credentials rejected", Details: details}
+		case resp.StatusCode == http.StatusTooManyRequests:
+			return Result{Status: StatusDegraded, Message: "rate limited", Details: details}
+		default:
+			return Result{Status: StatusDegraded, Message: fmt.Sprintf("unexpected status %d", resp.StatusCode), Details: details}
+		}
+	})
+}
+
+// newProviderRequest builds the authenticated probe request of a provider
+func newProviderRequest(ctx context.Context, cfg config.AIProviderConfig) (*http.Request, error) {
+	switch cfg.Provider {
+	case "openai":
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, openAIModelsURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+cfg.OpenAIKey)
+		return req, nil
+
+	case "anthropic":
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, anthropicModelsURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("x-api-key", cfg.AnthropicKey)
+		req.Header.Set("anthropic-version", "2023-06-01")
+		return req, nil
+
+	default:
+		return nil, fmt.Errorf("unknown AI provider %q", cfg.Provider)
+	}
+}
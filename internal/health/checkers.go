@@ -0,0 +1,61 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// PingChecker reports down when ping fails and degraded when it succeeds
+// but takes longer than slow
+func PingChecker(ping func(ctx context.Context) error, slow time.Duration) Checker {
+	return CheckerFunc(func(ctx context.Context) Result {
+		start := time.Now()
+		if err := ping(ctx); err != nil {
+			return Result{Status: StatusDown, Message: err.Error()}
+		}
+
+		if elapsed := time.Since(start); slow > 0 && elapsed > slow {
+			return Result{Status: StatusDegraded, Message: "slow response: " + elapsed.Round(time.Millisecond).String()}
+		}
+
+		return Result{Status: StatusUp}
+	})
+}
+
+// Cached wraps a checker so that it runs at most once per ttl. It suits
+// probes that are slow or cost money, such as calls to external APIs.
+func Cached(checker Checker, ttl time.Duration) Checker {
+	var (
+		mutex     sync.Mutex
+		last      Result
+		checkedAt time.Time
+	)
+
+	return CheckerFunc(func(ctx context.Context) Result {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		if checkedAt.IsZero() || time.Since(checkedAt) >= ttl {
+			last = checker.Check(ctx)
+			checkedAt = time.Now()
+		}
+
+		result := last
+		result.Details = make(map[string]interface{}, len(last.Details)+1)
+		for key, value := range last.Details {
+			result.Details[key] = value
+		}
+		result.Details["checked_at"] = checkedAt.Format(time.RFC3339)
+		return result
+	})
+}
@@ -0,0 +1,175 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package health
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Status is the health of a dependency or of the whole service
+type Status string
+
+// Health statuses, from best to worst
+const (
+	StatusUp       Status = "up"
+	StatusDegraded Status = "degraded"
+	StatusDown     Status = "down"
+)
+
+// Result is the outcome of a single check
+type Result struct {
+	Status  Status
+	Message string
+	Details map[string]interface{}
+}
+
+// Checker probes a dependency. Check must respect the deadline of ctx.
+type Checker interface {
+	Check(ctx context.Context) Result
+}
+
+// CheckerFunc adapts a function to the Checker interface
+type CheckerFunc func(ctx context.Context) Result
+
+// Check calls f(ctx)
+func (f CheckerFunc) Check(ctx context.Context) Result {
+	return f(ctx)
+}
+
+// CheckReport is the reported outcome of a check
+type CheckReport struct {
+	Status    Status                 `json:"status"`
+	Critical  bool                   `json:"critical"`
+	LatencyMs float64                `json:"latency_ms"`
+	TimedOut  bool                   `json:"timed_out,omitempty"`
+	Message   string                 `json:"message,omitempty"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+}
+
+// Report is the outcome of all checks of a registry
+type Report struct {
+	Status    Status                 `json:"status"`
+	Timestamp string                 `json:"timestamp"`
+	Message   string                 `json:"message,omitempty"`
+	Checks    map[string]CheckReport `json:"checks"`
+}
+
+// registration is a checker with its settings
+type registration struct {
+	name     string
+	checker  Checker
+	critical bool
+}
+
+// Registry runs a set of named checks
+type Registry struct {
+	timeout  time.Duration
+	checks   []registration
+	mutex    sync.RWMutex
+	draining atomic.Bool
+}
+
+// NewRegistry creates a registry whose checks each get at most timeout to complete
+func NewRegistry(timeout time.Duration) *Registry {
+	return &Registry{timeout: timeout}
+}
+
+// Register adds a check. A critical check that is down takes the whole
+// registry down; other checks can only degrade it.
+func (r *Registry) Register(name string, checker Checker, critical bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.checks = append(r.checks, registration{name: name, checker: checker, critical: critical})
+	sort.Slice(r.checks, func(i, j int) bool { return r.checks[i].name < r.checks[j].name })
+}
+
+// Drain marks the service as shutting down; from then on the registry
+// reports down so that load balancers stop routing new requests to it
+func (r *Registry) Drain() {
+	r.draining.Store(true)
+}
+
+// Check runs all checks concurrently and aggregates their results
+func (r *Registry) Check(ctx context.Context) Report {
+	r.mutex.RLock()
+	checks := r.checks
+	r.mutex.RUnlock()
+
+	reports := make([]CheckReport, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func(i int, check registration) {
+			defer wg.Done()
+			reports[i] = r.run(ctx, check)
+		}(i, check)
+	}
+	wg.Wait()
+
+	report := Report{
+		Status:    StatusUp,
+		Timestamp: time.Now().Format(time.RFC3339),
+		Checks:    make(map[string]CheckReport, len(checks)),
+	}
+
+	for i, check := range checks {
+		checkReport := reports[i]
+		report.Checks[check.name] = checkReport
+
+		switch {
+		case checkReport.Status == StatusDown && check.critical:
+			report.Status = StatusDown
+		case checkReport.Status != StatusUp && report.Status == StatusUp:
+			report.Status = StatusDegraded
+		}
+	}
+
+	if r.draining.Load() {
+		report.Status = StatusDown
+		report.Message = "shutting down"
+	}
+
+	return report
+}
+
+// run executes a single check within the registry timeout
+func (r *Registry) run(ctx context.Context, check registration) CheckReport {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan Result, 1)
+	go func() {
+		done <- check.checker.Check(ctx)
+	}()
+
+	var result Result
+	timedOut := false
+	select {
+	case result = <-done:
+	case <-ctx.Done():
+		result = Result{Status: StatusDown, Message: "check timed out"}
+		timedOut = true
+	}
+
+	return CheckReport{
+		Status:    result.Status,
+		Critical:  check.critical,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+		TimedOut:  timedOut,
+		Message:   result.Message,
+		Details:   result.Details,
+	}
+}
@@ -0,0 +1,244 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/config"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/models"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/repository"
+	apperrors "github.com/yasin-yalcin-dev/go-sse-ai-chat/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrDuplicateJob is returned when a job with the same unique key was already enqueued
+var ErrDuplicateJob = errors.New("job already enqueued")
+
+// Handler runs a job. A returned error fails the attempt; the job is then
+// retried unless it has no attempts left or the error is Permanent.
+type Handler func(ctx context.Context, job *models.Job) error
+
+// Options adjust how a job is enqueued; zero values use the defaults
+type Options struct {
+	TenantID    string    // Tenant the job runs for; its context is scoped to it
+	RunAt       time.Time // When the job becomes due; now when zero
+	MaxAttempts int       // Attempts before the job is dead-lettered; JOBS_MAX_ATTEMPTS when zero
+	UniqueKey   string    // Enqueue the job only once per key
+}
+
+// permanentError marks a failure that retrying cannot fix
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps err so that the failed job is dead-lettered without further retries
+func Permanent(err error) error {
+	return permanentError{err: err}
+}
+
+// IsPermanent reports whether err was marked with Permanent
+func IsPermanent(err error) bool {
+	return errors.As(err, new(permanentError))
+}
+
+// Queue is a MongoDB-backed job queue. It enqueues jobs and runs the handlers
+// registered on this instance; jobs are claimed atomically, so that each runs
+// on one instance at a time, and are retried with backoff until they succeed
+// or are dead-lettered.
+type Queue struct {
+	repo      repository.JobRepository
+	cfg       config.JobsConfig
+	workerID  string
+	handlers  map[string]Handler
+	schedules []*schedule
+	running   map[string]int // Jobs in flight per type
+	wake      chan struct{}  // Signals the worker to look for jobs at once
+	done      chan struct{}  // Closed when Run returns
+	mutex     sync.Mutex
+}
+
+// NewQueue creates a queue on repo. Handlers and schedules must be added before Run.
+func NewQueue(repo repository.JobRepository, cfg config.JobsConfig) *Queue {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+
+	return &Queue{
+		repo:     repo,
+		cfg:      cfg,
+		workerID: fmt.Sprintf("%s-%d-%s", host, os.Getpid(), uuid.New().String()[:8]),
+		handlers: make(map[string]Handler),
+		running:  make(map[string]int),
+		wake:     make(chan struct{}, 1),
+		done:     make(chan struct{}),
+	}
+}
+
+// Register sets the handler of a job type. Only registered types are claimed by this instance.
+func (q *Queue) Register(jobType string, handler Handler) {
+	q.handlers[jobType] = handler
+}
+
+// Enqueue adds a job with the given payload, which is stored with the field
+// names of its JSON encoding. It returns ErrDuplicateJob when opts.UniqueKey
+// was enqueued before.
+func (q *Queue) Enqueue(ctx context.Context, jobType string, payload interface{}, opts Options) (*models.Job, error) {
+	runAt := opts.RunAt
+	if runAt.IsZero() {
+		runAt = time.Now()
+	}
+	maxAttempts := opts.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = q.cfg.MaxAttempts
+	}
+
+	job, err := models.NewJob(jobType, payload, runAt, maxAttempts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode payload of %s job: %w", jobType, err)
+	}
+	job.TenantID = opts.TenantID
+	job.UniqueKey = opts.UniqueKey
+
+	created, err := q.repo.Create(ctx, job)
+	if err != nil {
+		return nil, err
+	}
+	if !created {
+		return nil, ErrDuplicateJob
+	}
+
+	if _, ok := q.handlers[jobType]; ok && !runAt.After(time.Now()) {
+		q.notify()
+	}
+	return job, nil
+}
+
+// GetJob retrieves a job by its ID
+func (q *Queue) GetJob(ctx context.Context, id string) (*models.Job, error) {
+	jobID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, apperrors.NewBadRequestError("Invalid job ID", err)
+	}
+
+	job, err := q.repo.FindByID(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	if job == nil {
+		return nil, apperrors.NewNotFoundError("Job not found", nil)
+	}
+	return job, nil
+}
+
+// ListJobs retrieves a page of jobs matching filter, newest first, and the total number of matches
+func (q *Queue) ListJobs(ctx context.Context, filter repository.JobFilter, page, pageSize int) ([]*models.Job, int64, error) {
+	if filter.Status != "" && !filter.Status.IsValid() {
+		return nil, 0, apperrors.NewBadRequestError("Invalid job status", nil)
+	}
+
+	jobs, err := q.repo.FindAll(ctx, filter, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	total, err := q.repo.CountAll(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return jobs, total, nil
+}
+
+// RetryJob makes a dead job, or a pending one waiting for its next attempt,
+// due at once with a fresh set of attempts
+func (q *Queue) RetryJob(ctx context.Context, id string) (*models.Job, error) {
+	job, err := q.GetJob(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	requeued, err := q.repo.Requeue(ctx, job.ID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if !requeued {
+		return nil, apperrors.NewConflictError("Only dead or pending jobs can be retried", nil)
+	}
+
+	if _, ok := q.handlers[job.Type]; ok {
+		q.notify()
+	}
+	return q.GetJob(ctx, id)
+}
+
+// Done returns a channel that is closed when Run returns
+func (q *Queue) Done() <-chan struct{} {
+	return q.done
+}
+
+// notify wakes the worker without blocking
+func (q *Queue) notify() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// limit returns how many jobs of a type may run at once on this instance
+func (q *Queue) limit(jobType string) int {
+	if limit, ok := q.cfg.Concurrency[jobType]; ok {
+		return limit
+	}
+	return q.cfg.DefaultConcurrency
+}
+
+// available returns the registered job types that have a free slot
+func (q *Queue) available() []string {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	types := make([]string, 0, len(q.handlers))
+	for jobType := range q.handlers {
+		if q.running[jobType] < q.limit(jobType) {
+			types = append(types, jobType)
+		}
+	}
+	sort.Strings(types)
+	return types
+}
+
+// acquire takes a slot of a job type
+func (q *Queue) acquire(jobType string) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.running[jobType]++
+}
+
+// release frees a slot of a job type and wakes the worker to fill it
+func (q *Queue) release(jobType string) {
+	q.mutex.Lock()
+	q.running[jobType]--
+	q.mutex.Unlock()
+
+	q.notify()
+}
@@ -0,0 +1,248 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package jobs
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/config"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/models"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// errExtendFailed is returned by memoryRepo.Extend for workers set to fail it
+var errExtendFailed = errors.New("extend failed")
+
+// memoryRepo is an in-memory JobRepository with the claim and lease
+// semantics of the MongoDB repository
+type memoryRepo struct {
+	jobs       map[primitive.ObjectID]*models.Job
+	failExtend map[string]bool     // Workers whose lease renewals fail
+	outcomes   map[string][]string // Outcomes recorded per worker
+	mutex      sync.Mutex
+}
+
+func newMemoryRepo() *memoryRepo {
+	return &memoryRepo{
+		jobs:       make(map[primitive.ObjectID]*models.Job),
+		failExtend: make(map[string]bool),
+		outcomes:   make(map[string][]string),
+	}
+}
+
+// job returns a copy of a stored job
+func (r *memoryRepo) job(id primitive.ObjectID) models.Job {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return *r.jobs[id]
+}
+
+// setFailExtend makes the lease renewals of a worker fail or succeed
+func (r *memoryRepo) setFailExtend(workerID string, fail bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.failExtend[workerID] = fail
+}
+
+// recorded returns the outcomes a worker recorded
+func (r *memoryRepo) recorded(workerID string) []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return slices.Clone(r.outcomes[workerID])
+}
+
+func (r *memoryRepo) Create(_ context.Context, job *models.Job) (bool, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, existing := range r.jobs {
+		if job.UniqueKey != "" && existing.UniqueKey == job.UniqueKey {
+			return false, nil
+		}
+	}
+	stored := *job
+	r.jobs[job.ID] = &stored
+	return true, nil
+}
+
+func (r *memoryRepo) FindByID(_ context.Context, id primitive.ObjectID) (*models.Job, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	job, ok := r.jobs[id]
+	if !ok {
+		return nil, nil
+	}
+	found := *job
+	return &found, nil
+}
+
+func (r *memoryRepo) FindAll(context.Context, repository.JobFilter, int, int) ([]*models.Job, error) {
+	return nil, nil
+}
+
+func (r *memoryRepo) CountAll(context.Context, repository.JobFilter) (int64, error) {
+	return 0, nil
+}
+
+func (r *memoryRepo) Claim(_ context.Context, types []string, workerID string, now, leaseUntil time.Time) (*models.Job, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	var visible []*models.Job
+	for _, job := range r.jobs {
+		if (job.Status == models.JobPending || job.Status == models.JobRunning) &&
+			slices.Contains(types, job.Type) && !job.VisibleAt.After(now) {
+			visible = append(visible, job)
+		}
+	}
+	if len(visible) == 0 {
+		return nil, nil
+	}
+	sort.Slice(visible, func(i, j int) bool { return visible[i].VisibleAt.Before(visible[j].VisibleAt) })
+
+	job := visible[0]
+	job.Status = models.JobRunning
+	job.LockedBy = workerID
+	job.VisibleAt = leaseUntil
+	job.StartedAt = &now
+	job.Attempts++
+
+	claimed := *job
+	return &claimed, nil
+}
+
+func (r *memoryRepo) Extend(_ context.Context, id primitive.ObjectID, workerID string, leaseUntil time.Time) (bool, error) {
+	r.mutex.Lock()
+	fail := r.failExtend[workerID]
+	r.mutex.Unlock()
+	if fail {
+		return false, errExtendFailed
+	}
+
+	return r.updateClaimed(id, workerID, "", func(job *models.Job) {
+		job.VisibleAt = leaseUntil
+	})
+}
+
+func (r *memoryRepo) Complete(_ context.Context, id primitive.ObjectID, workerID string, at, expiresAt time.Time) (bool, error) {
+	return r.updateClaimed(id, workerID, "succeeded", func(job *models.Job) {
+		job.Status = models.JobSucceeded
+		job.FinishedAt = &at
+		job.ExpiresAt = &expiresAt
+		job.LockedBy = ""
+		job.LastError = ""
+	})
+}
+
+func (r *memoryRepo) Retry(_ context.Context, id primitive.ObjectID, workerID, errMsg string, visibleAt time.Time) (bool, error) {
+	return r.updateClaimed(id, workerID, "retried", func(job *models.Job) {
+		job.Status = models.JobPending
+		job.VisibleAt = visibleAt
+		job.LastError = errMsg
+		job.LockedBy = ""
+	})
+}
+
+func (r *memoryRepo) Bury(_ context.Context, id primitive.ObjectID, workerID, errMsg string, at time.Time) (bool, error) {
+	return r.updateClaimed(id, workerID, "dead", func(job *models.Job) {
+		job.Status = models.JobDead
+		job.LastError = errMsg
+		job.FinishedAt = &at
+		job.LockedBy = ""
+	})
+}
+
+func (r *memoryRepo) Release(_ context.Context, id primitive.ObjectID, workerID string) (bool, error) {
+	return r.updateClaimed(id, workerID, "released", func(job *models.Job) {
+		job.Status = models.JobPending
+		job.VisibleAt = time.Now()
+		job.Attempts--
+		job.LockedBy = ""
+	})
+}
+
+func (r *memoryRepo) Requeue(_ context.Context, id primitive.ObjectID, at time.Time) (bool, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	job, ok := r.jobs[id]
+	if !ok || (job.Status != models.JobDead && job.Status != models.JobPending) {
+		return false, nil
+	}
+	job.Status = models.JobPending
+	job.Attempts = 0
+	job.VisibleAt = at
+	job.FinishedAt = nil
+	return true, nil
+}
+
+// updateClaimed applies update to a job still claimed by workerID and
+// records the outcome, if any, when it applied
+func (r *memoryRepo) updateClaimed(id primitive.ObjectID, workerID, outcome string, update func(*models.Job)) (bool, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	job, ok := r.jobs[id]
+	if !ok || job.Status != models.JobRunning || job.LockedBy != workerID {
+		return false, nil
+	}
+	update(job)
+	if outcome != "" {
+		r.outcomes[workerID] = append(r.outcomes[workerID], outcome)
+	}
+	return true, nil
+}
+
+// testJobsConfig returns a configuration with short timings for tests
+func testJobsConfig() config.JobsConfig {
+	return config.JobsConfig{
+		PollInterval:       10 * time.Millisecond,
+		VisibilityTimeout:  300 * time.Millisecond,
+		MaxAttempts:        3,
+		BackoffBase:        time.Second,
+		BackoffMax:         10 * time.Second,
+		Retention:          time.Hour,
+		DefaultConcurrency: 1,
+	}
+}
+
+// waitFor polls cond until it holds, failing the test after a few seconds
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// TestEnqueueUniqueKey checks that a unique key is only enqueued once
+func TestEnqueueUniqueKey(t *testing.T) {
+	queue := NewQueue(newMemoryRepo(), testJobsConfig())
+	ctx := context.Background()
+
+	if _, err := queue.Enqueue(ctx, "report", nil, Options{UniqueKey: "daily"}); err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+	if _, err := queue.Enqueue(ctx, "report", nil, Options{UniqueKey: "daily"}); !errors.Is(err, ErrDuplicateJob) {
+		t.Errorf("Enqueue() of a duplicate key error = %v, want %v", err, ErrDuplicateJob)
+	}
+}
@@ -0,0 +1,106 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/pkg/logger"
+)
+
+// schedule enqueues a job at every occurrence of a cron schedule
+type schedule struct {
+	name    string
+	spec    cron.Schedule
+	jobType string
+	payload interface{}
+}
+
+// everySchedule occurs at every multiple of an interval since the zero
+// time, so that all instances agree on its occurrences
+type everySchedule struct {
+	interval time.Duration
+}
+
+// Next implements cron.Schedule
+func (s everySchedule) Next(t time.Time) time.Time {
+	return t.Truncate(s.interval).Add(s.interval)
+}
+
+// Schedule enqueues a job of jobType with payload at every occurrence of
+// spec, a standard five-field cron expression, a descriptor such as
+// "@hourly", or "@every <duration>". Each occurrence is enqueued once no
+// matter how many instances run the schedule; occurrences missed while no
+// instance was running are skipped.
+func (q *Queue) Schedule(name, spec, jobType string, payload interface{}) error {
+	parsed, err := parseSchedule(spec)
+	if err != nil {
+		return fmt.Errorf("invalid schedule %s: %w", name, err)
+	}
+
+	q.schedules = append(q.schedules, &schedule{
+		name:    name,
+		spec:    parsed,
+		jobType: jobType,
+		payload: payload,
+	})
+	return nil
+}
+
+// parseSchedule parses a schedule specification
+func parseSchedule(spec string) (cron.Schedule, error) {
+	if interval, ok := strings.CutPrefix(spec, "@every "); ok {
+		d, err := time.ParseDuration(strings.TrimSpace(interval))
+		if err != nil {
+			return nil, err
+		}
+		if d < time.Second {
+			return nil, fmt.Errorf("interval must be at least 1s: %v", d)
+		}
+		return everySchedule{interval: d}, nil
+	}
+
+	return cron.ParseStandard(spec)
+}
+
+// runSchedule enqueues the occurrences of a schedule until ctx is canceled
+func (q *Queue) runSchedule(ctx context.Context, s *schedule) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		next := s.spec.Next(time.Now())
+		if next.IsZero() {
+			logger.Warnf("Schedule %s has no further occurrences", s.name)
+			return
+		}
+
+		timer.Reset(time.Until(next))
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		// The occurrence time in the key lets only the first instance enqueue it
+		_, err := q.Enqueue(ctx, s.jobType, s.payload, Options{
+			RunAt:     next,
+			UniqueKey: fmt.Sprintf("schedule:%s:%d", s.name, next.Unix()),
+		})
+		if err != nil && !errors.Is(err, ErrDuplicateJob) && ctx.Err() == nil {
+			logger.Errorf("Failed to enqueue %s job of schedule %s: %v", s.jobType, s.name, err)
+		}
+	}
+}
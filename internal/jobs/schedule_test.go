@@ -0,0 +1,56 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package jobs
+
+import (
+	"testing"
+	"time"
+)
+
+// TestParseSchedule checks the schedule forms and that every instance agrees on the occurrences
+func TestParseSchedule(t *testing.T) {
+	from := time.Date(2026, 10, 18, 10, 7, 30, 0, time.UTC)
+
+	tests := []struct {
+		spec    string
+		want    time.Time
+		wantErr bool
+	}{
+		{spec: "@every 15m", want: time.Date(2026, 10, 18, 10, 15, 0, 0, time.UTC)},
+		{spec: "@hourly", want: time.Date(2026, 10, 18, 11, 0, 0, 0, time.UTC)},
+		{spec: "30 3 * * *", want: time.Date(2026, 10, 19, 3, 30, 0, 0, time.UTC)},
+		{spec: "@every 500ms", wantErr: true},
+		{spec: "@every soon", wantErr: true},
+		{spec: "not a schedule", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			schedule, err := parseSchedule(tt.spec)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("parseSchedule() accepted %q", tt.spec)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseSchedule() error = %v", err)
+			}
+
+			if got := schedule.Next(from); !got.Equal(tt.want) {
+				t.Errorf("Next(%v) = %v, want %v", from, got, tt.want)
+			}
+			// An instance starting later within the interval sees the same occurrence
+			if got := schedule.Next(from.Add(time.Second)); !got.Equal(tt.want) {
+				t.Errorf("Next(%v) = %v, want %v", from.Add(time.Second), got, tt.want)
+			}
+		})
+	}
+}
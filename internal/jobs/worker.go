@@ -0,0 +1,210 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/metrics"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/models"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/shutdown"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/tenant"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/pkg/logger"
+)
+
+// writeTimeout bounds the update that records the outcome of a job
+const writeTimeout = 5 * time.Second
+
+// errLeaseLost cancels a job whose lease could not be renewed; another worker may already run it
+var errLeaseLost = errors.New("job lease lost")
+
+// Run claims and runs jobs of the registered types, and enqueues the runs of
+// schedules, until ctx is canceled. Jobs still running then are canceled with
+// shutdown.ErrShuttingDown and returned to the queue; Run waits for them.
+func (q *Queue) Run(ctx context.Context) {
+	defer close(q.done)
+
+	logger.Infof("Starting job worker %s (%d job types, %d schedules)", q.workerID, len(q.handlers), len(q.schedules))
+
+	var wg sync.WaitGroup
+	for _, s := range q.schedules {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			q.runSchedule(ctx, s)
+		}()
+	}
+
+	// Jobs outlive ctx until they are canceled explicitly, so that their outcome is recorded
+	jobCtx, cancelJobs := context.WithCancelCause(context.WithoutCancel(ctx))
+	defer cancelJobs(nil)
+
+	poll := time.NewTimer(0)
+	defer poll.Stop()
+
+	for {
+		if types := q.available(); len(types) > 0 {
+			now := time.Now()
+			job, err := q.repo.Claim(ctx, types, q.workerID, now, now.Add(q.cfg.VisibilityTimeout))
+			if err != nil && ctx.Err() == nil {
+				logger.Errorf("Failed to claim job: %v", err)
+			}
+
+			if job != nil {
+				q.acquire(job.Type)
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					defer q.release(job.Type)
+					q.execute(jobCtx, job)
+				}()
+				continue
+			}
+		}
+
+		poll.Reset(q.cfg.PollInterval)
+		select {
+		case <-ctx.Done():
+			logger.Info("Stopping job worker")
+			cancelJobs(shutdown.ErrShuttingDown)
+			wg.Wait()
+			return
+
+		case <-q.wake:
+		case <-poll.C:
+		}
+	}
+}
+
+// execute runs a claimed job and records its outcome
+func (q *Queue) execute(ctx context.Context, job *models.Job) {
+	ctx = logger.WithContext(ctx, logger.FieldJobID, job.ID.Hex(), logger.FieldJobType, job.Type)
+	if job.TenantID != "" {
+		ctx = logger.WithContext(tenant.WithTenant(ctx, job.TenantID), logger.FieldTenantID, job.TenantID)
+	}
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	log := logger.FromContext(ctx)
+
+	// Renew the lease while the job runs, so that it is not claimed again
+	stopHeartbeat := make(chan struct{})
+	heartbeatDone := make(chan struct{})
+	go func() {
+		defer close(heartbeatDone)
+		q.heartbeat(ctx, job, cancel, stopHeartbeat)
+	}()
+
+	started := time.Now()
+	err := q.call(ctx, job)
+	duration := time.Since(started)
+
+	close(stopHeartbeat)
+	<-heartbeatDone
+
+	writeCtx, cancelWrite := context.WithTimeout(context.WithoutCancel(ctx), writeTimeout)
+	defer cancelWrite()
+
+	var outcome string
+	var recorded bool
+	var writeErr error
+
+	switch cause := context.Cause(ctx); {
+	case errors.Is(cause, errLeaseLost):
+		log.Warnf("Abandoned job after losing its lease: %v", err)
+		return
+
+	case err == nil:
+		outcome = "succeeded"
+		now := time.Now()
+		recorded, writeErr = q.repo.Complete(writeCtx, job.ID, q.workerID, now, now.Add(q.cfg.Retention))
+
+	case errors.Is(cause, shutdown.ErrShuttingDown):
+		outcome = "released"
+		log.Infof("Returning job to the queue on shutdown")
+		recorded, writeErr = q.repo.Release(writeCtx, job.ID, q.workerID)
+
+	case job.Attempts >= job.MaxAttempts || IsPermanent(err):
+		outcome = "dead"
+		log.Errorf("Job failed on attempt %d/%d and was dead-lettered: %v", job.Attempts, job.MaxAttempts, err)
+		recorded, writeErr = q.repo.Bury(writeCtx, job.ID, q.workerID, err.Error(), time.Now())
+
+	default:
+		outcome = "retried"
+		delay := q.backoff(job.Attempts)
+		log.Warnf("Job failed on attempt %d/%d, retrying in %v: %v", job.Attempts, job.MaxAttempts, delay, err)
+		recorded, writeErr = q.repo.Retry(writeCtx, job.ID, q.workerID, err.Error(), time.Now().Add(delay))
+	}
+
+	metrics.ObserveJob(job.Type, outcome, duration)
+
+	if writeErr != nil {
+		log.Errorf("Failed to record job outcome %s: %v", outcome, writeErr)
+	} else if !recorded {
+		log.Warnf("Job outcome %s was not recorded; the job is no longer held by this worker", outcome)
+	}
+}
+
+// call runs the handler of a job, turning a panic into an error
+func (q *Queue) call(ctx context.Context, job *models.Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job handler panicked: %v", r)
+		}
+	}()
+
+	return q.handlers[job.Type](ctx, job)
+}
+
+// heartbeat extends the lease of a running job until stop is closed. When
+// the lease cannot be renewed, the job is canceled with errLeaseLost.
+func (q *Queue) heartbeat(ctx context.Context, job *models.Job, cancel context.CancelCauseFunc, stop <-chan struct{}) {
+	ticker := time.NewTicker(q.cfg.VisibilityTimeout / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+
+		case <-ticker.C:
+			writeCtx, cancelWrite := context.WithTimeout(context.WithoutCancel(ctx), writeTimeout)
+			extended, err := q.repo.Extend(writeCtx, job.ID, q.workerID, time.Now().Add(q.cfg.VisibilityTimeout))
+			cancelWrite()
+
+			if err != nil {
+				// Transient failures are tolerated; the lease still has two thirds left
+				logger.FromContext(ctx).Warnf("Failed to extend job lease: %v", err)
+				continue
+			}
+			if !extended {
+				cancel(errLeaseLost)
+				return
+			}
+		}
+	}
+}
+
+// backoff returns the delay before the next attempt of a job that failed
+// attempts times: JOBS_BACKOFF_BASE doubled per attempt, up to
+// JOBS_BACKOFF_MAX, plus up to 10% jitter
+func (q *Queue) backoff(attempts int) time.Duration {
+	delay := q.cfg.BackoffBase
+	for i := 1; i < attempts && delay < q.cfg.BackoffMax; i++ {
+		delay *= 2
+	}
+	delay = min(delay, q.cfg.BackoffMax)
+
+	return delay + rand.N(delay/10+1)
+}
@@ -0,0 +1,278 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package jobs
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/models"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/shutdown"
+)
+
+// TestBackoff checks that the retry delay doubles per attempt up to the maximum, plus at most 10% jitter
+func TestBackoff(t *testing.T) {
+	queue := NewQueue(newMemoryRepo(), testJobsConfig())
+
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{attempts: 1, want: time.Second},
+		{attempts: 2, want: 2 * time.Second},
+		{attempts: 3, want: 4 * time.Second},
+		{attempts: 4, want: 8 * time.Second},
+		{attempts: 5, want: 10 * time.Second},
+		{attempts: 50, want: 10 * time.Second},
+	}
+
+	for _, tt := range tests {
+		for range 20 {
+			if got := queue.backoff(tt.attempts); got < tt.want || got > tt.want+tt.want/10 {
+				t.Errorf("backoff(%d) = %v, want between %v and %v", tt.attempts, got, tt.want, tt.want+tt.want/10)
+			}
+		}
+	}
+}
+
+// runAttempt claims a job of jobType the way the worker does and executes it
+func runAttempt(t *testing.T, queue *Queue, repo *memoryRepo, jobType string) *models.Job {
+	t.Helper()
+
+	now := time.Now()
+	job, err := repo.Claim(context.Background(), []string{jobType}, queue.workerID, now, now.Add(queue.cfg.VisibilityTimeout))
+	if err != nil || job == nil {
+		t.Fatalf("Claim() = %v, %v, want a job", job, err)
+	}
+	queue.execute(context.Background(), job)
+	return job
+}
+
+// makeVisible makes a job due at once, as if its retry delay had passed
+func makeVisible(repo *memoryRepo, job *models.Job) {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+	repo.jobs[job.ID].VisibleAt = time.Now()
+}
+
+// TestExecuteRetriesWithBackoff checks that a failed attempt returns the job
+// to the queue, invisible until its backoff passes
+func TestExecuteRetriesWithBackoff(t *testing.T) {
+	repo := newMemoryRepo()
+	queue := NewQueue(repo, testJobsConfig())
+	queue.Register("flaky", func(context.Context, *models.Job) error {
+		return errors.New("upstream unavailable")
+	})
+
+	enqueued, err := queue.Enqueue(context.Background(), "flaky", nil, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for attempt := 1; attempt <= 2; attempt++ {
+		before := time.Now()
+		runAttempt(t, queue, repo, "flaky")
+		after := time.Now()
+
+		job := repo.job(enqueued.ID)
+		if job.Status != models.JobPending || job.LockedBy != "" {
+			t.Fatalf("attempt %d: job is %s locked by %q, want pending and unlocked", attempt, job.Status, job.LockedBy)
+		}
+		if job.LastError != "upstream unavailable" {
+			t.Errorf("attempt %d: last error = %q, want the handler's error", attempt, job.LastError)
+		}
+
+		delay := queue.cfg.BackoffBase << (attempt - 1)
+		if earliest, latest := before.Add(delay), after.Add(delay+delay/10); job.VisibleAt.Before(earliest) || job.VisibleAt.After(latest) {
+			t.Errorf("attempt %d: visible at %v, want between %v and %v", attempt, job.VisibleAt, earliest, latest)
+		}
+
+		// The job is not claimed again before its delay passed
+		if claimed, _ := repo.Claim(context.Background(), []string{"flaky"}, "other", time.Now(), time.Now().Add(time.Minute)); claimed != nil {
+			t.Fatalf("attempt %d: job was claimed again during its backoff", attempt)
+		}
+		makeVisible(repo, enqueued)
+	}
+}
+
+// TestExecuteBuriesFailedJobs checks that a job is dead-lettered once it has
+// no attempts left, or at once for a permanent error
+func TestExecuteBuriesFailedJobs(t *testing.T) {
+	tests := []struct {
+		name       string
+		handler    Handler
+		wantStatus []models.JobStatus // After each attempt
+	}{
+		{
+			name:       "after max attempts",
+			handler:    func(context.Context, *models.Job) error { return errors.New("boom") },
+			wantStatus: []models.JobStatus{models.JobPending, models.JobPending, models.JobDead},
+		},
+		{
+			name:       "panics count as failures",
+			handler:    func(context.Context, *models.Job) error { panic("boom") },
+			wantStatus: []models.JobStatus{models.JobPending, models.JobPending, models.JobDead},
+		},
+		{
+			name:       "permanent error",
+			handler:    func(context.Context, *models.Job) error { return Permanent(errors.New("bad payload")) },
+			wantStatus: []models.JobStatus{models.JobDead},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newMemoryRepo()
+			queue := NewQueue(repo, testJobsConfig())
+			queue.Register("failing", tt.handler)
+
+			enqueued, err := queue.Enqueue(context.Background(), "failing", nil, Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for i, want := range tt.wantStatus {
+				runAttempt(t, queue, repo, "failing")
+				if job := repo.job(enqueued.ID); job.Status != want {
+					t.Fatalf("status after attempt %d = %s, want %s", i+1, job.Status, want)
+				}
+				makeVisible(repo, enqueued)
+			}
+
+			job := repo.job(enqueued.ID)
+			if job.Attempts != len(tt.wantStatus) || job.FinishedAt == nil || job.LastError == "" {
+				t.Errorf("dead job = %+v, want %d attempts, a finish time and the last error", job, len(tt.wantStatus))
+			}
+
+			// Dead jobs are not claimed again
+			if claimed, _ := repo.Claim(context.Background(), []string{"failing"}, "other", time.Now(), time.Now().Add(time.Minute)); claimed != nil {
+				t.Error("dead job was claimed again")
+			}
+		})
+	}
+}
+
+// TestLeaseExpiryReclaim checks that a job whose lease was not renewed is
+// claimed by another worker, and that the first worker then abandons it
+// without recording an outcome
+func TestLeaseExpiryReclaim(t *testing.T) {
+	repo := newMemoryRepo()
+	cfg := testJobsConfig()
+
+	stalled := NewQueue(repo, cfg)
+	lostCause := make(chan error, 1)
+	stalled.Register("report", func(ctx context.Context, _ *models.Job) error {
+		<-ctx.Done()
+		lostCause <- context.Cause(ctx)
+		return ctx.Err()
+	})
+
+	healthy := NewQueue(repo, cfg)
+	healthyRuns := make(chan struct{}, 2)
+	healthy.Register("report", func(context.Context, *models.Job) error {
+		healthyRuns <- struct{}{}
+		return nil
+	})
+
+	enqueued, err := stalled.Enqueue(context.Background(), "report", nil, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The first worker claims the job but cannot renew its lease
+	repo.setFailExtend(stalled.workerID, true)
+	stalledCtx, stopStalled := context.WithCancel(context.Background())
+	defer func() { stopStalled(); <-stalled.Done() }()
+	go stalled.Run(stalledCtx)
+	waitFor(t, "the first worker to claim the job", func() bool { return repo.job(enqueued.ID).LockedBy == stalled.workerID })
+
+	healthyCtx, stopHealthy := context.WithCancel(context.Background())
+	defer func() { stopHealthy(); <-healthy.Done() }()
+	go healthy.Run(healthyCtx)
+
+	// The lease hides the job from the second worker until it expires
+	time.Sleep(cfg.VisibilityTimeout / 3)
+	if job := repo.job(enqueued.ID); job.LockedBy != stalled.workerID {
+		t.Fatalf("job was reclaimed by %q before its lease expired", job.LockedBy)
+	}
+
+	waitFor(t, "the second worker to finish the job", func() bool { return repo.job(enqueued.ID).Status == models.JobSucceeded })
+	if job := repo.job(enqueued.ID); job.Attempts != 2 {
+		t.Errorf("attempts = %d, want 2", job.Attempts)
+	}
+
+	// The first worker learns it lost the lease at its next renewal
+	repo.setFailExtend(stalled.workerID, false)
+	select {
+	case cause := <-lostCause:
+		if !errors.Is(cause, errLeaseLost) {
+			t.Errorf("first worker's job was canceled with %v, want %v", cause, errLeaseLost)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("first worker's job was not canceled after losing its lease")
+	}
+
+	// Give the first worker time to record an outcome it must not record
+	time.Sleep(50 * time.Millisecond)
+	if outcomes := repo.recorded(stalled.workerID); len(outcomes) != 0 {
+		t.Errorf("first worker recorded %v, want nothing", outcomes)
+	}
+	if outcomes := repo.recorded(healthy.workerID); !slices.Equal(outcomes, []string{"succeeded"}) {
+		t.Errorf("second worker recorded %v, want [succeeded]", outcomes)
+	}
+	if len(healthyRuns) != 1 {
+		t.Errorf("second worker ran the job %d times, want 1", len(healthyRuns))
+	}
+}
+
+// TestRunReleasesRunningJobsOnStop checks that jobs still running when the
+// worker stops are canceled and returned to the queue without using up an attempt
+func TestRunReleasesRunningJobsOnStop(t *testing.T) {
+	repo := newMemoryRepo()
+	queue := NewQueue(repo, testJobsConfig())
+
+	cause := make(chan error, 1)
+	queue.Register("export", func(ctx context.Context, _ *models.Job) error {
+		<-ctx.Done()
+		cause <- context.Cause(ctx)
+		return ctx.Err()
+	})
+
+	enqueued, err := queue.Enqueue(context.Background(), "export", nil, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, stopJobs := context.WithCancel(context.Background())
+	go queue.Run(ctx)
+	waitFor(t, "the job to start", func() bool { return repo.job(enqueued.ID).Status == models.JobRunning })
+
+	stopJobs()
+	select {
+	case <-queue.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after its context was canceled")
+	}
+
+	if got := <-cause; !errors.Is(got, shutdown.ErrShuttingDown) {
+		t.Errorf("job was canceled with %v, want %v", got, shutdown.ErrShuttingDown)
+	}
+
+	job := repo.job(enqueued.ID)
+	if job.Status != models.JobPending || job.LockedBy != "" || job.Attempts != 0 || job.VisibleAt.After(time.Now()) {
+		t.Errorf("released job = %+v, want pending, unlocked, due and with no attempts used", job)
+	}
+	if outcomes := repo.recorded(queue.workerID); !slices.Equal(outcomes, []string{"released"}) {
+		t.Errorf("worker recorded %v, want [released]", outcomes)
+	}
+}
@@ -0,0 +1,40 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	jobsProcessed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "jobs",
+		Name:      "processed_total",
+		Help:      "Background job attempts by type and outcome.",
+	}, []string{"type", "outcome"})
+
+	jobDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "jobs",
+		Name:      "duration_seconds",
+		Help:      "Background job attempt duration by type.",
+		Buckets:   []float64{0.01, 0.05, 0.1, 0.5, 1, 5, 10, 30, 60, 300},
+	}, []string{"type"})
+)
+
+// ObserveJob records a finished job attempt. outcome is succeeded, retried,
+// dead or released.
+func ObserveJob(jobType, outcome string, duration time.Duration) {
+	jobsProcessed.WithLabelValues(jobType, outcome).Inc()
+	jobDuration.WithLabelValues(jobType).Observe(duration.Seconds())
+}
@@ -0,0 +1,137 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/pkg/buildinfo"
+)
+
+// namespace prefixes every metric name
+const namespace = "chat"
+
+// Registry holds all application metrics along with Go runtime and process metrics
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "HTTP request latency by route, method and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	aiTimeToFirstToken = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "ai",
+		Name:      "time_to_first_token_seconds",
+		Help:      "Time from sending a generation request until the first token arrives.",
+		Buckets:   []float64{0.1, 0.25, 0.5, 1, 2, 4, 8, 16, 32},
+	}, []string{"provider"})
+
+	aiTokensPerSecond = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "ai",
+		Name:      "tokens_per_second",
+		Help:      "Output token throughput of completed generations.",
+		Buckets:   []float64{5, 10, 20, 40, 60, 80, 120, 160, 240},
+	}, []string{"provider"})
+
+	aiRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "ai",
+		Name:      "requests_total",
+		Help:      "Generation requests by provider and outcome (success or error).",
+	}, []string{"provider", "outcome"})
+
+	aiTokens = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "ai",
+		Name:      "tokens_total",
+		Help:      "Tokens billed by AI providers, by provider and type (prompt or completion).",
+	}, []string{"provider", "type"})
+)
+
+func init() {
+	info := buildinfo.Get()
+	buildInfo := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "build_info",
+		Help:      "Always 1; labeled with the version, VCS revision and Go version of the running binary.",
+		ConstLabels: prometheus.Labels{
+			"version":    info.Version,
+			"revision":   info.Revision,
+			"go_version": info.GoVersion,
+		},
+	}, func() float64 { return 1 })
+
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		buildInfo,
+		httpRequestDuration,
+		aiTimeToFirstToken,
+		aiTokensPerSecond,
+		aiRequests,
+		aiTokens,
+		sseEventsBroadcast,
+		sseEventsDropped,
+		sseEventsRetried,
+		mongoCommandDuration,
+		jobsProcessed,
+		jobDuration,
+	)
+}
+
+// Handler serves the metrics of Registry in the Prometheus text format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// ObserveHTTPRequest records the latency of a handled HTTP request.
+// route is the matched route pattern, so IDs in paths do not create new series.
+func ObserveHTTPRequest(method, route string, status int, latency time.Duration) {
+	if route == "" {
+		route = "unmatched"
+	}
+	httpRequestDuration.WithLabelValues(method, route, strconv.Itoa(status)).Observe(latency.Seconds())
+}
+
+// ObserveAIGeneration records a finished generation. ttft is the time to the first
+// token and duration the time until the last one; err marks failed generations.
+func ObserveAIGeneration(provider string, ttft, duration time.Duration, outputTokens int, err error) {
+	if err != nil {
+		aiRequests.WithLabelValues(provider, "error").Inc()
+		return
+	}
+	aiRequests.WithLabelValues(provider, "success").Inc()
+
+	if ttft > 0 {
+		aiTimeToFirstToken.WithLabelValues(provider).Observe(ttft.Seconds())
+	}
+
+	// Throughput is measured over the streaming phase, after the first token
+	if streaming := duration - ttft; outputTokens > 0 && streaming > 0 {
+		aiTokensPerSecond.WithLabelValues(provider).Observe(float64(outputTokens) / streaming.Seconds())
+	}
+}
+
+// AddAITokens records the tokens a provider reported for a completion
+func AddAITokens(provider string, promptTokens, completionTokens int) {
+	aiTokens.WithLabelValues(provider, "prompt").Add(float64(promptTokens))
+	aiTokens.WithLabelValues(provider, "completion").Add(float64(completionTokens))
+}
@@ -0,0 +1,59 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.mongodb.org/mongo-driver/event"
+)
+
+var mongoCommandDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: namespace,
+	Subsystem: "mongodb",
+	Name:      "command_duration_seconds",
+	Help:      "MongoDB command latency by command, collection and outcome.",
+	Buckets:   []float64{0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5},
+}, []string{"command", "collection", "outcome"})
+
+// NewCommandMonitor returns a MongoDB command monitor that records command latencies
+func NewCommandMonitor() *event.CommandMonitor {
+	// The collection is only part of the started event, so remember it until the command finishes
+	var collections sync.Map
+
+	key := func(connectionID string, requestID int64) string {
+		return fmt.Sprintf("%s/%d", connectionID, requestID)
+	}
+
+	finish := func(e event.CommandFinishedEvent, outcome string) {
+		collection := ""
+		if value, ok := collections.LoadAndDelete(key(e.ConnectionID, e.RequestID)); ok {
+			collection = value.(string)
+		}
+		mongoCommandDuration.WithLabelValues(e.CommandName, collection, outcome).Observe(e.Duration.Seconds())
+	}
+
+	return &event.CommandMonitor{
+		Started: func(_ context.Context, e *event.CommandStartedEvent) {
+			// CRUD commands name their collection as the value of the command field
+			collection, _ := e.Command.Lookup(e.CommandName).StringValueOK()
+			collections.Store(key(e.ConnectionID, e.RequestID), collection)
+		},
+		Succeeded: func(_ context.Context, e *event.CommandSucceededEvent) {
+			finish(e.CommandFinishedEvent, "success")
+		},
+		Failed: func(_ context.Context, e *event.CommandFailedEvent) {
+			finish(e.CommandFinishedEvent, "error")
+		},
+	}
+}
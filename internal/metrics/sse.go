@@ -0,0 +1,85 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package metrics
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var (
+	sseEventsBroadcast = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "sse",
+		Name:      "events_broadcast_total",
+		Help:      "Events accepted by the SSE broker for delivery, by event name.",
+	}, []string{"event"})
+
+	sseEventsDropped = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "sse",
+		Name:      "events_dropped_total",
+		Help:      "Event deliveries to a client that were given up, by event name.",
+	}, []string{"event"})
+
+	sseEventsRetried = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "sse",
+		Name:      "events_retried_total",
+		Help:      "Event deliveries to a client that were retried, by event name.",
+	}, []string{"event"})
+
+	sseClientsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "sse", "clients"),
+		"Connected SSE clients per tenant.",
+		[]string{"tenant"}, nil,
+	)
+)
+
+// SSEEventBroadcast counts an event accepted by the broker
+func SSEEventBroadcast(event string) {
+	sseEventsBroadcast.WithLabelValues(event).Inc()
+}
+
+// SSEEventDropped counts an event that could not be delivered to a client
+func SSEEventDropped(event string) {
+	sseEventsDropped.WithLabelValues(event).Inc()
+}
+
+// SSEEventRetried counts a retried event delivery
+func SSEEventRetried(event string) {
+	sseEventsRetried.WithLabelValues(event).Inc()
+}
+
+// TenantClients is the number of clients connected to the chats of a tenant
+type TenantClients struct {
+	TenantID string
+	Clients  int
+}
+
+// sseClientsCollector reads client counts from the broker on every scrape,
+// so tenants without clients disappear instead of lingering at zero
+type sseClientsCollector struct {
+	count func() []TenantClients
+}
+
+// Describe implements prometheus.Collector
+func (c sseClientsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- sseClientsDesc
+}
+
+// Collect implements prometheus.Collector
+func (c sseClientsCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, tenant := range c.count() {
+		ch <- prometheus.MustNewConstMetric(sseClientsDesc, prometheus.GaugeValue, float64(tenant.Clients), tenant.TenantID)
+	}
+}
+
+// RegisterSSEClients exports the per-tenant client counts returned by count
+func RegisterSSEClients(count func() []TenantClients) {
+	Registry.MustRegister(sseClientsCollector{count: count})
+}
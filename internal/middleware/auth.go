@@ -0,0 +1,154 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package middleware
+
+import (
+	"context"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/auth"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/pkg/errors"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/pkg/logger"
+)
+
+// PrincipalKey is the gin context key holding the authenticated *auth.Principal
+const PrincipalKey = "Principal"
+
+// AuthMiddleware rejects requests without a valid bearer token or API key
+func AuthMiddleware(authenticator auth.Authenticator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		credential := credentialFromRequest(c)
+		if credential == "" {
+			abortUnauthorized(c, "Authentication required")
+			return
+		}
+
+		principal, err := authenticator.Authenticate(c.Request.Context(), credential)
+		if err != nil {
+			rejectCredential(c, err)
+			return
+		}
+
+		setPrincipal(c, principal)
+		c.Next()
+	}
+}
+
+// StreamAuthMiddleware authenticates SSE stream requests. Besides the regular
+// credentials it accepts a stream token in the "token" query parameter, which
+// must have been issued for the chat in the ":id" path parameter.
+func StreamAuthMiddleware(authenticator auth.Authenticator, tokens *auth.StreamTokens) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token := c.Query("token"); token != "" {
+			principal, err := tokens.Verify(token, c.Param("id"))
+			if err != nil {
+				rejectCredential(c, err)
+				return
+			}
+
+			setPrincipal(c, principal)
+			c.Next()
+			return
+		}
+
+		AuthMiddleware(authenticator)(c)
+	}
+}
+
+// AdminMiddleware only lets the listed principals through. Admin routes act
+// across tenants, so credentials bound to a tenant are never admins.
+// It must run after authentication.
+func AdminMiddleware(admins Admins) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, ok := GetPrincipal(c)
+		if !ok || !admins.Has(principal) {
+			abortWithError(c, errors.NewForbiddenError("Admin access required", nil))
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// Admins is the set of API key IDs and JWT subjects with admin access
+type Admins map[string]bool
+
+// NewAdmins creates the set of admins with the given subjects
+func NewAdmins(subjects []string) Admins {
+	admins := make(Admins, len(subjects))
+	for _, subject := range subjects {
+		admins[subject] = true
+	}
+	return admins
+}
+
+// Has reports whether the principal is an admin. Principals bound to a
+// tenant never are.
+func (a Admins) Has(principal *auth.Principal) bool {
+	return principal != nil && principal.TenantID == "" && a[principal.Subject]
+}
+
+// GetPrincipal returns the principal authenticated for the request, if any
+func GetPrincipal(c *gin.Context) (*auth.Principal, bool) {
+	return auth.PrincipalFromContext(c.Request.Context())
+}
+
+// credentialFromRequest extracts a bearer token or API key from the request headers
+func credentialFromRequest(c *gin.Context) string {
+	return credentialFromHeaders(c.GetHeader("Authorization"), c.GetHeader("X-API-Key"))
+}
+
+// credentialFromHeaders returns the bearer token of an Authorization header,
+// or else the API key. A non-bearer Authorization header yields no credential.
+func credentialFromHeaders(authorization, apiKey string) string {
+	if authorization != "" {
+		scheme, credential, ok := strings.Cut(authorization, " ")
+		if ok && strings.EqualFold(scheme, "Bearer") {
+			return strings.TrimSpace(credential)
+		}
+		return ""
+	}
+	return apiKey
+}
+
+// setPrincipal stores the principal on both the gin and the request context
+func setPrincipal(c *gin.Context, principal *auth.Principal) {
+	c.Set(PrincipalKey, principal)
+	c.Request = c.Request.WithContext(auth.WithPrincipal(c.Request.Context(), principal))
+}
+
+// rejectCredential aborts the request after a failed authentication attempt
+func rejectCredential(c *gin.Context, err error) {
+	appErr := credentialError(c.Request.Context(), c.Request.URL.Path, err)
+	if appErr.Code == errors.CodeUnauthorized {
+		c.Header("WWW-Authenticate", `Bearer realm="api"`)
+	}
+	abortWithError(c, appErr)
+}
+
+// credentialError logs a failed authentication attempt on target and returns
+// the error it is reported with: unauthorized for a bad credential, internal
+// when the credential could not be checked
+func credentialError(ctx context.Context, target string, err error) *errors.AppError {
+	if errors.Is(err, auth.ErrInvalidCredential) || errors.Is(err, auth.ErrUnsupportedCredential) {
+		logger.FromContext(ctx).Debugf("Rejected credential for %s: %v", target, err)
+		return errors.NewUnauthorizedError("Invalid credentials", nil)
+	}
+
+	logger.FromContext(ctx).Errorf("Authentication failed for %s: %v", target, err)
+	return errors.NewInternalError("Authentication failed", err)
+}
+
+// abortUnauthorized aborts the request with a 401 response
+func abortUnauthorized(c *gin.Context, message string) {
+	c.Header("WWW-Authenticate", `Bearer realm="api"`)
+	abortWithError(c, errors.NewUnauthorizedError(message, nil))
+}
@@ -16,13 +16,14 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-// CORSMiddleware creates a middleware for handling Cross-Origin Resource Sharing (CORS)
-func CORSMiddleware(allowedOrigins []string) gin.HandlerFunc {
+// CORSMiddleware creates a middleware for handling Cross-Origin Resource Sharing (CORS).
+// extraHeaders are allowed in addition to the default request headers.
+func CORSMiddleware(allowedOrigins []string, extraHeaders ...string) gin.HandlerFunc {
 	// Default CORS configuration
 	corsConfig := cors.Config{
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Requested-With"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowHeaders:     append([]string{"Origin", "Content-Type", "Accept", "Authorization", "X-API-Key", "X-Requested-With"}, extraHeaders...),
+		ExposeHeaders:    []string{"Content-Length", RequestIDHeader},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}
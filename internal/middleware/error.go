@@ -46,21 +46,21 @@ func ErrorHandlerMiddleware() gin.HandlerFunc {
 			errorResponse = appError.ToResponse()
 
 			// Log error details
-			logger.With(
+			logger.FromContext(c.Request.Context()).With(
 				"status_code", statusCode,
 				"error_code", appError.Code,
 				"path", c.Request.URL.Path,
 			).Error(appError.Error())
 		} else {
 			// Generic error, don't leak internal details in production
-			logger.With(
+			logger.FromContext(c.Request.Context()).With(
 				"status_code", statusCode,
 				"path", c.Request.URL.Path,
 			).Error(err.Error())
 		}
 
 		// Add request ID to response if available
-		if requestID, exists := c.Get("RequestID"); exists {
+		if requestID := GetRequestID(c); requestID != "" {
 			errorResponse["request_id"] = requestID
 		}
 
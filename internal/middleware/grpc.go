@@ -0,0 +1,306 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/auth"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/ratelimit"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/tenant"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/pkg/errors"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/pkg/logger"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// grpcErrorDomain is the domain of the ErrorInfo details of gRPC errors
+const grpcErrorDomain = "go-sse-ai-chat"
+
+// grpcGuard prepares the context of a gRPC call, or rejects the call with
+// an error. The returned function, when not nil, runs once the call ended.
+type grpcGuard func(ctx context.Context, method string, isStream bool) (context.Context, func(), error)
+
+// interceptors returns the unary and stream interceptors applying the guard
+func (g grpcGuard) interceptors() (grpc.UnaryServerInterceptor, grpc.StreamServerInterceptor) {
+	unary := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, done, err := g(ctx, info.FullMethod, false)
+		if err != nil {
+			return nil, err
+		}
+		if done != nil {
+			defer done()
+		}
+		return handler(ctx, req)
+	}
+
+	stream := func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, done, err := g(ss.Context(), info.FullMethod, true)
+		if err != nil {
+			return err
+		}
+		if done != nil {
+			defer done()
+		}
+		return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+	}
+
+	return unary, stream
+}
+
+// contextStream is a server stream whose context was extended by an interceptor
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context returns the extended context
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
+
+// GRPCRequestInterceptors assign every gRPC call a request ID, like
+// RequestIDMiddleware, recover from panics, turn application errors into
+// gRPC statuses and log each call when it ends. They must run first.
+func GRPCRequestInterceptors() (grpc.UnaryServerInterceptor, grpc.StreamServerInterceptor) {
+	unary := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		ctx, finish := startGRPCCall(ctx, info.FullMethod)
+		defer func() {
+			err = finish(recover(), err)
+		}()
+		return handler(ctx, req)
+	}
+
+	stream := func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		ctx, finish := startGRPCCall(ss.Context(), info.FullMethod)
+		defer func() {
+			err = finish(recover(), err)
+		}()
+		return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+	}
+
+	return unary, stream
+}
+
+// startGRPCCall sets up the context of a call. The returned function turns
+// a panic or error of the call into its status and logs the call.
+func startGRPCCall(ctx context.Context, method string) (context.Context, func(panicked interface{}, err error) error) {
+	requestID := metadataValue(ctx, RequestIDHeader)
+	if !isValidRequestID(requestID) {
+		requestID = uuid.NewString()
+	}
+	_ = grpc.SetHeader(ctx, metadata.Pairs(RequestIDHeader, requestID))
+	ctx = logger.WithContext(ctx, logger.FieldRequestID, requestID)
+
+	start := time.Now()
+	return ctx, func(panicked interface{}, err error) error {
+		if panicked != nil {
+			logger.FromContext(ctx).Errorf("Panic in %s: %v\n%s", method, panicked, debug.Stack())
+			err = status.Error(codes.Internal, "Internal server error")
+		}
+		err = grpcError(ctx, err)
+
+		code := status.Code(err)
+		log := logger.FromContext(ctx).With(
+			"code", code.String(),
+			"latency", time.Since(start),
+			"client_ip", peerIP(ctx),
+			"method", method,
+		)
+		switch {
+		case isServerCode(code):
+			log.With("error", err.Error()).Error("Server error")
+		case code != codes.OK:
+			log.With("error", err.Error()).Warn("Client error")
+		default:
+			log.Info("Call completed")
+		}
+
+		return err
+	}
+}
+
+// GRPCAuthInterceptors authenticate gRPC calls with a bearer token in the
+// authorization metadata or an API key in x-api-key, like AuthMiddleware, and
+// resolve their tenant from the tenant header metadata, like TenantMiddleware.
+// A nil authenticator leaves calls unauthenticated, for when authentication
+// is disabled.
+func GRPCAuthInterceptors(authenticator auth.Authenticator, tenantHeader string, admins Admins) (grpc.UnaryServerInterceptor, grpc.StreamServerInterceptor) {
+	return grpcGuard(func(ctx context.Context, method string, _ bool) (context.Context, func(), error) {
+		if authenticator != nil {
+			credential := credentialFromHeaders(metadataValue(ctx, "Authorization"), metadataValue(ctx, "X-API-Key"))
+			if credential == "" {
+				return nil, nil, errors.NewUnauthorizedError("Authentication required", nil)
+			}
+
+			principal, err := authenticator.Authenticate(ctx, credential)
+			if err != nil {
+				return nil, nil, credentialError(ctx, method, err)
+			}
+			ctx = auth.WithPrincipal(ctx, principal)
+		}
+
+		tenantID, appErr := resolveTenant(ctx, metadataValue(ctx, tenantHeader), admins)
+		if appErr != nil {
+			return nil, nil, appErr
+		}
+
+		ctx = logger.WithContext(ctx, logger.FieldTenantID, tenantID)
+		return tenant.WithTenant(ctx, tenantID), nil, nil
+	}).interceptors()
+}
+
+// GRPCRateLimitInterceptors count each gRPC call against the principal and
+// client IP buckets of its class, as returned by classify, like
+// RateLimitMiddleware, and cap the concurrent streaming calls per principal
+// with streams. They must run after authentication.
+func GRPCRateLimitInterceptors(rules *ratelimit.RuleSet, classify func(method string) string, streams *ratelimit.ConcurrencyLimiter) (grpc.UnaryServerInterceptor, grpc.StreamServerInterceptor) {
+	return grpcGuard(func(ctx context.Context, method string, isStream bool) (context.Context, func(), error) {
+		clientIP := peerIP(ctx)
+
+		if result, limited := chargeRateLimit(ctx, rules, classify(method), clientIP); limited {
+			_ = grpc.SetHeader(ctx, metadata.Pairs(
+				"RateLimit-Limit", strconv.Itoa(result.Limit),
+				"RateLimit-Remaining", strconv.Itoa(result.Remaining),
+				"RateLimit-Reset", strconv.Itoa(seconds(result.Reset)),
+			))
+
+			if !result.Allowed {
+				logger.FromContext(ctx).Debugf("Rate limited %s from %s", method, clientIP)
+				return nil, nil, RateLimitError(result)
+			}
+		}
+
+		if !isStream {
+			return ctx, nil, nil
+		}
+
+		key, ok := principalKey(ctx)
+		if !ok {
+			key = "ip:" + clientIP
+		}
+		if !streams.Acquire(key) {
+			return nil, nil, errors.NewRateLimitError("Too many open streams", nil)
+		}
+		return ctx, func() { streams.Release(key) }, nil
+	}).interceptors()
+}
+
+// grpcError returns the gRPC status error of an error returned by a call.
+// Application errors keep their message, and their code and context are
+// attached as ErrorInfo details; other errors are not exposed.
+func grpcError(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return status.FromContextError(err).Err()
+	}
+
+	appErr, ok := errors.AsAppError(err)
+	if !ok {
+		logger.FromContext(ctx).Errorf("Unexpected error: %v", err)
+		return status.Error(codes.Internal, "Internal server error")
+	}
+
+	info := &errdetails.ErrorInfo{Reason: appErr.Code, Domain: grpcErrorDomain}
+	if len(appErr.Context) > 0 {
+		info.Metadata = make(map[string]string, len(appErr.Context))
+		for key, value := range appErr.Context {
+			info.Metadata[key] = fmt.Sprint(value)
+		}
+	}
+
+	st := status.New(grpcCode(appErr), appErr.Error())
+	if detailed, err := st.WithDetails(info); err == nil {
+		st = detailed
+	}
+	return st.Err()
+}
+
+// grpcCode returns the gRPC status code matching an application error
+func grpcCode(appErr *errors.AppError) codes.Code {
+	if appErr.Code == errors.CodeQuotaExceeded {
+		return codes.ResourceExhausted
+	}
+
+	switch statusCode := appErr.GetStatusCode(); statusCode {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.FailedPrecondition
+	case http.StatusRequestEntityTooLarge, http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case http.StatusNotImplemented:
+		return codes.Unimplemented
+	case http.StatusServiceUnavailable:
+		return codes.Unavailable
+	case http.StatusGatewayTimeout:
+		return codes.DeadlineExceeded
+	default:
+		if statusCode < http.StatusInternalServerError {
+			return codes.InvalidArgument
+		}
+		return codes.Internal
+	}
+}
+
+// isServerCode reports whether a status code means the server failed
+func isServerCode(code codes.Code) bool {
+	switch code {
+	case codes.Unknown, codes.Internal, codes.Unavailable, codes.DataLoss, codes.DeadlineExceeded, codes.Unimplemented:
+		return true
+	default:
+		return false
+	}
+}
+
+// metadataValue returns the first value of a metadata key of an incoming call
+func metadataValue(ctx context.Context, key string) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// peerIP returns the IP address of the client of a call
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
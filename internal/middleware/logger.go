@@ -13,9 +13,23 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/metrics"
 	"github.com/yasin-yalcin-dev/go-sse-ai-chat/pkg/logger"
 )
 
+// longLivedKey marks requests that are left out of the request latency metric
+const longLivedKey = "long_lived"
+
+// LongLived marks the requests of a route as long-lived streams. They stay
+// open as long as the client listens, so their duration is no latency and
+// they are not recorded in the request duration histogram.
+func LongLived() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(longLivedKey, true)
+		c.Next()
+	}
+}
+
 // LoggerMiddleware logs request information using the application's logger
 func LoggerMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -35,6 +49,10 @@ func LoggerMiddleware() gin.HandlerFunc {
 		statusCode := c.Writer.Status()
 		errorMessage := c.Errors.ByType(gin.ErrorTypePrivate).String()
 
+		if !c.GetBool(longLivedKey) {
+			metrics.ObserveHTTPRequest(method, c.FullPath(), statusCode, latency)
+		}
+
 		if raw != "" {
 			path = path + "?" + raw
 		}
@@ -52,14 +70,16 @@ func LoggerMiddleware() gin.HandlerFunc {
 			logFields = append(logFields, "error", errorMessage)
 		}
 
-		// Log with appropriate level based on status code
+		// Log with appropriate level based on status code; the request context
+		// carries the request ID and whatever later middleware added to it
+		log := logger.FromContext(c.Request.Context()).With(logFields...)
 		switch {
 		case statusCode >= 500:
-			logger.With(logFields...).Error("Server error")
+			log.Error("Server error")
 		case statusCode >= 400:
-			logger.With(logFields...).Warn("Client error")
+			log.Warn("Client error")
 		default:
-			logger.With(logFields...).Info("Request completed")
+			log.Info("Request completed")
 		}
 	}
 }
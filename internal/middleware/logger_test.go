@@ -0,0 +1,64 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/metrics"
+)
+
+// TestLongLivedRequestsAreNotTimed checks that only routes not marked as
+// long-lived are recorded in the request duration histogram
+func TestLongLivedRequestsAreNotTimed(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(LoggerMiddleware())
+	router.GET("/timed", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.GET("/stream", LongLived(), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	for _, path := range []string{"/timed", "/stream"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	routes := timedRoutes(t)
+	if !routes["/timed"] {
+		t.Error("request to /timed was not recorded")
+	}
+	if routes["/stream"] {
+		t.Error("request to long-lived /stream was recorded")
+	}
+}
+
+// timedRoutes returns the routes with samples in the request duration histogram
+func timedRoutes(t *testing.T) map[string]bool {
+	families, err := metrics.Registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	routes := make(map[string]bool)
+	for _, family := range families {
+		if family.GetName() != "chat_http_request_duration_seconds" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "route" {
+					routes[label.GetValue()] = true
+				}
+			}
+		}
+	}
+	return routes
+}
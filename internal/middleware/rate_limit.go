@@ -0,0 +1,140 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package middleware
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/auth"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/ratelimit"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/pkg/errors"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/pkg/logger"
+)
+
+// RateLimitMiddleware counts each request against the principal and client IP
+// buckets of its route class, as returned by classify. The tightest bucket is
+// reported in RateLimit-* headers. It must run after authentication.
+func RateLimitMiddleware(rules *ratelimit.RuleSet, classify func(*gin.Context) string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		result, limited := ChargeRateLimit(c, rules, classify(c))
+		if !limited {
+			c.Next()
+			return
+		}
+
+		c.Header("RateLimit-Limit", strconv.Itoa(result.Limit))
+		c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Header("RateLimit-Reset", strconv.Itoa(seconds(result.Reset)))
+
+		if !result.Allowed {
+			logger.FromContext(c.Request.Context()).Debugf("Rate limited %s %s from %s", c.Request.Method, c.FullPath(), c.ClientIP())
+			c.Header("Retry-After", strconv.Itoa(seconds(result.RetryAfter)))
+			abortWithError(c, RateLimitError(result))
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// ChargeRateLimit counts one request of class against the principal and
+// client IP buckets of the request in c and returns the tightest result. It
+// reports false when no bucket applies, including when rules is nil. Handlers
+// use it to charge work done over a long-lived connection, such as messages
+// sent over a WebSocket.
+func ChargeRateLimit(c *gin.Context, rules *ratelimit.RuleSet, class string) (ratelimit.Result, bool) {
+	return chargeRateLimit(c.Request.Context(), rules, class, c.ClientIP())
+}
+
+// chargeRateLimit counts one request of class from clientIP against the
+// buckets of the client IP and of the principal authenticated in ctx
+func chargeRateLimit(ctx context.Context, rules *ratelimit.RuleSet, class, clientIP string) (ratelimit.Result, bool) {
+	if rules == nil {
+		return ratelimit.Result{}, false
+	}
+
+	rule, ok := rules.Rule(class)
+	if !ok {
+		return ratelimit.Result{}, false
+	}
+
+	// The principal bucket is only charged for requests the IP bucket lets through
+	var results []ratelimit.Result
+	if rule.IP != nil {
+		results = append(results, rule.IP.Allow(clientIP))
+	}
+	if key, ok := principalKey(ctx); ok && rule.Principal != nil && (len(results) == 0 || results[0].Allowed) {
+		results = append(results, rule.Principal.Allow(key))
+	}
+
+	if len(results) == 0 {
+		return ratelimit.Result{}, false
+	}
+	return tightest(results), true
+}
+
+// RateLimitError returns the error reported for a rejected request
+func RateLimitError(result ratelimit.Result) *errors.AppError {
+	return errors.NewRateLimitError("Rate limit exceeded", nil).
+		WithContext("retry_after", seconds(result.RetryAfter))
+}
+
+// StreamLimitMiddleware caps the number of concurrent streams per principal,
+// or per client IP for unauthenticated requests. It must run after authentication.
+func StreamLimitMiddleware(limiter *ratelimit.ConcurrencyLimiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key, ok := principalKey(c.Request.Context())
+		if !ok {
+			key = "ip:" + c.ClientIP()
+		}
+
+		if !limiter.Acquire(key) {
+			abortWithError(c, errors.NewRateLimitError("Too many open streams", nil))
+			return
+		}
+		defer limiter.Release(key)
+
+		c.Next()
+	}
+}
+
+// principalKey identifies the principal authenticated in ctx across tenants.
+// It is built from the credential alone: the tenant a request resolved to
+// comes from a header, and must not hand out fresh buckets and stream slots.
+func principalKey(ctx context.Context) (string, bool) {
+	principal, ok := auth.PrincipalFromContext(ctx)
+	if !ok {
+		return "", false
+	}
+	return principal.TenantID + "/" + principal.Subject, true
+}
+
+// tightest returns the rejected result, or else the one with the fewest remaining requests
+func tightest(results []ratelimit.Result) ratelimit.Result {
+	best := results[0]
+	for _, result := range results[1:] {
+		switch {
+		case best.Allowed && !result.Allowed:
+			best = result
+		case best.Allowed == result.Allowed && result.Remaining < best.Remaining:
+			best = result
+		}
+	}
+	return best
+}
+
+// seconds rounds a duration up to whole seconds
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
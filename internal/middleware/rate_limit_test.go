@@ -0,0 +1,85 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/auth"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/config"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/ratelimit"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/tenant"
+)
+
+// TestPrincipalKeyIgnoresRequestTenant checks that switching the tenant of a
+// request does not give a principal another rate limit key
+func TestPrincipalKeyIgnoresRequestTenant(t *testing.T) {
+	admin := auth.WithPrincipal(context.Background(), &auth.Principal{Subject: "root"})
+
+	first, ok := principalKey(tenant.WithTenant(admin, "acme"))
+	if !ok {
+		t.Fatal("principalKey() found no principal")
+	}
+	if second, _ := principalKey(tenant.WithTenant(admin, "globex")); second != first {
+		t.Errorf("principalKey() = %q in another tenant, want %q", second, first)
+	}
+
+	bound := auth.WithPrincipal(context.Background(), &auth.Principal{Subject: "root", TenantID: "acme"})
+	if other, _ := principalKey(bound); other == first {
+		t.Errorf("principalKey() = %q for the same subject bound to a tenant, want another key", other)
+	}
+
+	if _, ok := principalKey(context.Background()); ok {
+		t.Error("principalKey() found a principal in an unauthenticated context")
+	}
+}
+
+// TestRateLimitIgnoresUntrustedForwardedFor checks that a peer which is not a
+// trusted proxy cannot get fresh IP buckets by rotating X-Forwarded-For
+func TestRateLimitIgnoresUntrustedForwardedFor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	rules := ratelimit.NewRuleSet(config.RateLimitConfig{
+		Enabled: true,
+		Default: config.RateLimitRule{IPPerMinute: 1, IPBurst: 1},
+	})
+
+	router := gin.New()
+	if err := router.SetTrustedProxies([]string{"10.0.0.1"}); err != nil {
+		t.Fatalf("SetTrustedProxies() error = %v", err)
+	}
+	router.Use(RateLimitMiddleware(rules, func(*gin.Context) string { return ratelimit.ClassDefault }))
+	router.GET("/", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	request := func(remoteAddr, forwardedFor string) int {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := request("203.0.113.7:1234", "198.51.100.1"); code != http.StatusNoContent {
+		t.Fatalf("first request status = %d, want %d", code, http.StatusNoContent)
+	}
+	if code := request("203.0.113.7:1234", "198.51.100.2"); code != http.StatusTooManyRequests {
+		t.Errorf("spoofed request status = %d, want %d", code, http.StatusTooManyRequests)
+	}
+
+	// A trusted proxy forwards the address of the client it serves
+	if code := request("10.0.0.1:1234", "198.51.100.3"); code != http.StatusNoContent {
+		t.Errorf("proxied request status = %d, want %d", code, http.StatusNoContent)
+	}
+}
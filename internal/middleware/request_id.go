@@ -0,0 +1,77 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/pkg/logger"
+)
+
+const (
+	// RequestIDHeader is the header carrying the request ID in both directions
+	RequestIDHeader = "X-Request-ID"
+
+	// RequestIDKey is the gin context key holding the request ID
+	RequestIDKey = "RequestID"
+
+	// maxRequestIDLength bounds request IDs supplied by callers
+	maxRequestIDLength = 128
+)
+
+// RequestIDMiddleware assigns every request an ID. A valid ID sent by the
+// caller in X-Request-ID is kept, otherwise a new one is generated. The ID is
+// echoed in the response header and added to the request context, so log
+// lines written with logger.FromContext carry it.
+func RequestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if !isValidRequestID(requestID) {
+			requestID = uuid.NewString()
+		}
+
+		c.Set(RequestIDKey, requestID)
+		c.Header(RequestIDHeader, requestID)
+		c.Request = c.Request.WithContext(logger.WithContext(c.Request.Context(), logger.FieldRequestID, requestID))
+		c.Next()
+	}
+}
+
+// LogFieldsMiddleware adds the key-value pairs returned by fields to the
+// request context, so log lines written with logger.FromContext carry them
+func LogFieldsMiddleware(fields func(c *gin.Context) []interface{}) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if keysAndValues := fields(c); len(keysAndValues) > 0 {
+			c.Request = c.Request.WithContext(logger.WithContext(c.Request.Context(), keysAndValues...))
+		}
+		c.Next()
+	}
+}
+
+// GetRequestID returns the ID of the request, or an empty string
+func GetRequestID(c *gin.Context) string {
+	return c.GetString(RequestIDKey)
+}
+
+// isValidRequestID reports whether a caller-supplied request ID is short and
+// made of printable ASCII characters other than spaces, so it is safe to log
+func isValidRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,91 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package middleware
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/auth"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/tenant"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/pkg/errors"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/pkg/logger"
+)
+
+// TenantKey is the gin context key holding the tenant ID of the request
+const TenantKey = "TenantID"
+
+// TenantMiddleware resolves the tenant of a request. A tenant bound to the
+// authenticated principal always wins; a conflicting header is rejected.
+// Admins and, with authentication disabled, all requests use the tenant named
+// in the header. Other requests use the default tenant.
+// It must run after authentication.
+func TenantMiddleware(header string, admins Admins) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenantID, appErr := resolveTenant(c.Request.Context(), c.GetHeader(header), admins)
+		if appErr != nil {
+			abortWithError(c, appErr)
+			return
+		}
+
+		c.Set(TenantKey, tenantID)
+		ctx := logger.WithContext(c.Request.Context(), logger.FieldTenantID, tenantID)
+		c.Request = c.Request.WithContext(tenant.WithTenant(ctx, tenantID))
+		c.Next()
+	}
+}
+
+// resolveTenant returns the tenant of a call that asked for the tenant
+// requested, which may be empty, with the principal authenticated in ctx
+func resolveTenant(ctx context.Context, requested string, admins Admins) (string, *errors.AppError) {
+	if requested != "" && !tenant.IsValidID(requested) {
+		return "", errors.NewBadRequestError("Invalid tenant ID", nil)
+	}
+
+	tenantID := requested
+	principal, ok := auth.PrincipalFromContext(ctx)
+	switch {
+	case !ok:
+		// Authentication is disabled, so the header is all there is to go by
+	case principal.TenantID != "":
+		if requested != "" && requested != principal.TenantID {
+			return "", errors.NewForbiddenError("Credentials are not valid for this tenant", nil)
+		}
+		tenantID = principal.TenantID
+	case admins.Has(principal):
+		// Admins may act in any tenant
+	default:
+		// Credentials bound to no tenant only act in the default one, so that
+		// they cannot reach the data, provider keys or quotas of the others
+		if requested != "" && requested != tenant.DefaultID {
+			return "", errors.NewForbiddenError("Credentials are not valid for this tenant", nil)
+		}
+		tenantID = tenant.DefaultID
+	}
+
+	if tenantID == "" {
+		tenantID = tenant.DefaultID
+	}
+	return tenantID, nil
+}
+
+// GetTenantID returns the tenant the request was resolved to
+func GetTenantID(c *gin.Context) string {
+	return tenant.FromContext(c.Request.Context())
+}
+
+// abortWithError aborts the request with the response of an application error
+func abortWithError(c *gin.Context, appErr *errors.AppError) {
+	response := appErr.ToResponse()
+	if requestID := GetRequestID(c); requestID != "" {
+		response["request_id"] = requestID
+	}
+	c.AbortWithStatusJSON(appErr.GetStatusCode(), response)
+}
@@ -0,0 +1,75 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/auth"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/tenant"
+)
+
+// TestResolveTenant checks the tenant of calls by bound and unbound principals
+func TestResolveTenant(t *testing.T) {
+	admins := NewAdmins([]string{"root", "acme-root"})
+
+	tests := []struct {
+		name      string
+		principal *auth.Principal // Nil when authentication is disabled
+		requested string
+		want      string
+		status    int // Status of the error, if the call is rejected
+	}{
+		{name: "anonymous", want: tenant.DefaultID},
+		{name: "anonymous with header", requested: "acme", want: "acme"},
+		{name: "invalid header", requested: "Not A Tenant", status: http.StatusBadRequest},
+
+		{name: "bound", principal: &auth.Principal{Subject: "alice", TenantID: "acme"}, want: "acme"},
+		{name: "bound with own tenant", principal: &auth.Principal{Subject: "alice", TenantID: "acme"}, requested: "acme", want: "acme"},
+		{name: "bound with other tenant", principal: &auth.Principal{Subject: "alice", TenantID: "acme"}, requested: "globex", status: http.StatusForbidden},
+		{name: "bound admin subject with other tenant", principal: &auth.Principal{Subject: "acme-root", TenantID: "acme"}, requested: "globex", status: http.StatusForbidden},
+
+		{name: "unbound", principal: &auth.Principal{Subject: "bob"}, want: tenant.DefaultID},
+		{name: "unbound with default tenant", principal: &auth.Principal{Subject: "bob"}, requested: tenant.DefaultID, want: tenant.DefaultID},
+		{name: "unbound with other tenant", principal: &auth.Principal{Subject: "bob"}, requested: "globex", status: http.StatusForbidden},
+
+		{name: "admin", principal: &auth.Principal{Subject: "root"}, want: tenant.DefaultID},
+		{name: "admin with other tenant", principal: &auth.Principal{Subject: "root"}, requested: "globex", want: "globex"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.principal != nil {
+				ctx = auth.WithPrincipal(ctx, tt.principal)
+			}
+
+			got, appErr := resolveTenant(ctx, tt.requested, admins)
+			if tt.status != 0 {
+				if appErr == nil {
+					t.Fatalf("resolveTenant() = %q, want a %d error", got, tt.status)
+				}
+				if status := appErr.GetStatusCode(); status != tt.status {
+					t.Errorf("resolveTenant() failed with %d, want %d", status, tt.status)
+				}
+				return
+			}
+
+			if appErr != nil {
+				t.Fatalf("resolveTenant() failed: %v", appErr)
+			}
+			if got != tt.want {
+				t.Errorf("resolveTenant() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// APIKey is a credential for API access. Only a hash of the secret is stored.
+type APIKey struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name       string             `bson:"name" json:"name"`
+	TenantID   string             `bson:"tenant_id,omitempty" json:"tenant_id,omitempty"`
+	SecretHash string             `bson:"secret_hash" json:"-"`
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
+	RevokedAt  *time.Time         `bson:"revoked_at,omitempty" json:"revoked_at,omitempty"`
+}
+
+// NewAPIKey creates a new API key record for the given secret hash
+func NewAPIKey(name, secretHash string) *APIKey {
+	return &APIKey{
+		ID:         primitive.NewObjectID(),
+		Name:       name,
+		SecretHash: secretHash,
+		CreatedAt:  time.Now(),
+	}
+}
+
+// IsRevoked reports whether the key has been revoked
+func (k *APIKey) IsRevoked() bool {
+	return k.RevokedAt != nil
+}
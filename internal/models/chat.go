@@ -10,6 +10,7 @@
 package models
 
 import (
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -17,13 +18,23 @@ import (
 
 // Chat represents a chat session
 type Chat struct {
-	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	Title         string             `bson:"title" json:"title"`
-	CreatedAt     time.Time          `bson:"created_at" json:"created_at"`
-	UpdatedAt     time.Time          `bson:"updated_at" json:"updated_at"`
-	LastMessageAt time.Time          `bson:"last_message_at,omitempty" json:"last_message_at,omitempty"`
-	MessageCount  int                `bson:"message_count" json:"message_count"`
-	Active        bool               `bson:"active" json:"active"`
+	ID            primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	TenantID      string              `bson:"tenant_id" json:"tenant_id"`
+	Title         string              `bson:"title" json:"title"`
+	TitleLower    string              `bson:"title_lower" json:"-"` // Lowercased title for indexed prefix matching
+	CreatedAt     time.Time           `bson:"created_at" json:"created_at"`
+	UpdatedAt     time.Time           `bson:"updated_at" json:"updated_at"`
+	LastMessageAt time.Time           `bson:"last_message_at,omitempty" json:"last_message_at,omitempty"`
+	MessageCount  int                 `bson:"message_count" json:"message_count"`
+	Active        bool                `bson:"active" json:"active"`
+	Archived      bool                `bson:"archived" json:"archived"`
+	ArchivedAt    *time.Time          `bson:"archived_at,omitempty" json:"archived_at,omitempty"`
+	Pinned        bool                `bson:"pinned" json:"pinned"`
+	Tags          []string            `bson:"tags,omitempty" json:"tags,omitempty"`
+	FolderID      *primitive.ObjectID `bson:"folder_id,omitempty" json:"folder_id,omitempty"`
+	DeletedAt     *time.Time          `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`
+	OwnerID       string              `bson:"owner_id,omitempty" json:"owner_id,omitempty"`
+	Members       []ChatMember        `bson:"members,omitempty" json:"members,omitempty"`
 }
 
 // NewChat creates a new chat with default values
@@ -39,9 +50,26 @@ func NewChat(title string) *Chat {
 	}
 }
 
-// BeforeSave updates the UpdatedAt field
+// SetOwner makes subject the owner of the chat and its only member
+func (c *Chat) SetOwner(subject string) {
+	c.OwnerID = subject
+	c.Members = []ChatMember{NewChatMember(subject, ChatRoleOwner)}
+}
+
+// MemberRole returns the role of subject in the chat, if it is a member
+func (c *Chat) MemberRole(subject string) (ChatRole, bool) {
+	for _, member := range c.Members {
+		if member.Subject == subject {
+			return member.Role, true
+		}
+	}
+	return "", false
+}
+
+// BeforeSave updates the UpdatedAt and TitleLower fields
 func (c *Chat) BeforeSave() {
 	c.UpdatedAt = time.Now()
+	c.TitleLower = strings.ToLower(c.Title)
 }
 
 // AddMessage updates the chat when a new message is added
@@ -51,3 +79,18 @@ func (c *Chat) AddMessage() {
 	c.UpdatedAt = now
 	c.MessageCount++
 }
+
+// Archive marks the chat as archived
+func (c *Chat) Archive() {
+	now := time.Now()
+	c.Archived = true
+	c.ArchivedAt = &now
+	c.UpdatedAt = now
+}
+
+// Unarchive restores an archived chat to the regular listing
+func (c *Chat) Unarchive() {
+	c.Archived = false
+	c.ArchivedAt = nil
+	c.UpdatedAt = time.Now()
+}
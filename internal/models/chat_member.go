@@ -0,0 +1,103 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ChatRole is the access level of a chat member
+type ChatRole string
+
+// Chat roles, from most to least privileged
+const (
+	ChatRoleOwner  ChatRole = "owner"  // Full control, including deletion and sharing
+	ChatRoleEditor ChatRole = "editor" // Can send and delete messages and organize the chat
+	ChatRoleViewer ChatRole = "viewer" // Can read and stream the chat
+)
+
+// IsValid reports whether the role is a known chat role
+func (r ChatRole) IsValid() bool {
+	switch r {
+	case ChatRoleOwner, ChatRoleEditor, ChatRoleViewer:
+		return true
+	default:
+		return false
+	}
+}
+
+// Allows reports whether the role grants at least the access of required
+func (r ChatRole) Allows(required ChatRole) bool {
+	return r.rank() >= required.rank()
+}
+
+// rank orders roles by privilege
+func (r ChatRole) rank() int {
+	switch r {
+	case ChatRoleOwner:
+		return 3
+	case ChatRoleEditor:
+		return 2
+	case ChatRoleViewer:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// ChatMember grants a principal access to a chat
+type ChatMember struct {
+	Subject string    `bson:"subject" json:"subject"`
+	Role    ChatRole  `bson:"role" json:"role"`
+	AddedAt time.Time `bson:"added_at" json:"added_at"`
+}
+
+// NewChatMember creates a new chat member
+func NewChatMember(subject string, role ChatRole) ChatMember {
+	return ChatMember{
+		Subject: subject,
+		Role:    role,
+		AddedAt: time.Now(),
+	}
+}
+
+// ChatInvite is a share link that adds whoever accepts it to a chat.
+// Only a hash of the invite code is stored.
+type ChatInvite struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	TenantID  string             `bson:"tenant_id" json:"tenant_id"`
+	ChatID    primitive.ObjectID `bson:"chat_id" json:"chat_id"`
+	CodeHash  string             `bson:"code_hash" json:"-"`
+	Role      ChatRole           `bson:"role" json:"role"`
+	CreatedBy string             `bson:"created_by" json:"created_by"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+	ExpiresAt time.Time          `bson:"expires_at" json:"expires_at"`
+}
+
+// NewChatInvite creates a new invite to a chat
+func NewChatInvite(chatID primitive.ObjectID, codeHash string, role ChatRole, createdBy string, ttl time.Duration) *ChatInvite {
+	now := time.Now()
+	return &ChatInvite{
+		ID:        primitive.NewObjectID(),
+		ChatID:    chatID,
+		CodeHash:  codeHash,
+		Role:      role,
+		CreatedBy: createdBy,
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	}
+}
+
+// IsExpired reports whether the invite can no longer be accepted
+func (i *ChatInvite) IsExpired() bool {
+	return time.Now().After(i.ExpiresAt)
+}
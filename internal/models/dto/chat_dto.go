@@ -18,21 +18,55 @@ type CreateChatRequest struct {
 
 // UpdateChatRequest represents the request to update a chat
 type UpdateChatRequest struct {
-	Title string `json:"title" binding:"required"`
+	Title  string `json:"title" binding:"required"`
+	Pinned *bool  `json:"pinned,omitempty"`
 }
 
 // ChatResponse represents the response for a chat
 type ChatResponse struct {
-	ID            string `json:"id"`
-	Title         string `json:"title"`
-	CreatedAt     string `json:"created_at"`
-	UpdatedAt     string `json:"updated_at"`
-	LastMessageAt string `json:"last_message_at,omitempty"`
-	MessageCount  int    `json:"message_count"`
+	ID            string   `json:"id"`
+	Title         string   `json:"title"`
+	CreatedAt     string   `json:"created_at"`
+	UpdatedAt     string   `json:"updated_at"`
+	LastMessageAt string   `json:"last_message_at,omitempty"`
+	MessageCount  int      `json:"message_count"`
+	Archived      bool     `json:"archived"`
+	ArchivedAt    string   `json:"archived_at,omitempty"`
+	Pinned        bool     `json:"pinned"`
+	Tags          []string `json:"tags,omitempty"`
+	FolderID      string   `json:"folder_id,omitempty"`
+	DeletedAt     string   `json:"deleted_at,omitempty"`
+	OwnerID       string   `json:"owner_id,omitempty"`
 }
 
 // ChatListResponse represents the response for a list of chats
 type ChatListResponse struct {
 	Chats      []ChatResponse `json:"chats"`
 	Pagination PaginationInfo `json:"pagination"`
+	Cursors    *CursorInfo    `json:"cursors,omitempty"`
+}
+
+// MoveChatsRequest represents the request to move chats into a folder.
+// An empty FolderID moves the chats out of any folder.
+type MoveChatsRequest struct {
+	ChatIDs  []string `json:"chat_ids" binding:"required,min=1,max=100"`
+	FolderID string   `json:"folder_id"`
+}
+
+// UpdateChatTagsRequest represents the request to add and remove tags on chats
+type UpdateChatTagsRequest struct {
+	ChatIDs []string `json:"chat_ids" binding:"required,min=1,max=100"`
+	Add     []string `json:"add"`
+	Remove  []string `json:"remove"`
+}
+
+// BulkUpdateResponse represents the result of a bulk chat update
+type BulkUpdateResponse struct {
+	Updated int64 `json:"updated"`
+}
+
+// StreamTokenResponse represents a short-lived token for opening a chat's SSE stream
+type StreamTokenResponse struct {
+	Token     string `json:"token"`
+	ExpiresAt string `json:"expires_at"`
 }
@@ -14,11 +14,19 @@ package dto
 // PaginationInfo represents pagination metadata
 type PaginationInfo struct {
 	Total    int64 `json:"total"`
-	Page     int   `json:"page"`
+	Page     int   `json:"page,omitempty"` // Omitted when paginating by cursor
 	PageSize int   `json:"page_size"`
 	Pages    int64 `json:"pages"`
 }
 
+// CursorInfo holds opaque keyset pagination tokens for a page.
+// Pass Before or After back as the query parameter of the same name
+// to fetch the adjacent page; a token is omitted when no such page exists.
+type CursorInfo struct {
+	Before string `json:"before,omitempty"`
+	After  string `json:"after,omitempty"`
+}
+
 // SuccessResponse represents a generic success response
 type SuccessResponse struct {
 	Message string `json:"message"`
@@ -26,7 +34,8 @@ type SuccessResponse struct {
 
 // ErrorResponse represents an error response
 type ErrorResponse struct {
-	Error ErrorDetails `json:"error"`
+	Error     ErrorDetails `json:"error"`
+	RequestID string       `json:"request_id,omitempty"`
 }
 
 // ErrorDetails contains detailed error information
@@ -35,3 +44,11 @@ type ErrorDetails struct {
 	Message string `json:"message"`
 	Details string `json:"details,omitempty"`
 }
+
+// TrashListResponse represents a page of trashed chats or messages
+type TrashListResponse struct {
+	Type       string            `json:"type"`
+	Chats      []ChatResponse    `json:"chats,omitempty"`
+	Messages   []MessageResponse `json:"messages,omitempty"`
+	Pagination PaginationInfo    `json:"pagination"`
+}
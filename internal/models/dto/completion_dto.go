@@ -0,0 +1,149 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package dto
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// OpenAI-compatible chat completion DTOs. They follow the OpenAI API, so
+// that its SDKs can talk to the server; unsupported fields are ignored.
+
+// ChatCompletionRequest represents the request to create a chat completion
+type ChatCompletionRequest struct {
+	Model               string                       `json:"model"`
+	Messages            []ChatCompletionMessage      `json:"messages" binding:"required,min=1,dive"`
+	Stream              bool                         `json:"stream"`
+	StreamOptions       *ChatCompletionStreamOptions `json:"stream_options,omitempty"`
+	MaxTokens           int                          `json:"max_tokens,omitempty"`
+	MaxCompletionTokens int                          `json:"max_completion_tokens,omitempty"` // Takes precedence over max_tokens
+	Temperature         *float64                     `json:"temperature,omitempty"`
+	TopP                *float64                     `json:"top_p,omitempty"`
+	Stop                StopSequences                `json:"stop,omitempty"`
+	N                   int                          `json:"n,omitempty"`    // Only 1 is supported
+	User                string                       `json:"user,omitempty"` // Chat to store the exchange in when it is a chat ID; otherwise an opaque end-user ID
+}
+
+// ChatCompletionMessage represents a message of the conversation to complete
+type ChatCompletionMessage struct {
+	Role    string         `json:"role" binding:"required,oneof=system developer user assistant"`
+	Content MessageContent `json:"content"`
+}
+
+// ChatCompletionStreamOptions represents the options of a streamed completion
+type ChatCompletionStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+// MessageContent is the text of a message, sent either as a string or as an
+// array of text parts
+type MessageContent string
+
+// UnmarshalJSON implements json.Unmarshaler
+func (c *MessageContent) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*c = MessageContent(text)
+		return nil
+	}
+
+	var parts []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(data, &parts); err != nil {
+		return errors.New("content must be a string or an array of content parts")
+	}
+
+	var texts []string
+	for _, part := range parts {
+		if part.Type != "text" {
+			return errors.New("only text content parts are supported")
+		}
+		texts = append(texts, part.Text)
+	}
+	*c = MessageContent(strings.Join(texts, "\n"))
+	return nil
+}
+
+// StopSequences are the sequences that end a completion, sent either as a
+// string or as an array of strings
+type StopSequences []string
+
+// UnmarshalJSON implements json.Unmarshaler
+func (s *StopSequences) UnmarshalJSON(data []byte) error {
+	var sequence string
+	if err := json.Unmarshal(data, &sequence); err == nil {
+		*s = StopSequences{sequence}
+		return nil
+	}
+
+	var sequences []string
+	if err := json.Unmarshal(data, &sequences); err != nil {
+		return errors.New("stop must be a string or an array of strings")
+	}
+	*s = sequences
+	return nil
+}
+
+// ChatCompletionResponse represents a completion returned at once
+type ChatCompletionResponse struct {
+	ID      string                 `json:"id"`
+	Object  string                 `json:"object"` // Always "chat.completion"
+	Created int64                  `json:"created"`
+	Model   string                 `json:"model"`
+	Choices []ChatCompletionChoice `json:"choices"`
+	Usage   ChatCompletionUsage    `json:"usage"`
+}
+
+// ChatCompletionChoice represents the completion of a response
+type ChatCompletionChoice struct {
+	Index        int                        `json:"index"`
+	Message      ChatCompletionReplyMessage `json:"message"`
+	FinishReason string                     `json:"finish_reason"`
+}
+
+// ChatCompletionReplyMessage represents the generated message
+type ChatCompletionReplyMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// ChatCompletionChunk represents an event of a streamed completion
+type ChatCompletionChunk struct {
+	ID      string                      `json:"id"`
+	Object  string                      `json:"object"` // Always "chat.completion.chunk"
+	Created int64                       `json:"created"`
+	Model   string                      `json:"model"`
+	Choices []ChatCompletionChunkChoice `json:"choices"`
+	Usage   *ChatCompletionUsage        `json:"usage,omitempty"` // Only set on the final chunk, when requested
+}
+
+// ChatCompletionChunkChoice represents the part of a completion a chunk carries
+type ChatCompletionChunkChoice struct {
+	Index        int                 `json:"index"`
+	Delta        ChatCompletionDelta `json:"delta"`
+	FinishReason *string             `json:"finish_reason"`
+}
+
+// ChatCompletionDelta represents the output added by a chunk
+type ChatCompletionDelta struct {
+	Role    string `json:"role,omitempty"`
+	Content string `json:"content,omitempty"`
+}
+
+// ChatCompletionUsage represents the tokens a completion used
+type ChatCompletionUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
@@ -0,0 +1,36 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package dto
+
+import "encoding/json"
+
+// Event response DTOs
+
+// EventResponse represents an event of a chat, as sent over SSE
+type EventResponse struct {
+	ID    string          `json:"id"`
+	Event string          `json:"event"`
+	Data  json.RawMessage `json:"data"`
+}
+
+// EventBatchResponse represents the events returned by a long-polling request
+type EventBatchResponse struct {
+	Events  []EventResponse `json:"events"`
+	Cursor  string          `json:"cursor,omitempty"`   // Pass as after to get the events that follow
+	RetryMs int64           `json:"retry_ms,omitempty"` // Set when the server shuts down; poll again after this delay
+}
+
+// StreamStatsResponse represents the number of clients streaming the chats of
+// a tenant, or a single chat when ChatID is set
+type StreamStatsResponse struct {
+	ChatID       string `json:"chat_id,omitempty"`
+	Clients      *int   `json:"clients,omitempty"`       // Clients of the chat
+	TotalClients *int   `json:"total_clients,omitempty"` // Clients of all chats, without a chat ID
+}
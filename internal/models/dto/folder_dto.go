@@ -0,0 +1,35 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package dto
+
+// Folder request and response DTOs
+
+// CreateFolderRequest represents the request to create a new folder
+type CreateFolderRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// UpdateFolderRequest represents the request to rename a folder
+type UpdateFolderRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// FolderResponse represents the response for a folder
+type FolderResponse struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+// FolderListResponse represents the response for a list of folders
+type FolderListResponse struct {
+	Folders []FolderResponse `json:"folders"`
+}
@@ -0,0 +1,34 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package dto
+
+// Generation response DTOs
+
+// GenerationResponse represents an AI generation
+type GenerationResponse struct {
+	ID          string `json:"id"`
+	ChatID      string `json:"chat_id"`
+	MessageID   string `json:"message_id"`
+	ResumedFrom string `json:"resumed_from,omitempty"`
+	Status      string `json:"status"`
+	Provider    string `json:"provider"`
+	Model       string `json:"model"`
+	Content     string `json:"content"`
+	Error       string `json:"error,omitempty"`
+	StartedAt   string `json:"started_at"`
+	UpdatedAt   string `json:"updated_at"`
+	FinishedAt  string `json:"finished_at,omitempty"`
+	Resumable   bool   `json:"resumable"`
+}
+
+// GenerationListResponse represents the recent generations of a chat
+type GenerationListResponse struct {
+	Generations []GenerationResponse `json:"generations"`
+}
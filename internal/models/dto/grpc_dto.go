@@ -0,0 +1,116 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package dto
+
+import (
+	"net/url"
+	"strconv"
+)
+
+// gRPC request DTOs. The gRPC handler converts the generated request messages
+// into these, or into CreateChatRequest, MoveChatsRequest and
+// UpdateChatTagsRequest, to validate them like REST requests.
+
+// ChatIDRequest names the chat a gRPC call acts on
+type ChatIDRequest struct {
+	ID string `json:"id" binding:"required"`
+}
+
+// MessageIDRequest names the message a gRPC call acts on
+type MessageIDRequest struct {
+	ID string `json:"id" binding:"required"`
+}
+
+// UpdateChatByIDRequest represents the gRPC request to update a chat
+type UpdateChatByIDRequest struct {
+	ID string `json:"id" binding:"required"`
+	UpdateChatRequest
+}
+
+// SendMessageRequest represents the gRPC request to add a message to a chat
+type SendMessageRequest struct {
+	ChatID string `json:"chat_id" binding:"required"`
+	CreateMessageRequest
+}
+
+// ListChatsRequest represents the gRPC request to list chats. Its fields
+// take the values of the query parameters of the same name in the REST API.
+type ListChatsRequest struct {
+	Page         int    `json:"page,omitempty"`
+	PageSize     int    `json:"page_size,omitempty"`
+	Before       string `json:"before,omitempty"`
+	After        string `json:"after,omitempty"`
+	Active       string `json:"active,omitempty"`
+	Archived     string `json:"archived,omitempty"`
+	Pinned       string `json:"pinned,omitempty"`
+	Tag          string `json:"tag,omitempty"`
+	TitlePrefix  string `json:"title_prefix,omitempty"`
+	FolderID     string `json:"folder_id,omitempty"`
+	CreatedAfter string `json:"created_after,omitempty"`
+	Sort         string `json:"sort,omitempty"`
+	Order        string `json:"order,omitempty"`
+}
+
+// Query returns the request as the query parameters of the REST API
+func (r *ListChatsRequest) Query() url.Values {
+	values := pageQuery(r.Page, r.PageSize, r.Before, r.After)
+	setQuery(values, "active", r.Active)
+	setQuery(values, "archived", r.Archived)
+	setQuery(values, "pinned", r.Pinned)
+	setQuery(values, "tag", r.Tag)
+	setQuery(values, "title_prefix", r.TitlePrefix)
+	setQuery(values, "folder_id", r.FolderID)
+	setQuery(values, "created_after", r.CreatedAfter)
+	setQuery(values, "sort", r.Sort)
+	setQuery(values, "order", r.Order)
+	return values
+}
+
+// ListMessagesRequest represents the gRPC request to list the messages of a chat
+type ListMessagesRequest struct {
+	ChatID   string `json:"chat_id" binding:"required"`
+	Page     int    `json:"page,omitempty"`
+	PageSize int    `json:"page_size,omitempty"`
+	Before   string `json:"before,omitempty"`
+	After    string `json:"after,omitempty"`
+}
+
+// Query returns the pagination of the request as the query parameters of the REST API
+func (r *ListMessagesRequest) Query() url.Values {
+	return pageQuery(r.Page, r.PageSize, r.Before, r.After)
+}
+
+// SubscribeRequest represents the gRPC request to stream the events of a
+// chat. Events after AfterEventID are replayed first, like with Last-Event-ID.
+type SubscribeRequest struct {
+	ChatID       string `json:"chat_id" binding:"required"`
+	AfterEventID string `json:"after_event_id,omitempty"`
+}
+
+// pageQuery returns the pagination parameters that are set
+func pageQuery(page, pageSize int, before, after string) url.Values {
+	values := url.Values{}
+	if page > 0 {
+		values.Set("page", strconv.Itoa(page))
+	}
+	if pageSize > 0 {
+		values.Set("page_size", strconv.Itoa(pageSize))
+	}
+	setQuery(values, "before", before)
+	setQuery(values, "after", after)
+	return values
+}
+
+// setQuery sets a query parameter unless value is empty
+func setQuery(values url.Values, name, value string) {
+	if value != "" {
+		values.Set(name, value)
+	}
+}
@@ -0,0 +1,37 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package dto
+
+// Background job response DTOs
+
+// JobResponse represents a background job
+type JobResponse struct {
+	ID          string                 `json:"id"`
+	TenantID    string                 `json:"tenant_id,omitempty"`
+	Type        string                 `json:"type"`
+	Payload     map[string]interface{} `json:"payload,omitempty"`
+	UniqueKey   string                 `json:"unique_key,omitempty"`
+	Status      string                 `json:"status"`
+	Attempts    int                    `json:"attempts"`
+	MaxAttempts int                    `json:"max_attempts"`
+	VisibleAt   string                 `json:"visible_at"`
+	LockedBy    string                 `json:"locked_by,omitempty"`
+	LastError   string                 `json:"last_error,omitempty"`
+	CreatedAt   string                 `json:"created_at"`
+	UpdatedAt   string                 `json:"updated_at"`
+	StartedAt   string                 `json:"started_at,omitempty"`
+	FinishedAt  string                 `json:"finished_at,omitempty"`
+}
+
+// JobListResponse represents a page of background jobs
+type JobListResponse struct {
+	Jobs       []JobResponse  `json:"jobs"`
+	Pagination PaginationInfo `json:"pagination"`
+}
@@ -29,10 +29,12 @@ type MessageResponse struct {
 	Type      models.MessageType     `json:"type"`
 	CreatedAt string                 `json:"created_at"`
 	Metadata  map[string]interface{} `json:"metadata,omitempty"`
+	DeletedAt string                 `json:"deleted_at,omitempty"`
 }
 
 // MessageListResponse represents the response for a list of messages
 type MessageListResponse struct {
 	Messages   []MessageResponse `json:"messages"`
 	Pagination PaginationInfo    `json:"pagination"`
+	Cursors    *CursorInfo       `json:"cursors,omitempty"`
 }
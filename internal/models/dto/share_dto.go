@@ -0,0 +1,52 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package dto
+
+// Sharing request and response DTOs
+
+// ChatMemberResponse represents a member of a chat
+type ChatMemberResponse struct {
+	Subject string `json:"subject"`
+	Role    string `json:"role"`
+	AddedAt string `json:"added_at"`
+}
+
+// ChatMemberListResponse represents the members of a chat
+type ChatMemberListResponse struct {
+	Members []ChatMemberResponse `json:"members"`
+}
+
+// UpdateMemberRequest represents the request to change a member's role
+type UpdateMemberRequest struct {
+	Role string `json:"role" binding:"required"`
+}
+
+// CreateInviteRequest represents the request to create a share invite
+type CreateInviteRequest struct {
+	Role           string `json:"role" binding:"required"`
+	ExpiresInHours int    `json:"expires_in_hours,omitempty"` // Defaults to 7 days, at most 30 days
+}
+
+// InviteResponse represents a share invite.
+// Code is only returned when the invite is created.
+type InviteResponse struct {
+	ID        string `json:"id"`
+	ChatID    string `json:"chat_id"`
+	Role      string `json:"role"`
+	Code      string `json:"code,omitempty"`
+	CreatedBy string `json:"created_by,omitempty"`
+	CreatedAt string `json:"created_at"`
+	ExpiresAt string `json:"expires_at"`
+}
+
+// InviteListResponse represents the active invites of a chat
+type InviteListResponse struct {
+	Invites []InviteResponse `json:"invites"`
+}
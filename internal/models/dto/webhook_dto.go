@@ -0,0 +1,69 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package dto
+
+// Webhook request and response DTOs
+
+// CreateWebhookRequest represents the request to create a webhook
+type CreateWebhookRequest struct {
+	URL         string   `json:"url" binding:"required"`
+	Events      []string `json:"events" binding:"required"`
+	Description string   `json:"description"`
+}
+
+// UpdateWebhookRequest represents the request to update a webhook; omitted fields are left unchanged
+type UpdateWebhookRequest struct {
+	URL         *string  `json:"url"`
+	Events      []string `json:"events"`
+	Description *string  `json:"description"`
+	Active      *bool    `json:"active"`
+}
+
+// WebhookResponse represents a webhook
+type WebhookResponse struct {
+	ID                  string   `json:"id"`
+	URL                 string   `json:"url"`
+	Events              []string `json:"events"`
+	Description         string   `json:"description,omitempty"`
+	Secret              string   `json:"secret,omitempty"` // Only returned on creation and rotation
+	Active              bool     `json:"active"`
+	ConsecutiveFailures int      `json:"consecutive_failures"`
+	DisabledReason      string   `json:"disabled_reason,omitempty"`
+	DisabledAt          string   `json:"disabled_at,omitempty"`
+	LastDeliveryAt      string   `json:"last_delivery_at,omitempty"`
+	LastSuccessAt       string   `json:"last_success_at,omitempty"`
+	CreatedAt           string   `json:"created_at"`
+	UpdatedAt           string   `json:"updated_at"`
+}
+
+// WebhookListResponse represents the response for a list of webhooks
+type WebhookListResponse struct {
+	Webhooks []WebhookResponse `json:"webhooks"`
+}
+
+// WebhookDeliveryResponse represents one delivery attempt of an event
+type WebhookDeliveryResponse struct {
+	ID         string `json:"id"`
+	EventID    string `json:"event_id"`
+	Event      string `json:"event"`
+	Attempt    int    `json:"attempt"`
+	URL        string `json:"url"`
+	StatusCode int    `json:"status_code,omitempty"`
+	Error      string `json:"error,omitempty"`
+	Success    bool   `json:"success"`
+	DurationMs int64  `json:"duration_ms"`
+	CreatedAt  string `json:"created_at"`
+}
+
+// WebhookDeliveryListResponse represents a page of a webhook's delivery log
+type WebhookDeliveryListResponse struct {
+	Deliveries []WebhookDeliveryResponse `json:"deliveries"`
+	Pagination PaginationInfo            `json:"pagination"`
+}
@@ -0,0 +1,26 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package dto
+
+// WebSocket command DTOs
+
+// WebSocket command types
+const (
+	CommandSendMessage      = "send_message"
+	CommandCancelGeneration = "cancel_generation"
+)
+
+// WebSocketCommand represents a command a client sends over a chat's WebSocket
+type WebSocketCommand struct {
+	Type         string                `json:"type"`
+	RequestID    string                `json:"request_id,omitempty"`    // Echoed in the ack or error event answering the command
+	Message      *CreateMessageRequest `json:"message,omitempty"`       // Message to send, for send_message
+	GenerationID string                `json:"generation_id,omitempty"` // Generation to stop, for cancel_generation
+}
@@ -0,0 +1,42 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Folder groups chats for organization
+type Folder struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	TenantID  string             `bson:"tenant_id" json:"tenant_id"`
+	Name      string             `bson:"name" json:"name"`
+	OwnerID   string             `bson:"owner_id,omitempty" json:"owner_id,omitempty"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// NewFolder creates a new folder with default values
+func NewFolder(name string) *Folder {
+	now := time.Now()
+	return &Folder{
+		ID:        primitive.NewObjectID(),
+		Name:      name,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
+
+// BeforeSave updates the UpdatedAt field
+func (f *Folder) BeforeSave() {
+	f.UpdatedAt = time.Now()
+}
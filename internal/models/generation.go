@@ -0,0 +1,74 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// GenerationStatus is the state of an AI generation
+type GenerationStatus string
+
+// Generation statuses
+const (
+	GenerationRunning     GenerationStatus = "running"
+	GenerationCompleted   GenerationStatus = "completed"
+	GenerationFailed      GenerationStatus = "failed"
+	GenerationInterrupted GenerationStatus = "interrupted" // Stopped by a shutdown or crash; can be continued or discarded
+	GenerationResumed     GenerationStatus = "resumed"     // Interrupted and continued by a newer generation
+	GenerationDiscarded   GenerationStatus = "discarded"   // Interrupted and dismissed by the user
+	GenerationCanceled    GenerationStatus = "canceled"    // Stopped by the user while running
+)
+
+// IsValid reports whether the status is a known generation status
+func (s GenerationStatus) IsValid() bool {
+	switch s {
+	case GenerationRunning, GenerationCompleted, GenerationFailed, GenerationInterrupted, GenerationResumed, GenerationDiscarded, GenerationCanceled:
+		return true
+	default:
+		return false
+	}
+}
+
+// Generation tracks an assistant reply while the AI provider produces it.
+// Partial content is saved periodically, so that a reply cut off by a
+// restart can be shown and continued.
+type Generation struct {
+	ID          primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	TenantID    string              `bson:"tenant_id" json:"tenant_id"`
+	ChatID      primitive.ObjectID  `bson:"chat_id" json:"chat_id"`
+	MessageID   primitive.ObjectID  `bson:"message_id" json:"message_id"` // ID of the assistant message stored on completion
+	ResumedFrom *primitive.ObjectID `bson:"resumed_from,omitempty" json:"resumed_from,omitempty"`
+	Status      GenerationStatus    `bson:"status" json:"status"`
+	Provider    string              `bson:"provider" json:"provider"`
+	Model       string              `bson:"model" json:"model"`
+	Content     string              `bson:"content" json:"content"` // Output so far, as of the last checkpoint
+	Error       string              `bson:"error,omitempty" json:"error,omitempty"`
+	StartedAt   time.Time           `bson:"started_at" json:"started_at"`
+	UpdatedAt   time.Time           `bson:"updated_at" json:"updated_at"` // Time of the last checkpoint
+	FinishedAt  *time.Time          `bson:"finished_at,omitempty" json:"finished_at,omitempty"`
+}
+
+// NewGeneration creates a running generation of a chat
+func NewGeneration(chatID primitive.ObjectID, provider, model string) *Generation {
+	now := time.Now()
+	return &Generation{
+		ID:        primitive.NewObjectID(),
+		ChatID:    chatID,
+		MessageID: primitive.NewObjectID(),
+		Status:    GenerationRunning,
+		Provider:  provider,
+		Model:     model,
+		StartedAt: now,
+		UpdatedAt: now,
+	}
+}
@@ -0,0 +1,96 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// JobStatus is the state of a background job
+type JobStatus string
+
+// Job statuses
+const (
+	JobPending   JobStatus = "pending"   // Waiting to become visible and be claimed
+	JobRunning   JobStatus = "running"   // Claimed by a worker
+	JobSucceeded JobStatus = "succeeded" // Finished; removed once its retention passes
+	JobDead      JobStatus = "dead"      // Failed on every attempt; kept until retried
+)
+
+// IsValid reports whether the status is a known job status
+func (s JobStatus) IsValid() bool {
+	switch s {
+	case JobPending, JobRunning, JobSucceeded, JobDead:
+		return true
+	default:
+		return false
+	}
+}
+
+// Job is a unit of background work of a given type. Workers claim visible
+// jobs one at a time; a claimed job becomes visible again when its worker
+// stops sending heartbeats, so that another worker retries it.
+type Job struct {
+	ID          primitive.ObjectID     `bson:"_id,omitempty" json:"id"`
+	TenantID    string                 `bson:"tenant_id,omitempty" json:"tenant_id,omitempty"` // Tenant the job runs for; empty for jobs across tenants
+	Type        string                 `bson:"type" json:"type"`
+	Payload     map[string]interface{} `bson:"payload,omitempty" json:"payload,omitempty"`
+	UniqueKey   string                 `bson:"unique_key,omitempty" json:"unique_key,omitempty"` // Jobs with the same key are only enqueued once
+	Status      JobStatus              `bson:"status" json:"status"`
+	Attempts    int                    `bson:"attempts" json:"attempts"`
+	MaxAttempts int                    `bson:"max_attempts" json:"max_attempts"`
+	VisibleAt   time.Time              `bson:"visible_at" json:"visible_at"` // Due time while pending; lease expiry while running
+	LockedBy    string                 `bson:"locked_by,omitempty" json:"locked_by,omitempty"`
+	LastError   string                 `bson:"last_error,omitempty" json:"last_error,omitempty"`
+	CreatedAt   time.Time              `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time              `bson:"updated_at" json:"updated_at"`
+	StartedAt   *time.Time             `bson:"started_at,omitempty" json:"started_at,omitempty"`
+	FinishedAt  *time.Time             `bson:"finished_at,omitempty" json:"finished_at,omitempty"`
+	ExpiresAt   *time.Time             `bson:"expires_at,omitempty" json:"expires_at,omitempty"`
+}
+
+// NewJob creates a pending job that becomes visible at runAt. The payload is
+// stored as a document, with the field names of its JSON encoding.
+func NewJob(jobType string, payload interface{}, runAt time.Time, maxAttempts int) (*Job, error) {
+	var fields map[string]interface{}
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, &fields); err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	return &Job{
+		ID:          primitive.NewObjectID(),
+		Type:        jobType,
+		Payload:     fields,
+		Status:      JobPending,
+		MaxAttempts: maxAttempts,
+		VisibleAt:   runAt,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}, nil
+}
+
+// DecodePayload decodes the payload into v, which uses the same JSON field names it was enqueued with
+func (j *Job) DecodePayload(v interface{}) error {
+	data, err := json.Marshal(j.Payload)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
@@ -38,12 +38,17 @@ const (
 // Message represents a message in a chat
 type Message struct {
 	ID        primitive.ObjectID     `bson:"_id,omitempty" json:"id"`
+	TenantID  string                 `bson:"tenant_id" json:"tenant_id"`
 	ChatID    primitive.ObjectID     `bson:"chat_id" json:"chat_id"`
 	Content   string                 `bson:"content" json:"content"`
 	Role      MessageRole            `bson:"role" json:"role"`
 	Type      MessageType            `bson:"type" json:"type"`
 	CreatedAt time.Time              `bson:"created_at" json:"created_at"`
 	Metadata  map[string]interface{} `bson:"metadata,omitempty" json:"metadata,omitempty"`
+	DeletedAt *time.Time             `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`
+
+	// DeletedWithChat marks messages that were trashed as part of deleting their chat
+	DeletedWithChat bool `bson:"deleted_with_chat,omitempty" json:"-"`
 }
 
 // NewMessage creates a new message with default values
@@ -0,0 +1,29 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package models
+
+import "time"
+
+// Usage holds a tenant's usage counters of one day (UTC). Messages counts
+// the messages charged against the daily message quota without being
+// stored; the token counters cover every completion, stored or not.
+type Usage struct {
+	TenantID         string    `bson:"tenant_id" json:"tenant_id"`
+	Day              time.Time `bson:"day" json:"day"`
+	Messages         int64     `bson:"messages" json:"messages"`
+	Completions      int64     `bson:"completions" json:"completions"`
+	PromptTokens     int64     `bson:"prompt_tokens" json:"prompt_tokens"`
+	CompletionTokens int64     `bson:"completion_tokens" json:"completion_tokens"`
+}
+
+// TotalTokens returns the number of prompt and completion tokens
+func (u *Usage) TotalTokens() int64 {
+	return u.PromptTokens + u.CompletionTokens
+}
@@ -0,0 +1,81 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package models
+
+import (
+	"slices"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// WebhookAllEvents subscribes a webhook to every event type
+const WebhookAllEvents = "*"
+
+// Webhook subscribes an HTTP endpoint to the events of the chats its owner
+// can access. Deliveries are signed with the webhook's secret.
+type Webhook struct {
+	ID                  primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	TenantID            string             `bson:"tenant_id" json:"tenant_id"`
+	OwnerID             string             `bson:"owner_id,omitempty" json:"owner_id,omitempty"`
+	URL                 string             `bson:"url" json:"url"`
+	Secret              string             `bson:"secret" json:"-"`
+	Events              []string           `bson:"events" json:"events"`
+	Description         string             `bson:"description,omitempty" json:"description,omitempty"`
+	Active              bool               `bson:"active" json:"active"`
+	ConsecutiveFailures int                `bson:"consecutive_failures" json:"consecutive_failures"`
+	DisabledReason      string             `bson:"disabled_reason,omitempty" json:"disabled_reason,omitempty"` // Why the webhook was disabled
+	DisabledAt          *time.Time         `bson:"disabled_at,omitempty" json:"disabled_at,omitempty"`
+	LastDeliveryAt      *time.Time         `bson:"last_delivery_at,omitempty" json:"last_delivery_at,omitempty"`
+	LastSuccessAt       *time.Time         `bson:"last_success_at,omitempty" json:"last_success_at,omitempty"`
+	CreatedAt           time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt           time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// NewWebhook creates an active webhook
+func NewWebhook(url, secret string, events []string) *Webhook {
+	now := time.Now()
+	return &Webhook{
+		ID:        primitive.NewObjectID(),
+		URL:       url,
+		Secret:    secret,
+		Events:    events,
+		Active:    true,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
+
+// BeforeSave updates the UpdatedAt field
+func (w *Webhook) BeforeSave() {
+	w.UpdatedAt = time.Now()
+}
+
+// Subscribes reports whether the webhook receives events of the given type
+func (w *Webhook) Subscribes(event string) bool {
+	return slices.Contains(w.Events, event) || slices.Contains(w.Events, WebhookAllEvents)
+}
+
+// WebhookDelivery records one attempt to deliver an event to a webhook
+type WebhookDelivery struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	TenantID   string             `bson:"tenant_id" json:"tenant_id"`
+	WebhookID  primitive.ObjectID `bson:"webhook_id" json:"webhook_id"`
+	EventID    string             `bson:"event_id" json:"event_id"` // Same for every attempt of an event
+	Event      string             `bson:"event" json:"event"`
+	Attempt    int                `bson:"attempt" json:"attempt"`
+	URL        string             `bson:"url" json:"url"`
+	StatusCode int                `bson:"status_code,omitempty" json:"status_code,omitempty"`
+	Error      string             `bson:"error,omitempty" json:"error,omitempty"`
+	Success    bool               `bson:"success" json:"success"`
+	DurationMs int64              `bson:"duration_ms" json:"duration_ms"`
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
+	ExpiresAt  time.Time          `bson:"expires_at" json:"-"`
+}
@@ -0,0 +1,222 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Content types of responses
+const (
+	ContentTypeJSON        = "application/json"
+	ContentTypeEventStream = "text/event-stream"
+)
+
+// Route documents the operation served at a route
+type Route struct {
+	Summary     string
+	Description string
+	Tag         string
+	Public      bool                   // Served without authentication
+	Security    []SecurityRequirement  // Ways to authenticate besides those of the document
+	Params      []Param                // Query and header parameters; path parameters are added from the path
+	Request     interface{}            // Zero value of the JSON request body; nil without a body
+	Status      int                    // Status of a successful response; zero means 200
+	Response    interface{}            // Zero value of the JSON response body; nil without a body
+	Stream      bool                   // Whether the response may be an event stream
+	Events      map[string]interface{} // Zero values of the event payloads the route sends, by event name
+	Commands    interface{}            // Zero value of the messages a client sends over a WebSocket route
+}
+
+// Param documents a query or header parameter
+type Param struct {
+	Name        string
+	In          string // "query" (the default) or "header"
+	Description string
+	Type        string // JSON type; defaults to "string"
+	Enum        []string
+}
+
+// Generator builds an OpenAPI document from documented routes
+type Generator struct {
+	doc     *Document
+	schemas *Schemas
+	errors  *Schema
+}
+
+// NewGenerator creates a generator of a document. Every operation may fail
+// with errorBody as its response.
+func NewGenerator(info Info, errorBody interface{}) *Generator {
+	schemas := NewSchemas()
+	return &Generator{
+		doc: &Document{
+			OpenAPI: Version,
+			Info:    info,
+			Paths:   make(map[string]PathItem),
+			Components: Components{
+				SecuritySchemes: make(map[string]SecurityScheme),
+			},
+		},
+		schemas: schemas,
+		errors:  schemas.Of(errorBody),
+	}
+}
+
+// Define sets the schema of the type of v; see Schemas.Define
+func (g *Generator) Define(v interface{}, schema *Schema) {
+	g.schemas.Define(v, schema)
+}
+
+// AddSecurityScheme adds a way to authenticate. Operations accept the schemes
+// added with required set, unless their route says otherwise.
+func (g *Generator) AddSecurityScheme(name string, scheme SecurityScheme, required bool) {
+	g.doc.Components.SecuritySchemes[name] = scheme
+	if required {
+		g.doc.Security = append(g.doc.Security, SecurityRequirement{name: {}})
+	}
+}
+
+// Add documents the operation served for method at a route path, written
+// with gin's :param and *param placeholders
+func (g *Generator) Add(method, routePath, operationID string, route Route) error {
+	path, params := pathParams(routePath)
+
+	item, ok := g.doc.Paths[path]
+	if !ok {
+		item = make(PathItem)
+		g.doc.Paths[path] = item
+	}
+	key := strings.ToLower(method)
+	if _, exists := item[key]; exists {
+		return fmt.Errorf("duplicate operation %s %s", method, routePath)
+	}
+
+	operation := &Operation{
+		OperationID: operationID,
+		Summary:     route.Summary,
+		Description: route.Description,
+		Parameters:  params,
+		Responses:   g.responses(route),
+	}
+	if route.Tag != "" {
+		operation.Tags = []string{route.Tag}
+	}
+
+	for _, param := range route.Params {
+		operation.Parameters = append(operation.Parameters, g.parameter(param))
+	}
+
+	if route.Request != nil {
+		operation.RequestBody = &RequestBody{
+			Required: true,
+			Content:  map[string]MediaType{ContentTypeJSON: {Schema: g.schemas.Of(route.Request)}},
+		}
+	}
+
+	switch {
+	case route.Public:
+		operation.Security = &[]SecurityRequirement{}
+	case route.Security != nil:
+		security := append(append([]SecurityRequirement{}, g.doc.Security...), route.Security...)
+		operation.Security = &security
+	}
+
+	if len(route.Events) > 0 {
+		operation.Events = make(map[string]*Schema, len(route.Events))
+		for name, payload := range route.Events {
+			operation.Events[name] = g.schemas.Of(payload)
+		}
+	}
+
+	if route.Commands != nil {
+		operation.Commands = g.schemas.Of(route.Commands)
+	}
+
+	item[key] = operation
+	return nil
+}
+
+// responses documents the successful and failed responses of a route
+func (g *Generator) responses(route Route) map[string]Response {
+	status := route.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	success := Response{Description: http.StatusText(status)}
+	if route.Stream || route.Response != nil {
+		success.Content = make(map[string]MediaType)
+	}
+	if route.Stream {
+		success.Content[ContentTypeEventStream] = MediaType{Schema: &Schema{
+			Type:        "string",
+			Description: "Server-Sent Events; the data of each event is the JSON payload listed for its name in x-events",
+		}}
+	}
+	if route.Response != nil {
+		success.Content[ContentTypeJSON] = MediaType{Schema: g.schemas.Of(route.Response)}
+	}
+
+	return map[string]Response{
+		strconv.Itoa(status): success,
+		"default": {
+			Description: "Error",
+			Content:     map[string]MediaType{ContentTypeJSON: {Schema: g.errors}},
+		},
+	}
+}
+
+// parameter documents a query or header parameter
+func (g *Generator) parameter(param Param) Parameter {
+	in := param.In
+	if in == "" {
+		in = "query"
+	}
+	typ := param.Type
+	if typ == "" {
+		typ = "string"
+	}
+
+	return Parameter{
+		Name:        param.Name,
+		In:          in,
+		Description: param.Description,
+		Schema:      &Schema{Type: typ, Enum: param.Enum},
+	}
+}
+
+// Document returns the document built so far
+func (g *Generator) Document() *Document {
+	g.doc.Components.Schemas = g.schemas.Components()
+	return g.doc
+}
+
+// pathParams converts a gin route path into an OpenAPI path and returns its parameters
+func pathParams(routePath string) (string, []Parameter) {
+	segments := strings.Split(routePath, "/")
+	var params []Parameter
+	for i, segment := range segments {
+		if !strings.HasPrefix(segment, ":") && !strings.HasPrefix(segment, "*") {
+			continue
+		}
+		name := segment[1:]
+		segments[i] = "{" + name + "}"
+		params = append(params, Parameter{
+			Name:     name,
+			In:       "path",
+			Required: true,
+			Schema:   &Schema{Type: "string"},
+		})
+	}
+	return strings.Join(segments, "/"), params
+}
@@ -0,0 +1,265 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package openapi
+
+import (
+	"encoding/json"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// schemaRefPrefix prefixes the references to component schemas
+const schemaRefPrefix = "#/components/schemas/"
+
+// Types with a JSON encoding that differs from their Go kind
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	objectIDType   = reflect.TypeOf(primitive.ObjectID{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+// Schemas builds the JSON schemas of Go types from their JSON encoding. Named
+// struct types become component schemas, which other schemas reference.
+type Schemas struct {
+	components map[string]*Schema
+	names      map[reflect.Type]string
+	defined    map[reflect.Type]*Schema
+}
+
+// NewSchemas creates an empty set of component schemas
+func NewSchemas() *Schemas {
+	return &Schemas{
+		components: make(map[string]*Schema),
+		names:      make(map[reflect.Type]string),
+		defined:    make(map[reflect.Type]*Schema),
+	}
+}
+
+// Define sets the schema of the type of v, for types whose JSON encoding
+// cannot be told from their Go type, such as those with custom unmarshaling
+func (s *Schemas) Define(v interface{}, schema *Schema) {
+	s.defined[reflect.TypeOf(v)] = schema
+}
+
+// Of returns the schema of the type of v
+func (s *Schemas) Of(v interface{}) *Schema {
+	return s.schema(reflect.TypeOf(v))
+}
+
+// Components returns the component schemas referenced so far, by name
+func (s *Schemas) Components() map[string]*Schema {
+	return s.components
+}
+
+// schema returns the schema of t
+func (s *Schemas) schema(t reflect.Type) *Schema {
+	if schema, ok := s.defined[t]; ok {
+		// Copy, since callers may change the schema they get
+		defined := *schema
+		return &defined
+	}
+
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case objectIDType:
+		return &Schema{Type: "string", Pattern: "^[0-9a-f]{24}$"}
+	case rawMessageType:
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return s.schema(t.Elem())
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: s.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: s.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.object(t)
+		}
+		return &Schema{Ref: schemaRefPrefix + s.component(t)}
+	default:
+		// Interfaces may hold any value
+		return &Schema{}
+	}
+}
+
+// component returns the name of the component schema of a named struct type,
+// adding it on first use
+func (s *Schemas) component(t reflect.Type) string {
+	if name, ok := s.names[t]; ok {
+		return name
+	}
+
+	name := exportedName(t.Name())
+	if _, taken := s.components[name]; taken {
+		// Types of different packages may share a name
+		name = exportedName(path.Base(t.PkgPath())) + name
+	}
+
+	// Reserve the name before building the schema, which may refer to itself
+	s.names[t] = name
+	s.components[name] = nil
+	s.components[name] = s.object(t)
+	return name
+}
+
+// object returns the schema of the JSON object a struct type encodes to
+func (s *Schemas) object(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	s.addFields(schema, t)
+	return schema
+}
+
+// addFields adds the properties of the fields of a struct type to schema.
+// Fields of embedded structs are promoted, as encoding/json does.
+func (s *Schemas) addFields(schema *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				s.addFields(schema, embedded)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		property := s.schema(field.Type)
+		if field.Type.Kind() == reflect.Ptr && !hasOption(options, "omitempty") {
+			property = nullable(property)
+		}
+		if applyBinding(property, field.Type, field.Tag.Get("binding")) {
+			schema.Required = append(schema.Required, name)
+		}
+		schema.Properties[name] = property
+	}
+}
+
+// applyBinding adds the constraints of gin binding rules to the schema of a
+// field of type t. It reports whether the rules require the field.
+func applyBinding(schema *Schema, t reflect.Type, rules string) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	required := false
+	for _, rule := range strings.Split(rules, ",") {
+		name, value, _ := strings.Cut(rule, "=")
+		switch name {
+		case "dive":
+			// Later rules apply to the elements
+			return required
+		case "required":
+			required = true
+		case "oneof":
+			schema.Enum = strings.Fields(value)
+		case "min", "max":
+			limit, err := strconv.Atoi(value)
+			if err != nil {
+				continue
+			}
+			applyLimit(schema, t, name == "min", limit)
+		}
+	}
+	return required
+}
+
+// applyLimit adds a min or max binding rule to the schema of a value of type t
+func applyLimit(schema *Schema, t reflect.Type, min bool, limit int) {
+	switch t.Kind() {
+	case reflect.String:
+		if min {
+			schema.MinLength = &limit
+		} else {
+			schema.MaxLength = &limit
+		}
+	case reflect.Slice, reflect.Array, reflect.Map:
+		if min {
+			schema.MinItems = &limit
+		} else {
+			schema.MaxItems = &limit
+		}
+	default:
+		bound := float64(limit)
+		if min {
+			schema.Minimum = &bound
+		} else {
+			schema.Maximum = &bound
+		}
+	}
+}
+
+// nullable returns a schema that also allows null
+func nullable(schema *Schema) *Schema {
+	if typ, ok := schema.Type.(string); ok {
+		schema.Type = []string{typ, "null"}
+		return schema
+	}
+	if schema.Ref == "" && len(schema.OneOf) == 0 {
+		// The empty schema allows null already
+		return schema
+	}
+	return &Schema{OneOf: []*Schema{schema, {Type: "null"}}}
+}
+
+// hasOption reports whether the options of a json tag include option
+func hasOption(options, option string) bool {
+	for _, o := range strings.Split(options, ",") {
+		if o == option {
+			return true
+		}
+	}
+	return false
+}
+
+// exportedName returns name with an upper-case first letter
+func exportedName(name string) string {
+	if name == "" {
+		return name
+	}
+	runes := []rune(name)
+	runes[0] = unicode.ToUpper(runes[0])
+	return string(runes)
+}
@@ -0,0 +1,65 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package ratelimit
+
+import "sync"
+
+// ConcurrencyLimiter caps the number of simultaneous operations per key
+type ConcurrencyLimiter struct {
+	max    int
+	active map[string]int
+	mutex  sync.Mutex
+}
+
+// NewConcurrencyLimiter creates a limiter that allows up to limit operations per key.
+// It returns nil when limit is not positive; a nil limiter allows everything.
+func NewConcurrencyLimiter(limit int) *ConcurrencyLimiter {
+	if limit <= 0 {
+		return nil
+	}
+
+	return &ConcurrencyLimiter{
+		max:    limit,
+		active: make(map[string]int),
+	}
+}
+
+// Acquire starts an operation for key, reporting false when key is at its limit.
+// Every successful Acquire must be paired with a Release.
+func (l *ConcurrencyLimiter) Acquire(key string) bool {
+	if l == nil {
+		return true
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.active[key] >= l.max {
+		return false
+	}
+	l.active[key]++
+	return true
+}
+
+// Release ends an operation for key
+func (l *ConcurrencyLimiter) Release(key string) {
+	if l == nil {
+		return
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.active[key] <= 1 {
+		delete(l.active, key)
+		return
+	}
+	l.active[key]--
+}
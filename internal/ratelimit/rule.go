@@ -0,0 +1,85 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package ratelimit
+
+import (
+	"sync"
+
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/config"
+)
+
+// Route classes with separate buckets
+const (
+	ClassDefault  = "default"
+	ClassMessages = "messages"
+	ClassStream   = "stream"
+)
+
+// Rule limits a route class per principal and per client IP. Either limiter may be nil.
+type Rule struct {
+	Principal *Limiter
+	IP        *Limiter
+}
+
+// NewRule creates the limiters of a route class from configuration
+func NewRule(cfg config.RateLimitRule) Rule {
+	return Rule{
+		Principal: NewLimiter(cfg.PrincipalPerMinute, cfg.PrincipalBurst),
+		IP:        NewLimiter(cfg.IPPerMinute, cfg.IPBurst),
+	}
+}
+
+// RuleSet holds the rules of every route class and can be reconfigured while in use
+type RuleSet struct {
+	configs map[string]config.RateLimitRule
+	rules   map[string]Rule
+	mutex   sync.RWMutex
+}
+
+// NewRuleSet creates the rules of every route class from configuration
+func NewRuleSet(cfg config.RateLimitConfig) *RuleSet {
+	s := &RuleSet{}
+	s.Update(cfg)
+	return s
+}
+
+// Rule returns the rule of a route class
+func (s *RuleSet) Rule(class string) (Rule, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	rule, ok := s.rules[class]
+	return rule, ok
+}
+
+// Update replaces the rules whose configuration changed. The buckets of
+// unchanged rules are kept, so that reloading does not reset every client's allowance.
+func (s *RuleSet) Update(cfg config.RateLimitConfig) {
+	configs := map[string]config.RateLimitRule{
+		ClassDefault:  cfg.Default,
+		ClassMessages: cfg.Messages,
+		ClassStream:   cfg.Stream,
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	rules := make(map[string]Rule, len(configs))
+	for class, ruleConfig := range configs {
+		if rule, ok := s.rules[class]; ok && s.configs[class] == ruleConfig {
+			rules[class] = rule
+			continue
+		}
+		rules[class] = NewRule(ruleConfig)
+	}
+
+	s.configs = configs
+	s.rules = rules
+}
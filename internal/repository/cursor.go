@@ -0,0 +1,113 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package repository
+
+import (
+	"encoding/base64"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrInvalidCursor is returned when a cursor token cannot be decoded
+// or does not belong to the requested sort order
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor identifies a position in a keyset-paginated result set.
+// The sort value and the document ID together form a unique, stable key.
+type Cursor struct {
+	Key       string             // Name of the sort field the cursor was created for
+	Ascending bool               // Sort direction the cursor was created for
+	Value     interface{}        // Value of the sort field at this position: a time, a string or nil
+	ID        primitive.ObjectID // Tie-breaker for documents sharing a sort value
+}
+
+// cursorToken is the wire format of an encoded cursor
+type cursorToken struct {
+	Key       string             `bson:"k"`
+	Ascending bool               `bson:"a"`
+	Value     interface{}        `bson:"v"`
+	ID        primitive.ObjectID `bson:"id"`
+}
+
+// NewCursor creates a cursor for the given sort field and direction, value and document ID
+func NewCursor(key string, ascending bool, value interface{}, id primitive.ObjectID) *Cursor {
+	return &Cursor{
+		Key:       key,
+		Ascending: ascending,
+		Value:     value,
+		ID:        id,
+	}
+}
+
+// Encode returns the opaque token representation of the cursor
+func (c *Cursor) Encode() string {
+	data, err := bson.Marshal(cursorToken{Key: c.Key, Ascending: c.Ascending, Value: c.Value, ID: c.ID})
+	if err != nil {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor parses an opaque cursor token. Tokens are not signed, so the
+// sort value is only accepted as a time, a string or null; anything else, such
+// as a document that would read as a query operator, is rejected.
+func DecodeCursor(token string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var raw bson.M
+	if err := bson.Unmarshal(data, &raw); err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	key, _ := raw["k"].(string)
+	ascending, hasDirection := raw["a"].(bool)
+	id, ok := raw["id"].(primitive.ObjectID)
+	if key == "" || !hasDirection || !ok {
+		return nil, ErrInvalidCursor
+	}
+
+	var value interface{}
+	switch v := raw["v"].(type) {
+	case nil:
+	case string:
+		value = v
+	case primitive.DateTime:
+		value = v.Time()
+	default:
+		return nil, ErrInvalidCursor
+	}
+
+	return &Cursor{
+		Key:       key,
+		Ascending: ascending,
+		Value:     value,
+		ID:        id,
+	}, nil
+}
+
+// CursorQuery describes a keyset-paginated query.
+// At most one of Before and After may be set; with neither set
+// the repository returns its default page (e.g. the newest messages).
+type CursorQuery struct {
+	Limit  int
+	Before *Cursor // Return items that precede this position
+	After  *Cursor // Return items that follow this position
+}
+
+// PageInfo describes whether more results exist on either side of a page
+type PageInfo struct {
+	HasBefore bool
+	HasAfter  bool
+}
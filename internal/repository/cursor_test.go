@@ -0,0 +1,72 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package repository
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TestCursorRoundTrip checks that every accepted value type survives encoding
+func TestCursorRoundTrip(t *testing.T) {
+	id := primitive.NewObjectID()
+	at := time.Date(2025, 3, 27, 10, 45, 30, 0, time.UTC)
+
+	for _, value := range []interface{}{at, "First conversation", nil} {
+		cursor, err := DecodeCursor(NewCursor("updated_at", false, value, id).Encode())
+		if err != nil {
+			t.Fatalf("DecodeCursor(%v) error = %v", value, err)
+		}
+		if cursor.Key != "updated_at" || cursor.Ascending || cursor.ID != id {
+			t.Errorf("DecodeCursor(%v) = %+v", value, cursor)
+		}
+		if decoded, ok := cursor.Value.(time.Time); ok {
+			if !decoded.Equal(at) {
+				t.Errorf("DecodeCursor() value = %v, want %v", decoded, at)
+			}
+		} else if cursor.Value != value {
+			t.Errorf("DecodeCursor() value = %v, want %v", cursor.Value, value)
+		}
+	}
+}
+
+// TestDecodeCursorRejectsTamperedTokens checks that tokens whose value could
+// change the meaning of a query, or that lack a key, are rejected
+func TestDecodeCursorRejectsTamperedTokens(t *testing.T) {
+	id := primitive.NewObjectID()
+
+	tests := map[string]bson.M{
+		"operator document": {"k": "updated_at", "a": false, "v": bson.M{"$ne": nil}, "id": id},
+		"array":             {"k": "updated_at", "a": false, "v": bson.A{1, 2}, "id": id},
+		"number":            {"k": "updated_at", "a": false, "v": 42, "id": id},
+		"missing key":       {"a": false, "v": "title", "id": id},
+		"missing direction": {"k": "title", "v": "title", "id": id},
+		"missing id":        {"k": "title", "a": true, "v": "title"},
+	}
+
+	for name, token := range tests {
+		t.Run(name, func(t *testing.T) {
+			data, err := bson.Marshal(token)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = DecodeCursor(base64.RawURLEncoding.EncodeToString(data))
+			if !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("DecodeCursor() error = %v, want %v", err, ErrInvalidCursor)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package repository
+
+import (
+	"time"
+
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Chat sort fields
+const (
+	ChatSortUpdatedAt     = "updated_at"
+	ChatSortLastMessageAt = "last_message_at"
+	ChatSortCreatedAt     = "created_at"
+	ChatSortTitle         = "title"
+)
+
+// ChatFilter restricts which chats a listing returns.
+// Nil pointers and empty strings leave the corresponding field unfiltered.
+type ChatFilter struct {
+	Active       *bool
+	Archived     *bool
+	Pinned       *bool
+	Tag          string
+	CreatedAfter *time.Time
+	TitlePrefix  string
+	FolderID     *primitive.ObjectID
+	Unfiled      bool   // Only chats that are not in any folder
+	Member       string // Only chats the given subject is a member of
+}
+
+// ChatSort describes the order of a chat listing
+type ChatSort struct {
+	Field     string
+	Ascending bool
+}
+
+// ChatListOptions combines the filter and sort order of a chat listing
+type ChatListOptions struct {
+	Filter ChatFilter
+	Sort   ChatSort
+}
+
+// IsValidChatSortField reports whether chats can be sorted by the given field
+func IsValidChatSortField(field string) bool {
+	switch field {
+	case ChatSortUpdatedAt, ChatSortLastMessageAt, ChatSortCreatedAt, ChatSortTitle:
+		return true
+	default:
+		return false
+	}
+}
+
+// DefaultChatSort returns the default order of a chat listing: most recently updated first
+func DefaultChatSort() ChatSort {
+	return ChatSort{Field: ChatSortUpdatedAt, Ascending: false}
+}
+
+// JobFilter restricts which jobs a listing returns.
+// Empty fields leave the corresponding field unfiltered.
+type JobFilter struct {
+	Status   models.JobStatus
+	Type     string
+	TenantID string
+}
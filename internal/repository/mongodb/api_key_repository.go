@@ -0,0 +1,85 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/db/mongodb"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/models"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/repository"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// APIKeyRepository implements the APIKeyRepository interface
+type APIKeyRepository struct {
+	db *mongodb.DBConnection
+}
+
+// NewAPIKeyRepository creates a new MongoDB API key repository
+func NewAPIKeyRepository(db *mongodb.DBConnection) repository.APIKeyRepository {
+	return &APIKeyRepository{db: db}
+}
+
+// Create inserts a new API key into the database
+func (r *APIKeyRepository) Create(ctx context.Context, key *models.APIKey) error {
+	_, err := r.db.APIKeys().InsertOne(ctx, key)
+	return err
+}
+
+// FindByID retrieves an API key by its ID
+func (r *APIKeyRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.APIKey, error) {
+	var key models.APIKey
+	err := r.db.APIKeys().FindOne(ctx, bson.M{"_id": id}).Decode(&key)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil // API key not found
+		}
+		return nil, err
+	}
+	return &key, nil
+}
+
+// FindAll retrieves all API keys, newest first
+func (r *APIKeyRepository) FindAll(ctx context.Context) ([]*models.APIKey, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+
+	cursor, err := r.db.APIKeys().Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var keys []*models.APIKey
+	if err := cursor.All(ctx, &keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// Revoke marks an API key as revoked
+func (r *APIKeyRepository) Revoke(ctx context.Context, id primitive.ObjectID, revokedAt time.Time) error {
+	update := bson.M{
+		"$set": bson.M{"revoked_at": revokedAt},
+	}
+	result, err := r.db.APIKeys().UpdateOne(ctx, bson.M{"_id": id, "revoked_at": nil}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
@@ -12,11 +12,14 @@ package mongodb
 import (
 	"context"
 	"errors"
+	"regexp"
+	"strings"
 	"time"
 
 	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/db/mongodb"
 	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/models"
 	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/repository"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/tenant"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -35,6 +38,7 @@ func NewChatRepository(db *mongodb.DBConnection) repository.ChatRepository {
 
 // Create inserts a new chat into the database
 func (r *ChatRepository) Create(ctx context.Context, chat *models.Chat) error {
+	chat.TenantID = tenant.FromContext(ctx)
 	chat.BeforeSave()
 	_, err := r.db.Chats().InsertOne(ctx, chat)
 	return err
@@ -43,7 +47,7 @@ func (r *ChatRepository) Create(ctx context.Context, chat *models.Chat) error {
 // FindByID retrieves a chat by its ID
 func (r *ChatRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Chat, error) {
 	var chat models.Chat
-	err := r.db.Chats().FindOne(ctx, bson.M{"_id": id}).Decode(&chat)
+	err := r.db.Chats().FindOne(ctx, scoped(ctx, notDeleted(bson.M{"_id": id}))).Decode(&chat)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
 			return nil, nil // Chat not found
@@ -53,14 +57,34 @@ func (r *ChatRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*
 	return &chat, nil
 }
 
-// FindAll retrieves all chats with pagination
-func (r *ChatRepository) FindAll(ctx context.Context, limit, offset int) ([]*models.Chat, error) {
+// FindByIDs retrieves the chats with the given IDs
+func (r *ChatRepository) FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]*models.Chat, error) {
+	cursor, err := r.db.Chats().Find(ctx, scoped(ctx, notDeleted(bson.M{"_id": bson.M{"$in": ids}})))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var chats []*models.Chat
+	if err := cursor.All(ctx, &chats); err != nil {
+		return nil, err
+	}
+	return chats, nil
+}
+
+// FindAll retrieves chats matching the listing options with pagination
+func (r *ChatRepository) FindAll(ctx context.Context, listOpts repository.ChatListOptions, limit, offset int) ([]*models.Chat, error) {
+	direction := -1
+	if listOpts.Sort.Ascending {
+		direction = 1
+	}
+
 	opts := options.Find().
-		SetSort(bson.D{{"updated_at", -1}}). // Sort by updated_at descending
+		SetSort(bson.D{{Key: listOpts.Sort.Field, Value: direction}, {Key: "_id", Value: direction}}).
 		SetLimit(int64(limit)).
 		SetSkip(int64(offset))
 
-	cursor, err := r.db.Chats().Find(ctx, bson.M{"active": true}, opts)
+	cursor, err := r.db.Chats().Find(ctx, scoped(ctx, chatFilter(listOpts.Filter)), opts)
 	if err != nil {
 		return nil, err
 	}
@@ -73,10 +97,75 @@ func (r *ChatRepository) FindAll(ctx context.Context, limit, offset int) ([]*mod
 	return chats, nil
 }
 
-// Update updates an existing chat
+// FindAllByCursor retrieves chats matching the listing options using keyset pagination
+func (r *ChatRepository) FindAllByCursor(ctx context.Context, listOpts repository.ChatListOptions, query repository.CursorQuery) ([]*models.Chat, repository.PageInfo, error) {
+	keys := keyset{
+		field:     listOpts.Sort.Field,
+		ascending: listOpts.Sort.Ascending,
+		nullable:  listOpts.Sort.Field == repository.ChatSortLastMessageAt,
+		text:      listOpts.Sort.Field == repository.ChatSortTitle,
+	}
+
+	filter, opts, reverse, err := keys.build(query)
+	if err != nil {
+		return nil, repository.PageInfo{}, err
+	}
+
+	cursor, err := r.db.Chats().Find(ctx, mergeFilters(scoped(ctx, chatFilter(listOpts.Filter)), filter), opts)
+	if err != nil {
+		return nil, repository.PageInfo{}, err
+	}
+	defer cursor.Close(ctx)
+
+	var chats []*models.Chat
+	if err := cursor.All(ctx, &chats); err != nil {
+		return nil, repository.PageInfo{}, err
+	}
+
+	count, info := keys.pageInfo(query, len(chats))
+	chats = chats[:count]
+	if reverse {
+		reverseChats(chats)
+	}
+
+	return chats, info, nil
+}
+
+// Update saves the editable fields of an existing chat: its title, tags,
+// folder and flags. Members and message counters are changed by their own
+// operations and are left as stored, so concurrent changes are not lost.
 func (r *ChatRepository) Update(ctx context.Context, chat *models.Chat) error {
 	chat.BeforeSave()
-	result, err := r.db.Chats().ReplaceOne(ctx, bson.M{"_id": chat.ID}, chat)
+
+	set := bson.M{
+		"title":       chat.Title,
+		"title_lower": chat.TitleLower,
+		"active":      chat.Active,
+		"archived":    chat.Archived,
+		"pinned":      chat.Pinned,
+		"updated_at":  chat.UpdatedAt,
+	}
+	unset := bson.M{}
+
+	// Empty optional fields are removed, as they are omitted on insert
+	if len(chat.Tags) > 0 {
+		set["tags"] = chat.Tags
+	} else {
+		unset["tags"] = ""
+	}
+	if chat.FolderID != nil {
+		set["folder_id"] = chat.FolderID
+	} else {
+		unset["folder_id"] = ""
+	}
+	if chat.ArchivedAt != nil {
+		set["archived_at"] = chat.ArchivedAt
+	} else {
+		unset["archived_at"] = ""
+	}
+
+	update := bson.M{"$set": set, "$unset": unset}
+	result, err := r.db.Chats().UpdateOne(ctx, scoped(ctx, notDeleted(bson.M{"_id": chat.ID})), update)
 	if err != nil {
 		return err
 	}
@@ -86,15 +175,160 @@ func (r *ChatRepository) Update(ctx context.Context, chat *models.Chat) error {
 	return nil
 }
 
-// Delete marks a chat as inactive (soft delete)
-func (r *ChatRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+// Delete moves a chat to the trash (soft delete)
+func (r *ChatRepository) Delete(ctx context.Context, id primitive.ObjectID, deletedAt time.Time) error {
 	update := bson.M{
 		"$set": bson.M{
-			"active":     false,
+			"deleted_at": deletedAt,
+			"updated_at": deletedAt,
+		},
+	}
+	result, err := r.db.Chats().UpdateOne(ctx, scoped(ctx, notDeleted(bson.M{"_id": id})), update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// FindDeletedByID retrieves a chat from the trash by its ID
+func (r *ChatRepository) FindDeletedByID(ctx context.Context, id primitive.ObjectID) (*models.Chat, error) {
+	var chat models.Chat
+	err := r.db.Chats().FindOne(ctx, scoped(ctx, onlyDeleted(bson.M{"_id": id}))).Decode(&chat)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil // Chat not in trash
+		}
+		return nil, err
+	}
+	return &chat, nil
+}
+
+// FindDeleted retrieves chats in the trash, most recently deleted first
+func (r *ChatRepository) FindDeleted(ctx context.Context, ownerID string, limit, offset int) ([]*models.Chat, error) {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "deleted_at", Value: -1}, {Key: "_id", Value: -1}}).
+		SetLimit(int64(limit)).
+		SetSkip(int64(offset))
+
+	cursor, err := r.db.Chats().Find(ctx, scoped(ctx, onlyDeleted(ownerFilter(ownerID))), opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var chats []*models.Chat
+	if err := cursor.All(ctx, &chats); err != nil {
+		return nil, err
+	}
+	return chats, nil
+}
+
+// CountDeleted returns the number of chats in the trash
+func (r *ChatRepository) CountDeleted(ctx context.Context, ownerID string) (int64, error) {
+	return r.db.Chats().CountDocuments(ctx, scoped(ctx, onlyDeleted(ownerFilter(ownerID))))
+}
+
+// FindIDsByMember returns the IDs of chats in which subject holds one of the given roles
+func (r *ChatRepository) FindIDsByMember(ctx context.Context, subject string, roles []models.ChatRole) ([]primitive.ObjectID, error) {
+	filter := scoped(ctx, notDeleted(bson.M{
+		"members": bson.M{"$elemMatch": bson.M{
+			"subject": subject,
+			"role":    bson.M{"$in": roles},
+		}},
+	}))
+	opts := options.Find().SetProjection(bson.M{"_id": 1})
+
+	cursor, err := r.db.Chats().Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	ids := []primitive.ObjectID{}
+	for cursor.Next(ctx) {
+		var doc struct {
+			ID primitive.ObjectID `bson:"_id"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, err
+		}
+		ids = append(ids, doc.ID)
+	}
+	return ids, cursor.Err()
+}
+
+// SetMember adds a member to a chat, replacing the role of an existing member with the same subject
+func (r *ChatRepository) SetMember(ctx context.Context, id primitive.ObjectID, member models.ChatMember) error {
+	others := bson.M{"$filter": bson.M{
+		"input": bson.M{"$ifNull": bson.A{"$members", bson.A{}}},
+		"cond":  bson.M{"$ne": bson.A{"$$this.subject", member.Subject}},
+	}}
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"members":    bson.M{"$concatArrays": bson.A{others, bson.A{member}}},
 			"updated_at": time.Now(),
+		}}},
+	}
+
+	result, err := r.db.Chats().UpdateOne(ctx, scoped(ctx, notDeleted(bson.M{"_id": id})), update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// RemoveMember removes a member from a chat
+func (r *ChatRepository) RemoveMember(ctx context.Context, id primitive.ObjectID, subject string) error {
+	update := bson.M{
+		"$pull": bson.M{"members": bson.M{"subject": subject}},
+		"$set":  bson.M{"updated_at": time.Now()},
+	}
+	result, err := r.db.Chats().UpdateOne(ctx, scoped(ctx, notDeleted(bson.M{"_id": id})), update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// ClaimUnowned makes subject the owner of every chat that has no owner, including trashed ones
+func (r *ChatRepository) ClaimUnowned(ctx context.Context, subject string) (int64, error) {
+	update := bson.M{
+		"$set": bson.M{
+			"owner_id": subject,
+			"members":  bson.A{models.NewChatMember(subject, models.ChatRoleOwner)},
 		},
 	}
-	result, err := r.db.Chats().UpdateOne(ctx, bson.M{"_id": id}, update)
+	result, err := r.db.Chats().UpdateMany(ctx, scoped(ctx, bson.M{"owner_id": nil}), update)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+// ownerFilter restricts a query to chats owned by ownerID; an empty ownerID matches all chats
+func ownerFilter(ownerID string) bson.M {
+	if ownerID == "" {
+		return bson.M{}
+	}
+	return bson.M{"owner_id": ownerID}
+}
+
+// Restore moves a chat out of the trash
+func (r *ChatRepository) Restore(ctx context.Context, id primitive.ObjectID) error {
+	update := bson.M{
+		"$set":   bson.M{"updated_at": time.Now()},
+		"$unset": bson.M{"deleted_at": ""},
+	}
+	result, err := r.db.Chats().UpdateOne(ctx, scoped(ctx, onlyDeleted(bson.M{"_id": id})), update)
 	if err != nil {
 		return err
 	}
@@ -104,17 +338,134 @@ func (r *ChatRepository) Delete(ctx context.Context, id primitive.ObjectID) erro
 	return nil
 }
 
-// IncrementMessageCount increases the message count of a chat
-func (r *ChatRepository) IncrementMessageCount(ctx context.Context, id primitive.ObjectID) error {
+// FindDeletedBefore returns the IDs of chats of any tenant that were moved to the trash before the cutoff
+func (r *ChatRepository) FindDeletedBefore(ctx context.Context, cutoff time.Time, limit int) ([]primitive.ObjectID, error) {
+	opts := options.Find().
+		SetProjection(bson.M{"_id": 1}).
+		SetLimit(int64(limit))
+
+	cursor, err := r.db.Chats().Find(ctx, bson.M{"deleted_at": bson.M{"$lt": cutoff}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var docs []struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+
+	ids := make([]primitive.ObjectID, len(docs))
+	for i, doc := range docs {
+		ids[i] = doc.ID
+	}
+	return ids, nil
+}
+
+// HardDelete permanently removes soft-deleted chats of any tenant
+func (r *ChatRepository) HardDelete(ctx context.Context, ids []primitive.ObjectID) (int64, error) {
+	result, err := r.db.Chats().DeleteMany(ctx, onlyDeleted(bson.M{"_id": bson.M{"$in": ids}}))
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+// SetArchived archives or unarchives a chat
+func (r *ChatRepository) SetArchived(ctx context.Context, id primitive.ObjectID, archived bool) error {
 	now := time.Now()
+	set := bson.M{
+		"archived":   archived,
+		"updated_at": now,
+	}
+
+	update := bson.M{"$set": set}
+	if archived {
+		set["archived_at"] = now
+	} else {
+		update["$unset"] = bson.M{"archived_at": ""}
+	}
+
+	result, err := r.db.Chats().UpdateOne(ctx, scoped(ctx, notDeleted(bson.M{"_id": id})), update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// SetFolder moves chats into a folder, or out of any folder when folderID is nil
+func (r *ChatRepository) SetFolder(ctx context.Context, ids []primitive.ObjectID, folderID *primitive.ObjectID) (int64, error) {
+	update := bson.M{
+		"$set": bson.M{"updated_at": time.Now()},
+	}
+	if folderID != nil {
+		update["$set"].(bson.M)["folder_id"] = *folderID
+	} else {
+		update["$unset"] = bson.M{"folder_id": ""}
+	}
+
+	result, err := r.db.Chats().UpdateMany(ctx, scoped(ctx, notDeleted(bson.M{"_id": bson.M{"$in": ids}})), update)
+	if err != nil {
+		return 0, err
+	}
+	return result.MatchedCount, nil
+}
+
+// ClearFolder removes every chat from the given folder
+func (r *ChatRepository) ClearFolder(ctx context.Context, folderID primitive.ObjectID) (int64, error) {
+	update := bson.M{
+		"$set":   bson.M{"updated_at": time.Now()},
+		"$unset": bson.M{"folder_id": ""},
+	}
+
+	result, err := r.db.Chats().UpdateMany(ctx, scoped(ctx, bson.M{"folder_id": folderID}), update)
+	if err != nil {
+		return 0, err
+	}
+	return result.MatchedCount, nil
+}
+
+// UpdateTags adds and removes tags on chats in a single atomic update per chat
+func (r *ChatRepository) UpdateTags(ctx context.Context, ids []primitive.ObjectID, add, remove []string) (int64, error) {
+	if add == nil {
+		add = []string{}
+	}
+	if remove == nil {
+		remove = []string{}
+	}
+
+	// A pipeline update lets us add and remove tags on the same field at once
+	pipeline := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"tags": bson.M{"$setUnion": bson.A{
+				bson.M{"$setDifference": bson.A{bson.M{"$ifNull": bson.A{"$tags", bson.A{}}}, remove}},
+				add,
+			}},
+			"updated_at": time.Now(),
+		}}},
+	}
+
+	result, err := r.db.Chats().UpdateMany(ctx, scoped(ctx, notDeleted(bson.M{"_id": bson.M{"$in": ids}})), pipeline)
+	if err != nil {
+		return 0, err
+	}
+	return result.MatchedCount, nil
+}
+
+// IncrementMessageCount increases the message count of a chat.
+// last_message_at only moves forward, so restoring an old message does not rewind it.
+func (r *ChatRepository) IncrementMessageCount(ctx context.Context, id primitive.ObjectID, messageAt time.Time) error {
 	update := bson.M{
 		"$inc": bson.M{"message_count": 1},
-		"$set": bson.M{
-			"last_message_at": now,
-			"updated_at":      now,
-		},
+		"$max": bson.M{"last_message_at": messageAt},
+		"$set": bson.M{"updated_at": time.Now()},
 	}
-	result, err := r.db.Chats().UpdateOne(ctx, bson.M{"_id": id}, update)
+	result, err := r.db.Chats().UpdateOne(ctx, scoped(ctx, notDeleted(bson.M{"_id": id})), update)
 	if err != nil {
 		return err
 	}
@@ -124,7 +475,130 @@ func (r *ChatRepository) IncrementMessageCount(ctx context.Context, id primitive
 	return nil
 }
 
-// CountAll returns the total number of active chats
-func (r *ChatRepository) CountAll(ctx context.Context) (int64, error) {
-	return r.db.Chats().CountDocuments(ctx, bson.M{"active": true})
+// DecrementMessageCount decreases the message count of a chat without going
+// below zero and sets last_message_at to the time of the newest remaining
+// message. A nil lastMessageAt removes the field.
+func (r *ChatRepository) DecrementMessageCount(ctx context.Context, id primitive.ObjectID, lastMessageAt *time.Time) error {
+	set := bson.M{
+		"message_count": bson.M{"$max": bson.A{0, bson.M{"$subtract": bson.A{"$message_count", 1}}}},
+		"updated_at":    time.Now(),
+	}
+	update := mongo.Pipeline{{{Key: "$set", Value: set}}}
+	if lastMessageAt != nil {
+		set["last_message_at"] = *lastMessageAt
+	} else {
+		update = append(update, bson.D{{Key: "$unset", Value: "last_message_at"}})
+	}
+	result, err := r.db.Chats().UpdateOne(ctx, scoped(ctx, notDeleted(bson.M{"_id": id})), update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// Scan retrieves chats of the tenant in _id order starting after the given ID, including trashed chats.
+// Pass primitive.NilObjectID to start from the beginning.
+func (r *ChatRepository) Scan(ctx context.Context, after primitive.ObjectID, limit int) ([]*models.Chat, error) {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "_id", Value: 1}}).
+		SetLimit(int64(limit))
+
+	cursor, err := r.db.Chats().Find(ctx, scoped(ctx, bson.M{"_id": bson.M{"$gt": after}}), opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var chats []*models.Chat
+	if err := cursor.All(ctx, &chats); err != nil {
+		return nil, err
+	}
+	return chats, nil
+}
+
+// SetMessageStats overwrites the message count and last message time of a chat.
+// A nil lastMessageAt removes the field. updated_at is left untouched.
+func (r *ChatRepository) SetMessageStats(ctx context.Context, id primitive.ObjectID, count int, lastMessageAt *time.Time) error {
+	set := bson.M{"message_count": count}
+	update := bson.M{"$set": set}
+	if lastMessageAt != nil {
+		set["last_message_at"] = *lastMessageAt
+	} else {
+		update["$unset"] = bson.M{"last_message_at": ""}
+	}
+
+	result, err := r.db.Chats().UpdateOne(ctx, scoped(ctx, bson.M{"_id": id}), update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// CountAll returns the total number of chats matching the filter
+func (r *ChatRepository) CountAll(ctx context.Context, filter repository.ChatFilter) (int64, error) {
+	return r.db.Chats().CountDocuments(ctx, scoped(ctx, chatFilter(filter)))
+}
+
+// chatFilter converts a chat listing filter into a MongoDB query
+func chatFilter(f repository.ChatFilter) bson.M {
+	filter := notDeleted(bson.M{})
+
+	if f.Active != nil {
+		filter["active"] = *f.Active
+	}
+
+	if f.Archived != nil {
+		// Chats created before archiving existed have no archived field
+		if *f.Archived {
+			filter["archived"] = true
+		} else {
+			filter["archived"] = bson.M{"$ne": true}
+		}
+	}
+
+	if f.Pinned != nil {
+		if *f.Pinned {
+			filter["pinned"] = true
+		} else {
+			filter["pinned"] = bson.M{"$ne": true}
+		}
+	}
+
+	if f.Tag != "" {
+		filter["tags"] = f.Tag
+	}
+
+	if f.CreatedAfter != nil {
+		filter["created_at"] = bson.M{"$gt": *f.CreatedAfter}
+	}
+
+	if f.TitlePrefix != "" {
+		// Matched case-sensitively against the lowercased title, so that the anchored prefix can use the index
+		filter["title_lower"] = primitive.Regex{Pattern: "^" + regexp.QuoteMeta(strings.ToLower(f.TitlePrefix))}
+	}
+
+	if f.FolderID != nil {
+		filter["folder_id"] = *f.FolderID
+	} else if f.Unfiled {
+		filter["folder_id"] = nil
+	}
+
+	if f.Member != "" {
+		filter["members.subject"] = f.Member
+	}
+
+	return filter
+}
+
+// reverseChats reverses a slice of chats in place
+func reverseChats(chats []*models.Chat) {
+	for i, j := 0, len(chats)-1; i < j; i, j = i+1, j-1 {
+		chats[i], chats[j] = chats[j], chats[i]
+	}
 }
@@ -0,0 +1,142 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package mongodb
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/db/mongodb"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/models"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/repository"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/tenant"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// TestChatUpdateKeepsMembers checks that saving a chat only sets its editable
+// fields, so members and counters changed meanwhile are not overwritten
+func TestChatUpdateKeepsMembers(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	mt.Run("update", func(mt *mtest.T) {
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}, {Key: "nModified", Value: 1}})
+
+		chat := models.NewChat("Renamed")
+		chat.SetOwner("alice")
+		chat.Tags = []string{"work"}
+		chat.MessageCount = 3
+
+		ctx := tenant.WithTenant(context.Background(), testTenant)
+		if err := NewChatRepository(mongodb.NewFromClient(mt.Client, testCollections)).Update(ctx, chat); err != nil {
+			mt.Fatalf("Update() error = %v", err)
+		}
+
+		evt := mt.GetStartedEvent()
+		if evt == nil || evt.CommandName != "update" {
+			mt.Fatalf("Update() sent %v, want an update command", evt)
+		}
+
+		update, err := evt.Command.LookupErr("updates", "0", "u")
+		if err != nil {
+			mt.Fatal(err)
+		}
+		set, err := update.Document().LookupErr("$set")
+		if err != nil {
+			mt.Fatalf("update %s has no $set", update)
+		}
+
+		for _, field := range []string{"title", "title_lower", "tags", "pinned", "archived", "updated_at"} {
+			if _, err := set.Document().LookupErr(field); err != nil {
+				mt.Errorf("$set does not set %s: %s", field, set)
+			}
+		}
+		for _, field := range []string{"members", "owner_id", "message_count", "last_message_at", "tenant_id", "created_at"} {
+			if _, err := update.Document().LookupErr("$set", field); err == nil {
+				mt.Errorf("$set overwrites %s: %s", field, set)
+			}
+			if _, err := update.Document().LookupErr("$unset", field); err == nil {
+				mt.Errorf("$unset removes %s: %s", field, update)
+			}
+		}
+	})
+}
+
+// TestDecrementMessageCountSetsLastMessageAt checks that deleting a message
+// moves last_message_at back to the newest remaining message, or removes it
+// when none remain
+func TestDecrementMessageCountSetsLastMessageAt(t *testing.T) {
+	newest := time.Date(2025, 3, 27, 10, 45, 30, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		lastMessageAt *time.Time
+		wantUnset     bool
+	}{
+		{name: "messages remain", lastMessageAt: &newest},
+		{name: "no messages remain", wantUnset: true},
+	}
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	for _, tt := range tests {
+		mt.Run(tt.name, func(mt *mtest.T) {
+			mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}, {Key: "nModified", Value: 1}})
+
+			ctx := tenant.WithTenant(context.Background(), testTenant)
+			repo := NewChatRepository(mongodb.NewFromClient(mt.Client, testCollections))
+			if err := repo.DecrementMessageCount(ctx, primitive.NewObjectID(), tt.lastMessageAt); err != nil {
+				mt.Fatalf("DecrementMessageCount() error = %v", err)
+			}
+
+			evt := mt.GetStartedEvent()
+			if evt == nil || evt.CommandName != "update" {
+				mt.Fatalf("DecrementMessageCount() sent %v, want an update command", evt)
+			}
+
+			stages, err := evt.Command.LookupErr("updates", "0", "u")
+			if err != nil {
+				mt.Fatal(err)
+			}
+			set, err := stages.Array().LookupErr("0", "$set")
+			if err != nil {
+				mt.Fatalf("update %s does not start with a $set stage", stages)
+			}
+
+			got, setErr := set.Document().LookupErr("last_message_at")
+			if tt.wantUnset {
+				if setErr == nil {
+					mt.Errorf("$set sets last_message_at to %s, want it removed", got)
+				}
+				if unset, err := stages.Array().LookupErr("1", "$unset"); err != nil || unset.StringValue() != "last_message_at" {
+					mt.Errorf("update %s does not remove last_message_at", stages)
+				}
+				return
+			}
+			if setErr != nil || !got.Time().Equal(newest) {
+				mt.Errorf("$set sets last_message_at to %v, want %v", got, newest)
+			}
+		})
+	}
+}
+
+// TestChatFilterTitlePrefix checks that title prefixes are matched against the
+// lowercased title with an anchored, case-sensitive regex that can use its index
+func TestChatFilterTitlePrefix(t *testing.T) {
+	filter := chatFilter(repository.ChatFilter{TitlePrefix: "Q3 (Draft)"})
+
+	if _, ok := filter["title"]; ok {
+		t.Errorf("chatFilter() matches the title field: %v", filter)
+	}
+	got, ok := filter["title_lower"].(primitive.Regex)
+	if want := (primitive.Regex{Pattern: `^q3 \(draft\)`}); !ok || got != want {
+		t.Errorf("chatFilter() title_lower = %v, want %v", filter["title_lower"], want)
+	}
+}
@@ -0,0 +1,111 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package mongodb
+
+import (
+	"context"
+	"errors"
+
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/db/mongodb"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/models"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/repository"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/tenant"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// FolderRepository implements the FolderRepository interface
+type FolderRepository struct {
+	db *mongodb.DBConnection
+}
+
+// NewFolderRepository creates a new MongoDB folder repository
+func NewFolderRepository(db *mongodb.DBConnection) repository.FolderRepository {
+	return &FolderRepository{db: db}
+}
+
+// Create inserts a new folder into the database
+func (r *FolderRepository) Create(ctx context.Context, folder *models.Folder) error {
+	folder.TenantID = tenant.FromContext(ctx)
+	folder.BeforeSave()
+	_, err := r.db.Folders().InsertOne(ctx, folder)
+	return err
+}
+
+// FindByID retrieves a folder by its ID
+func (r *FolderRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Folder, error) {
+	var folder models.Folder
+	err := r.db.Folders().FindOne(ctx, scoped(ctx, bson.M{"_id": id})).Decode(&folder)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil // Folder not found
+		}
+		return nil, err
+	}
+	return &folder, nil
+}
+
+// FindAll retrieves the folders of an owner sorted by name; an empty ownerID returns all folders
+func (r *FolderRepository) FindAll(ctx context.Context, ownerID string) ([]*models.Folder, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "name", Value: 1}})
+
+	filter := scoped(ctx, bson.M{})
+	if ownerID != "" {
+		filter["owner_id"] = ownerID
+	}
+
+	cursor, err := r.db.Folders().Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var folders []*models.Folder
+	if err := cursor.All(ctx, &folders); err != nil {
+		return nil, err
+	}
+	return folders, nil
+}
+
+// Update updates an existing folder
+func (r *FolderRepository) Update(ctx context.Context, folder *models.Folder) error {
+	folder.BeforeSave()
+	result, err := r.db.Folders().ReplaceOne(ctx, scoped(ctx, bson.M{"_id": folder.ID}), folder)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// Delete removes a folder
+func (r *FolderRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	result, err := r.db.Folders().DeleteOne(ctx, scoped(ctx, bson.M{"_id": id}))
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// ClaimUnowned makes subject the owner of every folder that has no owner
+func (r *FolderRepository) ClaimUnowned(ctx context.Context, subject string) (int64, error) {
+	result, err := r.db.Folders().UpdateMany(ctx, scoped(ctx, bson.M{"owner_id": nil}), bson.M{"$set": bson.M{"owner_id": subject}})
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
@@ -0,0 +1,177 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/db/mongodb"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/models"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/repository"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/tenant"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// GenerationRepository implements the GenerationRepository interface
+type GenerationRepository struct {
+	db *mongodb.DBConnection
+}
+
+// NewGenerationRepository creates a new MongoDB generation repository
+func NewGenerationRepository(db *mongodb.DBConnection) repository.GenerationRepository {
+	return &GenerationRepository{db: db}
+}
+
+// Create inserts a new generation into the database
+func (r *GenerationRepository) Create(ctx context.Context, generation *models.Generation) error {
+	generation.TenantID = tenant.FromContext(ctx)
+	_, err := r.db.Generations().InsertOne(ctx, generation)
+	return err
+}
+
+// FindByID retrieves a generation by its ID
+func (r *GenerationRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Generation, error) {
+	var generation models.Generation
+	err := r.db.Generations().FindOne(ctx, scoped(ctx, bson.M{"_id": id})).Decode(&generation)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil // Generation not found
+		}
+		return nil, err
+	}
+	return &generation, nil
+}
+
+// FindByChatID retrieves the generations of a chat, newest first.
+// An empty status matches every status.
+func (r *GenerationRepository) FindByChatID(ctx context.Context, chatID primitive.ObjectID, status models.GenerationStatus, limit int) ([]*models.Generation, error) {
+	filter := bson.M{"chat_id": chatID}
+	if status != "" {
+		filter["status"] = status
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "started_at", Value: -1}}).
+		SetLimit(int64(limit))
+
+	cursor, err := r.db.Generations().Find(ctx, scoped(ctx, filter), opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var generations []*models.Generation
+	if err := cursor.All(ctx, &generations); err != nil {
+		return nil, err
+	}
+	return generations, nil
+}
+
+// Checkpoint saves the output of a running generation so far
+func (r *GenerationRepository) Checkpoint(ctx context.Context, id primitive.ObjectID, content string, at time.Time) (bool, error) {
+	filter := scoped(ctx, bson.M{"_id": id, "status": models.GenerationRunning})
+	update := bson.M{"$set": bson.M{"content": content, "updated_at": at}}
+
+	result, err := r.db.Generations().UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
+
+// Finish ends a running generation with its final output
+func (r *GenerationRepository) Finish(ctx context.Context, id primitive.ObjectID, status models.GenerationStatus, content, errMsg string, at time.Time) (bool, error) {
+	filter := scoped(ctx, bson.M{"_id": id, "status": models.GenerationRunning})
+	set := bson.M{
+		"status":      status,
+		"content":     content,
+		"updated_at":  at,
+		"finished_at": at,
+	}
+	if errMsg != "" {
+		set["error"] = errMsg
+	}
+
+	result, err := r.db.Generations().UpdateOne(ctx, filter, bson.M{"$set": set})
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
+
+// Transition changes the status of a generation that is in status from
+func (r *GenerationRepository) Transition(ctx context.Context, id primitive.ObjectID, from, to models.GenerationStatus) (bool, error) {
+	filter := scoped(ctx, bson.M{"_id": id, "status": from})
+	update := bson.M{"$set": bson.M{"status": to, "updated_at": time.Now()}}
+
+	result, err := r.db.Generations().UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
+
+// InterruptStale marks running generations of every tenant that have not
+// checkpointed since cutoff as interrupted, and returns them
+func (r *GenerationRepository) InterruptStale(ctx context.Context, cutoff time.Time, limit int) ([]*models.Generation, error) {
+	filter := bson.M{
+		"status":     models.GenerationRunning,
+		"updated_at": bson.M{"$lt": cutoff},
+	}
+
+	cursor, err := r.db.Generations().Find(ctx, filter,
+		options.Find().SetLimit(int64(limit)).SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var stale []struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &stale); err != nil {
+		return nil, err
+	}
+
+	// Mark each one separately, so that a generation that checkpoints in the
+	// meantime is left alone
+	now := time.Now()
+	update := bson.M{"$set": bson.M{
+		"status":      models.GenerationInterrupted,
+		"finished_at": now,
+	}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var interrupted []*models.Generation
+	for _, doc := range stale {
+		filter := bson.M{
+			"_id":        doc.ID,
+			"status":     models.GenerationRunning,
+			"updated_at": bson.M{"$lt": cutoff},
+		}
+
+		var generation models.Generation
+		err := r.db.Generations().FindOneAndUpdate(ctx, filter, update, opts).Decode(&generation)
+		if err != nil {
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				continue
+			}
+			return interrupted, err
+		}
+		interrupted = append(interrupted, &generation)
+	}
+
+	return interrupted, nil
+}
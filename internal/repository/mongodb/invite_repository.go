@@ -0,0 +1,92 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/db/mongodb"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/models"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/repository"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/tenant"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// InviteRepository implements the InviteRepository interface
+type InviteRepository struct {
+	db *mongodb.DBConnection
+}
+
+// NewInviteRepository creates a new MongoDB chat invite repository
+func NewInviteRepository(db *mongodb.DBConnection) repository.InviteRepository {
+	return &InviteRepository{db: db}
+}
+
+// Create inserts a new invite into the database
+func (r *InviteRepository) Create(ctx context.Context, invite *models.ChatInvite) error {
+	invite.TenantID = tenant.FromContext(ctx)
+	_, err := r.db.Invites().InsertOne(ctx, invite)
+	return err
+}
+
+// FindByCodeHash retrieves an unexpired invite by the hash of its code
+func (r *InviteRepository) FindByCodeHash(ctx context.Context, codeHash string) (*models.ChatInvite, error) {
+	var invite models.ChatInvite
+	filter := scoped(ctx, bson.M{
+		"code_hash":  codeHash,
+		"expires_at": bson.M{"$gt": time.Now()},
+	})
+	err := r.db.Invites().FindOne(ctx, filter).Decode(&invite)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil // Invite not found or expired
+		}
+		return nil, err
+	}
+	return &invite, nil
+}
+
+// FindByChatID retrieves the unexpired invites of a chat, newest first
+func (r *InviteRepository) FindByChatID(ctx context.Context, chatID primitive.ObjectID) ([]*models.ChatInvite, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	filter := scoped(ctx, bson.M{
+		"chat_id":    chatID,
+		"expires_at": bson.M{"$gt": time.Now()},
+	})
+
+	cursor, err := r.db.Invites().Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var invites []*models.ChatInvite
+	if err := cursor.All(ctx, &invites); err != nil {
+		return nil, err
+	}
+	return invites, nil
+}
+
+// Delete revokes an invite of a chat
+func (r *InviteRepository) Delete(ctx context.Context, chatID, id primitive.ObjectID) error {
+	result, err := r.db.Invites().DeleteOne(ctx, scoped(ctx, bson.M{"_id": id, "chat_id": chatID}))
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
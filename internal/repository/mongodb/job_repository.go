@@ -0,0 +1,229 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/db/mongodb"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/models"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/repository"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// JobRepository implements the JobRepository interface
+type JobRepository struct {
+	db *mongodb.DBConnection
+}
+
+// NewJobRepository creates a new MongoDB job repository
+func NewJobRepository(db *mongodb.DBConnection) repository.JobRepository {
+	return &JobRepository{db: db}
+}
+
+// Create inserts a new job. It reports false, without an error, when a job
+// with the same unique key already exists.
+func (r *JobRepository) Create(ctx context.Context, job *models.Job) (bool, error) {
+	_, err := r.db.Jobs().InsertOne(ctx, job)
+	if err != nil {
+		if job.UniqueKey != "" && mongo.IsDuplicateKeyError(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// FindByID retrieves a job by its ID
+func (r *JobRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Job, error) {
+	var job models.Job
+	err := r.db.Jobs().FindOne(ctx, bson.M{"_id": id}).Decode(&job)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil // Job not found
+		}
+		return nil, err
+	}
+	return &job, nil
+}
+
+// FindAll retrieves jobs matching filter, newest first
+func (r *JobRepository) FindAll(ctx context.Context, filter repository.JobFilter, limit, offset int) ([]*models.Job, error) {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip(int64(offset)).
+		SetLimit(int64(limit))
+
+	cursor, err := r.db.Jobs().Find(ctx, jobFilter(filter), opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var jobs []*models.Job
+	if err := cursor.All(ctx, &jobs); err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// CountAll counts jobs matching filter
+func (r *JobRepository) CountAll(ctx context.Context, filter repository.JobFilter) (int64, error) {
+	return r.db.Jobs().CountDocuments(ctx, jobFilter(filter))
+}
+
+// jobFilter builds the query of a job listing
+func jobFilter(filter repository.JobFilter) bson.M {
+	query := bson.M{}
+	if filter.Status != "" {
+		query["status"] = filter.Status
+	}
+	if filter.Type != "" {
+		query["type"] = filter.Type
+	}
+	if filter.TenantID != "" {
+		query["tenant_id"] = filter.TenantID
+	}
+	return query
+}
+
+// Claim atomically takes the visible job of one of types that is due the
+// longest, including running jobs whose lease expired, and leases it to
+// workerID until leaseUntil. It returns nil when no job is visible.
+func (r *JobRepository) Claim(ctx context.Context, types []string, workerID string, now, leaseUntil time.Time) (*models.Job, error) {
+	filter := bson.M{
+		"status":     bson.M{"$in": []models.JobStatus{models.JobPending, models.JobRunning}},
+		"type":       bson.M{"$in": types},
+		"visible_at": bson.M{"$lte": now},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"status":     models.JobRunning,
+			"locked_by":  workerID,
+			"visible_at": leaseUntil,
+			"started_at": now,
+			"updated_at": now,
+		},
+		"$inc": bson.M{"attempts": 1},
+	}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "visible_at", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	var job models.Job
+	err := r.db.Jobs().FindOneAndUpdate(ctx, filter, update, opts).Decode(&job)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil // No job is due
+		}
+		return nil, err
+	}
+	return &job, nil
+}
+
+// Extend renews the lease of a claimed job
+func (r *JobRepository) Extend(ctx context.Context, id primitive.ObjectID, workerID string, leaseUntil time.Time) (bool, error) {
+	return r.updateClaimed(ctx, id, workerID, bson.M{
+		"$set": bson.M{"visible_at": leaseUntil, "updated_at": time.Now()},
+	})
+}
+
+// Complete marks a claimed job succeeded; MongoDB removes it after expiresAt
+func (r *JobRepository) Complete(ctx context.Context, id primitive.ObjectID, workerID string, at, expiresAt time.Time) (bool, error) {
+	return r.updateClaimed(ctx, id, workerID, bson.M{
+		"$set": bson.M{
+			"status":      models.JobSucceeded,
+			"finished_at": at,
+			"expires_at":  expiresAt,
+			"updated_at":  at,
+		},
+		"$unset": bson.M{"locked_by": "", "last_error": ""},
+	})
+}
+
+// Retry returns a failed claimed job to the queue, to become visible again at visibleAt
+func (r *JobRepository) Retry(ctx context.Context, id primitive.ObjectID, workerID, errMsg string, visibleAt time.Time) (bool, error) {
+	return r.updateClaimed(ctx, id, workerID, bson.M{
+		"$set": bson.M{
+			"status":     models.JobPending,
+			"visible_at": visibleAt,
+			"last_error": errMsg,
+			"updated_at": time.Now(),
+		},
+		"$unset": bson.M{"locked_by": ""},
+	})
+}
+
+// Bury moves a claimed job that failed for the last time to the dead letters
+func (r *JobRepository) Bury(ctx context.Context, id primitive.ObjectID, workerID, errMsg string, at time.Time) (bool, error) {
+	return r.updateClaimed(ctx, id, workerID, bson.M{
+		"$set": bson.M{
+			"status":      models.JobDead,
+			"last_error":  errMsg,
+			"finished_at": at,
+			"updated_at":  at,
+		},
+		"$unset": bson.M{"locked_by": ""},
+	})
+}
+
+// Release returns a claimed job to the queue without counting the attempt,
+// for work interrupted by shutdown
+func (r *JobRepository) Release(ctx context.Context, id primitive.ObjectID, workerID string) (bool, error) {
+	now := time.Now()
+	return r.updateClaimed(ctx, id, workerID, bson.M{
+		"$set": bson.M{
+			"status":     models.JobPending,
+			"visible_at": now,
+			"updated_at": now,
+		},
+		"$inc":   bson.M{"attempts": -1},
+		"$unset": bson.M{"locked_by": ""},
+	})
+}
+
+// updateClaimed applies update to a job that is still claimed by workerID
+func (r *JobRepository) updateClaimed(ctx context.Context, id primitive.ObjectID, workerID string, update bson.M) (bool, error) {
+	filter := bson.M{"_id": id, "status": models.JobRunning, "locked_by": workerID}
+
+	result, err := r.db.Jobs().UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
+
+// Requeue makes a dead or pending job visible at once, with its attempts reset
+func (r *JobRepository) Requeue(ctx context.Context, id primitive.ObjectID, at time.Time) (bool, error) {
+	filter := bson.M{
+		"_id":    id,
+		"status": bson.M{"$in": []models.JobStatus{models.JobDead, models.JobPending}},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"status":     models.JobPending,
+			"attempts":   0,
+			"visible_at": at,
+			"updated_at": at,
+		},
+		"$unset": bson.M{"finished_at": ""},
+	}
+
+	result, err := r.db.Jobs().UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
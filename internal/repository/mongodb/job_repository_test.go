@@ -0,0 +1,142 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package mongodb
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/db/mongodb"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// TestJobClaim checks that a claim takes the job due the longest, including
+// running jobs whose lease expired, and counts the attempt
+func TestJobClaim(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	mt.Run("claim", func(mt *mtest.T) {
+		id := primitive.NewObjectID()
+		mt.AddMockResponses(bson.D{
+			{Key: "ok", Value: 1},
+			{Key: "value", Value: bson.D{
+				{Key: "_id", Value: id},
+				{Key: "type", Value: "report"},
+				{Key: "status", Value: models.JobRunning},
+				{Key: "attempts", Value: 1},
+				{Key: "locked_by", Value: "worker-1"},
+			}},
+		})
+
+		now := time.Now()
+		job, err := NewJobRepository(mongodb.NewFromClient(mt.Client, testCollections)).
+			Claim(context.Background(), []string{"report"}, "worker-1", now, now.Add(time.Minute))
+		if err != nil {
+			mt.Fatalf("Claim() error = %v", err)
+		}
+		if job == nil || job.ID != id || job.LockedBy != "worker-1" {
+			mt.Fatalf("Claim() = %+v, want the job returned by the server", job)
+		}
+
+		evt := mt.GetStartedEvent()
+		if evt == nil || evt.CommandName != "findAndModify" {
+			mt.Fatalf("Claim() sent %v, want a findAndModify command", evt)
+		}
+
+		// Oldest due first
+		sort, err := evt.Command.LookupErr("sort")
+		if err != nil {
+			mt.Fatalf("Claim() does not sort: %s", evt.Command)
+		}
+		if keys, _ := sort.Document().Elements(); len(keys) != 1 || keys[0].Key() != "visible_at" || keys[0].Value().AsInt32() != 1 {
+			mt.Errorf("Claim() sorts by %s, want {visible_at: 1}", sort)
+		}
+
+		// Pending jobs that are due, and running jobs whose lease expired
+		statuses, err := evt.Command.LookupErr("query", "status", "$in")
+		if err != nil {
+			mt.Fatalf("Claim() does not filter on status: %s", evt.Command)
+		}
+		values, _ := statuses.Array().Values()
+		var got []string
+		for _, value := range values {
+			got = append(got, value.StringValue())
+		}
+		if len(got) != 2 || got[0] != string(models.JobPending) || got[1] != string(models.JobRunning) {
+			mt.Errorf("Claim() takes jobs in status %v, want pending and running", got)
+		}
+		if _, err := evt.Command.LookupErr("query", "visible_at", "$lte"); err != nil {
+			mt.Errorf("Claim() takes jobs that are not due yet: %s", evt.Command)
+		}
+
+		if inc, err := evt.Command.LookupErr("update", "$inc", "attempts"); err != nil || inc.AsInt64() != 1 {
+			mt.Errorf("Claim() does not count the attempt: %s", evt.Command)
+		}
+		if lockedBy, err := evt.Command.LookupErr("update", "$set", "locked_by"); err != nil || lockedBy.StringValue() != "worker-1" {
+			mt.Errorf("Claim() does not lock the job to the worker: %s", evt.Command)
+		}
+	})
+}
+
+// TestJobUpdatesRequireLease checks that every update of a claimed job only
+// applies while the worker still holds it, so that a worker which lost its
+// lease cannot overwrite the outcome of the worker that reclaimed the job
+func TestJobUpdatesRequireLease(t *testing.T) {
+	id := primitive.NewObjectID()
+	now := time.Now()
+
+	calls := map[string]func(repo *JobRepository) (bool, error){
+		"Extend": func(repo *JobRepository) (bool, error) {
+			return repo.Extend(context.Background(), id, "worker-1", now)
+		},
+		"Complete": func(repo *JobRepository) (bool, error) {
+			return repo.Complete(context.Background(), id, "worker-1", now, now)
+		},
+		"Retry": func(repo *JobRepository) (bool, error) {
+			return repo.Retry(context.Background(), id, "worker-1", "boom", now)
+		},
+		"Bury": func(repo *JobRepository) (bool, error) {
+			return repo.Bury(context.Background(), id, "worker-1", "boom", now)
+		},
+		"Release": func(repo *JobRepository) (bool, error) {
+			return repo.Release(context.Background(), id, "worker-1")
+		},
+	}
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	for name, call := range calls {
+		mt.Run(name, func(mt *mtest.T) {
+			// The job is held by another worker, so nothing matches
+			mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 0}, {Key: "nModified", Value: 0}})
+
+			applied, err := call(&JobRepository{db: mongodb.NewFromClient(mt.Client, testCollections)})
+			if err != nil {
+				mt.Fatalf("%s() error = %v", name, err)
+			}
+			if applied {
+				mt.Errorf("%s() = true for a job held by another worker", name)
+			}
+
+			evt := mt.GetStartedEvent()
+			if evt == nil || evt.CommandName != "update" {
+				mt.Fatalf("%s() sent %v, want an update command", name, evt)
+			}
+			if lockedBy, err := evt.Command.LookupErr("updates", "0", "q", "locked_by"); err != nil || lockedBy.StringValue() != "worker-1" {
+				mt.Errorf("%s() does not require the lease of the worker: %s", name, evt.Command)
+			}
+			if status, err := evt.Command.LookupErr("updates", "0", "q", "status"); err != nil || status.StringValue() != string(models.JobRunning) {
+				mt.Errorf("%s() does not require the job to be running: %s", name, evt.Command)
+			}
+		})
+	}
+}
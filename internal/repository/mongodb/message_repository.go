@@ -12,10 +12,12 @@ package mongodb
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/db/mongodb"
 	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/models"
 	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/repository"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/tenant"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -34,6 +36,7 @@ func NewMessageRepository(db *mongodb.DBConnection) repository.MessageRepository
 
 // Create inserts a new message into the database
 func (r *MessageRepository) Create(ctx context.Context, message *models.Message) error {
+	message.TenantID = tenant.FromContext(ctx)
 	_, err := r.db.Messages().InsertOne(ctx, message)
 	return err
 }
@@ -41,7 +44,7 @@ func (r *MessageRepository) Create(ctx context.Context, message *models.Message)
 // FindByID retrieves a message by its ID
 func (r *MessageRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Message, error) {
 	var message models.Message
-	err := r.db.Messages().FindOne(ctx, bson.M{"_id": id}).Decode(&message)
+	err := r.db.Messages().FindOne(ctx, scoped(ctx, notDeleted(bson.M{"_id": id}))).Decode(&message)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
 			return nil, nil // Message not found
@@ -54,11 +57,11 @@ func (r *MessageRepository) FindByID(ctx context.Context, id primitive.ObjectID)
 // FindByChatID retrieves messages for a specific chat with pagination
 func (r *MessageRepository) FindByChatID(ctx context.Context, chatID primitive.ObjectID, limit, offset int) ([]*models.Message, error) {
 	opts := options.Find().
-		SetSort(bson.D{{"created_at", -1}}). // Sort by created_at descending (newest first)
+		SetSort(bson.D{{Key: "created_at", Value: -1}}). // Sort by created_at descending (newest first)
 		SetLimit(int64(limit)).
 		SetSkip(int64(offset))
 
-	cursor, err := r.db.Messages().Find(ctx, bson.M{"chat_id": chatID}, opts)
+	cursor, err := r.db.Messages().Find(ctx, scoped(ctx, notDeleted(bson.M{"chat_id": chatID})), opts)
 	if err != nil {
 		return nil, err
 	}
@@ -71,32 +74,219 @@ func (r *MessageRepository) FindByChatID(ctx context.Context, chatID primitive.O
 
 	// Reverse the order to have chronological order (oldest first)
 	// This is often better for displaying chat messages
-	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
-		messages[i], messages[j] = messages[j], messages[i]
-	}
+	reverseMessages(messages)
 
 	return messages, nil
 }
 
+// messageKeyset paginates messages in chronological order, starting from the newest page
+var messageKeyset = keyset{field: "created_at", ascending: true, fromEnd: true}
+
+// FindByChatIDCursor retrieves messages for a specific chat using keyset pagination.
+// Messages are always returned in chronological order (oldest first).
+func (r *MessageRepository) FindByChatIDCursor(ctx context.Context, chatID primitive.ObjectID, query repository.CursorQuery) ([]*models.Message, repository.PageInfo, error) {
+	filter, opts, reverse, err := messageKeyset.build(query)
+	if err != nil {
+		return nil, repository.PageInfo{}, err
+	}
+
+	cursor, err := r.db.Messages().Find(ctx, mergeFilters(scoped(ctx, notDeleted(bson.M{"chat_id": chatID})), filter), opts)
+	if err != nil {
+		return nil, repository.PageInfo{}, err
+	}
+	defer cursor.Close(ctx)
+
+	var messages []*models.Message
+	if err := cursor.All(ctx, &messages); err != nil {
+		return nil, repository.PageInfo{}, err
+	}
+
+	count, info := messageKeyset.pageInfo(query, len(messages))
+	messages = messages[:count]
+	if reverse {
+		reverseMessages(messages)
+	}
+
+	return messages, info, nil
+}
+
 // CountByChatID counts the number of messages in a chat
 func (r *MessageRepository) CountByChatID(ctx context.Context, chatID primitive.ObjectID) (int64, error) {
-	return r.db.Messages().CountDocuments(ctx, bson.M{"chat_id": chatID})
+	return r.db.Messages().CountDocuments(ctx, scoped(ctx, notDeleted(bson.M{"chat_id": chatID})))
+}
+
+// CountSince counts the messages created since the given time, including trashed ones
+func (r *MessageRepository) CountSince(ctx context.Context, since time.Time) (int64, error) {
+	return r.db.Messages().CountDocuments(ctx, scoped(ctx, bson.M{"created_at": bson.M{"$gte": since}}))
 }
 
-// Delete removes a message
+// Delete moves a message to the trash (soft delete)
 func (r *MessageRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
-	result, err := r.db.Messages().DeleteOne(ctx, bson.M{"_id": id})
+	update := bson.M{
+		"$set": bson.M{"deleted_at": time.Now()},
+	}
+	result, err := r.db.Messages().UpdateOne(ctx, scoped(ctx, notDeleted(bson.M{"_id": id})), update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// DeleteByChatID moves all messages of a chat to the trash along with the chat.
+// Messages deleted this way are restored together with the chat.
+func (r *MessageRepository) DeleteByChatID(ctx context.Context, chatID primitive.ObjectID, deletedAt time.Time) error {
+	update := bson.M{
+		"$set": bson.M{
+			"deleted_at":        deletedAt,
+			"deleted_with_chat": true,
+		},
+	}
+	_, err := r.db.Messages().UpdateMany(ctx, scoped(ctx, notDeleted(bson.M{"chat_id": chatID})), update)
+	return err
+}
+
+// FindDeletedByID retrieves a message from the trash by its ID
+func (r *MessageRepository) FindDeletedByID(ctx context.Context, id primitive.ObjectID) (*models.Message, error) {
+	var message models.Message
+	err := r.db.Messages().FindOne(ctx, scoped(ctx, onlyDeleted(bson.M{"_id": id}))).Decode(&message)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil // Message not in trash
+		}
+		return nil, err
+	}
+	return &message, nil
+}
+
+// FindDeleted retrieves individually deleted messages, most recently deleted first.
+// Messages that were deleted together with their chat are listed through the chat.
+// A nil chatIDs lists messages of all chats.
+func (r *MessageRepository) FindDeleted(ctx context.Context, chatIDs []primitive.ObjectID, limit, offset int) ([]*models.Message, error) {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "deleted_at", Value: -1}, {Key: "_id", Value: -1}}).
+		SetLimit(int64(limit)).
+		SetSkip(int64(offset))
+
+	cursor, err := r.db.Messages().Find(ctx, scoped(ctx, deletedMessagesFilter(chatIDs)), opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var messages []*models.Message
+	if err := cursor.All(ctx, &messages); err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// CountDeleted returns the number of individually deleted messages in the trash
+func (r *MessageRepository) CountDeleted(ctx context.Context, chatIDs []primitive.ObjectID) (int64, error) {
+	return r.db.Messages().CountDocuments(ctx, scoped(ctx, deletedMessagesFilter(chatIDs)))
+}
+
+// deletedMessagesFilter matches individually deleted messages, optionally restricted to some chats
+func deletedMessagesFilter(chatIDs []primitive.ObjectID) bson.M {
+	filter := bson.M{"deleted_with_chat": bson.M{"$ne": true}}
+	if chatIDs != nil {
+		filter["chat_id"] = bson.M{"$in": chatIDs}
+	}
+	return onlyDeleted(filter)
+}
+
+// Restore moves a message out of the trash
+func (r *MessageRepository) Restore(ctx context.Context, id primitive.ObjectID) error {
+	update := bson.M{
+		"$unset": bson.M{"deleted_at": "", "deleted_with_chat": ""},
+	}
+	result, err := r.db.Messages().UpdateOne(ctx, scoped(ctx, onlyDeleted(bson.M{"_id": id})), update)
 	if err != nil {
 		return err
 	}
-	if result.DeletedCount == 0 {
+	if result.MatchedCount == 0 {
 		return mongo.ErrNoDocuments
 	}
 	return nil
 }
 
-// DeleteByChatID removes all messages for a specific chat
-func (r *MessageRepository) DeleteByChatID(ctx context.Context, chatID primitive.ObjectID) error {
-	_, err := r.db.Messages().DeleteMany(ctx, bson.M{"chat_id": chatID})
+// RestoreByChatID restores the messages that were deleted together with a chat
+func (r *MessageRepository) RestoreByChatID(ctx context.Context, chatID primitive.ObjectID) error {
+	update := bson.M{
+		"$unset": bson.M{"deleted_at": "", "deleted_with_chat": ""},
+	}
+	_, err := r.db.Messages().UpdateMany(ctx, scoped(ctx, onlyDeleted(bson.M{"chat_id": chatID, "deleted_with_chat": true})), update)
 	return err
 }
+
+// HardDeleteByChatIDs permanently removes all messages of the given chats, deleted or not, in any tenant
+func (r *MessageRepository) HardDeleteByChatIDs(ctx context.Context, chatIDs []primitive.ObjectID) (int64, error) {
+	result, err := r.db.Messages().DeleteMany(ctx, bson.M{"chat_id": bson.M{"$in": chatIDs}})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+// PurgeDeletedBefore permanently removes messages of any tenant that were moved to the trash before the cutoff
+func (r *MessageRepository) PurgeDeletedBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	result, err := r.db.Messages().DeleteMany(ctx, bson.M{"deleted_at": bson.M{"$lt": cutoff}})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+// StatsByChatIDs computes message statistics for the given chats.
+// Messages trashed together with their chat still count towards it, since
+// they come back when the chat is restored; individually trashed ones do not.
+// Chats without any counted messages are absent from the result.
+func (r *MessageRepository) StatsByChatIDs(ctx context.Context, chatIDs []primitive.ObjectID) (map[primitive.ObjectID]repository.MessageStats, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: scoped(ctx, bson.M{
+			"chat_id": bson.M{"$in": chatIDs},
+			"$or": bson.A{
+				bson.M{"deleted_at": nil},
+				bson.M{"deleted_with_chat": true},
+			},
+		})}},
+		{{Key: "$group", Value: bson.M{
+			"_id":             "$chat_id",
+			"count":           bson.M{"$sum": 1},
+			"last_message_at": bson.M{"$max": "$created_at"},
+		}}},
+	}
+
+	cursor, err := r.db.Messages().Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var rows []struct {
+		ChatID        primitive.ObjectID `bson:"_id"`
+		Count         int                `bson:"count"`
+		LastMessageAt time.Time          `bson:"last_message_at"`
+	}
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, err
+	}
+
+	stats := make(map[primitive.ObjectID]repository.MessageStats, len(rows))
+	for _, row := range rows {
+		stats[row.ChatID] = repository.MessageStats{
+			Count:         row.Count,
+			LastMessageAt: row.LastMessageAt,
+		}
+	}
+	return stats, nil
+}
+
+// reverseMessages reverses a slice of messages in place
+func reverseMessages(messages []*models.Message) {
+	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+		messages[i], messages[j] = messages[j], messages[i]
+	}
+}
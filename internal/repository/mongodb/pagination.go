@@ -0,0 +1,159 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package mongodb
+
+import (
+	"time"
+
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/repository"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// keyset describes how a collection is paginated by (field, _id)
+type keyset struct {
+	field     string // Sort field
+	ascending bool   // Order in which results are returned to the caller
+	fromEnd   bool   // Without a cursor, return the last page instead of the first
+	nullable  bool   // The sort field may be missing on some documents
+	text      bool   // The sort field holds strings; otherwise it holds times
+}
+
+// build returns the filter, find options and whether results must be reversed
+// after reading them for the given cursor query
+func (k keyset) build(q repository.CursorQuery) (bson.M, *options.FindOptions, bool, error) {
+	if q.Before != nil && q.After != nil {
+		return nil, nil, false, repository.ErrInvalidCursor
+	}
+
+	filter := bson.M{}
+	readAscending := k.ascending
+	reverse := false
+
+	switch {
+	case q.After != nil:
+		if !k.accepts(q.After) {
+			return nil, nil, false, repository.ErrInvalidCursor
+		}
+		filter = k.compare(q.After, k.ascending)
+
+	case q.Before != nil:
+		if !k.accepts(q.Before) {
+			return nil, nil, false, repository.ErrInvalidCursor
+		}
+		filter = k.compare(q.Before, !k.ascending)
+		readAscending = !k.ascending
+		reverse = true
+
+	case k.fromEnd:
+		readAscending = !k.ascending
+		reverse = true
+	}
+
+	direction := -1
+	if readAscending {
+		direction = 1
+	}
+
+	// Read one extra document to find out whether more results exist
+	opts := options.Find().
+		SetSort(bson.D{{Key: k.field, Value: direction}, {Key: "_id", Value: direction}}).
+		SetLimit(int64(q.Limit + 1))
+
+	return filter, opts, reverse, nil
+}
+
+// accepts reports whether a cursor was created for this sort order and holds
+// a value of the sort field's type. Cursors come from clients, so their
+// values must never reach a query as anything but a plain value.
+func (k keyset) accepts(c *repository.Cursor) bool {
+	if c.Key != k.field || c.Ascending != k.ascending {
+		return false
+	}
+
+	switch c.Value.(type) {
+	case nil:
+		return k.nullable
+	case string:
+		return k.text
+	case time.Time:
+		return !k.text
+	default:
+		return false
+	}
+}
+
+// compare returns a filter matching documents positioned after the cursor
+// (greater = true) or before it (greater = false) in ascending (field, _id) order
+func (k keyset) compare(c *repository.Cursor, greater bool) bson.M {
+	op := "$lt"
+	if greater {
+		op = "$gt"
+	}
+
+	tieBreak := bson.M{k.field: c.Value, "_id": bson.M{op: c.ID}}
+
+	// Missing values sort before everything else, so they need special handling
+	if c.Value == nil {
+		if greater {
+			return bson.M{"$or": bson.A{
+				bson.M{k.field: bson.M{"$ne": nil}},
+				tieBreak,
+			}}
+		}
+		return tieBreak
+	}
+
+	clauses := bson.A{
+		bson.M{k.field: bson.M{op: c.Value}},
+		tieBreak,
+	}
+	if !greater && k.nullable {
+		clauses = append(clauses, bson.M{k.field: nil})
+	}
+
+	return bson.M{"$or": clauses}
+}
+
+// pageInfo trims the extra document read by build and works out
+// in which directions more results are available
+func (k keyset) pageInfo(q repository.CursorQuery, count int) (int, repository.PageInfo) {
+	hasMore := count > q.Limit
+	if hasMore {
+		count = q.Limit
+	}
+
+	var info repository.PageInfo
+	switch {
+	case q.After != nil:
+		info.HasBefore = true
+		info.HasAfter = hasMore
+	case q.Before != nil:
+		info.HasBefore = hasMore
+		info.HasAfter = true
+	case k.fromEnd:
+		info.HasBefore = hasMore
+	default:
+		info.HasAfter = hasMore
+	}
+
+	return count, info
+}
+
+// mergeFilters combines two filters with $and, skipping empty ones
+func mergeFilters(a, b bson.M) bson.M {
+	if len(a) == 0 {
+		return b
+	}
+	if len(b) == 0 {
+		return a
+	}
+	return bson.M{"$and": bson.A{a, b}}
+}
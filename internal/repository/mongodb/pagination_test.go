@@ -0,0 +1,58 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package mongodb
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TestKeysetRejectsForeignCursors checks that a cursor is only accepted for
+// the sort order it was issued for and with a value of the sort field's type
+func TestKeysetRejectsForeignCursors(t *testing.T) {
+	id := primitive.NewObjectID()
+	now := time.Now()
+
+	updated := keyset{field: "updated_at"}
+	lastMessage := keyset{field: "last_message_at", nullable: true}
+	title := keyset{field: "title", ascending: true, text: true}
+
+	tests := []struct {
+		name   string
+		keys   keyset
+		cursor *repository.Cursor
+		valid  bool
+	}{
+		{"time for time field", updated, repository.NewCursor("updated_at", false, now, id), true},
+		{"string for time field", updated, repository.NewCursor("updated_at", false, "now", id), false},
+		{"null for time field", updated, repository.NewCursor("updated_at", false, nil, id), false},
+		{"null for nullable field", lastMessage, repository.NewCursor("last_message_at", false, nil, id), true},
+		{"string for title", title, repository.NewCursor("title", true, "chat", id), true},
+		{"time for title", title, repository.NewCursor("title", true, now, id), false},
+		{"other field", updated, repository.NewCursor("created_at", false, now, id), false},
+		{"other direction", updated, repository.NewCursor("updated_at", true, now, id), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, _, err := tt.keys.build(repository.CursorQuery{After: tt.cursor, Limit: 10})
+			if tt.valid && err != nil {
+				t.Errorf("build() error = %v", err)
+			}
+			if !tt.valid && !errors.Is(err, repository.ErrInvalidCursor) {
+				t.Errorf("build() error = %v, want %v", err, repository.ErrInvalidCursor)
+			}
+		})
+	}
+}
@@ -0,0 +1,25 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package mongodb
+
+import "go.mongodb.org/mongo-driver/bson"
+
+// notDeleted restricts a filter to documents that are not soft-deleted.
+// Every query on chats and messages goes through it unless it targets the trash.
+func notDeleted(filter bson.M) bson.M {
+	filter["deleted_at"] = nil
+	return filter
+}
+
+// onlyDeleted restricts a filter to soft-deleted documents
+func onlyDeleted(filter bson.M) bson.M {
+	filter["deleted_at"] = bson.M{"$ne": nil}
+	return filter
+}
@@ -0,0 +1,26 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package mongodb
+
+import (
+	"context"
+
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/tenant"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// scoped restricts a filter to documents of the tenant in ctx.
+// Every query on chats, messages, folders and invites goes through it; the
+// only exceptions are the trash purge methods and the sweep of stale
+// generations, which run across tenants.
+func scoped(ctx context.Context, filter bson.M) bson.M {
+	filter["tenant_id"] = tenant.FromContext(ctx)
+	return filter
+}
@@ -0,0 +1,201 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package mongodb
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/config"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/db/mongodb"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/models"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/repository"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/tenant"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/event"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// testTenant is the tenant the repositories are called in
+const testTenant = "acme"
+
+// testCollections names the collections of the mock database
+var testCollections = &config.MongoDBConfig{
+	Database:              "chat_test",
+	CollectionChats:       "chats",
+	CollectionMessages:    "messages",
+	CollectionFolders:     "folders",
+	CollectionAPIKeys:     "api_keys",
+	CollectionInvites:     "invites",
+	CollectionGenerations: "generations",
+	CollectionJobs:        "jobs",
+	CollectionWebhooks:    "webhooks",
+	CollectionDeliveries:  "webhook_deliveries",
+	CollectionUsage:       "usage",
+}
+
+// TestRepositoriesScopeToTenant checks that every command the repositories
+// send to MongoDB is restricted to the tenant of the context
+func TestRepositoriesScopeToTenant(t *testing.T) {
+	id := primitive.NewObjectID()
+	active := true
+
+	calls := map[string]func(ctx context.Context, db *mongodb.DBConnection){
+		"chats.Create": func(ctx context.Context, db *mongodb.DBConnection) {
+			_ = NewChatRepository(db).Create(ctx, &models.Chat{ID: id})
+		},
+		"chats.FindByID": func(ctx context.Context, db *mongodb.DBConnection) {
+			_, _ = NewChatRepository(db).FindByID(ctx, id)
+		},
+		"chats.FindAll": func(ctx context.Context, db *mongodb.DBConnection) {
+			opts := repository.ChatListOptions{Filter: repository.ChatFilter{Active: &active}, Sort: repository.DefaultChatSort()}
+			_, _ = NewChatRepository(db).FindAll(ctx, opts, 10, 0)
+		},
+		"chats.FindAllByCursor": func(ctx context.Context, db *mongodb.DBConnection) {
+			opts := repository.ChatListOptions{Sort: repository.DefaultChatSort()}
+			_, _, _ = NewChatRepository(db).FindAllByCursor(ctx, opts, repository.CursorQuery{Limit: 10})
+		},
+		"chats.CountAll": func(ctx context.Context, db *mongodb.DBConnection) {
+			_, _ = NewChatRepository(db).CountAll(ctx, repository.ChatFilter{})
+		},
+		"chats.Update": func(ctx context.Context, db *mongodb.DBConnection) {
+			_ = NewChatRepository(db).Update(ctx, &models.Chat{ID: id})
+		},
+		"chats.Delete": func(ctx context.Context, db *mongodb.DBConnection) {
+			_ = NewChatRepository(db).Delete(ctx, id, time.Now())
+		},
+		"chats.SetMember": func(ctx context.Context, db *mongodb.DBConnection) {
+			_ = NewChatRepository(db).SetMember(ctx, id, models.ChatMember{Subject: "bob", Role: models.ChatRoleViewer})
+		},
+		"chats.RemoveMember": func(ctx context.Context, db *mongodb.DBConnection) {
+			_ = NewChatRepository(db).RemoveMember(ctx, id, "bob")
+		},
+		"chats.UpdateTags": func(ctx context.Context, db *mongodb.DBConnection) {
+			_, _ = NewChatRepository(db).UpdateTags(ctx, []primitive.ObjectID{id}, []string{"a"}, nil)
+		},
+		"messages.Create": func(ctx context.Context, db *mongodb.DBConnection) {
+			_ = NewMessageRepository(db).Create(ctx, &models.Message{ID: id, ChatID: id})
+		},
+		"messages.FindByID": func(ctx context.Context, db *mongodb.DBConnection) {
+			_, _ = NewMessageRepository(db).FindByID(ctx, id)
+		},
+		"messages.FindByChatID": func(ctx context.Context, db *mongodb.DBConnection) {
+			_, _ = NewMessageRepository(db).FindByChatID(ctx, id, 10, 0)
+		},
+		"messages.CountSince": func(ctx context.Context, db *mongodb.DBConnection) {
+			_, _ = NewMessageRepository(db).CountSince(ctx, time.Now())
+		},
+		"messages.Delete": func(ctx context.Context, db *mongodb.DBConnection) {
+			_ = NewMessageRepository(db).Delete(ctx, id)
+		},
+		"folders.FindAll": func(ctx context.Context, db *mongodb.DBConnection) {
+			_, _ = NewFolderRepository(db).FindAll(ctx, "alice")
+		},
+		"folders.Update": func(ctx context.Context, db *mongodb.DBConnection) {
+			_ = NewFolderRepository(db).Update(ctx, &models.Folder{ID: id})
+		},
+		"folders.Delete": func(ctx context.Context, db *mongodb.DBConnection) {
+			_ = NewFolderRepository(db).Delete(ctx, id)
+		},
+		"invites.FindByCodeHash": func(ctx context.Context, db *mongodb.DBConnection) {
+			_, _ = NewInviteRepository(db).FindByCodeHash(ctx, "hash")
+		},
+		"invites.Delete": func(ctx context.Context, db *mongodb.DBConnection) {
+			_ = NewInviteRepository(db).Delete(ctx, id, id)
+		},
+		"generations.FindByChatID": func(ctx context.Context, db *mongodb.DBConnection) {
+			_, _ = NewGenerationRepository(db).FindByChatID(ctx, id, "", 10)
+		},
+		"generations.Transition": func(ctx context.Context, db *mongodb.DBConnection) {
+			_, _ = NewGenerationRepository(db).Transition(ctx, id, models.GenerationInterrupted, models.GenerationDiscarded)
+		},
+		"webhooks.FindSubscribed": func(ctx context.Context, db *mongodb.DBConnection) {
+			_, _ = NewWebhookRepository(db).FindSubscribed(ctx, "complete")
+		},
+		"webhooks.Delete": func(ctx context.Context, db *mongodb.DBConnection) {
+			_ = NewWebhookRepository(db).Delete(ctx, id)
+		},
+		"usage.AddMessages": func(ctx context.Context, db *mongodb.DBConnection) {
+			_ = NewUsageRepository(db).AddMessages(ctx, time.Now(), 2)
+		},
+		"usage.CountMessages": func(ctx context.Context, db *mongodb.DBConnection) {
+			_, _ = NewUsageRepository(db).CountMessages(ctx, time.Now())
+		},
+		"usage.AddTokens": func(ctx context.Context, db *mongodb.DBConnection) {
+			_ = NewUsageRepository(db).AddTokens(ctx, time.Now(), 12, 3)
+		},
+		"usage.FindByDays": func(ctx context.Context, db *mongodb.DBConnection) {
+			_, _ = NewUsageRepository(db).FindByDays(ctx, time.Now().AddDate(0, 0, -7), time.Now())
+		},
+	}
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	for name, call := range calls {
+		mt.Run(name, func(mt *mtest.T) {
+			// The replies do not matter; only the commands sent are checked
+			call(tenant.WithTenant(context.Background(), testTenant), mongodb.NewFromClient(mt.Client, testCollections))
+
+			started := mt.GetAllStartedEvents()
+			if len(started) == 0 {
+				mt.Fatal("No command was sent")
+			}
+			for _, evt := range started {
+				if got := commandTenant(mt, evt); got != testTenant {
+					mt.Errorf("%s command is scoped to tenant %q, want %q: %s", evt.CommandName, got, testTenant, evt.Command)
+				}
+			}
+		})
+	}
+}
+
+// commandTenant returns the tenant_id a command filters on, or sets on the
+// documents it inserts
+func commandTenant(mt *mtest.T, evt *event.CommandStartedEvent) string {
+	var path []string
+	switch evt.CommandName {
+	case "find":
+		path = []string{"filter"}
+	case "aggregate":
+		path = []string{"pipeline", "0", "$match"}
+	case "update":
+		path = []string{"updates", "0", "q"}
+	case "delete":
+		path = []string{"deletes", "0", "q"}
+	case "findAndModify", "count", "distinct":
+		path = []string{"query"}
+	case "insert":
+		path = []string{"documents", "0"}
+	default:
+		mt.Fatalf("Unexpected %s command", evt.CommandName)
+	}
+
+	value, err := evt.Command.LookupErr(append(path, "tenant_id")...)
+	if err != nil {
+		return ""
+	}
+	tenantID, _ := value.StringValueOK()
+	return tenantID
+}
+
+// TestScoped checks that a tenant of the context replaces one set in the filter
+func TestScoped(t *testing.T) {
+	ctx := tenant.WithTenant(context.Background(), testTenant)
+
+	filter := scoped(ctx, bson.M{"_id": 1, "tenant_id": "other"})
+	if filter["tenant_id"] != testTenant {
+		t.Errorf("tenant_id = %v, want %q", filter["tenant_id"], testTenant)
+	}
+
+	if filter := scoped(context.Background(), bson.M{}); filter["tenant_id"] != tenant.DefaultID {
+		t.Errorf("tenant_id without a tenant = %v, want %q", filter["tenant_id"], tenant.DefaultID)
+	}
+}
@@ -0,0 +1,98 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/db/mongodb"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/models"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/repository"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// usageRetention is how long a daily counter is kept after its day starts.
+// Quotas only read the current day; token usage is kept for reporting.
+const usageRetention = 90 * 24 * time.Hour
+
+// UsageRepository implements the UsageRepository interface
+type UsageRepository struct {
+	db *mongodb.DBConnection
+}
+
+// NewUsageRepository creates a new MongoDB usage repository
+func NewUsageRepository(db *mongodb.DBConnection) repository.UsageRepository {
+	return &UsageRepository{db: db}
+}
+
+// AddMessages adds count messages to the counter of the tenant's day,
+// creating the counter on first use
+func (r *UsageRepository) AddMessages(ctx context.Context, day time.Time, count int) error {
+	return r.add(ctx, day, bson.M{"messages": count})
+}
+
+// AddTokens adds a completion and its tokens to the counters of the tenant's day
+func (r *UsageRepository) AddTokens(ctx context.Context, day time.Time, promptTokens, completionTokens int) error {
+	return r.add(ctx, day, bson.M{
+		"completions":       1,
+		"prompt_tokens":     promptTokens,
+		"completion_tokens": completionTokens,
+	})
+}
+
+// add increments counters of the tenant's day, creating them on first use
+func (r *UsageRepository) add(ctx context.Context, day time.Time, counters bson.M) error {
+	update := bson.M{
+		"$inc":         counters,
+		"$setOnInsert": bson.M{"expires_at": day.Add(usageRetention)},
+	}
+	opts := options.Update().SetUpsert(true)
+
+	_, err := r.db.Usage().UpdateOne(ctx, scoped(ctx, bson.M{"day": day}), update, opts)
+	return err
+}
+
+// FindByDays retrieves the tenant's counters of the days from from to to,
+// inclusive, oldest first. Days without usage are left out.
+func (r *UsageRepository) FindByDays(ctx context.Context, from, to time.Time) ([]*models.Usage, error) {
+	filter := scoped(ctx, bson.M{"day": bson.M{"$gte": from, "$lte": to}})
+	opts := options.Find().SetSort(bson.D{{Key: "day", Value: 1}})
+
+	cursor, err := r.db.Usage().Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	usage := []*models.Usage{}
+	if err := cursor.All(ctx, &usage); err != nil {
+		return nil, err
+	}
+	return usage, nil
+}
+
+// CountMessages returns the messages counted for the tenant's day
+func (r *UsageRepository) CountMessages(ctx context.Context, day time.Time) (int64, error) {
+	var usage struct {
+		Messages int64 `bson:"messages"`
+	}
+	err := r.db.Usage().FindOne(ctx, scoped(ctx, bson.M{"day": day})).Decode(&usage)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return usage.Messages, nil
+}
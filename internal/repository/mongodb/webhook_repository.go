@@ -0,0 +1,239 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/db/mongodb"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/models"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/repository"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/tenant"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// WebhookRepository implements the WebhookRepository interface
+type WebhookRepository struct {
+	db *mongodb.DBConnection
+}
+
+// NewWebhookRepository creates a new MongoDB webhook repository
+func NewWebhookRepository(db *mongodb.DBConnection) repository.WebhookRepository {
+	return &WebhookRepository{db: db}
+}
+
+// Create inserts a new webhook into the database
+func (r *WebhookRepository) Create(ctx context.Context, webhook *models.Webhook) error {
+	webhook.TenantID = tenant.FromContext(ctx)
+	webhook.BeforeSave()
+	_, err := r.db.Webhooks().InsertOne(ctx, webhook)
+	return err
+}
+
+// FindByID retrieves a webhook by its ID
+func (r *WebhookRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Webhook, error) {
+	var webhook models.Webhook
+	err := r.db.Webhooks().FindOne(ctx, scoped(ctx, bson.M{"_id": id})).Decode(&webhook)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil // Webhook not found
+		}
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+// FindAll retrieves the webhooks of an owner, newest first; an empty ownerID returns all webhooks
+func (r *WebhookRepository) FindAll(ctx context.Context, ownerID string) ([]*models.Webhook, error) {
+	filter := scoped(ctx, bson.M{})
+	if ownerID != "" {
+		filter["owner_id"] = ownerID
+	}
+
+	return r.find(ctx, filter, options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}))
+}
+
+// FindSubscribed retrieves the active webhooks subscribed to an event type
+func (r *WebhookRepository) FindSubscribed(ctx context.Context, event string) ([]*models.Webhook, error) {
+	filter := scoped(ctx, bson.M{
+		"active": true,
+		"events": bson.M{"$in": []string{event, models.WebhookAllEvents}},
+	})
+
+	return r.find(ctx, filter, options.Find())
+}
+
+// find retrieves the webhooks matching filter
+func (r *WebhookRepository) find(ctx context.Context, filter bson.M, opts *options.FindOptions) ([]*models.Webhook, error) {
+	cursor, err := r.db.Webhooks().Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var webhooks []*models.Webhook
+	if err := cursor.All(ctx, &webhooks); err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+// Update updates the settings of an existing webhook. Its status and
+// delivery record are left alone, as deliveries may update them concurrently.
+func (r *WebhookRepository) Update(ctx context.Context, webhook *models.Webhook) error {
+	webhook.BeforeSave()
+	result, err := r.db.Webhooks().UpdateOne(ctx, scoped(ctx, bson.M{"_id": webhook.ID}), bson.M{
+		"$set": bson.M{
+			"url":         webhook.URL,
+			"secret":      webhook.Secret,
+			"events":      webhook.Events,
+			"description": webhook.Description,
+			"updated_at":  webhook.UpdatedAt,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// Delete removes a webhook
+func (r *WebhookRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	result, err := r.db.Webhooks().DeleteOne(ctx, scoped(ctx, bson.M{"_id": id}))
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// RecordSuccess records a successful delivery and resets the failure count
+func (r *WebhookRepository) RecordSuccess(ctx context.Context, id primitive.ObjectID, at time.Time) error {
+	_, err := r.db.Webhooks().UpdateOne(ctx, scoped(ctx, bson.M{"_id": id}), bson.M{
+		"$set": bson.M{
+			"consecutive_failures": 0,
+			"last_delivery_at":     at,
+			"last_success_at":      at,
+		},
+	})
+	return err
+}
+
+// RecordFailure records a failed delivery and returns the number of consecutive failures
+func (r *WebhookRepository) RecordFailure(ctx context.Context, id primitive.ObjectID, at time.Time) (int, error) {
+	opts := options.FindOneAndUpdate().
+		SetReturnDocument(options.After).
+		SetProjection(bson.M{"consecutive_failures": 1})
+
+	var webhook models.Webhook
+	err := r.db.Webhooks().FindOneAndUpdate(ctx, scoped(ctx, bson.M{"_id": id}), bson.M{
+		"$set": bson.M{"last_delivery_at": at},
+		"$inc": bson.M{"consecutive_failures": 1},
+	}, opts).Decode(&webhook)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return 0, nil // Webhook was deleted
+		}
+		return 0, err
+	}
+	return webhook.ConsecutiveFailures, nil
+}
+
+// Enable reactivates a webhook and clears its failure record
+func (r *WebhookRepository) Enable(ctx context.Context, id primitive.ObjectID, at time.Time) error {
+	result, err := r.db.Webhooks().UpdateOne(ctx, scoped(ctx, bson.M{"_id": id}), bson.M{
+		"$set":   bson.M{"active": true, "consecutive_failures": 0, "updated_at": at},
+		"$unset": bson.M{"disabled_reason": "", "disabled_at": ""},
+	})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// Disable deactivates an active webhook, reporting whether it was active
+func (r *WebhookRepository) Disable(ctx context.Context, id primitive.ObjectID, reason string, at time.Time) (bool, error) {
+	result, err := r.db.Webhooks().UpdateOne(ctx, scoped(ctx, bson.M{"_id": id, "active": true}), bson.M{
+		"$set": bson.M{
+			"active":          false,
+			"disabled_reason": reason,
+			"disabled_at":     at,
+			"updated_at":      at,
+		},
+	})
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+// WebhookDeliveryRepository implements the WebhookDeliveryRepository interface
+type WebhookDeliveryRepository struct {
+	db *mongodb.DBConnection
+}
+
+// NewWebhookDeliveryRepository creates a new MongoDB webhook delivery repository
+func NewWebhookDeliveryRepository(db *mongodb.DBConnection) repository.WebhookDeliveryRepository {
+	return &WebhookDeliveryRepository{db: db}
+}
+
+// Create inserts a delivery into the log
+func (r *WebhookDeliveryRepository) Create(ctx context.Context, delivery *models.WebhookDelivery) error {
+	delivery.TenantID = tenant.FromContext(ctx)
+	_, err := r.db.WebhookDeliveries().InsertOne(ctx, delivery)
+	return err
+}
+
+// FindByWebhookID retrieves the deliveries of a webhook, newest first
+func (r *WebhookDeliveryRepository) FindByWebhookID(ctx context.Context, webhookID primitive.ObjectID, limit, offset int) ([]*models.WebhookDelivery, error) {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip(int64(offset)).
+		SetLimit(int64(limit))
+
+	cursor, err := r.db.WebhookDeliveries().Find(ctx, scoped(ctx, bson.M{"webhook_id": webhookID}), opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var deliveries []*models.WebhookDelivery
+	if err := cursor.All(ctx, &deliveries); err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// CountByWebhookID counts the deliveries of a webhook
+func (r *WebhookDeliveryRepository) CountByWebhookID(ctx context.Context, webhookID primitive.ObjectID) (int64, error) {
+	return r.db.WebhookDeliveries().CountDocuments(ctx, scoped(ctx, bson.M{"webhook_id": webhookID}))
+}
+
+// DeleteByWebhookID removes the delivery log of a webhook
+func (r *WebhookDeliveryRepository) DeleteByWebhookID(ctx context.Context, webhookID primitive.ObjectID) (int64, error) {
+	result, err := r.db.WebhookDeliveries().DeleteMany(ctx, scoped(ctx, bson.M{"webhook_id": webhookID}))
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
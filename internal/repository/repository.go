@@ -11,6 +11,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/models"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -20,11 +21,30 @@ import (
 type ChatRepository interface {
 	Create(ctx context.Context, chat *models.Chat) error
 	FindByID(ctx context.Context, id primitive.ObjectID) (*models.Chat, error)
-	FindAll(ctx context.Context, limit, offset int) ([]*models.Chat, error)
+	FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]*models.Chat, error)
+	FindAll(ctx context.Context, opts ChatListOptions, limit, offset int) ([]*models.Chat, error)
+	FindAllByCursor(ctx context.Context, opts ChatListOptions, query CursorQuery) ([]*models.Chat, PageInfo, error)
 	Update(ctx context.Context, chat *models.Chat) error
-	Delete(ctx context.Context, id primitive.ObjectID) error
-	IncrementMessageCount(ctx context.Context, id primitive.ObjectID) error
-	CountAll(ctx context.Context) (int64, error)
+	Delete(ctx context.Context, id primitive.ObjectID, deletedAt time.Time) error
+	FindDeletedByID(ctx context.Context, id primitive.ObjectID) (*models.Chat, error)
+	FindDeleted(ctx context.Context, ownerID string, limit, offset int) ([]*models.Chat, error)
+	CountDeleted(ctx context.Context, ownerID string) (int64, error)
+	Restore(ctx context.Context, id primitive.ObjectID) error
+	FindDeletedBefore(ctx context.Context, cutoff time.Time, limit int) ([]primitive.ObjectID, error)
+	HardDelete(ctx context.Context, ids []primitive.ObjectID) (int64, error)
+	SetArchived(ctx context.Context, id primitive.ObjectID, archived bool) error
+	SetFolder(ctx context.Context, ids []primitive.ObjectID, folderID *primitive.ObjectID) (int64, error)
+	ClearFolder(ctx context.Context, folderID primitive.ObjectID) (int64, error)
+	UpdateTags(ctx context.Context, ids []primitive.ObjectID, add, remove []string) (int64, error)
+	IncrementMessageCount(ctx context.Context, id primitive.ObjectID, messageAt time.Time) error
+	DecrementMessageCount(ctx context.Context, id primitive.ObjectID, lastMessageAt *time.Time) error
+	Scan(ctx context.Context, after primitive.ObjectID, limit int) ([]*models.Chat, error)
+	SetMessageStats(ctx context.Context, id primitive.ObjectID, count int, lastMessageAt *time.Time) error
+	CountAll(ctx context.Context, filter ChatFilter) (int64, error)
+	FindIDsByMember(ctx context.Context, subject string, roles []models.ChatRole) ([]primitive.ObjectID, error)
+	SetMember(ctx context.Context, id primitive.ObjectID, member models.ChatMember) error
+	RemoveMember(ctx context.Context, id primitive.ObjectID, subject string) error
+	ClaimUnowned(ctx context.Context, subject string) (int64, error)
 }
 
 // MessageRepository defines the interface for message data access
@@ -32,7 +52,113 @@ type MessageRepository interface {
 	Create(ctx context.Context, message *models.Message) error
 	FindByID(ctx context.Context, id primitive.ObjectID) (*models.Message, error)
 	FindByChatID(ctx context.Context, chatID primitive.ObjectID, limit, offset int) ([]*models.Message, error)
+	FindByChatIDCursor(ctx context.Context, chatID primitive.ObjectID, query CursorQuery) ([]*models.Message, PageInfo, error)
 	CountByChatID(ctx context.Context, chatID primitive.ObjectID) (int64, error)
+	CountSince(ctx context.Context, since time.Time) (int64, error)
+	Delete(ctx context.Context, id primitive.ObjectID) error
+	DeleteByChatID(ctx context.Context, chatID primitive.ObjectID, deletedAt time.Time) error
+	FindDeletedByID(ctx context.Context, id primitive.ObjectID) (*models.Message, error)
+	FindDeleted(ctx context.Context, chatIDs []primitive.ObjectID, limit, offset int) ([]*models.Message, error)
+	CountDeleted(ctx context.Context, chatIDs []primitive.ObjectID) (int64, error)
+	Restore(ctx context.Context, id primitive.ObjectID) error
+	RestoreByChatID(ctx context.Context, chatID primitive.ObjectID) error
+	HardDeleteByChatIDs(ctx context.Context, chatIDs []primitive.ObjectID) (int64, error)
+	PurgeDeletedBefore(ctx context.Context, cutoff time.Time) (int64, error)
+	StatsByChatIDs(ctx context.Context, chatIDs []primitive.ObjectID) (map[primitive.ObjectID]MessageStats, error)
+}
+
+// MessageStats summarizes the messages of a chat
+type MessageStats struct {
+	Count         int
+	LastMessageAt time.Time
+}
+
+// FolderRepository defines the interface for folder data access
+type FolderRepository interface {
+	Create(ctx context.Context, folder *models.Folder) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.Folder, error)
+	FindAll(ctx context.Context, ownerID string) ([]*models.Folder, error)
+	Update(ctx context.Context, folder *models.Folder) error
 	Delete(ctx context.Context, id primitive.ObjectID) error
-	DeleteByChatID(ctx context.Context, chatID primitive.ObjectID) error
+	ClaimUnowned(ctx context.Context, subject string) (int64, error)
+}
+
+// InviteRepository defines the interface for chat invite data access
+type InviteRepository interface {
+	Create(ctx context.Context, invite *models.ChatInvite) error
+	FindByCodeHash(ctx context.Context, codeHash string) (*models.ChatInvite, error)
+	FindByChatID(ctx context.Context, chatID primitive.ObjectID) ([]*models.ChatInvite, error)
+	Delete(ctx context.Context, chatID, id primitive.ObjectID) error
+}
+
+// GenerationRepository defines the interface for AI generation data access.
+// Status changes only apply when the generation is in the expected status,
+// and report whether they did.
+type GenerationRepository interface {
+	Create(ctx context.Context, generation *models.Generation) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.Generation, error)
+	FindByChatID(ctx context.Context, chatID primitive.ObjectID, status models.GenerationStatus, limit int) ([]*models.Generation, error)
+	Checkpoint(ctx context.Context, id primitive.ObjectID, content string, at time.Time) (bool, error)
+	Finish(ctx context.Context, id primitive.ObjectID, status models.GenerationStatus, content, errMsg string, at time.Time) (bool, error)
+	Transition(ctx context.Context, id primitive.ObjectID, from, to models.GenerationStatus) (bool, error)
+	InterruptStale(ctx context.Context, cutoff time.Time, limit int) ([]*models.Generation, error)
+}
+
+// APIKeyRepository defines the interface for API key data access
+type APIKeyRepository interface {
+	Create(ctx context.Context, key *models.APIKey) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.APIKey, error)
+	FindAll(ctx context.Context) ([]*models.APIKey, error)
+	Revoke(ctx context.Context, id primitive.ObjectID, revokedAt time.Time) error
+}
+
+// JobRepository defines the interface for background job data access.
+// Jobs belong to no single tenant, so its queries are not tenant scoped.
+// Updates of a claimed job only apply while workerID still holds it, and
+// report whether they did.
+type JobRepository interface {
+	Create(ctx context.Context, job *models.Job) (bool, error)
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.Job, error)
+	FindAll(ctx context.Context, filter JobFilter, limit, offset int) ([]*models.Job, error)
+	CountAll(ctx context.Context, filter JobFilter) (int64, error)
+	Claim(ctx context.Context, types []string, workerID string, now, leaseUntil time.Time) (*models.Job, error)
+	Extend(ctx context.Context, id primitive.ObjectID, workerID string, leaseUntil time.Time) (bool, error)
+	Complete(ctx context.Context, id primitive.ObjectID, workerID string, at, expiresAt time.Time) (bool, error)
+	Retry(ctx context.Context, id primitive.ObjectID, workerID, errMsg string, visibleAt time.Time) (bool, error)
+	Bury(ctx context.Context, id primitive.ObjectID, workerID, errMsg string, at time.Time) (bool, error)
+	Release(ctx context.Context, id primitive.ObjectID, workerID string) (bool, error)
+	Requeue(ctx context.Context, id primitive.ObjectID, at time.Time) (bool, error)
+}
+
+// WebhookRepository defines the interface for webhook subscription data access
+type WebhookRepository interface {
+	Create(ctx context.Context, webhook *models.Webhook) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.Webhook, error)
+	FindAll(ctx context.Context, ownerID string) ([]*models.Webhook, error)
+	FindSubscribed(ctx context.Context, event string) ([]*models.Webhook, error)
+	Update(ctx context.Context, webhook *models.Webhook) error
+	Delete(ctx context.Context, id primitive.ObjectID) error
+	RecordSuccess(ctx context.Context, id primitive.ObjectID, at time.Time) error
+	RecordFailure(ctx context.Context, id primitive.ObjectID, at time.Time) (int, error)
+	Enable(ctx context.Context, id primitive.ObjectID, at time.Time) error
+	Disable(ctx context.Context, id primitive.ObjectID, reason string, at time.Time) (bool, error)
+}
+
+// UsageRepository defines the interface for daily usage counter data access.
+// The counters hold messages charged against the daily message quota that
+// are not stored, such as unstored exchanges of the OpenAI-compatible API,
+// and the tokens used by completions.
+type UsageRepository interface {
+	AddMessages(ctx context.Context, day time.Time, count int) error
+	CountMessages(ctx context.Context, day time.Time) (int64, error)
+	AddTokens(ctx context.Context, day time.Time, promptTokens, completionTokens int) error
+	FindByDays(ctx context.Context, from, to time.Time) ([]*models.Usage, error)
+}
+
+// WebhookDeliveryRepository defines the interface for webhook delivery log data access
+type WebhookDeliveryRepository interface {
+	Create(ctx context.Context, delivery *models.WebhookDelivery) error
+	FindByWebhookID(ctx context.Context, webhookID primitive.ObjectID, limit, offset int) ([]*models.WebhookDelivery, error)
+	CountByWebhookID(ctx context.Context, webhookID primitive.ObjectID) (int64, error)
+	DeleteByWebhookID(ctx context.Context, webhookID primitive.ObjectID) (int64, error)
 }
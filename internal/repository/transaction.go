@@ -0,0 +1,18 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package repository
+
+import "context"
+
+// Transactor runs a group of repository calls atomically.
+// Repository calls must use the context passed to fn to take part in the transaction.
+type Transactor interface {
+	WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
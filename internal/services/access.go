@@ -0,0 +1,81 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package services
+
+import (
+	"context"
+
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/auth"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/models"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/repository"
+	apperrors "github.com/yasin-yalcin-dev/go-sse-ai-chat/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// callerSubject returns the subject of the authenticated caller. Calls made
+// without a principal (authentication disabled, admin commands, background
+// jobs) are not restricted by chat membership.
+func callerSubject(ctx context.Context) (string, bool) {
+	principal, ok := auth.PrincipalFromContext(ctx)
+	if !ok {
+		return "", false
+	}
+	return principal.Subject, true
+}
+
+// authorizeChat checks that the caller holds at least the required role on a chat.
+// Callers that are not members get the same error as for a missing chat, so
+// chat IDs cannot be probed.
+func authorizeChat(ctx context.Context, chat *models.Chat, required models.ChatRole) error {
+	subject, ok := callerSubject(ctx)
+	if !ok {
+		return nil
+	}
+
+	role, isMember := chat.MemberRole(subject)
+	if !isMember {
+		return errChatNotFound()
+	}
+
+	if !role.Allows(required) {
+		return apperrors.NewForbiddenError("This action requires the "+string(required)+" role on the chat", nil)
+	}
+
+	return nil
+}
+
+// findChat retrieves a chat and checks that the caller holds at least the required role on it
+func findChat(ctx context.Context, chatRepo repository.ChatRepository, chatID primitive.ObjectID, required models.ChatRole) (*models.Chat, error) {
+	chat, err := chatRepo.FindByID(ctx, chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	if chat == nil {
+		return nil, errChatNotFound()
+	}
+
+	if err := authorizeChat(ctx, chat, required); err != nil {
+		return nil, err
+	}
+
+	return chat, nil
+}
+
+// errChatNotFound returns the error reported for missing or inaccessible chats
+func errChatNotFound() error {
+	return apperrors.NewNotFoundError("Chat not found", nil)
+}
+
+// isNotFound reports whether err is a not-found application error
+func isNotFound(err error) bool {
+	appErr, ok := apperrors.AsAppError(err)
+	return ok && appErr.Code == apperrors.CodeNotFound
+}
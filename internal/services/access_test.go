@@ -0,0 +1,386 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package services
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/models"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/repository"
+	apperrors "github.com/yasin-yalcin-dev/go-sse-ai-chat/pkg/errors"
+)
+
+// sharedChat is a chat owned by alice, edited by bob and viewed by carol,
+// with one message. dave is not a member.
+type sharedChat struct {
+	chats    *chatStore
+	messages *messageStore
+	invites  *inviteStore
+	chat     *models.Chat
+	message  *models.Message
+
+	chatService    ChatService
+	messageService MessageService
+	shareService   ShareService
+}
+
+func newSharedChat() *sharedChat {
+	s := &sharedChat{
+		chats:    newChatStore(),
+		messages: newMessageStore(),
+		invites:  newInviteStore(),
+	}
+	s.chat = s.chats.add("Shared", "alice",
+		models.NewChatMember("bob", models.ChatRoleEditor),
+		models.NewChatMember("carol", models.ChatRoleViewer),
+	)
+	s.message = s.messages.add(s.chat.ID, "Hello", time.Now())
+	_ = s.chats.IncrementMessageCount(context.Background(), s.chat.ID, s.message.CreatedAt)
+
+	s.chatService = NewChatService(s.chats, s.messages, nil, noTransactions{}, nil, nil)
+	s.messageService = NewMessageService(s.messages, s.chats, nil, noTransactions{}, nil, nil)
+	s.shareService = NewShareService(s.chats, s.invites, nil)
+	return s
+}
+
+// statusOf returns the HTTP status of an error, or 200 for nil
+func statusOf(err error) int {
+	if err == nil {
+		return http.StatusOK
+	}
+	if appErr, ok := apperrors.AsAppError(err); ok {
+		return appErr.GetStatusCode()
+	}
+	return http.StatusInternalServerError
+}
+
+// TestChatAccessByRole checks that each operation requires its role:
+// strangers get 404 as if the chat did not exist, members without the role 403
+func TestChatAccessByRole(t *testing.T) {
+	operations := []struct {
+		name     string
+		required models.ChatRole
+		run      func(ctx context.Context, s *sharedChat) error
+	}{
+		{name: "get chat", required: models.ChatRoleViewer, run: func(ctx context.Context, s *sharedChat) error {
+			_, err := s.chatService.GetChatByID(ctx, s.chat.ID.Hex())
+			return err
+		}},
+		{name: "list messages", required: models.ChatRoleViewer, run: func(ctx context.Context, s *sharedChat) error {
+			_, _, err := s.messageService.GetChatMessages(ctx, s.chat.ID.Hex(), 1, 20)
+			return err
+		}},
+		{name: "get message", required: models.ChatRoleViewer, run: func(ctx context.Context, s *sharedChat) error {
+			_, err := s.messageService.GetMessageByID(ctx, s.message.ID.Hex())
+			return err
+		}},
+		{name: "list members", required: models.ChatRoleViewer, run: func(ctx context.Context, s *sharedChat) error {
+			_, err := s.shareService.ListMembers(ctx, s.chat.ID.Hex())
+			return err
+		}},
+		{name: "send message", required: models.ChatRoleEditor, run: func(ctx context.Context, s *sharedChat) error {
+			_, err := s.messageService.CreateMessage(ctx, s.chat.ID.Hex(), "Hi", models.RoleUser, models.TypeText)
+			return err
+		}},
+		{name: "delete message", required: models.ChatRoleEditor, run: func(ctx context.Context, s *sharedChat) error {
+			return s.messageService.DeleteMessage(ctx, s.message.ID.Hex())
+		}},
+		{name: "rename chat", required: models.ChatRoleEditor, run: func(ctx context.Context, s *sharedChat) error {
+			_, err := s.chatService.UpdateChat(ctx, s.chat.ID.Hex(), "Renamed", nil)
+			return err
+		}},
+		{name: "archive chat", required: models.ChatRoleEditor, run: func(ctx context.Context, s *sharedChat) error {
+			_, err := s.chatService.ArchiveChat(ctx, s.chat.ID.Hex())
+			return err
+		}},
+		{name: "tag chat", required: models.ChatRoleEditor, run: func(ctx context.Context, s *sharedChat) error {
+			_, err := s.chatService.UpdateChatTags(ctx, []string{s.chat.ID.Hex()}, []string{"work"}, nil)
+			return err
+		}},
+		{name: "move chat", required: models.ChatRoleOwner, run: func(ctx context.Context, s *sharedChat) error {
+			_, err := s.chatService.MoveChats(ctx, []string{s.chat.ID.Hex()}, "")
+			return err
+		}},
+		{name: "delete chat", required: models.ChatRoleOwner, run: func(ctx context.Context, s *sharedChat) error {
+			return s.chatService.DeleteChat(ctx, s.chat.ID.Hex())
+		}},
+		{name: "create invite", required: models.ChatRoleOwner, run: func(ctx context.Context, s *sharedChat) error {
+			_, _, err := s.shareService.CreateInvite(ctx, s.chat.ID.Hex(), models.ChatRoleViewer, 0)
+			return err
+		}},
+		{name: "change member role", required: models.ChatRoleOwner, run: func(ctx context.Context, s *sharedChat) error {
+			_, err := s.shareService.UpdateMemberRole(ctx, s.chat.ID.Hex(), "carol", models.ChatRoleEditor)
+			return err
+		}},
+	}
+
+	callers := []struct {
+		subject string
+		role    models.ChatRole // Empty for strangers
+	}{
+		{subject: "alice", role: models.ChatRoleOwner},
+		{subject: "bob", role: models.ChatRoleEditor},
+		{subject: "carol", role: models.ChatRoleViewer},
+		{subject: "dave"},
+	}
+
+	for _, op := range operations {
+		for _, caller := range callers {
+			t.Run(op.name+"/"+caller.subject, func(t *testing.T) {
+				want := http.StatusOK
+				switch {
+				case caller.role == "":
+					want = http.StatusNotFound
+				case !caller.role.Allows(op.required):
+					want = http.StatusForbidden
+				}
+
+				s := newSharedChat()
+				if got := statusOf(op.run(as(caller.subject), s)); got != want {
+					t.Errorf("%s as %s = %d, want %d", op.name, caller.subject, got, want)
+				}
+			})
+		}
+	}
+}
+
+// TestDeniedWritesChangeNothing checks that writes rejected for the caller's
+// role leave the chat and its messages as they were
+func TestDeniedWritesChangeNothing(t *testing.T) {
+	s := newSharedChat()
+
+	if _, err := s.messageService.CreateMessage(as("carol"), s.chat.ID.Hex(), "Hi", models.RoleUser, models.TypeText); err == nil {
+		t.Fatal("viewer could send a message")
+	}
+	if err := s.messageService.DeleteMessage(as("dave"), s.message.ID.Hex()); err == nil {
+		t.Fatal("stranger could delete a message")
+	}
+	if err := s.chatService.DeleteChat(as("bob"), s.chat.ID.Hex()); err == nil {
+		t.Fatal("editor could delete the chat")
+	}
+
+	if messages := s.messages.live(s.chat.ID); len(messages) != 1 || messages[0].ID != s.message.ID {
+		t.Errorf("messages = %v, want only the original message", messages)
+	}
+	if chat := s.chats.get(s.chat.ID); chat.DeletedAt != nil || chat.MessageCount != 1 {
+		t.Errorf("chat = %+v, want it live with 1 message", chat)
+	}
+}
+
+// TestRestoreChatRequiresOwner checks that only the owner can restore a
+// trashed chat, and that other members cannot tell it is in the trash
+func TestRestoreChatRequiresOwner(t *testing.T) {
+	s := newSharedChat()
+	if err := s.chatService.DeleteChat(as("alice"), s.chat.ID.Hex()); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, subject := range []string{"bob", "carol", "dave"} {
+		if _, err := s.chatService.RestoreChat(as(subject), s.chat.ID.Hex()); statusOf(err) != http.StatusNotFound {
+			t.Errorf("RestoreChat() as %s status = %d, want %d", subject, statusOf(err), http.StatusNotFound)
+		}
+	}
+
+	if _, err := s.chatService.RestoreChat(as("alice"), s.chat.ID.Hex()); err != nil {
+		t.Fatalf("RestoreChat() as owner error = %v", err)
+	}
+	if len(s.messages.live(s.chat.ID)) != 1 {
+		t.Error("the chat's message was not restored with it")
+	}
+}
+
+// TestListChatsScopedToCaller checks that listings only contain the chats
+// the caller is a member of
+func TestListChatsScopedToCaller(t *testing.T) {
+	s := newSharedChat()
+	private := s.chats.add("Private", "bob")
+	other := s.chats.add("Other", "dave")
+
+	tests := []struct {
+		subject string
+		want    []string
+	}{
+		{subject: "alice", want: []string{s.chat.ID.Hex()}},
+		{subject: "bob", want: []string{s.chat.ID.Hex(), private.ID.Hex()}},
+		{subject: "carol", want: []string{s.chat.ID.Hex()}},
+		{subject: "dave", want: []string{other.ID.Hex()}},
+		{subject: "eve"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.subject, func(t *testing.T) {
+			chats, total, err := s.chatService.ListChats(as(tt.subject), repository.ChatListOptions{}, 1, 10)
+			if err != nil {
+				t.Fatalf("ListChats() error = %v", err)
+			}
+			if got := chatIDs(chats); !slices.Equal(got, tt.want) || total != int64(len(tt.want)) {
+				t.Errorf("ListChats() = %v (total %d), want %v", got, total, tt.want)
+			}
+
+			chats, _, total, err = s.chatService.ListChatsByCursor(as(tt.subject), repository.ChatListOptions{}, repository.CursorQuery{Limit: 10})
+			if err != nil {
+				t.Fatalf("ListChatsByCursor() error = %v", err)
+			}
+			if got := chatIDs(chats); !slices.Equal(got, tt.want) || total != int64(len(tt.want)) {
+				t.Errorf("ListChatsByCursor() = %v (total %d), want %v", got, total, tt.want)
+			}
+		})
+	}
+
+	// Calls without a principal, such as admin commands, see every chat
+	chats, _, err := s.chatService.ListChats(context.Background(), repository.ChatListOptions{}, 1, 10)
+	if err != nil || len(chats) != 3 {
+		t.Errorf("ListChats() without a principal = %d chats, %v, want 3", len(chats), err)
+	}
+}
+
+// TestAcceptInvite checks that invites add the caller with their role, never
+// lower an existing member's role, and cannot be used once expired or revoked
+func TestAcceptInvite(t *testing.T) {
+	tests := []struct {
+		name       string
+		subject    string
+		role       models.ChatRole
+		setup      func(t *testing.T, s *sharedChat, invite *models.ChatInvite)
+		wantStatus int
+		wantRole   models.ChatRole // Role of subject afterwards; empty when not a member
+	}{
+		{name: "new viewer", subject: "dave", role: models.ChatRoleViewer, wantStatus: http.StatusOK, wantRole: models.ChatRoleViewer},
+		{name: "new editor", subject: "dave", role: models.ChatRoleEditor, wantStatus: http.StatusOK, wantRole: models.ChatRoleEditor},
+		{name: "upgrade viewer", subject: "carol", role: models.ChatRoleEditor, wantStatus: http.StatusOK, wantRole: models.ChatRoleEditor},
+		{name: "no downgrade of editor", subject: "bob", role: models.ChatRoleViewer, wantStatus: http.StatusOK, wantRole: models.ChatRoleEditor},
+		{name: "no downgrade of owner", subject: "alice", role: models.ChatRoleViewer, wantStatus: http.StatusOK, wantRole: models.ChatRoleOwner},
+		{
+			name: "expired", subject: "dave", role: models.ChatRoleViewer,
+			setup: func(t *testing.T, s *sharedChat, invite *models.ChatInvite) {
+				s.invites.mutex.Lock()
+				defer s.invites.mutex.Unlock()
+				s.invites.invites[invite.ID].ExpiresAt = time.Now().Add(-time.Second)
+			},
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name: "revoked", subject: "dave", role: models.ChatRoleViewer,
+			setup: func(t *testing.T, s *sharedChat, invite *models.ChatInvite) {
+				if err := s.shareService.RevokeInvite(as("alice"), s.chat.ID.Hex(), invite.ID.Hex()); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name: "chat deleted", subject: "dave", role: models.ChatRoleViewer,
+			setup: func(t *testing.T, s *sharedChat, _ *models.ChatInvite) {
+				if err := s.chatService.DeleteChat(as("alice"), s.chat.ID.Hex()); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newSharedChat()
+			invite, code, err := s.shareService.CreateInvite(as("alice"), s.chat.ID.Hex(), tt.role, time.Hour)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.setup != nil {
+				tt.setup(t, s, invite)
+			}
+
+			_, err = s.shareService.AcceptInvite(as(tt.subject), code)
+			if got := statusOf(err); got != tt.wantStatus {
+				t.Fatalf("AcceptInvite() status = %d (%v), want %d", got, err, tt.wantStatus)
+			}
+
+			role, isMember := s.chats.get(s.chat.ID).MemberRole(tt.subject)
+			if tt.wantRole == "" {
+				if isMember {
+					t.Errorf("%s became a member with role %s", tt.subject, role)
+				}
+				return
+			}
+			if role != tt.wantRole {
+				t.Errorf("role of %s = %q, want %q", tt.subject, role, tt.wantRole)
+			}
+		})
+	}
+}
+
+// TestAcceptInviteRejects checks invites that must not be accepted at all
+func TestAcceptInviteRejects(t *testing.T) {
+	s := newSharedChat()
+	_, code, err := s.shareService.CreateInvite(as("alice"), s.chat.ID.Hex(), models.ChatRoleViewer, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.shareService.AcceptInvite(context.Background(), code); statusOf(err) != http.StatusUnauthorized {
+		t.Errorf("AcceptInvite() without a principal status = %d, want %d", statusOf(err), http.StatusUnauthorized)
+	}
+	if _, err := s.shareService.AcceptInvite(as("dave"), code+"x"); statusOf(err) != http.StatusNotFound {
+		t.Errorf("AcceptInvite() of an unknown code status = %d, want %d", statusOf(err), http.StatusNotFound)
+	}
+}
+
+// TestCreateInviteValidation checks the roles and lifetimes invites can have
+func TestCreateInviteValidation(t *testing.T) {
+	tests := []struct {
+		name       string
+		role       models.ChatRole
+		ttl        time.Duration
+		wantStatus int
+	}{
+		{name: "viewer", role: models.ChatRoleViewer, wantStatus: http.StatusOK},
+		{name: "editor at max ttl", role: models.ChatRoleEditor, ttl: MaxInviteTTL, wantStatus: http.StatusOK},
+		{name: "owner", role: models.ChatRoleOwner, wantStatus: http.StatusBadRequest},
+		{name: "ttl too long", role: models.ChatRoleViewer, ttl: MaxInviteTTL + time.Hour, wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newSharedChat()
+			invite, code, err := s.shareService.CreateInvite(as("alice"), s.chat.ID.Hex(), tt.role, tt.ttl)
+			if got := statusOf(err); got != tt.wantStatus {
+				t.Fatalf("CreateInvite() status = %d (%v), want %d", got, err, tt.wantStatus)
+			}
+			if err != nil {
+				return
+			}
+
+			// Only the hash of the code is stored
+			if code == "" || invite.CodeHash == code || invite.CodeHash != hashInviteCode(code) {
+				t.Errorf("CreateInvite() stored %q for code %q, want its hash", invite.CodeHash, code)
+			}
+			ttl := tt.ttl
+			if ttl == 0 {
+				ttl = DefaultInviteTTL
+			}
+			if got := invite.ExpiresAt.Sub(invite.CreatedAt); got != ttl {
+				t.Errorf("invite valid for %v, want %v", got, ttl)
+			}
+		})
+	}
+}
+
+// chatIDs returns the hex IDs of chats
+func chatIDs(chats []*models.Chat) []string {
+	ids := make([]string, len(chats))
+	for i, chat := range chats {
+		ids[i] = chat.ID.Hex()
+	}
+	return ids
+}
@@ -0,0 +1,66 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package services
+
+import (
+	"context"
+
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/ai"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/repository"
+	apperrors "github.com/yasin-yalcin-dev/go-sse-ai-chat/pkg/errors"
+)
+
+// maxHistoryMessages is how many of the latest messages of a chat are sent to the provider
+const maxHistoryMessages = 50
+
+// AIProviders returns the AI provider a tenant generates with. name selects a
+// provider other than the tenant's configured one; empty uses the configured one.
+type AIProviders interface {
+	Provider(tenantID, name string) (ai.Provider, error)
+}
+
+// AIGenerator implements Generator with the AI provider of each chat's tenant
+type AIGenerator struct {
+	messageRepo repository.MessageRepository
+	providers   AIProviders
+}
+
+// NewAIGenerator creates a generator that replies to the latest messages of a chat
+func NewAIGenerator(messageRepo repository.MessageRepository, providers AIProviders) *AIGenerator {
+	return &AIGenerator{
+		messageRepo: messageRepo,
+		providers:   providers,
+	}
+}
+
+// Generate implements Generator. A prefix is sent as a final assistant
+// message, which the provider continues.
+func (g *AIGenerator) Generate(ctx context.Context, req GenerationRequest, emit func(chunk string) error) error {
+	provider, err := g.providers.Provider(req.Chat.TenantID, req.Provider)
+	if err != nil {
+		return apperrors.NewServiceUnavailableError("AI provider is not available", err)
+	}
+
+	history, err := g.messageRepo.FindByChatID(ctx, req.Chat.ID, maxHistoryMessages, 0)
+	if err != nil {
+		return err
+	}
+
+	messages := make([]ai.Message, 0, len(history)+1)
+	for _, message := range history {
+		messages = append(messages, ai.Message{Role: string(message.Role), Content: message.Content})
+	}
+	if req.Prefix != "" {
+		messages = append(messages, ai.Message{Role: ai.RoleAssistant, Content: req.Prefix})
+	}
+
+	_, err = provider.Stream(ctx, ai.Request{Model: req.Model, Messages: messages}, emit)
+	return err
+}
@@ -12,9 +12,15 @@ package services
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strings"
+	"time"
 
 	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/models"
 	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/repository"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/sse"
+	apperrors "github.com/yasin-yalcin-dev/go-sse-ai-chat/pkg/errors"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/pkg/logger"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -22,48 +28,98 @@ import (
 type ChatServiceImpl struct {
 	chatRepo    repository.ChatRepository
 	messageRepo repository.MessageRepository
+	folderRepo  repository.FolderRepository
+	tx          repository.Transactor
+	publisher   EventPublisher
+	quotas      QuotaProvider
 }
 
-// NewChatService creates a new chat service
-func NewChatService(chatRepo repository.ChatRepository, messageRepo repository.MessageRepository) ChatService {
+// NewChatService creates a new chat service.
+// quotas may be nil, in which case chat creation is not limited.
+func NewChatService(chatRepo repository.ChatRepository, messageRepo repository.MessageRepository, folderRepo repository.FolderRepository, tx repository.Transactor, publisher EventPublisher, quotas QuotaProvider) ChatService {
 	return &ChatServiceImpl{
 		chatRepo:    chatRepo,
 		messageRepo: messageRepo,
+		folderRepo:  folderRepo,
+		tx:          tx,
+		publisher:   publisher,
+		quotas:      quotas,
 	}
 }
 
 // CreateChat creates a new chat session
 func (s *ChatServiceImpl) CreateChat(ctx context.Context, title string) (*models.Chat, error) {
+	if err := checkChatQuota(ctx, s.quotas, s.chatRepo); err != nil {
+		return nil, err
+	}
+
 	chat := models.NewChat(title)
+	if subject, ok := callerSubject(ctx); ok {
+		chat.SetOwner(subject)
+	}
 
 	if err := s.chatRepo.Create(ctx, chat); err != nil {
 		return nil, err
 	}
 
+	publishChatEvent(ctx, s.publisher, chat, sse.EventChatCreated)
+
 	return chat, nil
 }
 
 // GetChatByID retrieves a chat by its ID
 func (s *ChatServiceImpl) GetChatByID(ctx context.Context, id string) (*models.Chat, error) {
+	return s.loadChat(ctx, id, models.ChatRoleViewer)
+}
+
+// loadChat retrieves a chat and checks that the caller holds at least the required role on it
+func (s *ChatServiceImpl) loadChat(ctx context.Context, id string, required models.ChatRole) (*models.Chat, error) {
 	chatID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return nil, err
 	}
 
-	chat, err := s.chatRepo.FindByID(ctx, chatID)
+	return findChat(ctx, s.chatRepo, chatID, required)
+}
+
+// authorizeChats checks that the caller holds at least the required role on every chat
+func (s *ChatServiceImpl) authorizeChats(ctx context.Context, ids []primitive.ObjectID, required models.ChatRole) error {
+	if _, ok := callerSubject(ctx); !ok {
+		return nil
+	}
+
+	chats, err := s.chatRepo.FindByIDs(ctx, ids)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	if chat == nil {
-		return nil, errors.New("chat not found")
+	found := make(map[primitive.ObjectID]bool, len(chats))
+	for _, chat := range chats {
+		if err := authorizeChat(ctx, chat, required); err != nil {
+			return err
+		}
+		found[chat.ID] = true
 	}
 
-	return chat, nil
+	for _, id := range ids {
+		if !found[id] {
+			return errChatNotFound()
+		}
+	}
+
+	return nil
+}
+
+// scopeToCaller restricts a chat listing to the chats the caller is a member of
+func scopeToCaller(ctx context.Context, opts repository.ChatListOptions) repository.ChatListOptions {
+	if subject, ok := callerSubject(ctx); ok {
+		opts.Filter.Member = subject
+	}
+	return opts
 }
 
 // ListChats retrieves a paginated list of chats
-func (s *ChatServiceImpl) ListChats(ctx context.Context, page, pageSize int) ([]*models.Chat, int64, error) {
+func (s *ChatServiceImpl) ListChats(ctx context.Context, opts repository.ChatListOptions, page, pageSize int) ([]*models.Chat, int64, error) {
 	if page < 1 {
 		page = 1
 	}
@@ -72,14 +128,20 @@ func (s *ChatServiceImpl) ListChats(ctx context.Context, page, pageSize int) ([]
 		pageSize = 10
 	}
 
+	opts, err := normalizeChatListOptions(opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	opts = scopeToCaller(ctx, opts)
+
 	offset := (page - 1) * pageSize
 
-	chats, err := s.chatRepo.FindAll(ctx, pageSize, offset)
+	chats, err := s.chatRepo.FindAll(ctx, opts, pageSize, offset)
 	if err != nil {
 		return nil, 0, err
 	}
 
-	total, err := s.chatRepo.CountAll(ctx)
+	total, err := s.chatRepo.CountAll(ctx, opts.Filter)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -87,43 +149,287 @@ func (s *ChatServiceImpl) ListChats(ctx context.Context, page, pageSize int) ([]
 	return chats, total, nil
 }
 
-// UpdateChat updates a chat's title
-func (s *ChatServiceImpl) UpdateChat(ctx context.Context, id string, title string) (*models.Chat, error) {
-	chatID, err := primitive.ObjectIDFromHex(id)
+// ListChatsByCursor retrieves a keyset-paginated list of chats
+func (s *ChatServiceImpl) ListChatsByCursor(ctx context.Context, opts repository.ChatListOptions, query repository.CursorQuery) ([]*models.Chat, repository.PageInfo, int64, error) {
+	if query.Limit < 1 {
+		query.Limit = 10
+	}
+
+	opts, err := normalizeChatListOptions(opts)
 	if err != nil {
-		return nil, err
+		return nil, repository.PageInfo{}, 0, err
 	}
+	opts = scopeToCaller(ctx, opts)
 
-	chat, err := s.chatRepo.FindByID(ctx, chatID)
+	chats, info, err := s.chatRepo.FindAllByCursor(ctx, opts, query)
 	if err != nil {
-		return nil, err
+		if errors.Is(err, repository.ErrInvalidCursor) {
+			return nil, info, 0, apperrors.NewBadRequestError("Invalid cursor", err)
+		}
+		return nil, info, 0, err
 	}
 
-	if chat == nil {
-		return nil, errors.New("chat not found")
+	total, err := s.chatRepo.CountAll(ctx, opts.Filter)
+	if err != nil {
+		return nil, info, 0, err
+	}
+
+	return chats, info, total, nil
+}
+
+// UpdateChat updates a chat's title and, optionally, its pinned state
+func (s *ChatServiceImpl) UpdateChat(ctx context.Context, id string, title string, pinned *bool) (*models.Chat, error) {
+	chat, err := s.loadChat(ctx, id, models.ChatRoleEditor)
+	if err != nil {
+		return nil, err
 	}
 
+	fields := []string{"title"}
 	chat.Title = title
+	if pinned != nil {
+		chat.Pinned = *pinned
+		fields = append(fields, "pinned")
+	}
 
 	if err := s.chatRepo.Update(ctx, chat); err != nil {
 		return nil, err
 	}
 
+	publishChatUpdated(ctx, s.publisher, chat, fields...)
+
+	return chat, nil
+}
+
+// ArchiveChat hides a chat from the default chat listing
+func (s *ChatServiceImpl) ArchiveChat(ctx context.Context, id string) (*models.Chat, error) {
+	return s.setArchived(ctx, id, true)
+}
+
+// UnarchiveChat returns an archived chat to the default chat listing
+func (s *ChatServiceImpl) UnarchiveChat(ctx context.Context, id string) (*models.Chat, error) {
+	return s.setArchived(ctx, id, false)
+}
+
+// setArchived updates the archived state of a chat and returns the updated chat
+func (s *ChatServiceImpl) setArchived(ctx context.Context, id string, archived bool) (*models.Chat, error) {
+	chat, err := s.loadChat(ctx, id, models.ChatRoleEditor)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.chatRepo.SetArchived(ctx, chat.ID, archived); err != nil {
+		return nil, err
+	}
+
+	if archived {
+		chat.Archive()
+	} else {
+		chat.Unarchive()
+	}
+
+	publishChatUpdated(ctx, s.publisher, chat, "archived")
+
 	return chat, nil
 }
 
-// DeleteChat deletes a chat and all its messages
+// MoveChats moves chats into a folder, or out of any folder when folderID is
+// empty. The folder is stored on the chat and so applies to every member,
+// which is why only the owner can move a chat.
+func (s *ChatServiceImpl) MoveChats(ctx context.Context, chatIDs []string, folderID string) (int64, error) {
+	ids, err := parseObjectIDs(chatIDs)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := s.authorizeChats(ctx, ids, models.ChatRoleOwner); err != nil {
+		return 0, err
+	}
+
+	var folderObjID *primitive.ObjectID
+	if folderID != "" {
+		id, err := primitive.ObjectIDFromHex(folderID)
+		if err != nil {
+			return 0, apperrors.NewBadRequestError("Invalid folder ID", err)
+		}
+
+		folder, err := s.folderRepo.FindByID(ctx, id)
+		if err != nil {
+			return 0, err
+		}
+		if folder == nil || !canManageFolder(ctx, folder) {
+			return 0, apperrors.NewNotFoundError("Folder not found", nil)
+		}
+		folderObjID = &id
+	}
+
+	updated, err := s.chatRepo.SetFolder(ctx, ids, folderObjID)
+	if err != nil {
+		return 0, err
+	}
+
+	s.publishChatsUpdated(ctx, ids, "folder_id")
+
+	return updated, nil
+}
+
+// UpdateChatTags adds and removes tags on a set of chats
+func (s *ChatServiceImpl) UpdateChatTags(ctx context.Context, chatIDs []string, add, remove []string) (int64, error) {
+	ids, err := parseObjectIDs(chatIDs)
+	if err != nil {
+		return 0, err
+	}
+
+	if add, err = normalizeTags(add); err != nil {
+		return 0, err
+	}
+	if remove, err = normalizeTags(remove); err != nil {
+		return 0, err
+	}
+
+	if len(add) == 0 && len(remove) == 0 {
+		return 0, apperrors.NewValidationError("At least one tag to add or remove is required", nil)
+	}
+
+	if err := s.authorizeChats(ctx, ids, models.ChatRoleEditor); err != nil {
+		return 0, err
+	}
+
+	updated, err := s.chatRepo.UpdateTags(ctx, ids, add, remove)
+	if err != nil {
+		return 0, err
+	}
+
+	s.publishChatsUpdated(ctx, ids, "tags")
+
+	return updated, nil
+}
+
+// publishChatsUpdated broadcasts the current state of the given chats to their subscribers
+func (s *ChatServiceImpl) publishChatsUpdated(ctx context.Context, ids []primitive.ObjectID, fields ...string) {
+	chats, err := s.chatRepo.FindByIDs(ctx, ids)
+	if err != nil {
+		logger.FromContext(ctx).Warnf("Failed to load updated chats for broadcasting: %v", err)
+		return
+	}
+
+	for _, chat := range chats {
+		publishChatUpdated(ctx, s.publisher, chat, fields...)
+	}
+}
+
+// DeleteChat moves a chat and all its messages to the trash
 func (s *ChatServiceImpl) DeleteChat(ctx context.Context, id string) error {
-	chatID, err := primitive.ObjectIDFromHex(id)
+	chat, err := s.loadChat(ctx, id, models.ChatRoleOwner)
 	if err != nil {
 		return err
 	}
+	chatID := chat.ID
+
+	deletedAt := time.Now()
 
-	// Delete all messages first
-	if err := s.messageRepo.DeleteByChatID(ctx, chatID); err != nil {
+	err = s.tx.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := s.messageRepo.DeleteByChatID(ctx, chatID, deletedAt); err != nil {
+			return err
+		}
+
+		return s.chatRepo.Delete(ctx, chatID, deletedAt)
+	})
+	if err != nil {
 		return err
 	}
 
-	// Then delete the chat
-	return s.chatRepo.Delete(ctx, chatID)
+	publishEvent(ctx, s.publisher, chat.TenantID, chatID.Hex(), sse.EventChatDeleted, sse.ChatDeletedEvent{
+		ChatID:    chatID.Hex(),
+		DeletedAt: deletedAt,
+	})
+
+	return nil
+}
+
+// RestoreChat moves a chat out of the trash together with the messages deleted with it
+func (s *ChatServiceImpl) RestoreChat(ctx context.Context, id string) (*models.Chat, error) {
+	chatID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	chat, err := s.chatRepo.FindDeletedByID(ctx, chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	if chat == nil {
+		return nil, apperrors.NewNotFoundError("Chat not found in trash", nil)
+	}
+
+	if err := authorizeChat(ctx, chat, models.ChatRoleOwner); err != nil {
+		return nil, apperrors.NewNotFoundError("Chat not found in trash", nil)
+	}
+
+	err = s.tx.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := s.messageRepo.RestoreByChatID(ctx, chatID); err != nil {
+			return err
+		}
+
+		return s.chatRepo.Restore(ctx, chatID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	chat.DeletedAt = nil
+	chat.UpdatedAt = time.Now()
+
+	publishChatEvent(ctx, s.publisher, chat, sse.EventChatRestored)
+
+	return chat, nil
+}
+
+// normalizeChatListOptions applies the default sort order and validates the sort field
+func normalizeChatListOptions(opts repository.ChatListOptions) (repository.ChatListOptions, error) {
+	if opts.Sort.Field == "" {
+		opts.Sort = repository.DefaultChatSort()
+	}
+
+	if !repository.IsValidChatSortField(opts.Sort.Field) {
+		return opts, apperrors.NewValidationError("Invalid sort field: "+opts.Sort.Field, nil)
+	}
+
+	return opts, nil
+}
+
+// maxTagLength is the maximum length of a single chat tag
+const maxTagLength = 64
+
+// normalizeTags trims, lowercases and de-duplicates tags
+func normalizeTags(tags []string) ([]string, error) {
+	seen := make(map[string]bool, len(tags))
+	normalized := make([]string, 0, len(tags))
+
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if len(tag) > maxTagLength {
+			return nil, apperrors.NewValidationError(fmt.Sprintf("Tag %q exceeds %d characters", tag, maxTagLength), nil)
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+
+	return normalized, nil
+}
+
+// parseObjectIDs converts hex IDs into ObjectIDs
+func parseObjectIDs(ids []string) ([]primitive.ObjectID, error) {
+	objIDs := make([]primitive.ObjectID, 0, len(ids))
+	for _, id := range ids {
+		objID, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			return nil, apperrors.NewBadRequestError("Invalid chat ID: "+id, err)
+		}
+		objIDs = append(objIDs, objID)
+	}
+	return objIDs, nil
 }
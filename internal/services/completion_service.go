@@ -0,0 +1,210 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/ai"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/models"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/repository"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/shutdown"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/tenant"
+	apperrors "github.com/yasin-yalcin-dev/go-sse-ai-chat/pkg/errors"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/pkg/logger"
+)
+
+// CompletionRequest describes a completion requested through the OpenAI-compatible API
+type CompletionRequest struct {
+	ai.Request
+	ChatID string // Chat to store the exchange in; empty stores nothing
+}
+
+// CompletionResult describes a finished completion
+type CompletionResult struct {
+	ai.Result
+	Content   string
+	MessageID string // ID of the stored assistant message; empty when the exchange was not stored
+}
+
+// CompletionServiceImpl implements the CompletionService interface
+type CompletionServiceImpl struct {
+	providers         AIProviders
+	messageService    MessageService
+	generationService GenerationService
+	messageRepo       repository.MessageRepository
+	usageRepo         repository.UsageRepository
+	quotas            QuotaProvider
+	tracker           *shutdown.Tracker
+}
+
+// NewCompletionService creates a new completion service. Completions that are
+// not stored in a chat register with tracker, so that shutdown waits for them;
+// stored ones are tracked as generations. quotas may be nil, in which case
+// completions that are not stored are not limited.
+func NewCompletionService(providers AIProviders, messageService MessageService, generationService GenerationService, messageRepo repository.MessageRepository, usageRepo repository.UsageRepository, quotas QuotaProvider, tracker *shutdown.Tracker) CompletionService {
+	return &CompletionServiceImpl{
+		providers:         providers,
+		messageService:    messageService,
+		generationService: generationService,
+		messageRepo:       messageRepo,
+		usageRepo:         usageRepo,
+		quotas:            quotas,
+		tracker:           tracker,
+	}
+}
+
+// CreateCompletion generates a completion with the provider of the caller's
+// tenant, passing each chunk of output to emit. With a chat ID, the last
+// message of the request and the reply are stored in that chat, and the reply
+// streams to the chat's subscribers as a generation. Either way, the exchange
+// counts as two messages against the message quota; exchanges that are not
+// stored are only charged once they succeed. The tokens of every successful
+// completion are recorded as the tenant's usage. A request without a model is
+// given the provider's default model before the first chunk is emitted.
+func (s *CompletionServiceImpl) CreateCompletion(ctx context.Context, req *CompletionRequest, emit func(chunk string) error) (*CompletionResult, error) {
+	provider, err := s.providers.Provider(tenant.FromContext(ctx), "")
+	if err != nil {
+		return nil, apperrors.NewServiceUnavailableError("AI provider is not available", err)
+	}
+	if req.Model == "" {
+		req.Model = provider.DefaultModel()
+	}
+
+	if req.ChatID != "" {
+		return s.storedCompletion(ctx, provider, req, emit)
+	}
+
+	if err := checkMessageQuota(ctx, s.quotas, s.messageRepo, s.usageRepo); err != nil {
+		return nil, err
+	}
+
+	ctx, done, err := s.tracker.Start(ctx)
+	if err != nil {
+		return nil, apperrors.NewServiceUnavailableError("Server is shutting down; retry on another instance", err)
+	}
+	defer done()
+
+	result, err := streamCompletion(ctx, provider, req.Request, emit)
+	if err != nil {
+		return nil, err
+	}
+
+	// Charge the prompt and the reply, as storing them would, even when the
+	// client went away after the last chunk
+	ctx = context.WithoutCancel(ctx)
+	if err := s.usageRepo.AddMessages(ctx, quotaDay(), 2); err != nil {
+		logger.FromContext(ctx).Errorf("Failed to charge completion to the message quota: %v", err)
+	}
+	s.recordUsage(ctx, result.Usage)
+
+	return result, nil
+}
+
+// recordUsage adds the tokens of a successful completion to the tenant's
+// usage. The reply was already sent, so failures are only logged.
+func (s *CompletionServiceImpl) recordUsage(ctx context.Context, usage ai.Usage) {
+	if err := s.usageRepo.AddTokens(ctx, quotaDay(), usage.PromptTokens, usage.CompletionTokens); err != nil {
+		logger.FromContext(ctx).Errorf("Failed to record token usage: %v", err)
+	}
+}
+
+// storedCompletion generates a completion whose exchange is stored in a chat
+func (s *CompletionServiceImpl) storedCompletion(ctx context.Context, provider ai.Provider, req *CompletionRequest, emit func(chunk string) error) (*CompletionResult, error) {
+	last := req.Messages[len(req.Messages)-1]
+	if last.Role != ai.RoleUser {
+		return nil, apperrors.NewBadRequestError("The last message must be a user message to store the exchange in a chat", nil)
+	}
+
+	if _, err := s.messageService.CreateMessage(ctx, req.ChatID, last.Content, models.RoleUser, models.TypeText); err != nil {
+		return nil, err
+	}
+
+	recorder, err := s.generationService.StartGeneration(ctx, req.ChatID, provider.Name(), req.Model)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := streamCompletion(recorder.Context(), provider, req.Request, func(chunk string) error {
+		if err := emit(chunk); err != nil {
+			return err
+		}
+		return recorder.Append(chunk)
+	})
+	if err != nil {
+		if failErr := recorder.Fail(err); failErr != nil {
+			logger.FromContext(ctx).Errorf("Failed to record generation failure: %v", failErr)
+		}
+		return nil, err
+	}
+
+	recorder.SetMessageMetadata("model", result.Model)
+	recorder.SetMessageMetadata("usage", map[string]interface{}{
+		"prompt_tokens":     result.Usage.PromptTokens,
+		"completion_tokens": result.Usage.CompletionTokens,
+		"total_tokens":      result.Usage.TotalTokens(),
+	})
+	if err := recorder.Complete(); err != nil {
+		return nil, err
+	}
+	s.recordUsage(context.WithoutCancel(ctx), result.Usage)
+
+	result.MessageID = recorder.Generation().MessageID.Hex()
+	return result, nil
+}
+
+// streamCompletion generates a completion and collects its output. Errors
+// returned by emit are passed on as they are; errors of the provider are
+// converted into the errors reported to clients.
+func streamCompletion(ctx context.Context, provider ai.Provider, req ai.Request, emit func(chunk string) error) (*CompletionResult, error) {
+	var content strings.Builder
+	var emitErr error
+	result, err := provider.Stream(ctx, req, func(chunk string) error {
+		content.WriteString(chunk)
+		emitErr = emit(chunk)
+		return emitErr
+	})
+	if emitErr != nil {
+		return nil, emitErr
+	}
+	if err != nil {
+		return nil, providerError(err)
+	}
+
+	return &CompletionResult{Result: *result, Content: content.String()}, nil
+}
+
+// providerError converts an error of an AI provider into the error reported to clients
+func providerError(err error) error {
+	if _, ok := apperrors.AsAppError(err); ok || errors.Is(err, context.Canceled) {
+		return err
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return apperrors.NewTimeoutError("AI provider did not finish in time", err)
+	}
+
+	var providerErr *ai.Error
+	if !errors.As(err, &providerErr) {
+		return apperrors.NewExternalServiceError("AI provider request failed", err)
+	}
+
+	switch providerErr.StatusCode {
+	case http.StatusBadRequest, http.StatusNotFound, http.StatusRequestEntityTooLarge, http.StatusUnprocessableEntity:
+		// The request itself was rejected, for example for an unknown model
+		return apperrors.NewBadRequestError(providerErr.Message, err)
+	case http.StatusTooManyRequests:
+		return apperrors.NewRateLimitError("AI provider is rate limiting requests", err)
+	default:
+		return apperrors.NewExternalServiceError("AI provider request failed", err)
+	}
+}
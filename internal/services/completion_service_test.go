@@ -0,0 +1,395 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/ai"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/models"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/shutdown"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/tenant"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// scriptedProvider is an ai.Provider that streams fixed chunks, failing with
+// err after them when it is set
+type scriptedProvider struct {
+	chunks []string
+	usage  ai.Usage
+	err    error
+	calls  int
+}
+
+func (p *scriptedProvider) Name() string         { return "scripted" }
+func (p *scriptedProvider) DefaultModel() string { return "scripted-1" }
+
+func (p *scriptedProvider) Stream(_ context.Context, req ai.Request, emit func(chunk string) error) (*ai.Result, error) {
+	p.calls++
+	for _, chunk := range p.chunks {
+		if err := emit(chunk); err != nil {
+			return nil, err
+		}
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	return &ai.Result{Model: req.Model, FinishReason: "stop", Usage: p.usage}, nil
+}
+
+// singleProvider serves one provider to every tenant
+type singleProvider struct {
+	provider ai.Provider
+}
+
+func (p singleProvider) Provider(string, string) (ai.Provider, error) {
+	return p.provider, nil
+}
+
+// fixedQuotas applies the same quotas to every tenant
+type fixedQuotas tenant.Quotas
+
+func (q fixedQuotas) Quotas(string) tenant.Quotas {
+	return tenant.Quotas(q)
+}
+
+// usageStore is an in-memory UsageRepository for a single tenant
+type usageStore struct {
+	days  map[time.Time]*models.Usage
+	mutex sync.Mutex
+}
+
+func newUsageStore() *usageStore {
+	return &usageStore{days: make(map[time.Time]*models.Usage)}
+}
+
+func (s *usageStore) day(day time.Time) *models.Usage {
+	if _, ok := s.days[day]; !ok {
+		s.days[day] = &models.Usage{Day: day}
+	}
+	return s.days[day]
+}
+
+func (s *usageStore) AddMessages(_ context.Context, day time.Time, count int) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.day(day).Messages += int64(count)
+	return nil
+}
+
+func (s *usageStore) CountMessages(_ context.Context, day time.Time) (int64, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.day(day).Messages, nil
+}
+
+func (s *usageStore) AddTokens(_ context.Context, day time.Time, promptTokens, completionTokens int) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	usage := s.day(day)
+	usage.Completions++
+	usage.PromptTokens += int64(promptTokens)
+	usage.CompletionTokens += int64(completionTokens)
+	return nil
+}
+
+func (s *usageStore) FindByDays(_ context.Context, from, to time.Time) ([]*models.Usage, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	var usage []*models.Usage
+	for day, counters := range s.days {
+		if !day.Before(from) && !day.After(to) {
+			found := *counters
+			usage = append(usage, &found)
+		}
+	}
+	return usage, nil
+}
+
+// today returns the counters of the current quota day
+func (s *usageStore) today() models.Usage {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return *s.day(quotaDay())
+}
+
+// completionFixture is a completion service backed by in-memory repositories
+type completionFixture struct {
+	*sharedChat
+	provider    *scriptedProvider
+	usage       *usageStore
+	generations *generationStore
+	service     CompletionService
+}
+
+func newCompletionFixture(quotas QuotaProvider) *completionFixture {
+	f := &completionFixture{
+		sharedChat: newSharedChat(),
+		provider: &scriptedProvider{
+			chunks: []string{"Hel", "lo"},
+			usage:  ai.Usage{PromptTokens: 12, CompletionTokens: 3},
+		},
+		usage:       newUsageStore(),
+		generations: newGenerationStore(),
+	}
+
+	messageService := NewMessageService(f.messages, f.chats, f.usage, noTransactions{}, nil, quotas)
+	generationService := NewGenerationService(f.generations, f.chats, f.messages, noTransactions{}, nil, shutdown.NewTracker(), nil, time.Hour)
+	f.service = NewCompletionService(singleProvider{f.provider}, messageService, generationService, f.messages, f.usage, quotas, shutdown.NewTracker())
+	return f
+}
+
+// complete runs a completion of a single user message, collecting its chunks
+func (f *completionFixture) complete(ctx context.Context, chatID string, emit func(chunk string) error) (*CompletionResult, []string, error) {
+	var chunks []string
+	req := &CompletionRequest{
+		Request: ai.Request{Messages: []ai.Message{{Role: ai.RoleUser, Content: "Hi"}}},
+		ChatID:  chatID,
+	}
+	result, err := f.service.CreateCompletion(ctx, req, func(chunk string) error {
+		chunks = append(chunks, chunk)
+		if emit != nil {
+			return emit(chunk)
+		}
+		return nil
+	})
+	return result, chunks, err
+}
+
+func TestCreateCompletionChargesAfterSuccess(t *testing.T) {
+	f := newCompletionFixture(fixedQuotas{MaxMessagesPerDay: 100})
+
+	result, chunks, err := f.complete(as("bob"), "", nil)
+	if err != nil {
+		t.Fatalf("CreateCompletion() error = %v", err)
+	}
+	if result.Content != "Hello" || result.Model != "scripted-1" {
+		t.Errorf("CreateCompletion() = %q from %q, want %q from %q", result.Content, result.Model, "Hello", "scripted-1")
+	}
+	if want := []string{"Hel", "lo"}; !slices.Equal(chunks, want) {
+		t.Errorf("emitted chunks = %v, want %v", chunks, want)
+	}
+
+	want := models.Usage{Day: quotaDay(), Messages: 2, Completions: 1, PromptTokens: 12, CompletionTokens: 3}
+	if got := f.usage.today(); got != want {
+		t.Errorf("usage = %+v, want %+v", got, want)
+	}
+}
+
+func TestCreateCompletionFailuresAreNotCharged(t *testing.T) {
+	errClientGone := errors.New("client went away")
+	tests := []struct {
+		name        string
+		providerErr error
+		emit        func(chunk string) error
+		wantErr     error
+		wantChunks  []string
+	}{
+		{
+			name:        "provider fails after the first chunk",
+			providerErr: &ai.Error{StatusCode: http.StatusInternalServerError, Message: "overloaded"},
+			wantChunks:  []string{"Hel", "lo"},
+		},
+		{
+			name:       "client goes away after the first chunk",
+			emit:       func(string) error { return errClientGone },
+			wantErr:    errClientGone,
+			wantChunks: []string{"Hel"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newCompletionFixture(fixedQuotas{MaxMessagesPerDay: 100})
+			f.provider.err = tt.providerErr
+
+			_, chunks, err := f.complete(as("bob"), "", tt.emit)
+			if err == nil {
+				t.Fatal("CreateCompletion() succeeded, want an error")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("CreateCompletion() error = %v, want %v", err, tt.wantErr)
+			}
+			if !slices.Equal(chunks, tt.wantChunks) {
+				t.Errorf("emitted chunks = %v, want %v", chunks, tt.wantChunks)
+			}
+
+			if got := f.usage.today(); got != (models.Usage{Day: quotaDay()}) {
+				t.Errorf("usage = %+v, want nothing charged", got)
+			}
+		})
+	}
+}
+
+func TestCreateCompletionQuotaExceeded(t *testing.T) {
+	f := newCompletionFixture(fixedQuotas{MaxMessagesPerDay: 3})
+
+	if _, _, err := f.complete(as("bob"), "", nil); err != nil {
+		t.Fatalf("first CreateCompletion() error = %v", err)
+	}
+
+	// The fixture's stored message and the first exchange use up the quota
+	_, _, err := f.complete(as("bob"), "", nil)
+	if got := statusOf(err); got != http.StatusForbidden {
+		t.Fatalf("second CreateCompletion() status = %d, want %d", got, http.StatusForbidden)
+	}
+	if f.provider.calls != 1 {
+		t.Errorf("provider called %d times, want 1", f.provider.calls)
+	}
+}
+
+func TestCreateCompletionStoresExchange(t *testing.T) {
+	f := newCompletionFixture(nil)
+
+	result, _, err := f.complete(as("bob"), f.chat.ID.Hex(), nil)
+	if err != nil {
+		t.Fatalf("CreateCompletion() error = %v", err)
+	}
+
+	messages := f.messages.live(f.chat.ID)
+	if len(messages) != 3 {
+		t.Fatalf("chat has %d messages, want 3", len(messages))
+	}
+	prompt, reply := messages[1], messages[2]
+	if prompt.Role != models.RoleUser || prompt.Content != "Hi" {
+		t.Errorf("stored prompt = %s %q, want user %q", prompt.Role, prompt.Content, "Hi")
+	}
+	if reply.Role != models.RoleAssistant || reply.Content != "Hello" || reply.ID.Hex() != result.MessageID {
+		t.Errorf("stored reply = %s %q (%s), want assistant %q (%s)", reply.Role, reply.Content, reply.ID.Hex(), "Hello", result.MessageID)
+	}
+	if _, ok := reply.Metadata["usage"]; !ok {
+		t.Error("stored reply has no usage metadata")
+	}
+
+	// Stored messages count against the quota themselves
+	want := models.Usage{Day: quotaDay(), Completions: 1, PromptTokens: 12, CompletionTokens: 3}
+	if got := f.usage.today(); got != want {
+		t.Errorf("usage = %+v, want %+v", got, want)
+	}
+}
+
+func TestCreateCompletionStoredFailure(t *testing.T) {
+	f := newCompletionFixture(nil)
+	f.provider.err = &ai.Error{StatusCode: http.StatusInternalServerError, Message: "overloaded"}
+
+	if _, _, err := f.complete(as("bob"), f.chat.ID.Hex(), nil); err == nil {
+		t.Fatal("CreateCompletion() succeeded, want an error")
+	}
+
+	generation := f.generations.only(t)
+	if generation.Status != models.GenerationFailed || generation.Content != "Hello" {
+		t.Errorf("generation = %s with %q, want %s with the partial output", generation.Status, generation.Content, models.GenerationFailed)
+	}
+	if got := len(f.messages.live(f.chat.ID)); got != 2 {
+		t.Errorf("chat has %d messages, want the prompt only added", got)
+	}
+	if got := f.usage.today(); got != (models.Usage{Day: quotaDay()}) {
+		t.Errorf("usage = %+v, want no tokens recorded", got)
+	}
+}
+
+func TestCreateCompletionStoredRequiresEditor(t *testing.T) {
+	f := newCompletionFixture(nil)
+
+	_, _, err := f.complete(as("carol"), f.chat.ID.Hex(), nil)
+	if got := statusOf(err); got != http.StatusForbidden {
+		t.Fatalf("CreateCompletion() by a viewer status = %d, want %d", got, http.StatusForbidden)
+	}
+	if f.provider.calls != 0 {
+		t.Errorf("provider called %d times, want 0", f.provider.calls)
+	}
+}
+
+// generationStore is an in-memory GenerationRepository
+type generationStore struct {
+	generations map[primitive.ObjectID]*models.Generation
+	mutex       sync.Mutex
+}
+
+func newGenerationStore() *generationStore {
+	return &generationStore{generations: make(map[primitive.ObjectID]*models.Generation)}
+}
+
+// only returns the single stored generation
+func (s *generationStore) only(t *testing.T) models.Generation {
+	t.Helper()
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if len(s.generations) != 1 {
+		t.Fatalf("%d generations stored, want 1", len(s.generations))
+	}
+	for _, generation := range s.generations {
+		return *generation
+	}
+	return models.Generation{}
+}
+
+func (s *generationStore) Create(_ context.Context, generation *models.Generation) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	stored := *generation
+	s.generations[generation.ID] = &stored
+	return nil
+}
+
+func (s *generationStore) FindByID(_ context.Context, id primitive.ObjectID) (*models.Generation, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	generation, ok := s.generations[id]
+	if !ok {
+		return nil, nil
+	}
+	found := *generation
+	return &found, nil
+}
+
+func (s *generationStore) FindByChatID(context.Context, primitive.ObjectID, models.GenerationStatus, int) ([]*models.Generation, error) {
+	return nil, nil
+}
+
+func (s *generationStore) Checkpoint(_ context.Context, id primitive.ObjectID, content string, at time.Time) (bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	generation, ok := s.generations[id]
+	if !ok || generation.Status != models.GenerationRunning {
+		return false, nil
+	}
+	generation.Content = content
+	generation.UpdatedAt = at
+	return true, nil
+}
+
+func (s *generationStore) Finish(_ context.Context, id primitive.ObjectID, status models.GenerationStatus, content, errMsg string, at time.Time) (bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	generation, ok := s.generations[id]
+	if !ok || generation.Status != models.GenerationRunning {
+		return false, nil
+	}
+	generation.Status = status
+	generation.Content = content
+	generation.Error = errMsg
+	generation.FinishedAt = &at
+	return true, nil
+}
+
+func (s *generationStore) Transition(context.Context, primitive.ObjectID, models.GenerationStatus, models.GenerationStatus) (bool, error) {
+	return false, nil
+}
+
+func (s *generationStore) InterruptStale(context.Context, time.Time, int) ([]*models.Generation, error) {
+	return nil, nil
+}
@@ -0,0 +1,59 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package services
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/models"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/sse"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/pkg/logger"
+)
+
+// EventPublisher delivers events to the clients subscribed to a chat
+type EventPublisher interface {
+	SendToChat(ctx context.Context, tenantID, chatID string, messageID string, event string, data interface{}) error
+}
+
+// publishEvent sends an event to a chat's subscribers under a new event ID.
+// Publishing is best effort: failures are logged and never fail the operation.
+func publishEvent(ctx context.Context, publisher EventPublisher, tenantID, chatID, event string, data interface{}) {
+	if publisher == nil {
+		return
+	}
+
+	if err := publisher.SendToChat(ctx, tenantID, chatID, uuid.NewString(), event, data); err != nil {
+		logger.FromContext(ctx).Warnf("Failed to publish %s event for chat %s: %v", event, chatID, err)
+	}
+}
+
+// publishChatUpdated notifies a chat's subscribers that some of its fields changed
+func publishChatUpdated(ctx context.Context, publisher EventPublisher, chat *models.Chat, fields ...string) {
+	if chat == nil {
+		return
+	}
+
+	chatID := chat.ID.Hex()
+	publishEvent(ctx, publisher, chat.TenantID, chatID, sse.EventChatUpdated, sse.ChatUpdatedEvent{
+		ChatID: chatID,
+		Fields: fields,
+		Chat:   chat,
+	})
+}
+
+// publishChatEvent notifies a chat's subscribers that it was created or restored
+func publishChatEvent(ctx context.Context, publisher EventPublisher, chat *models.Chat, event string) {
+	chatID := chat.ID.Hex()
+	publishEvent(ctx, publisher, chat.TenantID, chatID, event, sse.ChatEvent{
+		ChatID: chatID,
+		Chat:   chat,
+	})
+}
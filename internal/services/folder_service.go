@@ -0,0 +1,142 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package services
+
+import (
+	"context"
+	"strings"
+
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/models"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/repository"
+	apperrors "github.com/yasin-yalcin-dev/go-sse-ai-chat/pkg/errors"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/pkg/logger"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// FolderServiceImpl implements the FolderService interface
+type FolderServiceImpl struct {
+	folderRepo repository.FolderRepository
+	chatRepo   repository.ChatRepository
+	publisher  EventPublisher
+}
+
+// NewFolderService creates a new folder service
+func NewFolderService(folderRepo repository.FolderRepository, chatRepo repository.ChatRepository, publisher EventPublisher) FolderService {
+	return &FolderServiceImpl{
+		folderRepo: folderRepo,
+		chatRepo:   chatRepo,
+		publisher:  publisher,
+	}
+}
+
+// CreateFolder creates a new folder
+func (s *FolderServiceImpl) CreateFolder(ctx context.Context, name string) (*models.Folder, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, apperrors.NewValidationError("Folder name is required", nil)
+	}
+
+	folder := models.NewFolder(name)
+	if subject, ok := callerSubject(ctx); ok {
+		folder.OwnerID = subject
+	}
+
+	if err := s.folderRepo.Create(ctx, folder); err != nil {
+		return nil, err
+	}
+
+	return folder, nil
+}
+
+// GetFolderByID retrieves a folder by its ID
+func (s *FolderServiceImpl) GetFolderByID(ctx context.Context, id string) (*models.Folder, error) {
+	folderID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, apperrors.NewBadRequestError("Invalid folder ID", err)
+	}
+
+	folder, err := s.folderRepo.FindByID(ctx, folderID)
+	if err != nil {
+		return nil, err
+	}
+
+	if folder == nil || !canManageFolder(ctx, folder) {
+		return nil, apperrors.NewNotFoundError("Folder not found", nil)
+	}
+
+	return folder, nil
+}
+
+// ListFolders retrieves the caller's folders
+func (s *FolderServiceImpl) ListFolders(ctx context.Context) ([]*models.Folder, error) {
+	subject, _ := callerSubject(ctx)
+	return s.folderRepo.FindAll(ctx, subject)
+}
+
+// canManageFolder reports whether the caller owns the folder
+func canManageFolder(ctx context.Context, folder *models.Folder) bool {
+	subject, ok := callerSubject(ctx)
+	return !ok || folder.OwnerID == subject
+}
+
+// UpdateFolder renames a folder
+func (s *FolderServiceImpl) UpdateFolder(ctx context.Context, id string, name string) (*models.Folder, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, apperrors.NewValidationError("Folder name is required", nil)
+	}
+
+	folder, err := s.GetFolderByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	folder.Name = name
+
+	if err := s.folderRepo.Update(ctx, folder); err != nil {
+		return nil, err
+	}
+
+	return folder, nil
+}
+
+// DeleteFolder deletes a folder; the chats it contains are moved out of it, not deleted
+func (s *FolderServiceImpl) DeleteFolder(ctx context.Context, id string) error {
+	folder, err := s.GetFolderByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	// Remember which chats were in the folder so they can be notified
+	folderID := folder.ID
+	chats, err := s.chatRepo.FindAll(ctx, repository.ChatListOptions{
+		Filter: repository.ChatFilter{FolderID: &folderID},
+		Sort:   repository.DefaultChatSort(),
+	}, 0, 0)
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.chatRepo.ClearFolder(ctx, folderID); err != nil {
+		return err
+	}
+
+	if err := s.folderRepo.Delete(ctx, folderID); err != nil {
+		return err
+	}
+
+	for _, chat := range chats {
+		chat.FolderID = nil
+		publishChatUpdated(ctx, s.publisher, chat, "folder_id")
+	}
+
+	logger.FromContext(ctx).Infof("Deleted folder %s, moved %d chats out of it", id, len(chats))
+	return nil
+}
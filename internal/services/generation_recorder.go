@@ -0,0 +1,310 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/models"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/shutdown"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/sse"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/pkg/logger"
+)
+
+// checkpointTimeout bounds each write of a generation's progress
+const checkpointTimeout = 5 * time.Second
+
+var (
+	// errGenerationCanceled cancels a generation stopped at a user's request
+	errGenerationCanceled = errors.New("generation canceled")
+	// errGenerationStopped cancels a generation that another instance or the sweeper finished
+	errGenerationStopped = errors.New("generation is no longer running")
+)
+
+// GenerationRecorder records the progress of a running generation. Output is
+// streamed to the chat as it arrives and saved every checkpoint interval;
+// Complete or Fail must be called when the generation ends.
+type GenerationRecorder struct {
+	service    *GenerationServiceImpl
+	generation *models.Generation
+	ctx        context.Context
+	cancel     context.CancelCauseFunc
+	done       func()
+	content    strings.Builder
+	metadata   map[string]interface{} // Metadata of the assistant message stored on completion
+	finished   bool
+	stop       chan struct{}
+	stopped    chan struct{}
+	mutex      sync.Mutex
+}
+
+// newGenerationRecorder starts recording a stored generation. ctx is the
+// tracked context of the generation and done releases it.
+func newGenerationRecorder(ctx context.Context, done func(), service *GenerationServiceImpl, generation *models.Generation) *GenerationRecorder {
+	ctx, cancel := context.WithCancelCause(ctx)
+	r := &GenerationRecorder{
+		service:    service,
+		generation: generation,
+		ctx:        logger.WithContext(ctx, logger.FieldGenerationID, generation.ID.Hex()),
+		cancel:     cancel,
+		done:       done,
+		stop:       make(chan struct{}),
+		stopped:    make(chan struct{}),
+	}
+	r.content.WriteString(generation.Content)
+
+	go r.checkpointLoop()
+
+	return r
+}
+
+// Context returns the context the generation should run in. It is canceled
+// with shutdown.ErrShuttingDown when the server cannot wait for it any longer,
+// and when the generation is canceled by a user.
+func (r *GenerationRecorder) Context() context.Context {
+	return r.ctx
+}
+
+// Cancel stops the generation at a user's request. The generator sees its
+// context canceled; Fail then records the generation as canceled.
+func (r *GenerationRecorder) Cancel() {
+	r.cancel(errGenerationCanceled)
+}
+
+// Generation returns the recorded generation
+func (r *GenerationRecorder) Generation() *models.Generation {
+	return r.generation
+}
+
+// SetMessageMetadata adds a metadata entry to the assistant message stored on completion
+func (r *GenerationRecorder) SetMessageMetadata(key string, value interface{}) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.metadata == nil {
+		r.metadata = make(map[string]interface{})
+	}
+	r.metadata[key] = value
+}
+
+// Append adds a chunk of output and streams it to the chat's subscribers
+func (r *GenerationRecorder) Append(chunk string) error {
+	r.mutex.Lock()
+	if r.finished {
+		r.mutex.Unlock()
+		return errors.New("generation already finished")
+	}
+	r.content.WriteString(chunk)
+	r.mutex.Unlock()
+
+	if r.service.publisher == nil {
+		return nil
+	}
+
+	chatID := r.generation.ChatID.Hex()
+	event := sse.MessageChunkEvent{
+		ID:           r.generation.MessageID.Hex(),
+		ChatID:       chatID,
+		GenerationID: r.generation.ID.Hex(),
+		Content:      chunk,
+		Type:         models.TypeText,
+		Role:         models.RoleAssistant,
+		CreatedAt:    time.Now(),
+		IsChunk:      true,
+	}
+
+	// Chunks carry no event ID: replaying them after a reconnect would
+	// duplicate output, and the complete reply is stored as a message
+	return r.service.publisher.SendToChat(r.ctx, r.generation.TenantID, chatID, "", sse.EventMessage, event)
+}
+
+// Complete stores the output as an assistant message and marks the generation completed
+func (r *GenerationRecorder) Complete() error {
+	content, ok := r.finish()
+	if !ok {
+		return nil
+	}
+	defer r.done()
+	defer r.cancel(nil)
+
+	ctx, cancel := r.writeContext()
+	defer cancel()
+
+	now := time.Now()
+	message := models.NewMessage(r.generation.ChatID, content, models.RoleAssistant, models.TypeText)
+	message.ID = r.generation.MessageID
+	message.CreatedAt = now
+	r.mutex.Lock()
+	for key, value := range r.metadata {
+		message.SetMetadata(key, value)
+	}
+	r.mutex.Unlock()
+
+	err := r.service.tx.WithTransaction(ctx, func(ctx context.Context) error {
+		finished, err := r.service.generationRepo.Finish(ctx, r.generation.ID, models.GenerationCompleted, content, "", now)
+		if err != nil {
+			return err
+		}
+		if !finished {
+			return errGenerationStopped
+		}
+
+		if err := r.service.messageRepo.Create(ctx, message); err != nil {
+			return err
+		}
+
+		return r.service.chatRepo.IncrementMessageCount(ctx, r.generation.ChatID, now)
+	})
+	if err != nil {
+		return err
+	}
+
+	r.generation.Status = models.GenerationCompleted
+	r.generation.Content = content
+	r.generation.FinishedAt = &now
+
+	if r.service.publisher != nil {
+		chatID := r.generation.ChatID.Hex()
+		event := sse.CompleteEvent{
+			ID:           message.ID.Hex(),
+			ChatID:       chatID,
+			GenerationID: r.generation.ID.Hex(),
+		}
+		if err := r.service.publisher.SendToChat(ctx, r.generation.TenantID, chatID, message.ID.Hex(), sse.EventComplete, event); err != nil {
+			logger.FromContext(ctx).Warnf("Failed to publish complete event: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// Fail records that the generation ended with cause. A generation canceled by
+// shutdown is marked interrupted, so that it can be continued later; one
+// canceled by a user is marked canceled.
+func (r *GenerationRecorder) Fail(cause error) error {
+	content, ok := r.finish()
+	if !ok {
+		return nil
+	}
+	defer r.done()
+	defer r.cancel(nil)
+
+	status := models.GenerationFailed
+	switch reason := context.Cause(r.ctx); {
+	case errors.Is(reason, shutdown.ErrShuttingDown):
+		status = models.GenerationInterrupted
+	case errors.Is(reason, errGenerationCanceled):
+		status = models.GenerationCanceled
+	}
+
+	errMsg := ""
+	if cause != nil && status != models.GenerationCanceled {
+		errMsg = cause.Error()
+	}
+
+	ctx, cancel := r.writeContext()
+	defer cancel()
+
+	now := time.Now()
+	finished, err := r.service.generationRepo.Finish(ctx, r.generation.ID, status, content, errMsg, now)
+	if err != nil {
+		return err
+	}
+	if !finished {
+		// Canceled on another instance or marked interrupted by the sweeper
+		logger.FromContext(ctx).Infof("Generation was already finished elsewhere; its outcome %s is not recorded", status)
+		return nil
+	}
+
+	r.generation.Status = status
+	r.generation.Content = content
+	r.generation.Error = errMsg
+	r.generation.FinishedAt = &now
+
+	switch status {
+	case models.GenerationInterrupted:
+		publishGenerationInterrupted(ctx, r.service.publisher, r.generation, r.service.CanContinue())
+	case models.GenerationCanceled:
+		publishGenerationCanceled(ctx, r.service.publisher, r.generation)
+	}
+
+	return nil
+}
+
+// finish stops checkpointing and returns the final output. It reports false
+// if the generation was already finished.
+func (r *GenerationRecorder) finish() (string, bool) {
+	r.mutex.Lock()
+	if r.finished {
+		r.mutex.Unlock()
+		return "", false
+	}
+	r.finished = true
+	r.mutex.Unlock()
+
+	close(r.stop)
+	<-r.stopped
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.content.String(), true
+}
+
+// writeContext returns a context for saving progress that survives the
+// cancellation of the generation itself
+func (r *GenerationRecorder) writeContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.WithoutCancel(r.ctx), checkpointTimeout)
+}
+
+// checkpointLoop saves the output every checkpoint interval until the generation finishes.
+// A checkpoint is written even without new output, as a heartbeat that keeps
+// the sweeper from treating the generation as orphaned.
+func (r *GenerationRecorder) checkpointLoop() {
+	defer close(r.stopped)
+
+	ticker := time.NewTicker(r.service.checkpointInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.stop:
+			return
+
+		case <-ticker.C:
+			r.checkpoint()
+		}
+	}
+}
+
+// checkpoint saves the output produced so far
+func (r *GenerationRecorder) checkpoint() {
+	r.mutex.Lock()
+	content := r.content.String()
+	r.mutex.Unlock()
+
+	ctx, cancel := r.writeContext()
+	defer cancel()
+
+	running, err := r.service.generationRepo.Checkpoint(ctx, r.generation.ID, content, time.Now())
+	if err != nil {
+		logger.FromContext(ctx).Warnf("Failed to checkpoint generation: %v", err)
+		return
+	}
+	if !running {
+		// Stop producing output nobody records, for example after a cancel on another instance
+		logger.FromContext(ctx).Warn("Generation is no longer running; stopping it")
+		r.cancel(errGenerationStopped)
+	}
+}
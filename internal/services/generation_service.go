@@ -0,0 +1,396 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/models"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/repository"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/shutdown"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/sse"
+	apperrors "github.com/yasin-yalcin-dev/go-sse-ai-chat/pkg/errors"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/pkg/logger"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Limits on how many generations are read at once
+const (
+	maxListedGenerations = 50
+	staleGenerationBatch = 100
+)
+
+// Generator produces assistant replies with an AI provider
+type Generator interface {
+	// Generate streams the reply to a chat, continuing req.Prefix if set, to emit
+	Generate(ctx context.Context, req GenerationRequest, emit func(chunk string) error) error
+}
+
+// GenerationRequest describes a reply to generate
+type GenerationRequest struct {
+	Chat     *models.Chat
+	Provider string
+	Model    string
+	Prefix   string // Partial reply of an interrupted generation to continue; empty for a new reply
+}
+
+// GenerationServiceImpl implements the GenerationService interface
+type GenerationServiceImpl struct {
+	generationRepo     repository.GenerationRepository
+	chatRepo           repository.ChatRepository
+	messageRepo        repository.MessageRepository
+	tx                 repository.Transactor
+	publisher          EventPublisher
+	tracker            *shutdown.Tracker
+	generator          Generator
+	checkpointInterval time.Duration
+	running            map[primitive.ObjectID]*GenerationRecorder // Generations running on this instance
+	mutex              sync.Mutex
+}
+
+// NewGenerationService creates a new generation service. Running generations
+// are registered with tracker, so that shutdown waits for them, and save their
+// output every checkpointInterval. generator may be nil, in which case
+// interrupted generations cannot be continued.
+func NewGenerationService(generationRepo repository.GenerationRepository, chatRepo repository.ChatRepository, messageRepo repository.MessageRepository, tx repository.Transactor, publisher EventPublisher, tracker *shutdown.Tracker, generator Generator, checkpointInterval time.Duration) GenerationService {
+	return &GenerationServiceImpl{
+		generationRepo:     generationRepo,
+		chatRepo:           chatRepo,
+		messageRepo:        messageRepo,
+		tx:                 tx,
+		publisher:          publisher,
+		tracker:            tracker,
+		generator:          generator,
+		checkpointInterval: checkpointInterval,
+		running:            make(map[primitive.ObjectID]*GenerationRecorder),
+	}
+}
+
+// StartGeneration starts tracking a new assistant reply in a chat
+func (s *GenerationServiceImpl) StartGeneration(ctx context.Context, chatID string, provider, model string) (*GenerationRecorder, error) {
+	chatObjID, err := primitive.ObjectIDFromHex(chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	chat, err := findChat(ctx, s.chatRepo, chatObjID, models.ChatRoleEditor)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.start(ctx, models.NewGeneration(chat.ID, provider, model))
+}
+
+// start stores a new generation and starts recording its progress
+func (s *GenerationServiceImpl) start(ctx context.Context, generation *models.Generation) (*GenerationRecorder, error) {
+	ctx, done, err := s.tracker.Start(ctx)
+	if err != nil {
+		return nil, apperrors.NewServiceUnavailableError("Server is shutting down; retry on another instance", err)
+	}
+
+	if err := s.generationRepo.Create(ctx, generation); err != nil {
+		done()
+		return nil, err
+	}
+
+	// Keep the recorder reachable for CancelGeneration while it runs
+	recorder := newGenerationRecorder(ctx, func() {
+		s.mutex.Lock()
+		delete(s.running, generation.ID)
+		s.mutex.Unlock()
+		done()
+	}, s, generation)
+
+	s.mutex.Lock()
+	s.running[generation.ID] = recorder
+	s.mutex.Unlock()
+
+	return recorder, nil
+}
+
+// GetGeneration retrieves a generation by its ID
+func (s *GenerationServiceImpl) GetGeneration(ctx context.Context, id string) (*models.Generation, error) {
+	generation, _, err := s.findGeneration(ctx, id, models.ChatRoleViewer)
+	return generation, err
+}
+
+// findGeneration retrieves a generation and its chat, checking that the caller
+// holds at least the required role on the chat
+func (s *GenerationServiceImpl) findGeneration(ctx context.Context, id string, required models.ChatRole) (*models.Generation, *models.Chat, error) {
+	generationID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	generation, err := s.generationRepo.FindByID(ctx, generationID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if generation == nil {
+		return nil, nil, errGenerationNotFound()
+	}
+
+	chat, err := findChat(ctx, s.chatRepo, generation.ChatID, required)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, nil, errGenerationNotFound()
+		}
+		return nil, nil, err
+	}
+
+	return generation, chat, nil
+}
+
+// errGenerationNotFound returns the error reported for missing or inaccessible generations
+func errGenerationNotFound() error {
+	return apperrors.NewNotFoundError("Generation not found", nil)
+}
+
+// ListGenerations retrieves the most recent generations of a chat, optionally only those in status
+func (s *GenerationServiceImpl) ListGenerations(ctx context.Context, chatID string, status models.GenerationStatus) ([]*models.Generation, error) {
+	chatObjID, err := primitive.ObjectIDFromHex(chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	if status != "" && !status.IsValid() {
+		return nil, apperrors.NewBadRequestError("Invalid generation status", nil)
+	}
+
+	if _, err := findChat(ctx, s.chatRepo, chatObjID, models.ChatRoleViewer); err != nil {
+		return nil, err
+	}
+
+	return s.generationRepo.FindByChatID(ctx, chatObjID, status, maxListedGenerations)
+}
+
+// CanContinue reports whether interrupted generations can be continued
+func (s *GenerationServiceImpl) CanContinue() bool {
+	return s.generator != nil
+}
+
+// ContinueGeneration starts a new generation that picks up the reply of an
+// interrupted one where it stopped. The new generation runs in the background
+// and streams to the chat.
+func (s *GenerationServiceImpl) ContinueGeneration(ctx context.Context, id string) (*models.Generation, error) {
+	if s.generator == nil {
+		return nil, apperrors.NewServiceUnavailableError("Continuing generations requires an AI provider, and none is configured", nil)
+	}
+
+	interrupted, chat, err := s.findGeneration(ctx, id, models.ChatRoleEditor)
+	if err != nil {
+		return nil, err
+	}
+
+	// Claiming the interrupted generation ensures it is only continued once
+	claimed, err := s.generationRepo.Transition(ctx, interrupted.ID, models.GenerationInterrupted, models.GenerationResumed)
+	if err != nil {
+		return nil, err
+	}
+	if !claimed {
+		return nil, apperrors.NewConflictError("Only interrupted generations can be continued", nil)
+	}
+
+	generation := models.NewGeneration(chat.ID, interrupted.Provider, interrupted.Model)
+	generation.ResumedFrom = &interrupted.ID
+	generation.Content = interrupted.Content
+
+	// The generation outlives the request that started it
+	recorder, err := s.start(context.WithoutCancel(ctx), generation)
+	if err != nil {
+		if _, rollbackErr := s.generationRepo.Transition(ctx, interrupted.ID, models.GenerationResumed, models.GenerationInterrupted); rollbackErr != nil {
+			logger.FromContext(ctx).Errorf("Failed to release interrupted generation %s: %v", id, rollbackErr)
+		}
+		return nil, err
+	}
+
+	go s.run(recorder, GenerationRequest{
+		Chat:     chat,
+		Provider: generation.Provider,
+		Model:    generation.Model,
+		Prefix:   interrupted.Content,
+	})
+
+	return generation, nil
+}
+
+// run produces a reply with the generator and records its outcome
+func (s *GenerationServiceImpl) run(recorder *GenerationRecorder, req GenerationRequest) {
+	ctx := recorder.Context()
+
+	if err := s.generator.Generate(ctx, req, recorder.Append); err != nil {
+		if failErr := recorder.Fail(err); failErr != nil {
+			logger.FromContext(ctx).Errorf("Failed to record generation failure: %v", failErr)
+		}
+		return
+	}
+
+	if err := recorder.Complete(); err != nil {
+		logger.FromContext(ctx).Errorf("Failed to record generation completion: %v", err)
+	}
+}
+
+// DiscardGeneration dismisses an interrupted generation, so that it is no longer offered for continuing
+func (s *GenerationServiceImpl) DiscardGeneration(ctx context.Context, id string) error {
+	generation, _, err := s.findGeneration(ctx, id, models.ChatRoleEditor)
+	if err != nil {
+		return err
+	}
+
+	discarded, err := s.generationRepo.Transition(ctx, generation.ID, models.GenerationInterrupted, models.GenerationDiscarded)
+	if err != nil {
+		return err
+	}
+	if !discarded {
+		return apperrors.NewConflictError("Only interrupted generations can be discarded", nil)
+	}
+
+	return nil
+}
+
+// CancelGeneration stops a running generation. A generation running on this
+// instance is canceled directly; one running elsewhere is marked canceled,
+// and its instance stops it at its next checkpoint.
+func (s *GenerationServiceImpl) CancelGeneration(ctx context.Context, id string) error {
+	generation, _, err := s.findGeneration(ctx, id, models.ChatRoleEditor)
+	if err != nil {
+		return err
+	}
+
+	if generation.Status != models.GenerationRunning {
+		return errGenerationNotRunning()
+	}
+
+	s.mutex.Lock()
+	recorder, local := s.running[generation.ID]
+	s.mutex.Unlock()
+
+	if local {
+		recorder.Cancel()
+		return nil
+	}
+
+	now := time.Now()
+	canceled, err := s.generationRepo.Finish(ctx, generation.ID, models.GenerationCanceled, generation.Content, "", now)
+	if err != nil {
+		return err
+	}
+	if !canceled {
+		return errGenerationNotRunning()
+	}
+
+	generation.Status = models.GenerationCanceled
+	generation.FinishedAt = &now
+	publishGenerationCanceled(ctx, s.publisher, generation)
+
+	return nil
+}
+
+// errGenerationNotRunning returns the error reported when canceling a generation that already ended
+func errGenerationNotRunning() error {
+	return apperrors.NewConflictError("Only running generations can be canceled", nil)
+}
+
+// InterruptOrphaned marks running generations of every tenant that have not
+// checkpointed for longer than timeout as interrupted. Their process most
+// likely died. It returns how many generations were marked.
+func (s *GenerationServiceImpl) InterruptOrphaned(ctx context.Context, timeout time.Duration) (int, error) {
+	total := 0
+	for {
+		generations, err := s.generationRepo.InterruptStale(ctx, time.Now().Add(-timeout), staleGenerationBatch)
+		total += len(generations)
+		for _, generation := range generations {
+			publishGenerationInterrupted(ctx, s.publisher, generation, s.CanContinue())
+		}
+		if err != nil {
+			return total, err
+		}
+
+		if len(generations) < staleGenerationBatch {
+			return total, nil
+		}
+	}
+}
+
+// publishGenerationInterrupted tells a chat's subscribers that a reply was cut off.
+// Publishing is best effort: failures are logged.
+func publishGenerationInterrupted(ctx context.Context, publisher EventPublisher, generation *models.Generation, resumable bool) {
+	if publisher == nil {
+		return
+	}
+
+	chatID := generation.ChatID.Hex()
+	event := sse.GenerationInterruptedEvent{
+		GenerationID: generation.ID.Hex(),
+		ChatID:       chatID,
+		Content:      generation.Content,
+		Resumable:    resumable,
+	}
+
+	// The generation ID doubles as event ID, so that replays do not repeat it
+	if err := publisher.SendToChat(ctx, generation.TenantID, chatID, generation.ID.Hex(), sse.EventGenerationInterrupted, event); err != nil {
+		logger.FromContext(ctx).Warnf("Failed to publish generation_interrupted event for generation %s: %v", generation.ID.Hex(), err)
+	}
+}
+
+// publishGenerationCanceled tells a chat's subscribers that a reply was stopped.
+// Publishing is best effort: failures are logged.
+func publishGenerationCanceled(ctx context.Context, publisher EventPublisher, generation *models.Generation) {
+	if publisher == nil {
+		return
+	}
+
+	chatID := generation.ChatID.Hex()
+	event := sse.GenerationCanceledEvent{
+		GenerationID: generation.ID.Hex(),
+		ChatID:       chatID,
+		Content:      generation.Content,
+	}
+
+	if err := publisher.SendToChat(ctx, generation.TenantID, chatID, generation.ID.Hex(), sse.EventGenerationCanceled, event); err != nil {
+		logger.FromContext(ctx).Warnf("Failed to publish generation_canceled event for generation %s: %v", generation.ID.Hex(), err)
+	}
+}
+
+// RunGenerationSweeper marks orphaned generations as interrupted at startup
+// and then every interval, until ctx is canceled
+func RunGenerationSweeper(ctx context.Context, generations GenerationService, interval, timeout time.Duration) {
+	logger.Infof("Starting generation sweeper (interval %v, orphan timeout %v)", interval, timeout)
+
+	sweep := func() {
+		count, err := generations.InterruptOrphaned(ctx, timeout)
+		if err != nil && !errors.Is(err, context.Canceled) {
+			logger.Errorf("Failed to interrupt orphaned generations: %v", err)
+		}
+		if count > 0 {
+			logger.Infof("Marked %d orphaned generations as interrupted", count)
+		}
+	}
+
+	sweep()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("Stopping generation sweeper")
+			return
+
+		case <-ticker.C:
+			sweep()
+		}
+	}
+}
@@ -0,0 +1,122 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/models"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/repository"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/pkg/logger"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// reconcileBatchSize limits how many chats are reconciled per round
+const reconcileBatchSize = 500
+
+// ReconcileReport summarizes a message statistics reconciliation run
+type ReconcileReport struct {
+	Scanned int // Chats examined
+	Drifted int // Chats whose stored statistics did not match their messages
+	Fixed   int // Chats that were updated (zero on a dry run)
+}
+
+// MaintenanceServiceImpl implements the MaintenanceService interface
+type MaintenanceServiceImpl struct {
+	chatRepo    repository.ChatRepository
+	messageRepo repository.MessageRepository
+}
+
+// NewMaintenanceService creates a new maintenance service
+func NewMaintenanceService(chatRepo repository.ChatRepository, messageRepo repository.MessageRepository) MaintenanceService {
+	return &MaintenanceServiceImpl{
+		chatRepo:    chatRepo,
+		messageRepo: messageRepo,
+	}
+}
+
+// ReconcileMessageStats recomputes message_count and last_message_at of every chat,
+// including trashed ones, from the messages collection
+func (s *MaintenanceServiceImpl) ReconcileMessageStats(ctx context.Context, dryRun bool) (*ReconcileReport, error) {
+	report := &ReconcileReport{}
+	after := primitive.NilObjectID
+
+	for {
+		chats, err := s.chatRepo.Scan(ctx, after, reconcileBatchSize)
+		if err != nil {
+			return report, err
+		}
+
+		if len(chats) == 0 {
+			break
+		}
+
+		ids := make([]primitive.ObjectID, len(chats))
+		for i, chat := range chats {
+			ids[i] = chat.ID
+		}
+
+		stats, err := s.messageRepo.StatsByChatIDs(ctx, ids)
+		if err != nil {
+			return report, err
+		}
+
+		for _, chat := range chats {
+			report.Scanned++
+
+			actual := stats[chat.ID]
+			if statsMatch(chat, actual) {
+				continue
+			}
+
+			report.Drifted++
+			logger.FromContext(ctx).Infof("Chat %s: message_count %d -> %d, last_message_at %s -> %s",
+				chat.ID.Hex(), chat.MessageCount, actual.Count,
+				formatStatsTime(chat.LastMessageAt), formatStatsTime(actual.LastMessageAt))
+
+			if dryRun {
+				continue
+			}
+
+			var lastMessageAt *time.Time
+			if !actual.LastMessageAt.IsZero() {
+				lastMessageAt = &actual.LastMessageAt
+			}
+
+			if err := s.chatRepo.SetMessageStats(ctx, chat.ID, actual.Count, lastMessageAt); err != nil {
+				return report, err
+			}
+			report.Fixed++
+		}
+
+		if len(chats) < reconcileBatchSize {
+			break
+		}
+		after = chats[len(chats)-1].ID
+	}
+
+	return report, nil
+}
+
+// statsMatch reports whether a chat's stored statistics agree with its messages.
+// Times are compared at millisecond precision, which is what MongoDB stores.
+func statsMatch(chat *models.Chat, actual repository.MessageStats) bool {
+	return chat.MessageCount == actual.Count &&
+		chat.LastMessageAt.Truncate(time.Millisecond).Equal(actual.LastMessageAt.Truncate(time.Millisecond))
+}
+
+// formatStatsTime formats a last message time for reconciliation logs
+func formatStatsTime(t time.Time) string {
+	if t.IsZero() {
+		return "none"
+	}
+	return t.UTC().Format(time.RFC3339)
+}
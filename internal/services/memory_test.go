@@ -0,0 +1,554 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/auth"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/models"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// as returns a context authenticated as subject
+func as(subject string) context.Context {
+	return auth.WithPrincipal(context.Background(), &auth.Principal{Subject: subject, Method: auth.MethodJWT})
+}
+
+// chatStore is an in-memory ChatRepository. Chats are stored as copies, so
+// that callers cannot change them without going through the repository.
+type chatStore struct {
+	chats map[primitive.ObjectID]*models.Chat
+	mutex sync.Mutex
+}
+
+func newChatStore() *chatStore {
+	return &chatStore{chats: make(map[primitive.ObjectID]*models.Chat)}
+}
+
+// add stores a chat owned by owner, shared with the given members
+func (s *chatStore) add(title, owner string, members ...models.ChatMember) *models.Chat {
+	chat := models.NewChat(title)
+	chat.SetOwner(owner)
+	chat.Members = append(chat.Members, members...)
+	_ = s.Create(context.Background(), chat)
+	return chat
+}
+
+// get returns a copy of a stored chat, trashed or not
+func (s *chatStore) get(id primitive.ObjectID) *models.Chat {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	chat, ok := s.chats[id]
+	if !ok {
+		return nil
+	}
+	found := *chat
+	found.Members = slices.Clone(chat.Members)
+	return &found
+}
+
+// update applies fn to a stored chat that is not trashed
+func (s *chatStore) update(id primitive.ObjectID, fn func(*models.Chat)) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	chat, ok := s.chats[id]
+	if !ok || chat.DeletedAt != nil {
+		return mongo.ErrNoDocuments
+	}
+	fn(chat)
+	return nil
+}
+
+func (s *chatStore) Create(_ context.Context, chat *models.Chat) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	chat.BeforeSave()
+	stored := *chat
+	stored.Members = slices.Clone(chat.Members)
+	s.chats[chat.ID] = &stored
+	return nil
+}
+
+func (s *chatStore) FindByID(_ context.Context, id primitive.ObjectID) (*models.Chat, error) {
+	if chat := s.get(id); chat != nil && chat.DeletedAt == nil {
+		return chat, nil
+	}
+	return nil, nil
+}
+
+func (s *chatStore) FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]*models.Chat, error) {
+	var chats []*models.Chat
+	for _, id := range ids {
+		if chat, _ := s.FindByID(ctx, id); chat != nil {
+			chats = append(chats, chat)
+		}
+	}
+	return chats, nil
+}
+
+// matching returns the chats that are not trashed and match the filter's member, in _id order
+func (s *chatStore) matching(filter repository.ChatFilter) []*models.Chat {
+	s.mutex.Lock()
+	ids := make([]primitive.ObjectID, 0, len(s.chats))
+	for id, chat := range s.chats {
+		if chat.DeletedAt != nil {
+			continue
+		}
+		if filter.Member != "" {
+			if _, ok := chat.MemberRole(filter.Member); !ok {
+				continue
+			}
+		}
+		ids = append(ids, id)
+	}
+	s.mutex.Unlock()
+
+	slices.SortFunc(ids, func(a, b primitive.ObjectID) int { return bytes.Compare(a[:], b[:]) })
+	chats := make([]*models.Chat, 0, len(ids))
+	for _, id := range ids {
+		chats = append(chats, s.get(id))
+	}
+	return chats
+}
+
+func (s *chatStore) FindAll(_ context.Context, opts repository.ChatListOptions, limit, offset int) ([]*models.Chat, error) {
+	chats := s.matching(opts.Filter)
+	if offset > len(chats) {
+		return nil, nil
+	}
+	chats = chats[offset:]
+	if limit > 0 && limit < len(chats) {
+		chats = chats[:limit]
+	}
+	return chats, nil
+}
+
+func (s *chatStore) FindAllByCursor(_ context.Context, opts repository.ChatListOptions, query repository.CursorQuery) ([]*models.Chat, repository.PageInfo, error) {
+	chats := s.matching(opts.Filter)
+	if query.Limit > 0 && query.Limit < len(chats) {
+		chats = chats[:query.Limit]
+	}
+	return chats, repository.PageInfo{}, nil
+}
+
+func (s *chatStore) Update(_ context.Context, chat *models.Chat) error {
+	chat.BeforeSave()
+	return s.update(chat.ID, func(stored *models.Chat) {
+		stored.Title = chat.Title
+		stored.TitleLower = chat.TitleLower
+		stored.Pinned = chat.Pinned
+		stored.Archived = chat.Archived
+		stored.ArchivedAt = chat.ArchivedAt
+		stored.Tags = chat.Tags
+		stored.FolderID = chat.FolderID
+		stored.UpdatedAt = chat.UpdatedAt
+	})
+}
+
+func (s *chatStore) Delete(_ context.Context, id primitive.ObjectID, deletedAt time.Time) error {
+	return s.update(id, func(chat *models.Chat) { chat.DeletedAt = &deletedAt })
+}
+
+func (s *chatStore) FindDeletedByID(_ context.Context, id primitive.ObjectID) (*models.Chat, error) {
+	if chat := s.get(id); chat != nil && chat.DeletedAt != nil {
+		return chat, nil
+	}
+	return nil, nil
+}
+
+func (s *chatStore) FindDeleted(context.Context, string, int, int) ([]*models.Chat, error) {
+	return nil, nil
+}
+
+func (s *chatStore) CountDeleted(context.Context, string) (int64, error) {
+	return 0, nil
+}
+
+func (s *chatStore) Restore(_ context.Context, id primitive.ObjectID) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	chat, ok := s.chats[id]
+	if !ok || chat.DeletedAt == nil {
+		return mongo.ErrNoDocuments
+	}
+	chat.DeletedAt = nil
+	return nil
+}
+
+func (s *chatStore) FindDeletedBefore(context.Context, time.Time, int) ([]primitive.ObjectID, error) {
+	return nil, nil
+}
+
+func (s *chatStore) HardDelete(context.Context, []primitive.ObjectID) (int64, error) {
+	return 0, nil
+}
+
+func (s *chatStore) SetArchived(_ context.Context, id primitive.ObjectID, archived bool) error {
+	return s.update(id, func(chat *models.Chat) {
+		if archived {
+			chat.Archive()
+		} else {
+			chat.Unarchive()
+		}
+	})
+}
+
+func (s *chatStore) SetFolder(_ context.Context, ids []primitive.ObjectID, folderID *primitive.ObjectID) (int64, error) {
+	var updated int64
+	for _, id := range ids {
+		if s.update(id, func(chat *models.Chat) { chat.FolderID = folderID }) == nil {
+			updated++
+		}
+	}
+	return updated, nil
+}
+
+func (s *chatStore) ClearFolder(context.Context, primitive.ObjectID) (int64, error) {
+	return 0, nil
+}
+
+func (s *chatStore) UpdateTags(_ context.Context, ids []primitive.ObjectID, add, remove []string) (int64, error) {
+	var updated int64
+	for _, id := range ids {
+		err := s.update(id, func(chat *models.Chat) {
+			chat.Tags = slices.DeleteFunc(chat.Tags, func(tag string) bool { return slices.Contains(remove, tag) })
+			for _, tag := range add {
+				if !slices.Contains(chat.Tags, tag) {
+					chat.Tags = append(chat.Tags, tag)
+				}
+			}
+		})
+		if err == nil {
+			updated++
+		}
+	}
+	return updated, nil
+}
+
+func (s *chatStore) IncrementMessageCount(_ context.Context, id primitive.ObjectID, messageAt time.Time) error {
+	return s.update(id, func(chat *models.Chat) {
+		chat.MessageCount++
+		if messageAt.After(chat.LastMessageAt) {
+			chat.LastMessageAt = messageAt
+		}
+	})
+}
+
+func (s *chatStore) DecrementMessageCount(_ context.Context, id primitive.ObjectID, lastMessageAt *time.Time) error {
+	return s.update(id, func(chat *models.Chat) {
+		chat.MessageCount = max(0, chat.MessageCount-1)
+		chat.LastMessageAt = time.Time{}
+		if lastMessageAt != nil {
+			chat.LastMessageAt = *lastMessageAt
+		}
+	})
+}
+
+func (s *chatStore) Scan(context.Context, primitive.ObjectID, int) ([]*models.Chat, error) {
+	return nil, nil
+}
+
+func (s *chatStore) SetMessageStats(context.Context, primitive.ObjectID, int, *time.Time) error {
+	return nil
+}
+
+func (s *chatStore) CountAll(_ context.Context, filter repository.ChatFilter) (int64, error) {
+	return int64(len(s.matching(filter))), nil
+}
+
+func (s *chatStore) FindIDsByMember(_ context.Context, subject string, roles []models.ChatRole) ([]primitive.ObjectID, error) {
+	var ids []primitive.ObjectID
+	for _, chat := range s.matching(repository.ChatFilter{Member: subject}) {
+		if role, _ := chat.MemberRole(subject); len(roles) == 0 || slices.Contains(roles, role) {
+			ids = append(ids, chat.ID)
+		}
+	}
+	return ids, nil
+}
+
+func (s *chatStore) SetMember(_ context.Context, id primitive.ObjectID, member models.ChatMember) error {
+	return s.update(id, func(chat *models.Chat) {
+		chat.Members = slices.DeleteFunc(chat.Members, func(m models.ChatMember) bool { return m.Subject == member.Subject })
+		chat.Members = append(chat.Members, member)
+	})
+}
+
+func (s *chatStore) RemoveMember(_ context.Context, id primitive.ObjectID, subject string) error {
+	return s.update(id, func(chat *models.Chat) {
+		chat.Members = slices.DeleteFunc(chat.Members, func(m models.ChatMember) bool { return m.Subject == subject })
+	})
+}
+
+func (s *chatStore) ClaimUnowned(context.Context, string) (int64, error) {
+	return 0, nil
+}
+
+// messageStore is an in-memory MessageRepository
+type messageStore struct {
+	messages map[primitive.ObjectID]*models.Message
+	mutex    sync.Mutex
+}
+
+func newMessageStore() *messageStore {
+	return &messageStore{messages: make(map[primitive.ObjectID]*models.Message)}
+}
+
+// add stores a message in a chat, created at the given time
+func (s *messageStore) add(chatID primitive.ObjectID, content string, at time.Time) *models.Message {
+	message := models.NewMessage(chatID, content, models.RoleUser, models.TypeText)
+	message.CreatedAt = at
+	_ = s.Create(context.Background(), message)
+	return message
+}
+
+// live returns the messages of a chat that are not trashed
+func (s *messageStore) live(chatID primitive.ObjectID) []*models.Message {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	var messages []*models.Message
+	for _, message := range s.messages {
+		if message.ChatID == chatID && message.DeletedAt == nil {
+			found := *message
+			messages = append(messages, &found)
+		}
+	}
+	slices.SortFunc(messages, func(a, b *models.Message) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	return messages
+}
+
+func (s *messageStore) Create(_ context.Context, message *models.Message) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	stored := *message
+	s.messages[message.ID] = &stored
+	return nil
+}
+
+func (s *messageStore) FindByID(_ context.Context, id primitive.ObjectID) (*models.Message, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	message, ok := s.messages[id]
+	if !ok || message.DeletedAt != nil {
+		return nil, nil
+	}
+	found := *message
+	return &found, nil
+}
+
+func (s *messageStore) FindByChatID(_ context.Context, chatID primitive.ObjectID, limit, offset int) ([]*models.Message, error) {
+	messages := s.live(chatID)
+	if offset > len(messages) {
+		return nil, nil
+	}
+	messages = messages[offset:]
+	if limit > 0 && limit < len(messages) {
+		messages = messages[:limit]
+	}
+	return messages, nil
+}
+
+func (s *messageStore) FindByChatIDCursor(_ context.Context, chatID primitive.ObjectID, query repository.CursorQuery) ([]*models.Message, repository.PageInfo, error) {
+	messages, _ := s.FindByChatID(context.Background(), chatID, query.Limit, 0)
+	return messages, repository.PageInfo{}, nil
+}
+
+func (s *messageStore) CountByChatID(_ context.Context, chatID primitive.ObjectID) (int64, error) {
+	return int64(len(s.live(chatID))), nil
+}
+
+func (s *messageStore) CountSince(_ context.Context, since time.Time) (int64, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	var count int64
+	for _, message := range s.messages {
+		if !message.CreatedAt.Before(since) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (s *messageStore) Delete(_ context.Context, id primitive.ObjectID) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	message, ok := s.messages[id]
+	if !ok || message.DeletedAt != nil {
+		return mongo.ErrNoDocuments
+	}
+	now := time.Now()
+	message.DeletedAt = &now
+	return nil
+}
+
+func (s *messageStore) DeleteByChatID(_ context.Context, chatID primitive.ObjectID, deletedAt time.Time) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, message := range s.messages {
+		if message.ChatID == chatID && message.DeletedAt == nil {
+			message.DeletedAt = &deletedAt
+			message.DeletedWithChat = true
+		}
+	}
+	return nil
+}
+
+func (s *messageStore) FindDeletedByID(context.Context, primitive.ObjectID) (*models.Message, error) {
+	return nil, nil
+}
+
+func (s *messageStore) FindDeleted(context.Context, []primitive.ObjectID, int, int) ([]*models.Message, error) {
+	return nil, nil
+}
+
+func (s *messageStore) CountDeleted(context.Context, []primitive.ObjectID) (int64, error) {
+	return 0, nil
+}
+
+func (s *messageStore) Restore(context.Context, primitive.ObjectID) error {
+	return nil
+}
+
+func (s *messageStore) RestoreByChatID(_ context.Context, chatID primitive.ObjectID) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, message := range s.messages {
+		if message.ChatID == chatID && message.DeletedWithChat {
+			message.DeletedAt = nil
+			message.DeletedWithChat = false
+		}
+	}
+	return nil
+}
+
+func (s *messageStore) HardDeleteByChatIDs(context.Context, []primitive.ObjectID) (int64, error) {
+	return 0, nil
+}
+
+func (s *messageStore) PurgeDeletedBefore(context.Context, time.Time) (int64, error) {
+	return 0, nil
+}
+
+func (s *messageStore) StatsByChatIDs(_ context.Context, chatIDs []primitive.ObjectID) (map[primitive.ObjectID]repository.MessageStats, error) {
+	stats := make(map[primitive.ObjectID]repository.MessageStats)
+	for _, chatID := range chatIDs {
+		messages := s.live(chatID)
+		if len(messages) == 0 {
+			continue
+		}
+		stats[chatID] = repository.MessageStats{Count: len(messages), LastMessageAt: messages[len(messages)-1].CreatedAt}
+	}
+	return stats, nil
+}
+
+// inviteStore is an in-memory InviteRepository
+type inviteStore struct {
+	invites map[primitive.ObjectID]*models.ChatInvite
+	mutex   sync.Mutex
+}
+
+func newInviteStore() *inviteStore {
+	return &inviteStore{invites: make(map[primitive.ObjectID]*models.ChatInvite)}
+}
+
+func (s *inviteStore) Create(_ context.Context, invite *models.ChatInvite) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	stored := *invite
+	s.invites[invite.ID] = &stored
+	return nil
+}
+
+func (s *inviteStore) FindByCodeHash(_ context.Context, codeHash string) (*models.ChatInvite, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, invite := range s.invites {
+		if invite.CodeHash == codeHash {
+			found := *invite
+			return &found, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *inviteStore) FindByChatID(_ context.Context, chatID primitive.ObjectID) ([]*models.ChatInvite, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	var invites []*models.ChatInvite
+	for _, invite := range s.invites {
+		if invite.ChatID == chatID {
+			found := *invite
+			invites = append(invites, &found)
+		}
+	}
+	return invites, nil
+}
+
+func (s *inviteStore) Delete(_ context.Context, chatID, id primitive.ObjectID) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	invite, ok := s.invites[id]
+	if !ok || invite.ChatID != chatID {
+		return mongo.ErrNoDocuments
+	}
+	delete(s.invites, id)
+	return nil
+}
+
+// noTransactions runs transactional functions directly
+type noTransactions struct{}
+
+func (noTransactions) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+// publishedEvent is an event sent through an eventRecorder
+type publishedEvent struct {
+	ChatID    string
+	MessageID string
+	Event     string
+	Data      json.RawMessage
+}
+
+// eventRecorder is an EventPublisher that records the events it is sent
+type eventRecorder struct {
+	events []publishedEvent
+	mutex  sync.Mutex
+}
+
+func (r *eventRecorder) SendToChat(_ context.Context, _, chatID, messageID, event string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.events = append(r.events, publishedEvent{ChatID: chatID, MessageID: messageID, Event: event, Data: payload})
+	return nil
+}
+
+// names returns the names of the recorded events in order
+func (r *eventRecorder) names() []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	names := make([]string, len(r.events))
+	for i, event := range r.events {
+		names[i] = event.Event
+	}
+	return names
+}
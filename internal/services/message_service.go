@@ -12,9 +12,12 @@ package services
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/models"
 	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/repository"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/sse"
+	apperrors "github.com/yasin-yalcin-dev/go-sse-ai-chat/pkg/errors"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -22,14 +25,23 @@ import (
 type MessageServiceImpl struct {
 	messageRepo repository.MessageRepository
 	chatRepo    repository.ChatRepository
+	usageRepo   repository.UsageRepository
+	tx          repository.Transactor
+	publisher   EventPublisher
+	quotas      QuotaProvider
 }
 
-// NewMessageService creates a new message service
-func NewMessageService(messageRepo repository.MessageRepository, chatRepo repository.ChatRepository) MessageService {
-	return &MessageServiceImpl{
+// NewMessageService creates a new message service whose calls are traced.
+// quotas may be nil, in which case message creation is not limited.
+func NewMessageService(messageRepo repository.MessageRepository, chatRepo repository.ChatRepository, usageRepo repository.UsageRepository, tx repository.Transactor, publisher EventPublisher, quotas QuotaProvider) MessageService {
+	return &tracedMessageService{next: &MessageServiceImpl{
 		messageRepo: messageRepo,
 		chatRepo:    chatRepo,
-	}
+		usageRepo:   usageRepo,
+		tx:          tx,
+		publisher:   publisher,
+		quotas:      quotas,
+	}}
 }
 
 // CreateMessage creates a new message
@@ -39,29 +51,34 @@ func (s *MessageServiceImpl) CreateMessage(ctx context.Context, chatID string, c
 		return nil, err
 	}
 
-	// Verify the chat exists
-	chat, err := s.chatRepo.FindByID(ctx, chatObjID)
-	if err != nil {
+	// Verify the chat exists and the caller may post to it
+	if _, err := findChat(ctx, s.chatRepo, chatObjID, models.ChatRoleEditor); err != nil {
 		return nil, err
 	}
 
-	if chat == nil {
-		return nil, errors.New("chat not found")
+	if err := checkMessageQuota(ctx, s.quotas, s.messageRepo, s.usageRepo); err != nil {
+		return nil, err
 	}
 
 	// Create the message
 	message := models.NewMessage(chatObjID, content, role, msgType)
 
-	if err := s.messageRepo.Create(ctx, message); err != nil {
+	// Store the message and update the chat's message count together
+	err = s.tx.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := s.messageRepo.Create(ctx, message); err != nil {
+			return err
+		}
+
+		return s.chatRepo.IncrementMessageCount(ctx, chatObjID, message.CreatedAt)
+	})
+	if err != nil {
 		return nil, err
 	}
 
-	// Update the chat's message count
-	if err := s.chatRepo.IncrementMessageCount(ctx, chatObjID); err != nil {
-		// Log the error but don't fail the operation
-		// TODO: Add proper logging
-		// logger.Errorf("Failed to increment message count: %v", err)
-	}
+	publishEvent(ctx, s.publisher, message.TenantID, chatID, sse.EventMessageCreated, sse.MessageCreatedEvent{
+		ChatID:  chatID,
+		Message: message,
+	})
 
 	return message, nil
 }
@@ -79,12 +96,24 @@ func (s *MessageServiceImpl) GetMessageByID(ctx context.Context, id string) (*mo
 	}
 
 	if message == nil {
-		return nil, errors.New("message not found")
+		return nil, errMessageNotFound()
+	}
+
+	if _, err := findChat(ctx, s.chatRepo, message.ChatID, models.ChatRoleViewer); err != nil {
+		if isNotFound(err) {
+			return nil, errMessageNotFound()
+		}
+		return nil, err
 	}
 
 	return message, nil
 }
 
+// errMessageNotFound returns the error reported for missing or inaccessible messages
+func errMessageNotFound() error {
+	return apperrors.NewNotFoundError("Message not found", nil)
+}
+
 // GetChatMessages retrieves paginated messages for a chat
 func (s *MessageServiceImpl) GetChatMessages(ctx context.Context, chatID string, page, pageSize int) ([]*models.Message, int64, error) {
 	chatObjID, err := primitive.ObjectIDFromHex(chatID)
@@ -92,16 +121,11 @@ func (s *MessageServiceImpl) GetChatMessages(ctx context.Context, chatID string,
 		return nil, 0, err
 	}
 
-	// Verify the chat exists
-	chat, err := s.chatRepo.FindByID(ctx, chatObjID)
-	if err != nil {
+	// Verify the chat exists and the caller may read it
+	if _, err := findChat(ctx, s.chatRepo, chatObjID, models.ChatRoleViewer); err != nil {
 		return nil, 0, err
 	}
 
-	if chat == nil {
-		return nil, 0, errors.New("chat not found")
-	}
-
 	if page < 1 {
 		page = 1
 	}
@@ -125,6 +149,38 @@ func (s *MessageServiceImpl) GetChatMessages(ctx context.Context, chatID string,
 	return messages, total, nil
 }
 
+// GetChatMessagesByCursor retrieves keyset-paginated messages for a chat
+func (s *MessageServiceImpl) GetChatMessagesByCursor(ctx context.Context, chatID string, query repository.CursorQuery) ([]*models.Message, repository.PageInfo, int64, error) {
+	chatObjID, err := primitive.ObjectIDFromHex(chatID)
+	if err != nil {
+		return nil, repository.PageInfo{}, 0, err
+	}
+
+	// Verify the chat exists and the caller may read it
+	if _, err := findChat(ctx, s.chatRepo, chatObjID, models.ChatRoleViewer); err != nil {
+		return nil, repository.PageInfo{}, 0, err
+	}
+
+	if query.Limit < 1 {
+		query.Limit = 20
+	}
+
+	messages, info, err := s.messageRepo.FindByChatIDCursor(ctx, chatObjID, query)
+	if err != nil {
+		if errors.Is(err, repository.ErrInvalidCursor) {
+			return nil, info, 0, apperrors.NewBadRequestError("Invalid cursor", err)
+		}
+		return nil, info, 0, err
+	}
+
+	total, err := s.messageRepo.CountByChatID(ctx, chatObjID)
+	if err != nil {
+		return nil, info, 0, err
+	}
+
+	return messages, info, total, nil
+}
+
 // DeleteMessage deletes a message
 func (s *MessageServiceImpl) DeleteMessage(ctx context.Context, id string) error {
 	msgID, err := primitive.ObjectIDFromHex(id)
@@ -139,18 +195,102 @@ func (s *MessageServiceImpl) DeleteMessage(ctx context.Context, id string) error
 	}
 
 	if message == nil {
-		return errors.New("message not found")
+		return errMessageNotFound()
+	}
+
+	if _, err := findChat(ctx, s.chatRepo, message.ChatID, models.ChatRoleEditor); err != nil {
+		if isNotFound(err) {
+			return errMessageNotFound()
+		}
+		return err
 	}
 
-	// Delete the message
-	if err := s.messageRepo.Delete(ctx, msgID); err != nil {
+	// Delete the message and update the chat's message count together
+	deletedAt := time.Now()
+	err = s.tx.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := s.messageRepo.Delete(ctx, msgID); err != nil {
+			return err
+		}
+
+		// The deleted message may have been the newest one
+		stats, err := s.messageRepo.StatsByChatIDs(ctx, []primitive.ObjectID{message.ChatID})
+		if err != nil {
+			return err
+		}
+		var lastMessageAt *time.Time
+		if last := stats[message.ChatID].LastMessageAt; !last.IsZero() {
+			lastMessageAt = &last
+		}
+
+		return s.chatRepo.DecrementMessageCount(ctx, message.ChatID, lastMessageAt)
+	})
+	if err != nil {
 		return err
 	}
 
-	// Update the chat's message count (decrement)
-	// This could be implemented with a new repository method
-	// For now, we'll just note that it should be done
-	// TODO: Implement chat.DecrementMessageCount
+	chatID := message.ChatID.Hex()
+	publishEvent(ctx, s.publisher, message.TenantID, chatID, sse.EventMessageDeleted, sse.MessageDeletedEvent{
+		ID:        id,
+		ChatID:    chatID,
+		DeletedAt: deletedAt,
+	})
 
 	return nil
 }
+
+// RestoreMessage moves a message out of the trash
+func (s *MessageServiceImpl) RestoreMessage(ctx context.Context, id string) (*models.Message, error) {
+	msgID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	message, err := s.messageRepo.FindDeletedByID(ctx, msgID)
+	if err != nil {
+		return nil, err
+	}
+
+	if message == nil {
+		return nil, apperrors.NewNotFoundError("Message not found in trash", nil)
+	}
+
+	// A message can only be restored into a chat that is not itself in the trash
+	chat, err := s.chatRepo.FindByID(ctx, message.ChatID)
+	if err != nil {
+		return nil, err
+	}
+
+	if chat == nil {
+		chat, err = s.chatRepo.FindDeletedByID(ctx, message.ChatID)
+		if err != nil {
+			return nil, err
+		}
+		if chat == nil || authorizeChat(ctx, chat, models.ChatRoleEditor) != nil {
+			return nil, apperrors.NewNotFoundError("Message not found in trash", nil)
+		}
+		return nil, apperrors.NewConflictError("The message's chat is deleted; restore the chat first", nil)
+	}
+
+	if err := authorizeChat(ctx, chat, models.ChatRoleEditor); err != nil {
+		if isNotFound(err) {
+			return nil, apperrors.NewNotFoundError("Message not found in trash", nil)
+		}
+		return nil, err
+	}
+
+	err = s.tx.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := s.messageRepo.Restore(ctx, msgID); err != nil {
+			return err
+		}
+
+		return s.chatRepo.IncrementMessageCount(ctx, message.ChatID, message.CreatedAt)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	message.DeletedAt = nil
+	message.DeletedWithChat = false
+
+	return message, nil
+}
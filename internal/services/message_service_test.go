@@ -0,0 +1,46 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// TestDeleteMessageRewindsLastMessageAt checks that deleting the newest
+// message moves the chat's last message time back to the newest remaining
+// one, and clears it once no messages remain
+func TestDeleteMessageRewindsLastMessageAt(t *testing.T) {
+	s := newSharedChat()
+	oldest := s.message
+	newest := s.messages.add(s.chat.ID, "Later", oldest.CreatedAt.Add(time.Minute))
+	_ = s.chats.IncrementMessageCount(context.Background(), s.chat.ID, newest.CreatedAt)
+
+	steps := []struct {
+		delete    string
+		wantCount int
+		want      time.Time
+	}{
+		{delete: newest.ID.Hex(), wantCount: 1, want: oldest.CreatedAt},
+		{delete: oldest.ID.Hex(), wantCount: 0},
+	}
+
+	for _, step := range steps {
+		if err := s.messageService.DeleteMessage(as("bob"), step.delete); err != nil {
+			t.Fatalf("DeleteMessage(%s) error = %v", step.delete, err)
+		}
+		chat := s.chats.get(s.chat.ID)
+		if chat.MessageCount != step.wantCount || !chat.LastMessageAt.Equal(step.want) {
+			t.Errorf("after deleting %s: count %d, last message at %v, want %d and %v",
+				step.delete, chat.MessageCount, chat.LastMessageAt, step.wantCount, step.want)
+		}
+	}
+}
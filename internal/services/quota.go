@@ -0,0 +1,89 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/repository"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/tenant"
+	apperrors "github.com/yasin-yalcin-dev/go-sse-ai-chat/pkg/errors"
+)
+
+// QuotaProvider returns the quotas that apply to a tenant
+type QuotaProvider interface {
+	Quotas(tenantID string) tenant.Quotas
+}
+
+// checkChatQuota fails when the tenant of ctx already has as many chats as it may
+func checkChatQuota(ctx context.Context, quotas QuotaProvider, chatRepo repository.ChatRepository) error {
+	if quotas == nil {
+		return nil
+	}
+
+	tenantID := tenant.FromContext(ctx)
+	limit := quotas.Quotas(tenantID).MaxChats
+	if limit <= 0 {
+		return nil
+	}
+
+	count, err := chatRepo.CountAll(ctx, repository.ChatFilter{})
+	if err != nil {
+		return err
+	}
+
+	if count >= int64(limit) {
+		return apperrors.NewQuotaExceededError(fmt.Sprintf("Tenant %s has reached its limit of %d chats", tenantID, limit), nil)
+	}
+	return nil
+}
+
+// checkMessageQuota fails when the tenant of ctx already created as many
+// messages today (UTC) as it may. Messages charged without being stored, as
+// recorded in usageRepo, count as well.
+func checkMessageQuota(ctx context.Context, quotas QuotaProvider, messageRepo repository.MessageRepository, usageRepo repository.UsageRepository) error {
+	limit, ok := messageQuota(ctx, quotas)
+	if !ok {
+		return nil
+	}
+
+	day := quotaDay()
+	count, err := messageRepo.CountSince(ctx, day)
+	if err != nil {
+		return err
+	}
+
+	unstored, err := usageRepo.CountMessages(ctx, day)
+	if err != nil {
+		return err
+	}
+
+	if count+unstored >= int64(limit) {
+		return apperrors.NewQuotaExceededError(fmt.Sprintf("Tenant %s has reached its limit of %d messages per day", tenant.FromContext(ctx), limit), nil)
+	}
+	return nil
+}
+
+// messageQuota returns the daily message quota of the tenant of ctx, if it has one
+func messageQuota(ctx context.Context, quotas QuotaProvider) (int, bool) {
+	if quotas == nil {
+		return 0, false
+	}
+
+	limit := quotas.Quotas(tenant.FromContext(ctx)).MaxMessagesPerDay
+	return limit, limit > 0
+}
+
+// quotaDay returns the start of the current quota day, midnight UTC
+func quotaDay() time.Time {
+	return time.Now().UTC().Truncate(24 * time.Hour)
+}
@@ -11,17 +11,25 @@ package services
 
 import (
 	"context"
+	"time"
 
 	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/models"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/repository"
 )
 
 // ChatService defines operations for managing chat sessions
 type ChatService interface {
 	CreateChat(ctx context.Context, title string) (*models.Chat, error)
 	GetChatByID(ctx context.Context, id string) (*models.Chat, error)
-	ListChats(ctx context.Context, page, pageSize int) ([]*models.Chat, int64, error)
-	UpdateChat(ctx context.Context, id string, title string) (*models.Chat, error)
+	ListChats(ctx context.Context, opts repository.ChatListOptions, page, pageSize int) ([]*models.Chat, int64, error)
+	ListChatsByCursor(ctx context.Context, opts repository.ChatListOptions, query repository.CursorQuery) ([]*models.Chat, repository.PageInfo, int64, error)
+	UpdateChat(ctx context.Context, id string, title string, pinned *bool) (*models.Chat, error)
+	ArchiveChat(ctx context.Context, id string) (*models.Chat, error)
+	UnarchiveChat(ctx context.Context, id string) (*models.Chat, error)
+	MoveChats(ctx context.Context, chatIDs []string, folderID string) (int64, error)
+	UpdateChatTags(ctx context.Context, chatIDs []string, add, remove []string) (int64, error)
 	DeleteChat(ctx context.Context, id string) error
+	RestoreChat(ctx context.Context, id string) (*models.Chat, error)
 }
 
 // MessageService defines operations for managing messages
@@ -29,5 +37,67 @@ type MessageService interface {
 	CreateMessage(ctx context.Context, chatID string, content string, role models.MessageRole, msgType models.MessageType) (*models.Message, error)
 	GetMessageByID(ctx context.Context, id string) (*models.Message, error)
 	GetChatMessages(ctx context.Context, chatID string, page, pageSize int) ([]*models.Message, int64, error)
+	GetChatMessagesByCursor(ctx context.Context, chatID string, query repository.CursorQuery) ([]*models.Message, repository.PageInfo, int64, error)
 	DeleteMessage(ctx context.Context, id string) error
+	RestoreMessage(ctx context.Context, id string) (*models.Message, error)
+}
+
+// FolderService defines operations for managing chat folders
+type FolderService interface {
+	CreateFolder(ctx context.Context, name string) (*models.Folder, error)
+	GetFolderByID(ctx context.Context, id string) (*models.Folder, error)
+	ListFolders(ctx context.Context) ([]*models.Folder, error)
+	UpdateFolder(ctx context.Context, id string, name string) (*models.Folder, error)
+	DeleteFolder(ctx context.Context, id string) error
+}
+
+// TrashService defines operations on soft-deleted chats and messages
+type TrashService interface {
+	ListDeletedChats(ctx context.Context, page, pageSize int) ([]*models.Chat, int64, error)
+	ListDeletedMessages(ctx context.Context, page, pageSize int) ([]*models.Message, int64, error)
+	PurgeExpired(ctx context.Context, retention time.Duration) (chats int64, messages int64, err error)
+}
+
+// MaintenanceService defines administrative data repair operations
+type MaintenanceService interface {
+	ReconcileMessageStats(ctx context.Context, dryRun bool) (*ReconcileReport, error)
+}
+
+// ShareService defines operations for sharing chats with other principals
+type ShareService interface {
+	ListMembers(ctx context.Context, chatID string) ([]models.ChatMember, error)
+	UpdateMemberRole(ctx context.Context, chatID, subject string, role models.ChatRole) (*models.ChatMember, error)
+	RemoveMember(ctx context.Context, chatID, subject string) error
+	CreateInvite(ctx context.Context, chatID string, role models.ChatRole, ttl time.Duration) (*models.ChatInvite, string, error)
+	ListInvites(ctx context.Context, chatID string) ([]*models.ChatInvite, error)
+	RevokeInvite(ctx context.Context, chatID, inviteID string) error
+	AcceptInvite(ctx context.Context, code string) (*models.Chat, error)
+}
+
+// GenerationService defines operations for tracking and resuming AI generations
+type GenerationService interface {
+	StartGeneration(ctx context.Context, chatID string, provider, model string) (*GenerationRecorder, error)
+	GetGeneration(ctx context.Context, id string) (*models.Generation, error)
+	ListGenerations(ctx context.Context, chatID string, status models.GenerationStatus) ([]*models.Generation, error)
+	ContinueGeneration(ctx context.Context, id string) (*models.Generation, error)
+	DiscardGeneration(ctx context.Context, id string) error
+	CancelGeneration(ctx context.Context, id string) error
+	InterruptOrphaned(ctx context.Context, timeout time.Duration) (int, error)
+	CanContinue() bool
+}
+
+// CompletionService defines operations for the OpenAI-compatible completion API
+type CompletionService interface {
+	CreateCompletion(ctx context.Context, req *CompletionRequest, emit func(chunk string) error) (*CompletionResult, error)
+}
+
+// WebhookService defines operations for managing webhook subscriptions
+type WebhookService interface {
+	CreateWebhook(ctx context.Context, url string, events []string, description string) (*models.Webhook, error)
+	GetWebhook(ctx context.Context, id string) (*models.Webhook, error)
+	ListWebhooks(ctx context.Context) ([]*models.Webhook, error)
+	UpdateWebhook(ctx context.Context, id string, update WebhookUpdate) (*models.Webhook, error)
+	RotateWebhookSecret(ctx context.Context, id string) (*models.Webhook, error)
+	DeleteWebhook(ctx context.Context, id string) error
+	ListDeliveries(ctx context.Context, id string, page, pageSize int) ([]*models.WebhookDelivery, int64, error)
 }
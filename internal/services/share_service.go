@@ -0,0 +1,273 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/models"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/repository"
+	apperrors "github.com/yasin-yalcin-dev/go-sse-ai-chat/pkg/errors"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/pkg/logger"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Invite lifetime limits
+const (
+	DefaultInviteTTL = 7 * 24 * time.Hour
+	MaxInviteTTL     = 30 * 24 * time.Hour
+)
+
+// inviteCodeBytes is the amount of randomness in an invite code
+const inviteCodeBytes = 24
+
+// ShareServiceImpl implements the ShareService interface
+type ShareServiceImpl struct {
+	chatRepo   repository.ChatRepository
+	inviteRepo repository.InviteRepository
+	publisher  EventPublisher
+}
+
+// NewShareService creates a new share service
+func NewShareService(chatRepo repository.ChatRepository, inviteRepo repository.InviteRepository, publisher EventPublisher) ShareService {
+	return &ShareServiceImpl{
+		chatRepo:   chatRepo,
+		inviteRepo: inviteRepo,
+		publisher:  publisher,
+	}
+}
+
+// ListMembers retrieves the members of a chat
+func (s *ShareServiceImpl) ListMembers(ctx context.Context, chatID string) ([]models.ChatMember, error) {
+	chat, err := s.loadChat(ctx, chatID, models.ChatRoleViewer)
+	if err != nil {
+		return nil, err
+	}
+
+	return chat.Members, nil
+}
+
+// UpdateMemberRole changes the role of an existing member; only the owner may do this
+func (s *ShareServiceImpl) UpdateMemberRole(ctx context.Context, chatID, subject string, role models.ChatRole) (*models.ChatMember, error) {
+	if err := validateShareRole(role); err != nil {
+		return nil, err
+	}
+
+	chat, err := s.loadChat(ctx, chatID, models.ChatRoleOwner)
+	if err != nil {
+		return nil, err
+	}
+
+	if subject == chat.OwnerID {
+		return nil, apperrors.NewConflictError("The owner's role cannot be changed", nil)
+	}
+
+	if _, ok := chat.MemberRole(subject); !ok {
+		return nil, apperrors.NewNotFoundError("Member not found", nil)
+	}
+
+	member := models.NewChatMember(subject, role)
+	if err := s.chatRepo.SetMember(ctx, chat.ID, member); err != nil {
+		return nil, err
+	}
+
+	s.publishMembersUpdated(ctx, chat.ID)
+
+	return &member, nil
+}
+
+// RemoveMember removes a member from a chat. The owner may remove anyone but
+// themselves; other members may only remove themselves.
+func (s *ShareServiceImpl) RemoveMember(ctx context.Context, chatID, subject string) error {
+	chat, err := s.loadChat(ctx, chatID, models.ChatRoleViewer)
+	if err != nil {
+		return err
+	}
+
+	caller, _ := callerSubject(ctx)
+	if subject != caller {
+		if err := authorizeChat(ctx, chat, models.ChatRoleOwner); err != nil {
+			return err
+		}
+	}
+
+	if subject == chat.OwnerID {
+		return apperrors.NewConflictError("The owner cannot be removed from the chat", nil)
+	}
+
+	if _, ok := chat.MemberRole(subject); !ok {
+		return apperrors.NewNotFoundError("Member not found", nil)
+	}
+
+	if err := s.chatRepo.RemoveMember(ctx, chat.ID, subject); err != nil {
+		return err
+	}
+
+	s.publishMembersUpdated(ctx, chat.ID)
+
+	return nil
+}
+
+// CreateInvite creates a share link granting role on a chat. The returned code
+// is shown once; only its hash is stored.
+func (s *ShareServiceImpl) CreateInvite(ctx context.Context, chatID string, role models.ChatRole, ttl time.Duration) (*models.ChatInvite, string, error) {
+	if err := validateShareRole(role); err != nil {
+		return nil, "", err
+	}
+
+	if ttl <= 0 {
+		ttl = DefaultInviteTTL
+	}
+	if ttl > MaxInviteTTL {
+		return nil, "", apperrors.NewValidationError("Invites can be valid for at most 30 days", nil)
+	}
+
+	chat, err := s.loadChat(ctx, chatID, models.ChatRoleOwner)
+	if err != nil {
+		return nil, "", err
+	}
+
+	secret := make([]byte, inviteCodeBytes)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, "", err
+	}
+	code := base64.RawURLEncoding.EncodeToString(secret)
+
+	createdBy, _ := callerSubject(ctx)
+	invite := models.NewChatInvite(chat.ID, hashInviteCode(code), role, createdBy, ttl)
+
+	if err := s.inviteRepo.Create(ctx, invite); err != nil {
+		return nil, "", err
+	}
+
+	return invite, code, nil
+}
+
+// ListInvites retrieves the active invites of a chat
+func (s *ShareServiceImpl) ListInvites(ctx context.Context, chatID string) ([]*models.ChatInvite, error) {
+	chat, err := s.loadChat(ctx, chatID, models.ChatRoleOwner)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.inviteRepo.FindByChatID(ctx, chat.ID)
+}
+
+// RevokeInvite deletes an invite so it can no longer be accepted
+func (s *ShareServiceImpl) RevokeInvite(ctx context.Context, chatID, inviteID string) error {
+	inviteObjID, err := primitive.ObjectIDFromHex(inviteID)
+	if err != nil {
+		return apperrors.NewBadRequestError("Invalid invite ID", err)
+	}
+
+	chat, err := s.loadChat(ctx, chatID, models.ChatRoleOwner)
+	if err != nil {
+		return err
+	}
+
+	if err := s.inviteRepo.Delete(ctx, chat.ID, inviteObjID); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return apperrors.NewNotFoundError("Invite not found", nil)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// AcceptInvite adds the caller to the invite's chat. Existing members keep
+// their role unless the invite grants a higher one.
+func (s *ShareServiceImpl) AcceptInvite(ctx context.Context, code string) (*models.Chat, error) {
+	subject, ok := callerSubject(ctx)
+	if !ok {
+		return nil, apperrors.NewUnauthorizedError("Accepting an invite requires authentication", nil)
+	}
+
+	invite, err := s.inviteRepo.FindByCodeHash(ctx, hashInviteCode(code))
+	if err != nil {
+		return nil, err
+	}
+
+	if invite == nil || invite.IsExpired() {
+		return nil, apperrors.NewNotFoundError("Invite not found or expired", nil)
+	}
+
+	chat, err := s.chatRepo.FindByID(ctx, invite.ChatID)
+	if err != nil {
+		return nil, err
+	}
+
+	if chat == nil {
+		return nil, apperrors.NewNotFoundError("Invite not found or expired", nil)
+	}
+
+	if role, isMember := chat.MemberRole(subject); isMember && role.Allows(invite.Role) {
+		return chat, nil
+	}
+
+	if err := s.chatRepo.SetMember(ctx, chat.ID, models.NewChatMember(subject, invite.Role)); err != nil {
+		return nil, err
+	}
+
+	chat, err = s.chatRepo.FindByID(ctx, chat.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if chat == nil {
+		return nil, apperrors.NewNotFoundError("Invite not found or expired", nil)
+	}
+
+	publishChatUpdated(ctx, s.publisher, chat, "members")
+
+	return chat, nil
+}
+
+// loadChat parses a chat ID and checks that the caller holds at least the required role on the chat
+func (s *ShareServiceImpl) loadChat(ctx context.Context, id string, required models.ChatRole) (*models.Chat, error) {
+	chatID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, apperrors.NewBadRequestError("Invalid chat ID", err)
+	}
+
+	return findChat(ctx, s.chatRepo, chatID, required)
+}
+
+// publishMembersUpdated reloads a chat after a membership change and broadcasts it
+func (s *ShareServiceImpl) publishMembersUpdated(ctx context.Context, chatID primitive.ObjectID) {
+	chat, err := s.chatRepo.FindByID(ctx, chatID)
+	if err != nil || chat == nil {
+		logger.FromContext(ctx).Warnf("Failed to load chat %s for broadcasting: %v", chatID.Hex(), err)
+		return
+	}
+
+	publishChatUpdated(ctx, s.publisher, chat, "members")
+}
+
+// validateShareRole checks that a role can be granted through sharing
+func validateShareRole(role models.ChatRole) error {
+	if role != models.ChatRoleEditor && role != models.ChatRoleViewer {
+		return apperrors.NewValidationError("Role must be editor or viewer", nil)
+	}
+	return nil
+}
+
+// hashInviteCode returns the stored representation of an invite code
+func hashInviteCode(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,74 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package services
+
+import (
+	"context"
+
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/models"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/repository"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// tracedMessageService wraps a MessageService in a span per call.
+// Repository and broker spans started by the call become its children.
+type tracedMessageService struct {
+	next MessageService
+}
+
+// CreateMessage traces MessageService.CreateMessage
+func (s *tracedMessageService) CreateMessage(ctx context.Context, chatID string, content string, role models.MessageRole, msgType models.MessageType) (*models.Message, error) {
+	ctx, span := tracing.Start(ctx, "MessageService.CreateMessage",
+		attribute.String("chat.id", chatID), attribute.String("message.role", string(role)))
+	message, err := s.next.CreateMessage(ctx, chatID, content, role, msgType)
+	tracing.End(span, err)
+	return message, err
+}
+
+// GetMessageByID traces MessageService.GetMessageByID
+func (s *tracedMessageService) GetMessageByID(ctx context.Context, id string) (*models.Message, error) {
+	ctx, span := tracing.Start(ctx, "MessageService.GetMessageByID", attribute.String("message.id", id))
+	message, err := s.next.GetMessageByID(ctx, id)
+	tracing.End(span, err)
+	return message, err
+}
+
+// GetChatMessages traces MessageService.GetChatMessages
+func (s *tracedMessageService) GetChatMessages(ctx context.Context, chatID string, page, pageSize int) ([]*models.Message, int64, error) {
+	ctx, span := tracing.Start(ctx, "MessageService.GetChatMessages", attribute.String("chat.id", chatID))
+	messages, total, err := s.next.GetChatMessages(ctx, chatID, page, pageSize)
+	tracing.End(span, err)
+	return messages, total, err
+}
+
+// GetChatMessagesByCursor traces MessageService.GetChatMessagesByCursor
+func (s *tracedMessageService) GetChatMessagesByCursor(ctx context.Context, chatID string, query repository.CursorQuery) ([]*models.Message, repository.PageInfo, int64, error) {
+	ctx, span := tracing.Start(ctx, "MessageService.GetChatMessagesByCursor", attribute.String("chat.id", chatID))
+	messages, info, total, err := s.next.GetChatMessagesByCursor(ctx, chatID, query)
+	tracing.End(span, err)
+	return messages, info, total, err
+}
+
+// DeleteMessage traces MessageService.DeleteMessage
+func (s *tracedMessageService) DeleteMessage(ctx context.Context, id string) error {
+	ctx, span := tracing.Start(ctx, "MessageService.DeleteMessage", attribute.String("message.id", id))
+	err := s.next.DeleteMessage(ctx, id)
+	tracing.End(span, err)
+	return err
+}
+
+// RestoreMessage traces MessageService.RestoreMessage
+func (s *tracedMessageService) RestoreMessage(ctx context.Context, id string) (*models.Message, error) {
+	ctx, span := tracing.Start(ctx, "MessageService.RestoreMessage", attribute.String("message.id", id))
+	message, err := s.next.RestoreMessage(ctx, id)
+	tracing.End(span, err)
+	return message, err
+}
@@ -0,0 +1,163 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/jobs"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/models"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/repository"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/pkg/logger"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// purgeBatchSize limits how many chats are hard-deleted per purge round
+const purgeBatchSize = 500
+
+// TrashServiceImpl implements the TrashService interface
+type TrashServiceImpl struct {
+	chatRepo    repository.ChatRepository
+	messageRepo repository.MessageRepository
+	tx          repository.Transactor
+}
+
+// NewTrashService creates a new trash service
+func NewTrashService(chatRepo repository.ChatRepository, messageRepo repository.MessageRepository, tx repository.Transactor) TrashService {
+	return &TrashServiceImpl{
+		chatRepo:    chatRepo,
+		messageRepo: messageRepo,
+		tx:          tx,
+	}
+}
+
+// ListDeletedChats retrieves a paginated list of chats in the trash
+func (s *TrashServiceImpl) ListDeletedChats(ctx context.Context, page, pageSize int) ([]*models.Chat, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+
+	if pageSize < 1 {
+		pageSize = 10
+	}
+
+	// Only owners can restore chats, so only their trashed chats are listed
+	ownerID, _ := callerSubject(ctx)
+
+	chats, err := s.chatRepo.FindDeleted(ctx, ownerID, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	total, err := s.chatRepo.CountDeleted(ctx, ownerID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return chats, total, nil
+}
+
+// ListDeletedMessages retrieves a paginated list of individually deleted messages
+func (s *TrashServiceImpl) ListDeletedMessages(ctx context.Context, page, pageSize int) ([]*models.Message, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+
+	if pageSize < 1 {
+		pageSize = 20
+	}
+
+	// List messages from the chats in which the caller can restore them
+	var chatIDs []primitive.ObjectID
+	if subject, ok := callerSubject(ctx); ok {
+		ids, err := s.chatRepo.FindIDsByMember(ctx, subject, []models.ChatRole{models.ChatRoleOwner, models.ChatRoleEditor})
+		if err != nil {
+			return nil, 0, err
+		}
+		chatIDs = ids
+	}
+
+	messages, err := s.messageRepo.FindDeleted(ctx, chatIDs, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	total, err := s.messageRepo.CountDeleted(ctx, chatIDs)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return messages, total, nil
+}
+
+// PurgeExpired permanently deletes chats and messages that have been in the trash longer than retention
+func (s *TrashServiceImpl) PurgeExpired(ctx context.Context, retention time.Duration) (int64, int64, error) {
+	cutoff := time.Now().Add(-retention)
+	var chatsPurged, messagesPurged int64
+
+	for {
+		ids, err := s.chatRepo.FindDeletedBefore(ctx, cutoff, purgeBatchSize)
+		if err != nil {
+			return chatsPurged, messagesPurged, err
+		}
+
+		if len(ids) == 0 {
+			break
+		}
+
+		var chats, messages int64
+		err = s.tx.WithTransaction(ctx, func(ctx context.Context) error {
+			// Remove messages first so a failure never leaves orphaned messages behind
+			var err error
+			if messages, err = s.messageRepo.HardDeleteByChatIDs(ctx, ids); err != nil {
+				return err
+			}
+
+			chats, err = s.chatRepo.HardDelete(ctx, ids)
+			return err
+		})
+		if err != nil {
+			return chatsPurged, messagesPurged, err
+		}
+		chatsPurged += chats
+		messagesPurged += messages
+
+		if len(ids) < purgeBatchSize {
+			break
+		}
+	}
+
+	deleted, err := s.messageRepo.PurgeDeletedBefore(ctx, cutoff)
+	if err != nil {
+		return chatsPurged, messagesPurged, err
+	}
+	messagesPurged += deleted
+
+	return chatsPurged, messagesPurged, nil
+}
+
+// JobTrashPurge is the type of the scheduled job that purges expired trash
+const JobTrashPurge = "trash.purge"
+
+// NewTrashPurgeHandler returns the job handler that purges trash older than retention
+func NewTrashPurgeHandler(trash TrashService, retention time.Duration) jobs.Handler {
+	return func(ctx context.Context, job *models.Job) error {
+		chats, messages, err := trash.PurgeExpired(ctx, retention)
+		if err != nil {
+			return err
+		}
+
+		if chats > 0 || messages > 0 {
+			logger.FromContext(ctx).Infof("Purged %d chats and %d messages from the trash", chats, messages)
+		}
+		return nil
+	}
+}
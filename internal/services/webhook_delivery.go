@@ -0,0 +1,354 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/config"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/jobs"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/models"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/repository"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/tenant"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/tracing"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/pkg/buildinfo"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/pkg/logger"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// JobWebhookDelivery is the type of the job that delivers an event to a webhook
+const JobWebhookDelivery = "webhook.deliver"
+
+// Headers of webhook deliveries
+const (
+	WebhookSignatureHeader = "X-Webhook-Signature" // "t=<unix time>,v1=<hex HMAC-SHA256 of "<t>.<body>">"
+	WebhookEventHeader     = "X-Webhook-Event"
+	WebhookIDHeader        = "X-Webhook-ID" // Event ID, the same for every attempt
+)
+
+// webhookResponseLimit bounds how much of a webhook's response is read
+const webhookResponseLimit = 64 << 10
+
+// WebhookEvent is the body of a webhook delivery
+type WebhookEvent struct {
+	ID        string          `json:"id"`
+	Event     string          `json:"event"`
+	TenantID  string          `json:"tenant_id"`
+	ChatID    string          `json:"chat_id"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"` // Same payload as the SSE event
+}
+
+// webhookDeliveryPayload is the payload of a webhook delivery job
+type webhookDeliveryPayload struct {
+	WebhookID string       `json:"webhook_id"`
+	Event     WebhookEvent `json:"event"`
+}
+
+// WebhookPublisher forwards events to the next publisher and enqueues a
+// delivery job for every webhook subscribed to them
+type WebhookPublisher struct {
+	next        EventPublisher
+	webhookRepo repository.WebhookRepository
+	chatRepo    repository.ChatRepository
+	queue       *jobs.Queue
+	maxAttempts int
+}
+
+// NewWebhookPublisher creates a publisher that also delivers events to webhooks
+func NewWebhookPublisher(next EventPublisher, webhookRepo repository.WebhookRepository, chatRepo repository.ChatRepository, queue *jobs.Queue, maxAttempts int) *WebhookPublisher {
+	return &WebhookPublisher{
+		next:        next,
+		webhookRepo: webhookRepo,
+		chatRepo:    chatRepo,
+		queue:       queue,
+		maxAttempts: maxAttempts,
+	}
+}
+
+// SendToChat implements EventPublisher. Failures to enqueue deliveries are
+// logged; only the error of the next publisher is returned.
+func (p *WebhookPublisher) SendToChat(ctx context.Context, tenantID, chatID string, messageID string, event string, data interface{}) error {
+	err := p.next.SendToChat(ctx, tenantID, chatID, messageID, event, data)
+
+	if slices.Contains(WebhookEvents, event) {
+		if enqueueErr := p.enqueue(tenant.WithTenant(ctx, tenantID), chatID, messageID, event, data); enqueueErr != nil {
+			logger.FromContext(ctx).Errorf("Failed to enqueue %s webhook deliveries for chat %s: %v", event, chatID, enqueueErr)
+		}
+	}
+
+	return err
+}
+
+// enqueue adds a delivery job per webhook that subscribes to the event and
+// whose owner can access the chat
+func (p *WebhookPublisher) enqueue(ctx context.Context, chatID, messageID, event string, data interface{}) error {
+	webhooks, err := p.webhookRepo.FindSubscribed(ctx, event)
+	if err != nil || len(webhooks) == 0 {
+		return err
+	}
+
+	chat, err := p.loadChat(ctx, chatID, webhooks)
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	eventID := messageID
+	if eventID == "" {
+		eventID = uuid.NewString()
+	}
+	webhookEvent := WebhookEvent{
+		ID:        eventID,
+		Event:     event,
+		TenantID:  tenant.FromContext(ctx),
+		ChatID:    chatID,
+		CreatedAt: time.Now().UTC(),
+		Data:      payload,
+	}
+
+	var errs []error
+	for _, webhook := range webhooks {
+		if webhook.OwnerID != "" {
+			if chat == nil {
+				continue
+			}
+			if _, ok := chat.MemberRole(webhook.OwnerID); !ok {
+				continue
+			}
+		}
+
+		_, err := p.queue.Enqueue(ctx, JobWebhookDelivery, webhookDeliveryPayload{
+			WebhookID: webhook.ID.Hex(),
+			Event:     webhookEvent,
+		}, jobs.Options{
+			TenantID:    webhookEvent.TenantID,
+			MaxAttempts: p.maxAttempts,
+			UniqueKey:   fmt.Sprintf("webhook:%s:%s:%s", webhook.ID.Hex(), event, eventID),
+		})
+		if err != nil && !errors.Is(err, jobs.ErrDuplicateJob) {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// loadChat retrieves the chat of an event when a webhook is restricted to
+// its owner's chats. Chats in the trash are included, so that deletions reach
+// their members; it returns nil when the chat no longer exists.
+func (p *WebhookPublisher) loadChat(ctx context.Context, chatID string, webhooks []*models.Webhook) (*models.Chat, error) {
+	owned := slices.ContainsFunc(webhooks, func(webhook *models.Webhook) bool {
+		return webhook.OwnerID != ""
+	})
+	if !owned {
+		return nil, nil
+	}
+
+	id, err := primitive.ObjectIDFromHex(chatID)
+	if err != nil {
+		return nil, nil
+	}
+
+	chat, err := p.chatRepo.FindByID(ctx, id)
+	if err != nil || chat != nil {
+		return chat, err
+	}
+
+	return p.chatRepo.FindDeletedByID(ctx, id)
+}
+
+// NewWebhookDeliveryHandler returns the job handler that delivers events to
+// webhooks. Every attempt is logged; a webhook is disabled once
+// cfg.DisableAfter deliveries in a row failed.
+func NewWebhookDeliveryHandler(webhookRepo repository.WebhookRepository, deliveryRepo repository.WebhookDeliveryRepository, cfg config.WebhooksConfig) jobs.Handler {
+	client := tracing.NewHTTPClient(&http.Client{
+		Timeout:   cfg.Timeout,
+		Transport: webhookTransport(cfg.AllowPrivateNetworks),
+		// Redirects are not followed; they count as failed deliveries
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	})
+
+	return func(ctx context.Context, job *models.Job) error {
+		var payload webhookDeliveryPayload
+		if err := job.DecodePayload(&payload); err != nil {
+			return jobs.Permanent(fmt.Errorf("invalid webhook delivery payload: %w", err))
+		}
+
+		webhookID, err := primitive.ObjectIDFromHex(payload.WebhookID)
+		if err != nil {
+			return jobs.Permanent(fmt.Errorf("invalid webhook ID %q: %w", payload.WebhookID, err))
+		}
+
+		webhook, err := webhookRepo.FindByID(ctx, webhookID)
+		if err != nil {
+			return err
+		}
+		if webhook == nil || !webhook.Active {
+			logger.FromContext(ctx).Debugf("Dropped %s event for deleted or disabled webhook %s", payload.Event.Event, payload.WebhookID)
+			return nil
+		}
+
+		delivery, deliveryErr := deliverWebhook(ctx, client, webhook, &payload.Event)
+		delivery.Attempt = job.Attempts
+		delivery.ExpiresAt = delivery.CreatedAt.Add(cfg.DeliveryRetention)
+		if err := deliveryRepo.Create(ctx, delivery); err != nil {
+			logger.FromContext(ctx).Warnf("Failed to log delivery to webhook %s: %v", payload.WebhookID, err)
+		}
+
+		if deliveryErr == nil {
+			return webhookRepo.RecordSuccess(ctx, webhook.ID, delivery.CreatedAt)
+		}
+
+		failures, err := webhookRepo.RecordFailure(ctx, webhook.ID, delivery.CreatedAt)
+		if err != nil {
+			logger.FromContext(ctx).Warnf("Failed to record failed delivery to webhook %s: %v", payload.WebhookID, err)
+		}
+
+		if failures >= cfg.DisableAfter {
+			reason := fmt.Sprintf("Disabled after %d consecutive failed deliveries", failures)
+			disabled, err := webhookRepo.Disable(ctx, webhook.ID, reason, time.Now())
+			if err != nil {
+				logger.FromContext(ctx).Warnf("Failed to disable webhook %s: %v", payload.WebhookID, err)
+			} else if disabled {
+				logger.FromContext(ctx).Warnf("Disabled webhook %s after %d consecutive failed deliveries", payload.WebhookID, failures)
+			}
+			return jobs.Permanent(deliveryErr)
+		}
+
+		// Client errors other than timeouts and rate limiting will not go away on retry
+		if delivery.StatusCode >= 400 && delivery.StatusCode < 500 &&
+			delivery.StatusCode != http.StatusRequestTimeout && delivery.StatusCode != http.StatusTooManyRequests {
+			return jobs.Permanent(deliveryErr)
+		}
+
+		return deliveryErr
+	}
+}
+
+// deliverWebhook sends a signed event to a webhook. It returns the delivery
+// record and, unless the webhook answered with a 2xx status, the error.
+func deliverWebhook(ctx context.Context, client *http.Client, webhook *models.Webhook, event *WebhookEvent) (*models.WebhookDelivery, error) {
+	delivery := &models.WebhookDelivery{
+		ID:        primitive.NewObjectID(),
+		WebhookID: webhook.ID,
+		EventID:   event.ID,
+		Event:     event.Event,
+		URL:       webhook.URL,
+		CreatedAt: time.Now(),
+	}
+
+	err := func() error {
+		body, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", "go-sse-ai-chat-webhooks/"+buildinfo.Get().Version)
+		req.Header.Set(WebhookEventHeader, event.Event)
+		req.Header.Set(WebhookIDHeader, event.ID)
+		req.Header.Set(WebhookSignatureHeader, SignWebhook(webhook.Secret, delivery.CreatedAt, body))
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, webhookResponseLimit))
+
+		delivery.StatusCode = resp.StatusCode
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+		}
+		return nil
+	}()
+
+	delivery.DurationMs = time.Since(delivery.CreatedAt).Milliseconds()
+	delivery.Success = err == nil
+	if err != nil {
+		delivery.Error = err.Error()
+	}
+
+	return delivery, err
+}
+
+// SignWebhook returns the signature header of a webhook body sent at the
+// given time. Receivers recompute the HMAC over "<t>.<body>" to verify it.
+func SignWebhook(secret string, at time.Time, body []byte) string {
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return "t=" + timestamp + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// errPrivateAddress rejects connections to addresses webhooks may not target
+var errPrivateAddress = errors.New("webhook address is not public")
+
+// webhookTransport returns the transport of webhook deliveries. Unless
+// allowPrivate is set, it refuses to connect to loopback, private and
+// link-local addresses, so that webhooks cannot reach internal services.
+func webhookTransport(allowPrivate bool) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if allowPrivate {
+		return transport
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		// Checked after name resolution, so that DNS cannot point around it
+		Control: func(network, address string, conn syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+				ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsMulticast() {
+				return fmt.Errorf("%w: %s", errPrivateAddress, host)
+			}
+			return nil
+		},
+	}
+	transport.DialContext = dialer.DialContext
+	transport.Proxy = nil
+
+	return transport
+}
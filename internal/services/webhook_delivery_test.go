@@ -0,0 +1,659 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/config"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/jobs"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/models"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/repository"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/sse"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// webhookTolerance is how far the signed timestamp may be from the receiver's clock
+const webhookTolerance = 5 * time.Minute
+
+// webhookStore is an in-memory WebhookRepository
+type webhookStore struct {
+	webhooks map[primitive.ObjectID]*models.Webhook
+	mutex    sync.Mutex
+}
+
+func newWebhookStore(webhooks ...*models.Webhook) *webhookStore {
+	store := &webhookStore{webhooks: make(map[primitive.ObjectID]*models.Webhook)}
+	for _, webhook := range webhooks {
+		store.webhooks[webhook.ID] = webhook
+	}
+	return store
+}
+
+func (s *webhookStore) Create(_ context.Context, webhook *models.Webhook) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.webhooks[webhook.ID] = webhook
+	return nil
+}
+
+func (s *webhookStore) FindByID(_ context.Context, id primitive.ObjectID) (*models.Webhook, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	webhook, ok := s.webhooks[id]
+	if !ok {
+		return nil, nil
+	}
+	found := *webhook
+	return &found, nil
+}
+
+func (s *webhookStore) FindAll(context.Context, string) ([]*models.Webhook, error) {
+	return nil, nil
+}
+
+func (s *webhookStore) FindSubscribed(_ context.Context, event string) ([]*models.Webhook, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	var webhooks []*models.Webhook
+	for _, webhook := range s.webhooks {
+		if webhook.Active && webhook.Subscribes(event) {
+			webhooks = append(webhooks, webhook)
+		}
+	}
+	return webhooks, nil
+}
+
+func (s *webhookStore) Update(ctx context.Context, webhook *models.Webhook) error {
+	return s.Create(ctx, webhook)
+}
+
+func (s *webhookStore) Delete(_ context.Context, id primitive.ObjectID) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.webhooks, id)
+	return nil
+}
+
+func (s *webhookStore) RecordSuccess(_ context.Context, id primitive.ObjectID, at time.Time) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	webhook := s.webhooks[id]
+	webhook.ConsecutiveFailures = 0
+	webhook.LastDeliveryAt = &at
+	webhook.LastSuccessAt = &at
+	return nil
+}
+
+func (s *webhookStore) RecordFailure(_ context.Context, id primitive.ObjectID, at time.Time) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	webhook := s.webhooks[id]
+	webhook.ConsecutiveFailures++
+	webhook.LastDeliveryAt = &at
+	return webhook.ConsecutiveFailures, nil
+}
+
+func (s *webhookStore) Enable(_ context.Context, id primitive.ObjectID, at time.Time) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	webhook := s.webhooks[id]
+	webhook.Active = true
+	webhook.ConsecutiveFailures = 0
+	webhook.DisabledReason = ""
+	webhook.DisabledAt = nil
+	return nil
+}
+
+func (s *webhookStore) Disable(_ context.Context, id primitive.ObjectID, reason string, at time.Time) (bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	webhook := s.webhooks[id]
+	if !webhook.Active {
+		return false, nil
+	}
+	webhook.Active = false
+	webhook.DisabledReason = reason
+	webhook.DisabledAt = &at
+	return true, nil
+}
+
+// deliveryLog is an in-memory WebhookDeliveryRepository
+type deliveryLog struct {
+	deliveries []*models.WebhookDelivery
+	mutex      sync.Mutex
+}
+
+func (l *deliveryLog) Create(_ context.Context, delivery *models.WebhookDelivery) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.deliveries = append(l.deliveries, delivery)
+	return nil
+}
+
+func (l *deliveryLog) FindByWebhookID(context.Context, primitive.ObjectID, int, int) ([]*models.WebhookDelivery, error) {
+	return nil, nil
+}
+
+func (l *deliveryLog) CountByWebhookID(context.Context, primitive.ObjectID) (int64, error) {
+	return 0, nil
+}
+
+func (l *deliveryLog) DeleteByWebhookID(context.Context, primitive.ObjectID) (int64, error) {
+	return 0, nil
+}
+
+// last returns the most recently logged delivery
+func (l *deliveryLog) last() *models.WebhookDelivery {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if len(l.deliveries) == 0 {
+		return nil
+	}
+	return l.deliveries[len(l.deliveries)-1]
+}
+
+// verifyWebhookSignature checks a delivery the way receivers are told to:
+// it parses the signature header, rejects timestamps outside the tolerance
+// and compares the HMAC of "<t>.<body>" in constant time
+func verifyWebhookSignature(secret, header string, body []byte, now time.Time) (string, bool) {
+	var timestamp, signature string
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return "malformed header", false
+		}
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signature = value
+		}
+	}
+	if timestamp == "" || signature == "" {
+		return "missing t or v1", false
+	}
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return "timestamp is not a unix time", false
+	}
+	if skew := now.Sub(time.Unix(unix, 0)); skew > webhookTolerance || skew < -webhookTolerance {
+		return "timestamp outside the tolerance", false
+	}
+
+	got, err := hex.DecodeString(signature)
+	if err != nil {
+		return "signature is not hex", false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	if !hmac.Equal(got, mac.Sum(nil)) {
+		return "signature mismatch", false
+	}
+	return "", true
+}
+
+// receivedWebhook is a request received by the test server
+type receivedWebhook struct {
+	header http.Header
+	body   []byte
+}
+
+// newWebhookServer starts a server that records requests and answers with status
+func newWebhookServer(t *testing.T, status int) (*httptest.Server, chan receivedWebhook) {
+	t.Helper()
+
+	received := make(chan receivedWebhook, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("reading webhook body: %v", err)
+		}
+		received <- receivedWebhook{header: r.Header.Clone(), body: body}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, received
+}
+
+// testWebhooksConfig returns a configuration that allows the loopback test server
+func testWebhooksConfig() config.WebhooksConfig {
+	return config.WebhooksConfig{
+		Timeout:              5 * time.Second,
+		MaxAttempts:          3,
+		DisableAfter:         10,
+		DeliveryRetention:    time.Hour,
+		AllowPrivateNetworks: true,
+	}
+}
+
+// newDeliveryJob returns a claimed delivery job of an event to webhook
+func newDeliveryJob(t *testing.T, webhook *models.Webhook, event WebhookEvent) *models.Job {
+	t.Helper()
+
+	job, err := models.NewJob(JobWebhookDelivery, webhookDeliveryPayload{WebhookID: webhook.ID.Hex(), Event: event}, time.Now(), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	job.Attempts = 1
+	return job
+}
+
+// testWebhookEvent returns a complete event of a chat
+func testWebhookEvent() WebhookEvent {
+	return WebhookEvent{
+		ID:        "6123456789abcdef01234568",
+		Event:     "complete",
+		TenantID:  "default",
+		ChatID:    "6123456789abcdef01234567",
+		CreatedAt: time.Now().UTC(),
+		Data:      json.RawMessage(`{"id":"6123456789abcdef01234568"}`),
+	}
+}
+
+// TestWebhookDeliverySignature checks that a receiver can verify a delivery
+// by recomputing the HMAC over the timestamp and the raw body
+func TestWebhookDeliverySignature(t *testing.T) {
+	server, received := newWebhookServer(t, http.StatusNoContent)
+	webhook := newWebhook(server.URL, "whsec_test")
+	webhooks := newWebhookStore(webhook)
+	deliveries := &deliveryLog{}
+	event := testWebhookEvent()
+
+	handler := NewWebhookDeliveryHandler(webhooks, deliveries, testWebhooksConfig())
+	if err := handler(context.Background(), newDeliveryJob(t, webhook, event)); err != nil {
+		t.Fatalf("handler() error = %v", err)
+	}
+
+	req := <-received
+	header := req.header.Get(WebhookSignatureHeader)
+	if reason, ok := verifyWebhookSignature("whsec_test", header, req.body, time.Now()); !ok {
+		t.Errorf("%s = %q does not verify: %s", WebhookSignatureHeader, header, reason)
+	}
+	if got := req.header.Get(WebhookEventHeader); got != event.Event {
+		t.Errorf("%s = %q, want %q", WebhookEventHeader, got, event.Event)
+	}
+	if got := req.header.Get(WebhookIDHeader); got != event.ID {
+		t.Errorf("%s = %q, want %q", WebhookIDHeader, got, event.ID)
+	}
+
+	var body WebhookEvent
+	if err := json.Unmarshal(req.body, &body); err != nil {
+		t.Fatalf("body is not a webhook event: %v", err)
+	}
+	if body.ID != event.ID || body.ChatID != event.ChatID || string(body.Data) != string(event.Data) {
+		t.Errorf("body = %+v, want %+v", body, event)
+	}
+
+	// The signature does not verify with another secret, another body or too late
+	tests := []struct {
+		name   string
+		secret string
+		body   []byte
+		now    time.Time
+	}{
+		{name: "other secret", secret: "whsec_other", body: req.body, now: time.Now()},
+		{name: "tampered body", secret: "whsec_test", body: append([]byte(" "), req.body...), now: time.Now()},
+		{name: "replayed", secret: "whsec_test", body: req.body, now: time.Now().Add(webhookTolerance + time.Minute)},
+		{name: "from the future", secret: "whsec_test", body: req.body, now: time.Now().Add(-webhookTolerance - time.Minute)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := verifyWebhookSignature(tt.secret, header, tt.body, tt.now); ok {
+				t.Errorf("signature %q verified, want it rejected", header)
+			}
+		})
+	}
+
+	if delivery := deliveries.last(); delivery == nil || !delivery.Success || delivery.StatusCode != http.StatusNoContent || delivery.Attempt != 1 {
+		t.Errorf("logged delivery = %+v, want a successful first attempt with status 204", delivery)
+	}
+	if stored, _ := webhooks.FindByID(context.Background(), webhook.ID); stored.LastSuccessAt == nil {
+		t.Error("success was not recorded on the webhook")
+	}
+}
+
+// TestSignWebhookFormat checks the signature header against a known value
+func TestSignWebhookFormat(t *testing.T) {
+	got := SignWebhook("secret", time.Unix(1700000000, 0), []byte(`{"id":"1"}`))
+
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte(`1700000000.{"id":"1"}`))
+	want := "t=1700000000,v1=" + hex.EncodeToString(mac.Sum(nil))
+
+	if got != want {
+		t.Errorf("SignWebhook() = %q, want %q", got, want)
+	}
+}
+
+// TestWebhookDeliveryRetries checks that server errors, timeouts and rate
+// limiting are retried, while other client errors drop the event
+func TestWebhookDeliveryRetries(t *testing.T) {
+	tests := []struct {
+		status        int
+		wantErr       bool
+		wantPermanent bool
+	}{
+		{status: http.StatusOK},
+		{status: http.StatusInternalServerError, wantErr: true},
+		{status: http.StatusBadGateway, wantErr: true},
+		{status: http.StatusServiceUnavailable, wantErr: true},
+		{status: http.StatusRequestTimeout, wantErr: true},
+		{status: http.StatusTooManyRequests, wantErr: true},
+		{status: http.StatusBadRequest, wantErr: true, wantPermanent: true},
+		{status: http.StatusUnauthorized, wantErr: true, wantPermanent: true},
+		{status: http.StatusNotFound, wantErr: true, wantPermanent: true},
+		{status: http.StatusGone, wantErr: true, wantPermanent: true},
+		{status: http.StatusFound, wantErr: true}, // Redirects are not followed
+	}
+
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(tt.status), func(t *testing.T) {
+			server, received := newWebhookServer(t, tt.status)
+			webhook := newWebhook(server.URL, "whsec_test")
+			webhooks := newWebhookStore(webhook)
+			deliveries := &deliveryLog{}
+
+			handler := NewWebhookDeliveryHandler(webhooks, deliveries, testWebhooksConfig())
+			err := handler(context.Background(), newDeliveryJob(t, webhook, testWebhookEvent()))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("handler() error = %v, want error %v", err, tt.wantErr)
+			}
+			if got := jobs.IsPermanent(err); got != tt.wantPermanent {
+				t.Errorf("IsPermanent(%v) = %v, want %v", err, got, tt.wantPermanent)
+			}
+			if len(received) != 1 {
+				t.Errorf("server received %d requests, want 1", len(received))
+			}
+
+			delivery := deliveries.last()
+			if delivery == nil || delivery.StatusCode != tt.status || delivery.Success == tt.wantErr {
+				t.Errorf("logged delivery = %+v, want status %d and success %v", delivery, tt.status, !tt.wantErr)
+			}
+
+			stored, _ := webhooks.FindByID(context.Background(), webhook.ID)
+			if wantFailures := map[bool]int{false: 0, true: 1}[tt.wantErr]; stored.ConsecutiveFailures != wantFailures {
+				t.Errorf("consecutive failures = %d, want %d", stored.ConsecutiveFailures, wantFailures)
+			}
+		})
+	}
+}
+
+// TestWebhookDeliveryDisables checks that a webhook is disabled after
+// DisableAfter failures in a row, and that events for it are then dropped
+func TestWebhookDeliveryDisables(t *testing.T) {
+	server, received := newWebhookServer(t, http.StatusServiceUnavailable)
+	webhook := newWebhook(server.URL, "whsec_test")
+	webhooks := newWebhookStore(webhook)
+	cfg := testWebhooksConfig()
+	cfg.DisableAfter = 2
+
+	handler := NewWebhookDeliveryHandler(webhooks, &deliveryLog{}, cfg)
+
+	if err := handler(context.Background(), newDeliveryJob(t, webhook, testWebhookEvent())); err == nil || jobs.IsPermanent(err) {
+		t.Fatalf("first failure error = %v, want a retried error", err)
+	}
+	if err := handler(context.Background(), newDeliveryJob(t, webhook, testWebhookEvent())); !jobs.IsPermanent(err) {
+		t.Fatalf("failure %d error = %v, want a permanent error", cfg.DisableAfter, err)
+	}
+
+	stored, _ := webhooks.FindByID(context.Background(), webhook.ID)
+	if stored.Active || stored.DisabledReason == "" || stored.DisabledAt == nil {
+		t.Errorf("webhook = %+v, want it disabled with a reason", stored)
+	}
+
+	// Events for a disabled webhook are dropped without a request
+	if err := handler(context.Background(), newDeliveryJob(t, webhook, testWebhookEvent())); err != nil {
+		t.Errorf("handler() for a disabled webhook error = %v, want nil", err)
+	}
+	if len(received) != cfg.DisableAfter {
+		t.Errorf("server received %d requests, want %d", len(received), cfg.DisableAfter)
+	}
+}
+
+// newWebhook returns an active webhook subscribed to every event
+func newWebhook(url, secret string) *models.Webhook {
+	return models.NewWebhook(url, secret, []string{models.WebhookAllEvents})
+}
+
+// jobLog is a JobRepository that records the jobs created through it. Only
+// Create is implemented.
+type jobLog struct {
+	repository.JobRepository
+	jobs  []*models.Job
+	mutex sync.Mutex
+}
+
+func (l *jobLog) Create(_ context.Context, job *models.Job) (bool, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.jobs = append(l.jobs, job)
+	return true, nil
+}
+
+// deliveries returns the webhook events of the recorded delivery jobs
+func (l *jobLog) deliveries(t *testing.T) []webhookDeliveryPayload {
+	t.Helper()
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	payloads := make([]webhookDeliveryPayload, len(l.jobs))
+	for i, job := range l.jobs {
+		if err := job.DecodePayload(&payloads[i]); err != nil {
+			t.Fatalf("DecodePayload() error = %v", err)
+		}
+	}
+	return payloads
+}
+
+func TestLifecycleEventsAreWebhookEvents(t *testing.T) {
+	events := []string{
+		sse.EventChatCreated,
+		sse.EventChatUpdated,
+		sse.EventChatDeleted,
+		sse.EventChatRestored,
+		sse.EventMessageCreated,
+		sse.EventMessageDeleted,
+	}
+
+	for _, event := range events {
+		if !slices.Contains(WebhookEvents, event) {
+			t.Errorf("WebhookEvents is missing %s", event)
+		}
+	}
+}
+
+func TestChatServicePublishesLifecycleEvents(t *testing.T) {
+	s := newSharedChat()
+	recorder := &eventRecorder{}
+	service := NewChatService(s.chats, s.messages, nil, noTransactions{}, recorder, nil)
+	ctx := as("alice")
+	pinned := true
+
+	chat, err := service.CreateChat(ctx, "Plans")
+	if err != nil {
+		t.Fatalf("CreateChat() error = %v", err)
+	}
+	chatID := chat.ID.Hex()
+	if _, err := service.UpdateChat(ctx, chatID, "Holiday plans", &pinned); err != nil {
+		t.Fatalf("UpdateChat() error = %v", err)
+	}
+	if _, err := service.ArchiveChat(ctx, chatID); err != nil {
+		t.Fatalf("ArchiveChat() error = %v", err)
+	}
+	if err := service.DeleteChat(ctx, chatID); err != nil {
+		t.Fatalf("DeleteChat() error = %v", err)
+	}
+	if _, err := service.RestoreChat(ctx, chatID); err != nil {
+		t.Fatalf("RestoreChat() error = %v", err)
+	}
+
+	want := []string{
+		sse.EventChatCreated,
+		sse.EventChatUpdated,
+		sse.EventChatUpdated,
+		sse.EventChatDeleted,
+		sse.EventChatRestored,
+	}
+	if got := recorder.names(); !slices.Equal(got, want) {
+		t.Fatalf("published events = %v, want %v", got, want)
+	}
+
+	wantFields := [][]string{{"title", "pinned"}, {"archived"}}
+	for i, event := range recorder.events[1:3] {
+		var data sse.ChatUpdatedEvent
+		if err := json.Unmarshal(event.Data, &data); err != nil {
+			t.Fatalf("chat_updated data error = %v", err)
+		}
+		if !slices.Equal(data.Fields, wantFields[i]) {
+			t.Errorf("chat_updated fields = %v, want %v", data.Fields, wantFields[i])
+		}
+	}
+
+	var deleted sse.ChatDeletedEvent
+	if err := json.Unmarshal(recorder.events[3].Data, &deleted); err != nil {
+		t.Fatalf("chat_deleted data error = %v", err)
+	}
+	if deleted.ChatID != chatID || deleted.DeletedAt.IsZero() {
+		t.Errorf("chat_deleted data = %+v, want chat_id %s and deleted_at", deleted, chatID)
+	}
+
+	for _, event := range recorder.events {
+		if event.ChatID != chatID {
+			t.Errorf("%s published to chat %s, want %s", event.Event, event.ChatID, chatID)
+		}
+	}
+}
+
+func TestChatServiceDeniedWritesPublishNothing(t *testing.T) {
+	s := newSharedChat()
+	recorder := &eventRecorder{}
+	service := NewChatService(s.chats, s.messages, nil, noTransactions{}, recorder, nil)
+	chatID := s.chat.ID.Hex()
+
+	if _, err := service.UpdateChat(as("carol"), chatID, "Mine", nil); err == nil {
+		t.Fatal("UpdateChat() by a viewer succeeded")
+	}
+	if err := service.DeleteChat(as("bob"), chatID); err == nil {
+		t.Fatal("DeleteChat() by an editor succeeded")
+	}
+
+	if got := recorder.names(); len(got) != 0 {
+		t.Errorf("published events = %v, want none", got)
+	}
+}
+
+func TestMessageServicePublishesLifecycleEvents(t *testing.T) {
+	s := newSharedChat()
+	recorder := &eventRecorder{}
+	service := NewMessageService(s.messages, s.chats, nil, noTransactions{}, recorder, nil)
+	ctx := as("bob")
+	chatID := s.chat.ID.Hex()
+
+	message, err := service.CreateMessage(ctx, chatID, "Hi", models.RoleUser, models.TypeText)
+	if err != nil {
+		t.Fatalf("CreateMessage() error = %v", err)
+	}
+	if err := service.DeleteMessage(ctx, message.ID.Hex()); err != nil {
+		t.Fatalf("DeleteMessage() error = %v", err)
+	}
+
+	want := []string{sse.EventMessageCreated, sse.EventMessageDeleted}
+	if got := recorder.names(); !slices.Equal(got, want) {
+		t.Fatalf("published events = %v, want %v", got, want)
+	}
+
+	var created sse.MessageCreatedEvent
+	if err := json.Unmarshal(recorder.events[0].Data, &created); err != nil {
+		t.Fatalf("message_created data error = %v", err)
+	}
+	if created.ChatID != chatID || created.Message == nil || created.Message.ID != message.ID {
+		t.Errorf("message_created data = %+v, want message %s in chat %s", created, message.ID.Hex(), chatID)
+	}
+
+	var deleted sse.MessageDeletedEvent
+	if err := json.Unmarshal(recorder.events[1].Data, &deleted); err != nil {
+		t.Fatalf("message_deleted data error = %v", err)
+	}
+	if deleted.ID != message.ID.Hex() || deleted.ChatID != chatID || deleted.DeletedAt.IsZero() {
+		t.Errorf("message_deleted data = %+v, want message %s in chat %s", deleted, message.ID.Hex(), chatID)
+	}
+}
+
+func TestWebhookPublisherEnqueuesLifecycleEvents(t *testing.T) {
+	s := newSharedChat()
+	member := newWebhook("https://example.com/member", "whsec_member")
+	member.OwnerID = "carol"
+	stranger := newWebhook("https://example.com/stranger", "whsec_stranger")
+	stranger.OwnerID = "dave"
+	chatOnly := models.NewWebhook("https://example.com/chats", "whsec_chats", []string{sse.EventChatDeleted})
+	webhooks := newWebhookStore(member, stranger, chatOnly)
+	jobLog := &jobLog{}
+	publisher := NewWebhookPublisher(&eventRecorder{}, webhooks, s.chats, jobs.NewQueue(jobLog, config.JobsConfig{MaxAttempts: 3}), 5)
+
+	messageService := NewMessageService(s.messages, s.chats, nil, noTransactions{}, publisher, nil)
+	chatService := NewChatService(s.chats, s.messages, nil, noTransactions{}, publisher, nil)
+	if _, err := messageService.CreateMessage(as("bob"), s.chat.ID.Hex(), "Hi", models.RoleUser, models.TypeText); err != nil {
+		t.Fatalf("CreateMessage() error = %v", err)
+	}
+	// Members' webhooks still hear of a deletion once the chat is in the trash
+	if err := chatService.DeleteChat(as("alice"), s.chat.ID.Hex()); err != nil {
+		t.Fatalf("DeleteChat() error = %v", err)
+	}
+
+	got := make(map[string][]string)
+	for _, delivery := range jobLog.deliveries(t) {
+		got[delivery.Event.Event] = append(got[delivery.Event.Event], delivery.WebhookID)
+		if delivery.Event.ChatID != s.chat.ID.Hex() {
+			t.Errorf("%s delivery chat = %s, want %s", delivery.Event.Event, delivery.Event.ChatID, s.chat.ID.Hex())
+		}
+	}
+	for _, ids := range got {
+		slices.Sort(ids)
+	}
+
+	want := map[string][]string{
+		sse.EventMessageCreated: {member.ID.Hex()},
+		sse.EventChatDeleted:    sortedIDs(member, chatOnly),
+	}
+	for event, ids := range want {
+		if !slices.Equal(got[event], ids) {
+			t.Errorf("%s deliveries = %v, want %v", event, got[event], ids)
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("delivered events = %v, want %v", got, want)
+	}
+}
+
+// sortedIDs returns the sorted IDs of webhooks
+func sortedIDs(webhooks ...*models.Webhook) []string {
+	ids := make([]string, len(webhooks))
+	for i, webhook := range webhooks {
+		ids[i] = webhook.ID.Hex()
+	}
+	slices.Sort(ids)
+	return ids
+}
@@ -0,0 +1,278 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/models"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/repository"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/sse"
+	apperrors "github.com/yasin-yalcin-dev/go-sse-ai-chat/pkg/errors"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/pkg/logger"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// webhookSecretPrefix marks webhook signing secrets
+const webhookSecretPrefix = "whsec_"
+
+// webhookSecretBytes is the amount of randomness in a webhook signing secret
+const webhookSecretBytes = 32
+
+// WebhookEvents are the event types webhooks can subscribe to. Message chunk
+// events are streamed to SSE clients only; complete reports the stored reply.
+var WebhookEvents = []string{
+	sse.EventChatCreated,
+	sse.EventChatUpdated,
+	sse.EventChatDeleted,
+	sse.EventChatRestored,
+	sse.EventMessageCreated,
+	sse.EventMessageDeleted,
+	sse.EventComplete,
+	sse.EventGenerationInterrupted,
+	sse.EventGenerationCanceled,
+}
+
+// WebhookUpdate holds the changes to a webhook; nil fields are left unchanged
+type WebhookUpdate struct {
+	URL         *string
+	Events      []string
+	Description *string
+	Active      *bool
+}
+
+// WebhookServiceImpl implements the WebhookService interface
+type WebhookServiceImpl struct {
+	webhookRepo  repository.WebhookRepository
+	deliveryRepo repository.WebhookDeliveryRepository
+}
+
+// NewWebhookService creates a new webhook service
+func NewWebhookService(webhookRepo repository.WebhookRepository, deliveryRepo repository.WebhookDeliveryRepository) WebhookService {
+	return &WebhookServiceImpl{
+		webhookRepo:  webhookRepo,
+		deliveryRepo: deliveryRepo,
+	}
+}
+
+// CreateWebhook subscribes a URL to events. It returns the webhook with its
+// signing secret, which is only returned again when it is rotated.
+func (s *WebhookServiceImpl) CreateWebhook(ctx context.Context, rawURL string, events []string, description string) (*models.Webhook, error) {
+	endpoint, err := validateWebhookURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	events, err = validateWebhookEvents(events)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := generateWebhookSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	webhook := models.NewWebhook(endpoint, secret, events)
+	webhook.Description = strings.TrimSpace(description)
+	if subject, ok := callerSubject(ctx); ok {
+		webhook.OwnerID = subject
+	}
+
+	if err := s.webhookRepo.Create(ctx, webhook); err != nil {
+		return nil, err
+	}
+
+	return webhook, nil
+}
+
+// GetWebhook retrieves one of the caller's webhooks by its ID
+func (s *WebhookServiceImpl) GetWebhook(ctx context.Context, id string) (*models.Webhook, error) {
+	webhookID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, apperrors.NewBadRequestError("Invalid webhook ID", err)
+	}
+
+	webhook, err := s.webhookRepo.FindByID(ctx, webhookID)
+	if err != nil {
+		return nil, err
+	}
+
+	if webhook == nil || !canManageWebhook(ctx, webhook) {
+		return nil, apperrors.NewNotFoundError("Webhook not found", nil)
+	}
+
+	return webhook, nil
+}
+
+// ListWebhooks retrieves the caller's webhooks
+func (s *WebhookServiceImpl) ListWebhooks(ctx context.Context) ([]*models.Webhook, error) {
+	subject, _ := callerSubject(ctx)
+	return s.webhookRepo.FindAll(ctx, subject)
+}
+
+// canManageWebhook reports whether the caller owns the webhook
+func canManageWebhook(ctx context.Context, webhook *models.Webhook) bool {
+	subject, ok := callerSubject(ctx)
+	return !ok || webhook.OwnerID == subject
+}
+
+// UpdateWebhook changes the settings of a webhook. Enabling a disabled
+// webhook clears its failure record.
+func (s *WebhookServiceImpl) UpdateWebhook(ctx context.Context, id string, update WebhookUpdate) (*models.Webhook, error) {
+	webhook, err := s.GetWebhook(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if update.URL != nil {
+		if webhook.URL, err = validateWebhookURL(*update.URL); err != nil {
+			return nil, err
+		}
+	}
+
+	if update.Events != nil {
+		if webhook.Events, err = validateWebhookEvents(update.Events); err != nil {
+			return nil, err
+		}
+	}
+
+	if update.Description != nil {
+		webhook.Description = strings.TrimSpace(*update.Description)
+	}
+
+	if err := s.webhookRepo.Update(ctx, webhook); err != nil {
+		return nil, err
+	}
+
+	if update.Active != nil && *update.Active != webhook.Active {
+		now := time.Now()
+		if *update.Active {
+			err = s.webhookRepo.Enable(ctx, webhook.ID, now)
+		} else {
+			_, err = s.webhookRepo.Disable(ctx, webhook.ID, "Disabled by its owner", now)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		return s.GetWebhook(ctx, id)
+	}
+
+	return webhook, nil
+}
+
+// RotateWebhookSecret replaces the signing secret of a webhook and returns it with the new secret
+func (s *WebhookServiceImpl) RotateWebhookSecret(ctx context.Context, id string) (*models.Webhook, error) {
+	webhook, err := s.GetWebhook(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if webhook.Secret, err = generateWebhookSecret(); err != nil {
+		return nil, err
+	}
+
+	if err := s.webhookRepo.Update(ctx, webhook); err != nil {
+		return nil, err
+	}
+
+	return webhook, nil
+}
+
+// DeleteWebhook deletes a webhook and its delivery log. Deliveries still
+// queued for it are dropped.
+func (s *WebhookServiceImpl) DeleteWebhook(ctx context.Context, id string) error {
+	webhook, err := s.GetWebhook(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.webhookRepo.Delete(ctx, webhook.ID); err != nil {
+		return err
+	}
+
+	if _, err := s.deliveryRepo.DeleteByWebhookID(ctx, webhook.ID); err != nil {
+		logger.FromContext(ctx).Warnf("Failed to delete delivery log of webhook %s: %v", id, err)
+	}
+
+	return nil
+}
+
+// ListDeliveries retrieves a page of a webhook's delivery log, newest first
+func (s *WebhookServiceImpl) ListDeliveries(ctx context.Context, id string, page, pageSize int) ([]*models.WebhookDelivery, int64, error) {
+	webhook, err := s.GetWebhook(ctx, id)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	deliveries, err := s.deliveryRepo.FindByWebhookID(ctx, webhook.ID, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	total, err := s.deliveryRepo.CountByWebhookID(ctx, webhook.ID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return deliveries, total, nil
+}
+
+// validateWebhookURL checks that a webhook URL is an absolute HTTP(S) URL
+func validateWebhookURL(rawURL string) (string, error) {
+	rawURL = strings.TrimSpace(rawURL)
+
+	endpoint, err := url.Parse(rawURL)
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		return "", apperrors.NewValidationError("Webhook URL must be an absolute http or https URL", err)
+	}
+
+	if endpoint.User != nil {
+		return "", apperrors.NewValidationError("Webhook URL must not contain credentials", nil)
+	}
+
+	return rawURL, nil
+}
+
+// validateWebhookEvents checks and deduplicates the event types of a subscription
+func validateWebhookEvents(events []string) ([]string, error) {
+	var valid []string
+	for _, event := range events {
+		event = strings.TrimSpace(event)
+		if event != models.WebhookAllEvents && !slices.Contains(WebhookEvents, event) {
+			return nil, apperrors.NewValidationError("Unknown webhook event: "+event, nil)
+		}
+		if !slices.Contains(valid, event) {
+			valid = append(valid, event)
+		}
+	}
+
+	if len(valid) == 0 {
+		return nil, apperrors.NewValidationError("At least one webhook event is required", nil)
+	}
+
+	return valid, nil
+}
+
+// generateWebhookSecret creates a random signing secret
+func generateWebhookSecret() (string, error) {
+	secret := make([]byte, webhookSecretBytes)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return webhookSecretPrefix + base64.RawURLEncoding.EncodeToString(secret), nil
+}
@@ -0,0 +1,128 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package shutdown
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrShuttingDown is the cause of work contexts canceled by a drain, and is
+// returned when new work is started during one
+var ErrShuttingDown = errors.New("server is shutting down")
+
+// checkpointGrace is how long canceled work gets to save its progress
+const checkpointGrace = 2 * time.Second
+
+// Tracker counts in-flight work, such as AI generations, that shutdown waits for
+type Tracker struct {
+	draining bool
+	active   map[*work]struct{}
+	idle     chan struct{} // Closed when draining and no work is left
+	mutex    sync.Mutex
+}
+
+// work is a tracked unit of work
+type work struct {
+	cancel context.CancelCauseFunc
+}
+
+// NewTracker creates a tracker with no work in flight
+func NewTracker() *Tracker {
+	return &Tracker{
+		active: make(map[*work]struct{}),
+		idle:   make(chan struct{}),
+	}
+}
+
+// Start begins tracking a unit of work. The returned context is canceled with
+// ErrShuttingDown when a drain runs out of time, which is the signal to
+// checkpoint; done must be called when the work ends.
+func (t *Tracker) Start(ctx context.Context) (context.Context, func(), error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.draining {
+		return nil, nil, ErrShuttingDown
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	w := &work{cancel: cancel}
+	t.active[w] = struct{}{}
+
+	var once sync.Once
+	done := func() {
+		once.Do(func() {
+			cancel(nil)
+			t.finish(w)
+		})
+	}
+
+	return ctx, done, nil
+}
+
+// finish stops tracking w
+func (t *Tracker) finish(w *work) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	delete(t.active, w)
+	if t.draining && len(t.active) == 0 {
+		close(t.idle)
+	}
+}
+
+// Active returns the number of units of work in flight
+func (t *Tracker) Active() int {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	return len(t.active)
+}
+
+// Drain refuses new work and waits for in-flight work to finish. When ctx is
+// done first, the remaining work is canceled with ErrShuttingDown and given
+// a short grace period to checkpoint. It returns the number of units of work
+// that had to be canceled.
+func (t *Tracker) Drain(ctx context.Context) int {
+	t.mutex.Lock()
+	if !t.draining {
+		t.draining = true
+		if len(t.active) == 0 {
+			close(t.idle)
+		}
+	}
+	t.mutex.Unlock()
+
+	select {
+	case <-t.idle:
+		return 0
+	case <-ctx.Done():
+	}
+
+	t.mutex.Lock()
+	canceled := len(t.active)
+	for w := range t.active {
+		w.cancel(ErrShuttingDown)
+	}
+	t.mutex.Unlock()
+
+	grace := time.NewTimer(checkpointGrace)
+	defer grace.Stop()
+
+	select {
+	case <-t.idle:
+	case <-grace.C:
+	}
+
+	return canceled
+}
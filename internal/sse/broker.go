@@ -12,16 +12,28 @@ package sse
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"math/rand/v2"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/metrics"
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/tracing"
 	"github.com/yasin-yalcin-dev/go-sse-ai-chat/pkg/logger"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
-// Broker manages SSE clients and message distribution
+// ErrBrokerStopped is returned for messages sent after the broker loop stopped
+var ErrBrokerStopped = errors.New("broker stopped")
+
+// Broker manages SSE clients and message distribution.
+// Clients and messages belong to a tenant, and messages are only ever
+// delivered to clients of the same tenant.
 type Broker struct {
-	// Client management
+	// Client management, keyed by tenant-scoped client ID
 	Clients    map[string]*Client
 	Register   chan *Client
 	Unregister chan *Client
@@ -29,12 +41,23 @@ type Broker struct {
 	// Message broadcasting
 	Broadcast chan *Message
 
+	// Liveness probes, answered by the broker loop
+	pings chan chan struct{}
+
+	// Closed when the broker loop has stopped
+	done chan struct{}
+
+	// Set once shutdown has asked clients to reconnect elsewhere
+	draining        atomic.Bool
+	reconnectRetry  time.Duration
+	reconnectJitter time.Duration
+
 	// Message store for reconnection replay
 	messageStore *MessageStore
 
-	// Configuration
-	MaxClients        int
-	KeepaliveInterval time.Duration
+	// Configuration; the client limit and keepalive interval can change while running
+	maxClients        atomic.Int64
+	keepaliveInterval atomic.Int64
 	MaxRetryAttempts  int
 	RetryDelay        time.Duration
 
@@ -44,12 +67,15 @@ type Broker struct {
 
 // Message represents a message to be sent to clients
 type Message struct {
-	ID       string          // Unique message ID
-	ChatID   string          // Chat ID this message belongs to
-	Event    string          // Event name
-	Data     json.RawMessage // Message data as JSON
-	Target   string          // Target client ID (empty for broadcast to whole chat)
-	Attempts int             // Number of delivery attempts made
+	ID       string            // Unique message ID
+	TenantID string            // Tenant this message belongs to
+	ChatID   string            // Chat ID this message belongs to
+	Event    string            // Event name
+	Data     json.RawMessage   // Message data as JSON
+	Target   string            // Target client ID (empty for broadcast to whole chat or tenant)
+	Attempts int               // Number of delivery attempts made
+	Trace    trace.SpanContext // Span that published the message
+	Retry    time.Duration     // Reconnection delay to suggest to the client; zero sends none
 }
 
 // NewBroker creates a new SSE broker
@@ -57,22 +83,43 @@ func NewBroker(maxClients int, keepaliveInterval time.Duration) *Broker {
 	// Create message store that keeps messages for 5 minutes, up to 50 per chat
 	messageStore := NewMessageStore(50, 5*time.Minute)
 
-	return &Broker{
-		Clients:           make(map[string]*Client),
-		Register:          make(chan *Client),
-		Unregister:        make(chan *Client),
-		Broadcast:         make(chan *Message, 256), // Buffer for messages
-		messageStore:      messageStore,
-		MaxClients:        maxClients,
-		KeepaliveInterval: keepaliveInterval,
-		MaxRetryAttempts:  3,                      // Retry failed messages 3 times
-		RetryDelay:        500 * time.Millisecond, // Wait 500ms between retries
+	b := &Broker{
+		Clients:          make(map[string]*Client),
+		Register:         make(chan *Client),
+		Unregister:       make(chan *Client),
+		Broadcast:        make(chan *Message, 256), // Buffer for messages
+		pings:            make(chan chan struct{}),
+		done:             make(chan struct{}),
+		messageStore:     messageStore,
+		MaxRetryAttempts: 3,                      // Retry failed messages 3 times
+		RetryDelay:       500 * time.Millisecond, // Wait 500ms between retries
 	}
+	b.Configure(maxClients, keepaliveInterval)
+
+	return b
+}
+
+// Configure changes the client limit and keepalive interval. Connected clients
+// are kept even above the new limit, and switch to the new interval after their next keepalive.
+func (b *Broker) Configure(maxClients int, keepaliveInterval time.Duration) {
+	b.maxClients.Store(int64(maxClients))
+	b.keepaliveInterval.Store(int64(keepaliveInterval))
+}
+
+// MaxClients returns the maximum number of connected clients
+func (b *Broker) MaxClients() int {
+	return int(b.maxClients.Load())
+}
+
+// KeepaliveInterval returns how often clients are pinged
+func (b *Broker) KeepaliveInterval() time.Duration {
+	return time.Duration(b.keepaliveInterval.Load())
 }
 
 // Start starts the broker
 func (b *Broker) Start(ctx context.Context) {
 	logger.Info("Starting SSE broker")
+	defer close(b.done)
 
 	for {
 		select {
@@ -93,6 +140,10 @@ func (b *Broker) Start(ctx context.Context) {
 		case message := <-b.Broadcast:
 			// New message to broadcast
 			b.processMessage(message)
+
+		case reply := <-b.pings:
+			// Liveness probe
+			close(reply)
 		}
 	}
 }
@@ -102,10 +153,11 @@ func (b *Broker) registerClient(client *Client) {
 	b.mutex.Lock()
 	defer b.mutex.Unlock()
 
-	// Check max clients limit
-	if len(b.Clients) >= b.MaxClients {
-		logger.Warnf("Max SSE clients reached (%d), rejecting new connection", b.MaxClients)
-		client.Close()
+	// Check max clients limit. The client closes itself once its context is
+	// canceled; closing it here would block on the Unregister channel this loop serves.
+	if maxClients := b.MaxClients(); len(b.Clients) >= maxClients {
+		logger.FromContext(client.Ctx).Warnf("Max SSE clients reached (%d), rejecting new connection", maxClients)
+		client.Cancel()
 		return
 	}
 
@@ -113,13 +165,17 @@ func (b *Broker) registerClient(client *Client) {
 	chatID := getChatIDFromClientID(client.ID)
 
 	// Add client to the map
-	b.Clients[client.ID] = client
-	logger.Infof("SSE client connected: %s (total clients: %d)", client.ID, len(b.Clients))
+	b.Clients[scopedKey(client.TenantID, client.ID)] = client
+	logger.FromContext(client.Ctx).Infof("SSE client connected: %s in tenant %s (total clients: %d)", client.ID, client.TenantID, len(b.Clients))
 
 	// Check if we need to replay messages for this chat
 	if chatID != "" {
-		// Send last 5 minutes of messages
-		b.replayMessages(client, chatID, time.Now().Add(-5*time.Minute))
+		b.replayMessages(client, chatID)
+	}
+
+	// Clients connecting during shutdown are told to move right away
+	if b.draining.Load() {
+		b.sendReconnect(client)
 	}
 }
 
@@ -129,13 +185,14 @@ func (b *Broker) unregisterClient(client *Client) {
 	defer b.mutex.Unlock()
 
 	// Check if client exists
-	if _, exists := b.Clients[client.ID]; !exists {
+	key := scopedKey(client.TenantID, client.ID)
+	if _, exists := b.Clients[key]; !exists {
 		return
 	}
 
 	// Remove client from the map
-	delete(b.Clients, client.ID)
-	logger.Debugf("Unregistered SSE client: %s (remaining clients: %d)", client.ID, len(b.Clients))
+	delete(b.Clients, key)
+	logger.FromContext(client.Ctx).Debugf("Unregistered SSE client: %s (remaining clients: %d)", client.ID, len(b.Clients))
 }
 
 // processMessage handles a new message, storing it and delivering it
@@ -151,105 +208,140 @@ func (b *Broker) processMessage(message *Message) {
 		// Convert message to JSON for storage
 		messageJSON, err := json.Marshal(message.Data)
 		if err == nil {
-			b.messageStore.StoreMessage(chatID, message.ID, message.Event, messageJSON)
+			b.messageStore.StoreMessage(scopedKey(message.TenantID, chatID), message.ID, message.Event, messageJSON)
 		}
 	}
 
-	// Deliver the message
-	b.deliverMessage(message)
+	// Retries were counted when the message was first accepted
+	if message.Attempts == 0 {
+		metrics.SSEEventBroadcast(message.Event)
+	}
+
+	// Deliver the message in a span continuing the publisher's trace
+	ctx := trace.ContextWithRemoteSpanContext(context.Background(), message.Trace)
+	_, span := tracing.Start(ctx, "sse.deliver",
+		attribute.String("tenant.id", message.TenantID),
+		attribute.String("chat.id", chatID),
+		attribute.String("sse.event", message.Event),
+		attribute.Int("sse.attempt", message.Attempts+1),
+	)
+	delivered, failed := b.deliverMessage(message)
+	span.SetAttributes(attribute.Int("sse.delivered", delivered), attribute.Int("sse.failed", failed))
+	span.End()
 }
 
-// deliverMessage sends a message to the targeted client(s)
-func (b *Broker) deliverMessage(message *Message) {
+// deliverMessage sends a message to the targeted client(s) and returns
+// how many sends succeeded and failed
+func (b *Broker) deliverMessage(message *Message) (delivered, failed int) {
 	b.mutex.RLock()
 	defer b.mutex.RUnlock()
 
-	// Convert message to JSON
-	messageJSON, err := json.Marshal(message.Data)
-	if err != nil {
-		logger.Errorf("Failed to marshal message: %v", err)
-		return
-	}
-
 	// Check if message is targeted to a specific client
 	if message.Target != "" {
-		// Send to specific client
-		client, exists := b.Clients[message.Target]
+		// Send to specific client of the message's tenant
+		client, exists := b.Clients[scopedKey(message.TenantID, message.Target)]
 		if exists {
-			if err := client.Send(messageJSON); err != nil {
-				logger.Warnf("Failed to send message to client %s: %v", client.ID, err)
+			if err := client.Send(message); err != nil {
+				logger.FromContext(client.Ctx).Warnf("Failed to send message to client %s: %v", client.ID, err)
+				failed++
 				// If sending failed and we haven't reached max retries, queue for retry
 				if message.Attempts < b.MaxRetryAttempts {
 					go b.retryMessage(message)
+				} else {
+					metrics.SSEEventDropped(message.Event)
 				}
+			} else {
+				delivered++
 			}
 		}
 	} else if message.ChatID != "" {
 		// Send to all clients in this chat
-		for clientID, client := range b.Clients {
-			// Check if this client belongs to the target chat
-			if getChatIDFromClientID(clientID) == message.ChatID {
-				if err := client.Send(messageJSON); err != nil {
-					logger.Warnf("Failed to send message to client %s: %v", client.ID, err)
+		for _, client := range b.Clients {
+			// Check if this client belongs to the target chat of the same tenant
+			if client.TenantID == message.TenantID && getChatIDFromClientID(client.ID) == message.ChatID {
+				if err := client.Send(message); err != nil {
+					logger.FromContext(client.Ctx).Warnf("Failed to send message to client %s: %v", client.ID, err)
+					metrics.SSEEventDropped(message.Event)
+					failed++
+				} else {
+					delivered++
 				}
 			}
 		}
 	} else {
-		// Broadcast to all clients
+		// Broadcast to all clients of the tenant
 		for _, client := range b.Clients {
-			if err := client.Send(messageJSON); err != nil {
-				logger.Warnf("Failed to send message to client %s: %v", client.ID, err)
+			if client.TenantID != message.TenantID {
+				continue
+			}
+			if err := client.Send(message); err != nil {
+				logger.FromContext(client.Ctx).Warnf("Failed to send message to client %s: %v", client.ID, err)
+				metrics.SSEEventDropped(message.Event)
+				failed++
+			} else {
+				delivered++
 			}
 		}
 	}
+
+	return delivered, failed
 }
 
 // retryMessage attempts to resend a failed message after a delay
 func (b *Broker) retryMessage(message *Message) {
 	// Increment attempt count
 	message.Attempts++
-
-	// Wait before retrying
-	time.Sleep(b.RetryDelay)
+	metrics.SSEEventRetried(message.Event)
+
+	// Wait before retrying, unless the broker stops meanwhile
+	timer := time.NewTimer(b.RetryDelay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-b.done:
+		return
+	}
 
 	// Try to send again
-	logger.Debugf("Retrying message delivery (attempt %d/%d)",
+	logger.With(logger.FieldChatID, message.ChatID, logger.FieldClientID, message.Target).Debugf("Retrying message delivery (attempt %d/%d)",
 		message.Attempts, b.MaxRetryAttempts)
 
-	b.Broadcast <- message
+	select {
+	case b.Broadcast <- message:
+	case <-b.done:
+	}
 }
 
-// replayMessages sends recent messages to a newly connected client
-func (b *Broker) replayMessages(client *Client, chatID string, since time.Time) {
-	// Get recent messages for this chat
-	messages := b.messageStore.GetRecentMessages(chatID, since)
+// replayMessages queues recent messages for a newly connected client: those
+// after the client's last event ID if it is still stored, otherwise those of
+// the last 5 minutes
+func (b *Broker) replayMessages(client *Client, chatID string) {
+	key := scopedKey(client.TenantID, chatID)
+	messages, found := b.messageStore.GetMessagesAfter(key, client.LastEventID)
+	if !found {
+		messages = b.messageStore.GetRecentMessages(key, time.Now().Add(-5*time.Minute))
+	}
 
 	if len(messages) > 0 {
-		logger.Infof("Replaying %d messages for client %s", len(messages), client.ID)
+		logger.FromContext(client.Ctx).Infof("Replaying %d messages for client %s", len(messages), client.ID)
 
 		// Send a notification that we're replaying messages
-		replayStart := map[string]interface{}{
-			"type":  "replay_start",
-			"count": len(messages),
-		}
-		replayStartJSON, _ := json.Marshal(replayStart)
-		client.sendEvent("control", replayStartJSON)
+		replayStartJSON, _ := json.Marshal(ControlEvent{Type: ControlReplayStart, Count: len(messages)})
+		client.Send(&Message{Event: EventControl, Data: replayStartJSON})
 
 		// Send each message
 		for _, msg := range messages {
-			client.sendEvent(msg.Event, msg.Data)
+			client.Send(&Message{ID: msg.ID, TenantID: client.TenantID, ChatID: chatID, Event: msg.Event, Data: msg.Data})
 		}
 
 		// Send replay complete notification
-		replayEnd := map[string]interface{}{
-			"type": "replay_end",
-		}
-		replayEndJSON, _ := json.Marshal(replayEnd)
-		client.sendEvent("control", replayEndJSON)
+		replayEndJSON, _ := json.Marshal(ControlEvent{Type: ControlReplayEnd})
+		client.Send(&Message{Event: EventControl, Data: replayEndJSON})
 	}
 }
 
-// closeAllClients closes all client connections
+// closeAllClients ends all client connections. Each client closes itself
+// once its context is canceled.
 func (b *Broker) closeAllClients() {
 	b.mutex.Lock()
 	defer b.mutex.Unlock()
@@ -257,13 +349,77 @@ func (b *Broker) closeAllClients() {
 	logger.Infof("Closing all SSE connections (%d clients)", len(b.Clients))
 
 	for _, client := range b.Clients {
-		client.Close()
+		client.Cancel()
 	}
 
 	// Clear the clients map
 	b.Clients = make(map[string]*Client)
 }
 
+// Drain asks every client to reconnect, to another instance once this one
+// is out of rotation, after retry plus a random delay of up to jitter.
+// Connections stay open, so that in-flight generations can still stream
+// their output, until the broker is stopped. Clients connecting afterwards
+// get the same request.
+func (b *Broker) Drain(retry, jitter time.Duration) {
+	b.mutex.Lock()
+	b.reconnectRetry = retry
+	b.reconnectJitter = jitter
+	b.draining.Store(true)
+	b.mutex.Unlock()
+
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	logger.Infof("Asking %d SSE clients to reconnect", len(b.Clients))
+	for _, client := range b.Clients {
+		b.sendReconnect(client)
+	}
+}
+
+// sendReconnect queues a reconnect request with a jittered retry hint.
+// The caller must hold the broker mutex.
+func (b *Broker) sendReconnect(client *Client) {
+	retry := b.reconnectRetry
+	if b.reconnectJitter > 0 {
+		retry += rand.N(b.reconnectJitter)
+	}
+
+	data, _ := json.Marshal(ControlEvent{Type: ControlReconnect, RetryMs: retry.Milliseconds()})
+	if err := client.Send(&Message{Event: EventControl, Data: data, Retry: retry}); err != nil {
+		logger.FromContext(client.Ctx).Warnf("Failed to send reconnect request to client %s: %v", client.ID, err)
+	}
+}
+
+// Done returns a channel that is closed once the broker loop has stopped
+// and all clients were told to close
+func (b *Broker) Done() <-chan struct{} {
+	return b.done
+}
+
+// Ping checks that the broker loop is running and responsive
+func (b *Broker) Ping(ctx context.Context) error {
+	reply := make(chan struct{})
+
+	select {
+	case b.pings <- reply:
+	case <-ctx.Done():
+		return errors.New("broker loop is not responding")
+	}
+
+	select {
+	case <-reply:
+		return nil
+	case <-ctx.Done():
+		return errors.New("broker loop is not responding")
+	}
+}
+
+// ReplayStoreStats returns the size of the replay message store
+func (b *Broker) ReplayStoreStats() MessageStoreStats {
+	return b.messageStore.Stats()
+}
+
 // GetClientCount returns the number of connected clients
 func (b *Broker) GetClientCount() int {
 	b.mutex.RLock()
@@ -272,14 +428,14 @@ func (b *Broker) GetClientCount() int {
 	return len(b.Clients)
 }
 
-// GetClientsInChat returns the number of clients connected to a specific chat
-func (b *Broker) GetClientsInChat(chatID string) int {
+// GetClientCountInTenant returns the number of clients connected in a tenant
+func (b *Broker) GetClientCountInTenant(tenantID string) int {
 	b.mutex.RLock()
 	defer b.mutex.RUnlock()
 
 	count := 0
-	for clientID := range b.Clients {
-		if getChatIDFromClientID(clientID) == chatID {
+	for _, client := range b.Clients {
+		if client.TenantID == tenantID {
 			count++
 		}
 	}
@@ -287,57 +443,116 @@ func (b *Broker) GetClientsInChat(chatID string) int {
 	return count
 }
 
-// SendToClient sends a message to a specific client
-func (b *Broker) SendToClient(clientID string, messageID string, event string, data interface{}) error {
+// GetClientsPerTenant returns the number of connected clients of every tenant with at least one
+func (b *Broker) GetClientsPerTenant() []metrics.TenantClients {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	counts := make(map[string]int)
+	for _, client := range b.Clients {
+		counts[client.TenantID]++
+	}
+
+	tenants := make([]metrics.TenantClients, 0, len(counts))
+	for tenantID, clients := range counts {
+		tenants = append(tenants, metrics.TenantClients{TenantID: tenantID, Clients: clients})
+	}
+	return tenants
+}
+
+// GetClientsInChat returns the number of clients connected to a specific chat of a tenant
+func (b *Broker) GetClientsInChat(tenantID, chatID string) int {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	count := 0
+	for _, client := range b.Clients {
+		if client.TenantID == tenantID && getChatIDFromClientID(client.ID) == chatID {
+			count++
+		}
+	}
+
+	return count
+}
+
+// SendToClient sends a message to a specific client of a tenant
+func (b *Broker) SendToClient(ctx context.Context, tenantID, clientID string, messageID string, event string, data interface{}) error {
 	dataJSON, err := json.Marshal(data)
 	if err != nil {
 		return err
 	}
 
 	message := &Message{
-		ID:     messageID,
-		Event:  event,
-		Data:   dataJSON,
-		Target: clientID,
+		ID:       messageID,
+		TenantID: tenantID,
+		Event:    event,
+		Data:     dataJSON,
+		Target:   clientID,
+		Trace:    trace.SpanContextFromContext(ctx),
 	}
 
 	// If this is a targeted message, try to extract the chat ID from client ID
 	message.ChatID = getChatIDFromClientID(clientID)
 
-	b.Broadcast <- message
-	return nil
+	return b.publish(ctx, message)
 }
 
-// SendToChat sends a message to all clients in a chat
-func (b *Broker) SendToChat(chatID string, messageID string, event string, data interface{}) error {
+// SendToChat sends a message to all clients in a chat of a tenant
+func (b *Broker) SendToChat(ctx context.Context, tenantID, chatID string, messageID string, event string, data interface{}) error {
 	dataJSON, err := json.Marshal(data)
 	if err != nil {
 		return err
 	}
 
-	b.Broadcast <- &Message{
-		ID:     messageID,
-		ChatID: chatID,
-		Event:  event,
-		Data:   dataJSON,
-	}
-
-	return nil
+	return b.publish(ctx, &Message{
+		ID:       messageID,
+		TenantID: tenantID,
+		ChatID:   chatID,
+		Event:    event,
+		Data:     dataJSON,
+		Trace:    trace.SpanContextFromContext(ctx),
+	})
 }
 
-// BroadcastToAll sends a message to all clients
-func (b *Broker) BroadcastToAll(event string, data interface{}) error {
+// BroadcastToTenant sends a message to all clients of a tenant
+func (b *Broker) BroadcastToTenant(ctx context.Context, tenantID string, event string, data interface{}) error {
 	dataJSON, err := json.Marshal(data)
 	if err != nil {
 		return err
 	}
 
-	b.Broadcast <- &Message{
-		Event: event,
-		Data:  dataJSON,
+	return b.publish(ctx, &Message{
+		TenantID: tenantID,
+		Event:    event,
+		Data:     dataJSON,
+		Trace:    trace.SpanContextFromContext(ctx),
+	})
+}
+
+// publish queues a message for the broker loop. It gives up once the loop
+// has stopped or ctx is done, so that senders never block shutdown on a
+// full queue nobody reads anymore.
+func (b *Broker) publish(ctx context.Context, message *Message) error {
+	select {
+	case <-b.done:
+		return ErrBrokerStopped
+	default:
 	}
 
-	return nil
+	select {
+	case b.Broadcast <- message:
+		return nil
+	case <-b.done:
+		return ErrBrokerStopped
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// scopedKey qualifies a client or chat ID with its tenant. Tenant IDs cannot
+// contain "/", so keys of different tenants never collide.
+func scopedKey(tenantID, id string) string {
+	return tenantID + "/" + id
 }
 
 // Helper function to extract chat ID from client ID
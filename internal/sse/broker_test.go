@@ -0,0 +1,207 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package sse
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// testTimeout bounds the waits of the broker tests
+const testTimeout = 2 * time.Second
+
+// recordingTransport records the events written to a client, except pings
+type recordingTransport struct {
+	events chan *Message
+}
+
+func newRecordingTransport() *recordingTransport {
+	return &recordingTransport{events: make(chan *Message, 64)}
+}
+
+func (t *recordingTransport) Name() string { return "recording" }
+func (t *recordingTransport) Open() error  { return nil }
+func (t *recordingTransport) Close() error { return nil }
+
+func (t *recordingTransport) WriteMessage(msg *Message) error {
+	if msg.Event != EventPing {
+		t.events <- msg
+	}
+	return nil
+}
+
+// next returns the next event written, failing the test when none comes
+func (t *recordingTransport) next(tb testing.TB) *Message {
+	tb.Helper()
+	select {
+	case msg := <-t.events:
+		return msg
+	case <-time.After(testTimeout):
+		tb.Fatal("No event was written")
+		return nil
+	}
+}
+
+// startBroker runs a broker until the test ends
+func startBroker(t *testing.T) *Broker {
+	broker := NewBroker(100, time.Hour)
+	ctx, cancel := context.WithCancel(context.Background())
+	go broker.Start(ctx)
+	t.Cleanup(func() {
+		cancel()
+		<-broker.Done()
+	})
+	return broker
+}
+
+// connect registers a client of a tenant and returns its transport
+func connect(t *testing.T, broker *Broker, tenantID, clientID string) *recordingTransport {
+	t.Helper()
+	transport := newRecordingTransport()
+	client := NewClient(context.Background(), clientID, tenantID, transport, broker)
+	go client.Listen()
+	t.Cleanup(client.Cancel)
+
+	deadline := time.Now().Add(testTimeout)
+	for broker.GetClientsInChat(tenantID, getChatIDFromClientID(clientID)) == 0 ||
+		broker.GetClientCountInTenant(tenantID) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Client %s of tenant %s did not register", clientID, tenantID)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return transport
+}
+
+// TestBrokerIsolatesTenants checks that chat, client and tenant messages only
+// reach clients of the tenant they were sent in, even when the chat and
+// client IDs of two tenants are the same
+func TestBrokerIsolatesTenants(t *testing.T) {
+	broker := startBroker(t)
+	ctx := context.Background()
+
+	acme := connect(t, broker, "acme", "chat1_client")
+	globex := connect(t, broker, "globex", "chat1_client")
+
+	if err := broker.SendToChat(ctx, "acme", "chat1", "1", EventComplete, CompleteEvent{ID: "1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := broker.SendToClient(ctx, "acme", "chat1_client", "", EventAck, AckEvent{Type: "send_message"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := broker.BroadcastToTenant(ctx, "acme", EventChatUpdated, ChatUpdatedEvent{ChatID: "chat1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The broker delivers in order, so globex sees its own event first only
+	// if none of acme's reached it
+	if err := broker.SendToChat(ctx, "globex", "chat1", "2", EventComplete, CompleteEvent{ID: "2"}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{EventComplete, EventAck, EventChatUpdated} {
+		if got := acme.next(t); got.Event != want || got.TenantID != "acme" {
+			t.Errorf("acme got %s event of tenant %s, want %s of acme", got.Event, got.TenantID, want)
+		}
+	}
+
+	if got := globex.next(t); got.TenantID != "globex" || got.ID != "2" {
+		t.Errorf("globex got %s event %s of tenant %s, want its own event 2", got.Event, got.ID, got.TenantID)
+	}
+}
+
+// TestBrokerReplaysWithinTenant checks that a connecting client is only
+// replayed the recent events of its own tenant's chat
+func TestBrokerReplaysWithinTenant(t *testing.T) {
+	broker := startBroker(t)
+	ctx := context.Background()
+
+	if err := broker.SendToChat(ctx, "acme", "chat1", "1", EventComplete, CompleteEvent{ID: "1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The broker loop may serve a registration before a queued message
+	deadline := time.Now().Add(testTimeout)
+	for len(broker.messageStore.GetRecentMessages(scopedKey("acme", "chat1"), time.Time{})) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("The event was not stored for replay")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	acme := connect(t, broker, "acme", "chat1_late")
+	if got := acme.next(t); got.Event != EventControl {
+		t.Fatalf("acme got %s event, want the replay_start control event", got.Event)
+	}
+	if got := acme.next(t); got.ID != "1" {
+		t.Errorf("acme was replayed event %q, want 1", got.ID)
+	}
+
+	globex := connect(t, broker, "globex", "chat1_late")
+	if err := broker.SendToChat(ctx, "globex", "chat1", "2", EventComplete, CompleteEvent{ID: "2"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := globex.next(t); got.ID != "2" {
+		t.Errorf("globex got %s event %q first, want its own event 2 without a replay", got.Event, got.ID)
+	}
+}
+
+// TestSendAfterStopDoesNotBlock checks that senders are released once the
+// broker loop stopped, even when the queue is full
+func TestSendAfterStopDoesNotBlock(t *testing.T) {
+	broker := NewBroker(100, time.Hour)
+	ctx, cancel := context.WithCancel(context.Background())
+	go broker.Start(ctx)
+	cancel()
+	<-broker.Done()
+
+	sent := make(chan error, 1)
+	go func() {
+		var err error
+		for i := 0; i <= cap(broker.Broadcast); i++ {
+			err = broker.SendToChat(context.Background(), "acme", "chat1", "", EventMessage, "hello")
+		}
+		sent <- err
+	}()
+
+	select {
+	case err := <-sent:
+		if !errors.Is(err, ErrBrokerStopped) {
+			t.Errorf("SendToChat() error = %v, want %v", err, ErrBrokerStopped)
+		}
+	case <-time.After(testTimeout):
+		t.Fatal("SendToChat() blocked after the broker stopped")
+	}
+}
+
+// TestSendGivesUpWithContext checks that a sender waiting on a full queue
+// returns once its context is done
+func TestSendGivesUpWithContext(t *testing.T) {
+	broker := NewBroker(100, time.Hour) // Not started, so nothing drains the queue
+	for i := 0; i < cap(broker.Broadcast); i++ {
+		broker.Broadcast <- &Message{}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := broker.SendToChat(ctx, "acme", "chat1", "", EventMessage, "hello"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("SendToChat() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+// TestScopedKey checks that the same ID in different tenants gets different keys
+func TestScopedKey(t *testing.T) {
+	if scopedKey("acme", "chat1") == scopedKey("globex", "chat1") {
+		t.Error("Keys of the same chat ID collide across tenants")
+	}
+}
@@ -11,34 +11,41 @@ package sse
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
-	"net/http"
+	"sync"
 	"time"
 
 	"github.com/yasin-yalcin-dev/go-sse-ai-chat/pkg/logger"
 )
 
-// Client represents a connected SSE client
+// Client represents a client connected to the broker. The event protocol is
+// the same for every client; its Transport carries it over SSE or a WebSocket.
 type Client struct {
 	ID           string
-	Connection   http.ResponseWriter
-	MessageChan  chan []byte
+	TenantID     string
+	Transport    Transport
+	LastEventID  string // ID of the last event the client received; replay continues after it
+	MessageChan  chan *Message
 	ConnectedAt  time.Time
 	LastActivity time.Time
 	IsClosed     bool
 	Ctx          context.Context
 	Cancel       context.CancelFunc
 	Broker       *Broker
+	mutex        sync.RWMutex // Guards IsClosed and closing MessageChan against concurrent sends
 }
 
-// NewClient creates a new SSE client
-func NewClient(id string, w http.ResponseWriter, broker *Broker) *Client {
-	ctx, cancel := context.WithCancel(context.Background())
+// NewClient creates a new client of a tenant that is reached through transport.
+// The client keeps the values of ctx, such as log fields, but not its cancellation.
+func NewClient(ctx context.Context, id, tenantID string, transport Transport, broker *Broker) *Client {
+	ctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
 
 	return &Client{
 		ID:           id,
-		Connection:   w,
-		MessageChan:  make(chan []byte, 256), // Buffer for messages
+		TenantID:     tenantID,
+		Transport:    transport,
+		MessageChan:  make(chan *Message, 256), // Buffer for messages
 		ConnectedAt:  time.Now(),
 		LastActivity: time.Now(),
 		IsClosed:     false,
@@ -48,8 +55,13 @@ func NewClient(id string, w http.ResponseWriter, broker *Broker) *Client {
 	}
 }
 
-// Send sends a message to the client
-func (c *Client) Send(message []byte) error {
+// Send queues a message for delivery to the client. It is safe to call
+// while the client closes, for example from a handler reading commands sent
+// over a WebSocket.
+func (c *Client) Send(message *Message) error {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
 	// Check if client is already closed
 	if c.IsClosed {
 		return fmt.Errorf("client %s is closed", c.ID)
@@ -60,36 +72,39 @@ func (c *Client) Send(message []byte) error {
 	case c.MessageChan <- message:
 		c.LastActivity = time.Now()
 		return nil
+	case <-c.Ctx.Done():
+		return fmt.Errorf("client %s is closed", c.ID)
 	case <-time.After(5 * time.Second): // Timeout if channel is full
 		return fmt.Errorf("send timeout for client %s", c.ID)
 	}
 }
 
-// Listen starts listening for messages and sends them to the client
+// Listen starts listening for messages and writes them to the transport
 func (c *Client) Listen() {
-	// Set necessary headers for SSE
-	c.setupHeaders()
-
-	// Create a flush for the client
-	flusher, ok := c.Connection.(http.Flusher)
-	if !ok {
-		logger.Errorf("Could not initialize SSE connection: %s - client doesn't support flushing", c.ID)
+	if err := c.Transport.Open(); err != nil {
+		logger.FromContext(c.Ctx).Errorf("Could not open %s connection for client %s: %v", c.Transport.Name(), c.ID, err)
 		c.Close()
 		return
 	}
 
 	// Signal to the broker that this client is ready
-	c.Broker.Register <- c
+	select {
+	case c.Broker.Register <- c:
+	case <-c.Broker.done:
+		c.Close()
+		return
+	}
 
 	// Send an initial ping to establish connection
 	if err := c.sendPing(); err != nil {
-		logger.Errorf("Failed to send initial ping to client %s: %v", c.ID, err)
+		logger.FromContext(c.Ctx).Errorf("Failed to send initial ping to client %s: %v", c.ID, err)
 		c.Close()
 		return
 	}
 
 	// Create keepalive ticker
-	keepalive := time.NewTicker(c.Broker.KeepaliveInterval)
+	interval := c.Broker.KeepaliveInterval()
+	keepalive := time.NewTicker(interval)
 	defer keepalive.Stop()
 
 	// Listen for messages and send them to the client
@@ -97,35 +112,38 @@ func (c *Client) Listen() {
 		select {
 		case <-c.Ctx.Done():
 			// Context was canceled, exit
-			logger.Debugf("Context canceled for client %s", c.ID)
+			logger.FromContext(c.Ctx).Debugf("Context canceled for client %s", c.ID)
 			c.Close()
 			return
 
 		case <-keepalive.C:
 			// Send keepalive ping
 			if err := c.sendPing(); err != nil {
-				logger.Warnf("Failed to send keepalive to client %s: %v", c.ID, err)
+				logger.FromContext(c.Ctx).Warnf("Failed to send keepalive to client %s: %v", c.ID, err)
 				c.Close()
 				return
 			}
 
+			// Pick up a reloaded keepalive interval
+			if next := c.Broker.KeepaliveInterval(); next != interval {
+				interval = next
+				keepalive.Reset(interval)
+			}
+
 		case msg, ok := <-c.MessageChan:
 			if !ok {
 				// Channel was closed
-				logger.Debugf("Message channel closed for client %s", c.ID)
+				logger.FromContext(c.Ctx).Debugf("Message channel closed for client %s", c.ID)
 				c.Close()
 				return
 			}
 
 			// Write message to the connection
-			if _, err := fmt.Fprintf(c.Connection, "data: %s\n\n", msg); err != nil {
-				logger.Warnf("Failed to send message to client %s: %v", c.ID, err)
+			if err := c.Transport.WriteMessage(msg); err != nil {
+				logger.FromContext(c.Ctx).Warnf("Failed to send message to client %s: %v", c.ID, err)
 				c.Close()
 				return
 			}
-
-			// Flush to send data immediately
-			flusher.Flush()
 			c.LastActivity = time.Now()
 		}
 	}
@@ -133,57 +151,45 @@ func (c *Client) Listen() {
 
 // Close closes the client connection
 func (c *Client) Close() {
+	// Cancel the context first, so that pending sends give up
+	c.Cancel()
+
 	// Prevent double closing
+	c.mutex.Lock()
 	if c.IsClosed {
+		c.mutex.Unlock()
 		return
 	}
-
 	c.IsClosed = true
-	c.Cancel() // Cancel the context
-
-	// Tell broker to unregister this client
-	c.Broker.Unregister <- c
 
 	// Close message channel
 	close(c.MessageChan)
+	c.mutex.Unlock()
 
-	logger.Infof("SSE client disconnected: %s (connected for %v)",
-		c.ID, time.Since(c.ConnectedAt))
-}
-
-// setupHeaders sets the necessary headers for SSE
-func (c *Client) setupHeaders() {
-	header := c.Connection.Header()
+	// Tell broker to unregister this client, unless it has stopped
+	select {
+	case c.Broker.Unregister <- c:
+	case <-c.Broker.done:
+	}
 
-	// Required SSE headers
-	header.Set("Content-Type", "text/event-stream")
-	header.Set("Cache-Control", "no-cache")
-	header.Set("Connection", "keep-alive")
-	header.Set("X-Accel-Buffering", "no") // Disable buffering in Nginx
+	if err := c.Transport.Close(); err != nil {
+		logger.FromContext(c.Ctx).Debugf("Failed to close connection of client %s: %v", c.ID, err)
+	}
 
-	// CORS headers (if needed)
-	header.Set("Access-Control-Allow-Origin", "*")
+	logger.FromContext(c.Ctx).Infof("%s client disconnected: %s (connected for %v)",
+		c.Transport.Name(), c.ID, time.Since(c.ConnectedAt))
 }
 
-// sendEvent sends a named event to the client
-func (c *Client) sendEvent(event string, data []byte) error {
-	flusher, ok := c.Connection.(http.Flusher)
-	if !ok {
-		return fmt.Errorf("client doesn't support flushing")
+// sendPing writes a keepalive ping
+func (c *Client) sendPing() error {
+	data, err := json.Marshal(PingEvent{Time: time.Now().Unix()})
+	if err != nil {
+		return err
 	}
 
-	if _, err := fmt.Fprintf(c.Connection, "event: %s\ndata: %s\n\n", event, data); err != nil {
+	if err := c.Transport.WriteMessage(&Message{Event: EventPing, Data: data}); err != nil {
 		return err
 	}
-
-	flusher.Flush()
 	c.LastActivity = time.Now()
 	return nil
 }
-
-// sendPing sends a keepalive ping
-func (c *Client) sendPing() error {
-	timestamp := time.Now().Unix()
-	ping := fmt.Sprintf(`{"time":%d}`, timestamp)
-	return c.sendEvent("ping", []byte(ping))
-}
@@ -0,0 +1,146 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package sse
+
+import (
+	"time"
+
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/models"
+)
+
+// Event names sent to clients
+const (
+	EventPing                  = "ping"
+	EventControl               = "control"
+	EventChatCreated           = "chat_created"
+	EventChatUpdated           = "chat_updated"
+	EventChatDeleted           = "chat_deleted"
+	EventChatRestored          = "chat_restored"
+	EventMessage               = "message"
+	EventMessageCreated        = "message_created"
+	EventMessageDeleted        = "message_deleted"
+	EventComplete              = "complete"
+	EventGenerationInterrupted = "generation_interrupted"
+	EventGenerationCanceled    = "generation_canceled"
+	EventAck                   = "ack"
+	EventError                 = "error"
+)
+
+// Types of control events
+const (
+	ControlReplayStart = "replay_start"
+	ControlReplayEnd   = "replay_end"
+	ControlReconnect   = "reconnect"
+)
+
+// PingEvent is the payload of a ping event, sent to keep a connection open
+type PingEvent struct {
+	Time int64 `json:"time"` // Unix time the ping was sent at
+}
+
+// ControlEvent is the payload of a control event, which is about the
+// connection rather than the chat
+type ControlEvent struct {
+	Type    string `json:"type"`               // replay_start, replay_end or reconnect
+	Count   int    `json:"count,omitempty"`    // Number of events replayed, for replay_start
+	RetryMs int64  `json:"retry_ms,omitempty"` // Delay before reconnecting, for reconnect
+}
+
+// ChatUpdatedEvent is the payload of a chat_updated event
+type ChatUpdatedEvent struct {
+	ChatID string       `json:"chat_id"`
+	Fields []string     `json:"fields"` // Names of the fields that changed
+	Chat   *models.Chat `json:"chat"`
+}
+
+// ChatEvent is the payload of chat_created and chat_restored events
+type ChatEvent struct {
+	ChatID string       `json:"chat_id"`
+	Chat   *models.Chat `json:"chat"`
+}
+
+// ChatDeletedEvent is the payload of a chat_deleted event, sent when a chat
+// is moved to the trash
+type ChatDeletedEvent struct {
+	ChatID    string    `json:"chat_id"`
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+// MessageCreatedEvent is the payload of a message_created event, sent when a
+// message is stored through the API. Generated replies are reported by
+// complete events instead.
+type MessageCreatedEvent struct {
+	ChatID  string          `json:"chat_id"`
+	Message *models.Message `json:"message"`
+}
+
+// MessageDeletedEvent is the payload of a message_deleted event, sent when a
+// message is moved to the trash
+type MessageDeletedEvent struct {
+	ID        string    `json:"id"`
+	ChatID    string    `json:"chat_id"`
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+// MessageChunkEvent is the payload of a message event carrying part of an
+// assistant reply while it is generated
+type MessageChunkEvent struct {
+	ID           string             `json:"id"` // ID the message is stored under once complete
+	ChatID       string             `json:"chat_id"`
+	GenerationID string             `json:"generation_id"`
+	Content      string             `json:"content"`
+	Type         models.MessageType `json:"type"`
+	Role         models.MessageRole `json:"role"`
+	CreatedAt    time.Time          `json:"created_at"`
+	IsChunk      bool               `json:"is_chunk"`
+}
+
+// CompleteEvent is the payload of a complete event, sent when a generated
+// reply has been stored
+type CompleteEvent struct {
+	ID           string `json:"id"`
+	ChatID       string `json:"chat_id"`
+	GenerationID string `json:"generation_id"`
+}
+
+// GenerationInterruptedEvent is the payload of a generation_interrupted
+// event, sent when a reply was cut off by a shutdown or crash
+type GenerationInterruptedEvent struct {
+	GenerationID string `json:"generation_id"`
+	ChatID       string `json:"chat_id"`
+	Content      string `json:"content"`   // Partial reply as of the last checkpoint
+	Resumable    bool   `json:"resumable"` // Whether POST /api/v1/generations/:id/continue is available
+}
+
+// GenerationCanceledEvent is the payload of a generation_canceled event, sent
+// when a running reply was stopped at a user's request
+type GenerationCanceledEvent struct {
+	GenerationID string `json:"generation_id"`
+	ChatID       string `json:"chat_id"`
+	Content      string `json:"content"` // Reply produced before the generation stopped
+}
+
+// AckEvent is the payload of an ack event, sent to a WebSocket client when a
+// command it sent succeeded
+type AckEvent struct {
+	RequestID string      `json:"request_id,omitempty"` // Request ID of the command, if it set one
+	Type      string      `json:"type"`                 // Type of the command
+	Result    interface{} `json:"result,omitempty"`
+}
+
+// ErrorEvent is the payload of an error event, sent to a WebSocket client
+// when a command it sent failed
+type ErrorEvent struct {
+	RequestID string `json:"request_id,omitempty"`
+	Type      string `json:"type,omitempty"`
+	Status    int    `json:"status"` // HTTP status the same failure has in the REST API
+	Code      string `json:"code,omitempty"`
+	Message   string `json:"message"`
+}
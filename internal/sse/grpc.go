@@ -0,0 +1,45 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package sse
+
+// TransportGRPC is the name of the gRPC transport
+const TransportGRPC = "grpc"
+
+// GRPCTransport sends messages as frames on a server-streaming gRPC call
+type GRPCTransport struct {
+	send func(*Frame) error
+}
+
+// NewGRPCTransport creates a transport on the stream of a call. send
+// converts a frame into the call's reply message and sends it.
+func NewGRPCTransport(send func(*Frame) error) *GRPCTransport {
+	return &GRPCTransport{send: send}
+}
+
+// Name implements Transport
+func (t *GRPCTransport) Name() string {
+	return TransportGRPC
+}
+
+// Open implements Transport. Headers are sent with the first frame.
+func (t *GRPCTransport) Open() error {
+	return nil
+}
+
+// WriteMessage sends a message as a frame. It blocks while the client is
+// not reading, and fails once the call ended.
+func (t *GRPCTransport) WriteMessage(msg *Message) error {
+	return t.send(newFrame(msg))
+}
+
+// Close implements Transport. The call ends when its handler returns.
+func (t *GRPCTransport) Close() error {
+	return nil
+}
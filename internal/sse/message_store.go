@@ -18,7 +18,7 @@ import (
 
 // MessageStore keeps track of recent messages for replay on reconnect
 type MessageStore struct {
-	// Maps chat ID to a list of recent messages for that chat
+	// Maps tenant-scoped chat ID to a list of recent messages for that chat
 	messages map[string][]*StoredMessage
 
 	// Maximum number of messages to store per chat
@@ -82,6 +82,29 @@ func (s *MessageStore) StoreMessage(chatID string, messageID string, event strin
 	}
 }
 
+// MessageStoreStats describes the contents of a message store
+type MessageStoreStats struct {
+	Chats    int
+	Messages int
+	Bytes    int // Total size of the stored message data
+}
+
+// Stats returns the number of chats and messages held by the store
+func (s *MessageStore) Stats() MessageStoreStats {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	stats := MessageStoreStats{Chats: len(s.messages)}
+	for _, messages := range s.messages {
+		stats.Messages += len(messages)
+		for _, msg := range messages {
+			stats.Bytes += len(msg.Data)
+		}
+	}
+
+	return stats
+}
+
 // GetRecentMessages retrieves recent messages for a chat
 func (s *MessageStore) GetRecentMessages(chatID string, since time.Time) []*StoredMessage {
 	s.mutex.RLock()
@@ -103,6 +126,27 @@ func (s *MessageStore) GetRecentMessages(chatID string, since time.Time) []*Stor
 	return recentMessages
 }
 
+// GetMessagesAfter retrieves the messages of a chat stored after the one with
+// ID afterID. It reports false when that message is not stored, for example
+// because it aged out; the caller cannot tell then which messages were missed.
+func (s *MessageStore) GetMessagesAfter(chatID string, afterID string) ([]*StoredMessage, bool) {
+	if afterID == "" {
+		return nil, false
+	}
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	messages := s.messages[chatID]
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].ID == afterID {
+			return append([]*StoredMessage(nil), messages[i+1:]...), true
+		}
+	}
+
+	return nil, false
+}
+
 // cleanup removes old messages
 func (s *MessageStore) cleanup() {
 	s.mutex.Lock()
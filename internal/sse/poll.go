@@ -0,0 +1,109 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package sse
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// TransportPoll is the name of the long-polling transport
+const TransportPoll = "poll"
+
+// PollTransport collects the events of one long-polling request. Only events
+// with an ID are collected, since the client resumes from the last ID it got;
+// pings and message chunks are dropped. A batch is ready once an event
+// arrived outside of a replay, or a replay ended, or the broker asked clients
+// to reconnect.
+type PollTransport struct {
+	events    []*Message
+	retry     time.Duration
+	replaying bool
+	ready     chan struct{}
+	readyOnce sync.Once
+	mutex     sync.Mutex
+}
+
+// NewPollTransport creates a transport collecting a batch of events
+func NewPollTransport() *PollTransport {
+	return &PollTransport{ready: make(chan struct{})}
+}
+
+// Name implements Transport
+func (t *PollTransport) Name() string {
+	return TransportPoll
+}
+
+// Open implements Transport
+func (t *PollTransport) Open() error {
+	return nil
+}
+
+// WriteMessage collects an event, or follows the replay and reconnect control events
+func (t *PollTransport) WriteMessage(msg *Message) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if msg.Event == EventControl {
+		var control ControlEvent
+		if err := json.Unmarshal(msg.Data, &control); err != nil {
+			return nil
+		}
+
+		switch control.Type {
+		case ControlReplayStart:
+			t.replaying = true
+		case ControlReplayEnd:
+			t.replaying = false
+			if len(t.events) > 0 {
+				t.signal()
+			}
+		case ControlReconnect:
+			t.retry = msg.Retry
+			t.signal()
+		}
+		return nil
+	}
+
+	if msg.ID == "" {
+		return nil
+	}
+
+	t.events = append(t.events, msg)
+	if !t.replaying {
+		t.signal()
+	}
+	return nil
+}
+
+// Close implements Transport
+func (t *PollTransport) Close() error {
+	return nil
+}
+
+// Ready returns a channel that is closed once a batch is ready
+func (t *PollTransport) Ready() <-chan struct{} {
+	return t.ready
+}
+
+// Batch returns the events collected so far and the reconnection delay
+// suggested by the broker, if it asked clients to reconnect
+func (t *PollTransport) Batch() ([]*Message, time.Duration) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	return append([]*Message(nil), t.events...), t.retry
+}
+
+// signal marks the batch ready. The caller must hold the mutex.
+func (t *PollTransport) signal() {
+	t.readyOnce.Do(func() { close(t.ready) })
+}
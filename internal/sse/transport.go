@@ -0,0 +1,107 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package sse
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Transport names
+const (
+	TransportSSE       = "sse"
+	TransportWebSocket = "websocket"
+)
+
+// Transport carries the event protocol to one connected client. Only the
+// client's Listen loop writes to it, so implementations need not be safe for
+// concurrent use.
+type Transport interface {
+	// Name identifies the transport in logs
+	Name() string
+	// Open prepares the connection before the first message is written
+	Open() error
+	// WriteMessage writes a message and sends it out immediately
+	WriteMessage(msg *Message) error
+	// Close ends the connection from the server side
+	Close() error
+}
+
+// SSETransport writes messages as Server-Sent Events to an HTTP response
+type SSETransport struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+}
+
+// NewSSETransport creates a transport on a streaming HTTP response
+func NewSSETransport(w http.ResponseWriter) (*SSETransport, error) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return nil, errors.New("response writer doesn't support flushing")
+	}
+	return &SSETransport{w: w, flusher: flusher}, nil
+}
+
+// Name implements Transport
+func (t *SSETransport) Name() string {
+	return TransportSSE
+}
+
+// Open sets the headers of the event stream and sends them
+func (t *SSETransport) Open() error {
+	header := t.w.Header()
+
+	// Required SSE headers
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	header.Set("X-Accel-Buffering", "no") // Disable buffering in Nginx
+
+	// CORS headers (if needed)
+	header.Set("Access-Control-Allow-Origin", "*")
+
+	t.w.WriteHeader(http.StatusOK)
+	t.flusher.Flush()
+	return nil
+}
+
+// WriteMessage writes a message as an SSE frame, including its ID, event name and retry hint when set
+func (t *SSETransport) WriteMessage(msg *Message) error {
+	if msg.ID != "" {
+		if _, err := fmt.Fprintf(t.w, "id: %s\n", msg.ID); err != nil {
+			return err
+		}
+	}
+
+	if msg.Event != "" {
+		if _, err := fmt.Fprintf(t.w, "event: %s\n", msg.Event); err != nil {
+			return err
+		}
+	}
+
+	if msg.Retry > 0 {
+		if _, err := fmt.Fprintf(t.w, "retry: %d\n", msg.Retry.Milliseconds()); err != nil {
+			return err
+		}
+	}
+
+	if _, err := fmt.Fprintf(t.w, "data: %s\n\n", msg.Data); err != nil {
+		return err
+	}
+
+	t.flusher.Flush()
+	return nil
+}
+
+// Close implements Transport. The stream ends when the handler returns.
+func (t *SSETransport) Close() error {
+	return nil
+}
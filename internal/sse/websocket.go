@@ -0,0 +1,78 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package sse
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// closeGracePeriod bounds how long closing a WebSocket waits to send the close frame
+const closeGracePeriod = time.Second
+
+// Frame is a message of the event protocol sent over a WebSocket as a JSON
+// text message, or over a gRPC stream. Its fields are those of an SSE frame.
+type Frame struct {
+	ID      string          `json:"id,omitempty"`
+	Event   string          `json:"event"`
+	Data    json.RawMessage `json:"data"`
+	RetryMs int64           `json:"retry_ms,omitempty"` // Reconnection delay suggested to the client
+}
+
+// newFrame returns the frame of a message
+func newFrame(msg *Message) *Frame {
+	return &Frame{
+		ID:      msg.ID,
+		Event:   msg.Event,
+		Data:    msg.Data,
+		RetryMs: msg.Retry.Milliseconds(),
+	}
+}
+
+// WebSocketTransport writes messages as JSON frames to a WebSocket
+type WebSocketTransport struct {
+	conn         *websocket.Conn
+	writeTimeout time.Duration
+}
+
+// NewWebSocketTransport creates a transport on an upgraded connection.
+// Writes that take longer than writeTimeout fail, closing the client.
+func NewWebSocketTransport(conn *websocket.Conn, writeTimeout time.Duration) *WebSocketTransport {
+	return &WebSocketTransport{conn: conn, writeTimeout: writeTimeout}
+}
+
+// Name implements Transport
+func (t *WebSocketTransport) Name() string {
+	return TransportWebSocket
+}
+
+// Open implements Transport. The connection is ready once upgraded.
+func (t *WebSocketTransport) Open() error {
+	return nil
+}
+
+// WriteMessage writes a message as a JSON frame
+func (t *WebSocketTransport) WriteMessage(msg *Message) error {
+	if t.writeTimeout > 0 {
+		if err := t.conn.SetWriteDeadline(time.Now().Add(t.writeTimeout)); err != nil {
+			return err
+		}
+	}
+	return t.conn.WriteJSON(newFrame(msg))
+}
+
+// Close sends a close frame and closes the connection
+func (t *WebSocketTransport) Close() error {
+	message := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+	_ = t.conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(closeGracePeriod))
+	return t.conn.Close()
+}
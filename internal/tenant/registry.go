@@ -0,0 +1,135 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package tenant
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/internal/config"
+)
+
+// Quotas limits how much a tenant may create; zero means unlimited
+type Quotas struct {
+	MaxChats          int `json:"max_chats"`
+	MaxMessagesPerDay int `json:"max_messages_per_day"`
+}
+
+// Override holds per-tenant settings; empty fields fall back to the global configuration
+type Override struct {
+	AIProvider     string  `json:"ai_provider,omitempty"`
+	OpenAIKey      string  `json:"openai_api_key,omitempty"`
+	OpenAIModel    string  `json:"openai_model,omitempty"`
+	AnthropicKey   string  `json:"anthropic_api_key,omitempty"`
+	AnthropicModel string  `json:"anthropic_model,omitempty"`
+	MaxTokens      int     `json:"max_tokens,omitempty"`
+	Quotas         *Quotas `json:"quotas,omitempty"`
+}
+
+// Registry resolves the effective settings of each tenant
+type Registry struct {
+	aiProvider config.AIProviderConfig
+	quotas     Quotas
+	overrides  map[string]Override
+	mutex      sync.RWMutex // Guards aiProvider, which changes on configuration reload
+}
+
+// NewRegistry creates a registry from the global defaults and per-tenant overrides
+func NewRegistry(aiProvider config.AIProviderConfig, quotas Quotas, overrides map[string]Override) *Registry {
+	if overrides == nil {
+		overrides = map[string]Override{}
+	}
+	return &Registry{
+		aiProvider: aiProvider,
+		quotas:     quotas,
+		overrides:  overrides,
+	}
+}
+
+// LoadOverrides reads per-tenant overrides from a JSON file keyed by tenant ID
+func LoadOverrides(path string) (map[string]Override, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tenant config: %w", err)
+	}
+
+	var overrides map[string]Override
+	if err := json.Unmarshal(data, &overrides); err != nil {
+		return nil, fmt.Errorf("failed to parse tenant config: %w", err)
+	}
+
+	for id, override := range overrides {
+		if !IsValidID(id) {
+			return nil, fmt.Errorf("invalid tenant ID in tenant config: %q", id)
+		}
+		if p := override.AIProvider; p != "" && p != "openai" && p != "anthropic" {
+			return nil, fmt.Errorf("tenant %s: ai_provider must be 'openai' or 'anthropic', received: %s", id, p)
+		}
+	}
+
+	return overrides, nil
+}
+
+// SetDefaultAIProvider replaces the global AI provider configuration that
+// tenant overrides apply to
+func (r *Registry) SetDefaultAIProvider(cfg config.AIProviderConfig) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.aiProvider = cfg
+}
+
+// DefaultAIProvider returns the global AI provider configuration
+func (r *Registry) DefaultAIProvider() config.AIProviderConfig {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	return r.aiProvider
+}
+
+// AIProvider returns the AI provider configuration of a tenant
+func (r *Registry) AIProvider(id string) config.AIProviderConfig {
+	cfg := r.DefaultAIProvider()
+	override, ok := r.overrides[id]
+	if !ok {
+		return cfg
+	}
+
+	if override.AIProvider != "" {
+		cfg.Provider = override.AIProvider
+	}
+	if override.OpenAIKey != "" {
+		cfg.OpenAIKey = override.OpenAIKey
+	}
+	if override.OpenAIModel != "" {
+		cfg.OpenAIModel = override.OpenAIModel
+	}
+	if override.AnthropicKey != "" {
+		cfg.AnthropicKey = override.AnthropicKey
+	}
+	if override.AnthropicModel != "" {
+		cfg.AnthropicModel = override.AnthropicModel
+	}
+	if override.MaxTokens > 0 {
+		cfg.MaxTokens = override.MaxTokens
+	}
+
+	return cfg
+}
+
+// Quotas returns the quotas of a tenant
+func (r *Registry) Quotas(id string) Quotas {
+	if override, ok := r.overrides[id]; ok && override.Quotas != nil {
+		return *override.Quotas
+	}
+	return r.quotas
+}
@@ -0,0 +1,42 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package tenant
+
+import (
+	"context"
+	"regexp"
+)
+
+// DefaultID is the tenant used when a request does not name one
+const DefaultID = "default"
+
+// idPattern restricts tenant IDs to short, URL- and log-safe identifiers
+var idPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+
+// IsValidID reports whether id can be used as a tenant ID
+func IsValidID(id string) bool {
+	return idPattern.MatchString(id)
+}
+
+// tenantKey is the context key for the tenant ID
+type tenantKey struct{}
+
+// WithTenant returns a copy of ctx scoped to the given tenant
+func WithTenant(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, id)
+}
+
+// FromContext returns the tenant ctx is scoped to, or DefaultID when it is not scoped
+func FromContext(ctx context.Context) string {
+	if id, ok := ctx.Value(tenantKey{}).(string); ok && id != "" {
+		return id
+	}
+	return DefaultID
+}
@@ -0,0 +1,29 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package tracing
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+// NewHTTPClient returns an HTTP client for outgoing calls, such as AI provider
+// requests. Each request gets a client span and carries the trace context of
+// its request context in the traceparent header.
+func NewHTTPClient(client *http.Client) *http.Client {
+	traced := *client
+	base := traced.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	traced.Transport = otelhttp.NewTransport(base)
+	return &traced
+}
@@ -0,0 +1,73 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/event"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// NewCommandMonitor returns a MongoDB command monitor that records a client span
+// per command, as a child of the span in the context of the repository call
+func NewCommandMonitor() *event.CommandMonitor {
+	// Spans are started and ended by separate events, so remember them in between
+	var spans sync.Map
+
+	key := func(connectionID string, requestID int64) string {
+		return fmt.Sprintf("%s/%d", connectionID, requestID)
+	}
+
+	finish := func(e event.CommandFinishedEvent, failure string) {
+		value, ok := spans.LoadAndDelete(key(e.ConnectionID, e.RequestID))
+		if !ok {
+			return
+		}
+		span := value.(trace.Span)
+		if failure != "" {
+			span.SetStatus(codes.Error, failure)
+		}
+		span.End()
+	}
+
+	return &event.CommandMonitor{
+		Started: func(ctx context.Context, e *event.CommandStartedEvent) {
+			// CRUD commands name their collection as the value of the command field
+			collection, _ := e.Command.Lookup(e.CommandName).StringValueOK()
+
+			name := e.CommandName
+			if collection != "" {
+				name += " " + collection
+			}
+
+			_, span := tracer().Start(ctx, name,
+				trace.WithSpanKind(trace.SpanKindClient),
+				trace.WithAttributes(
+					attribute.String("db.system.name", "mongodb"),
+					attribute.String("db.namespace", e.DatabaseName),
+					attribute.String("db.collection.name", collection),
+					attribute.String("db.operation.name", e.CommandName),
+				),
+			)
+			spans.Store(key(e.ConnectionID, e.RequestID), span)
+		},
+		Succeeded: func(_ context.Context, e *event.CommandSucceededEvent) {
+			finish(e.CommandFinishedEvent, "")
+		},
+		Failed: func(_ context.Context, e *event.CommandFailedEvent) {
+			finish(e.CommandFinishedEvent, e.Failure)
+		},
+	}
+}
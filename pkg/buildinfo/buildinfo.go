@@ -0,0 +1,110 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package buildinfo
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync"
+)
+
+// Values injected at build time, for example:
+//
+//	go build -ldflags "-X github.com/yasin-yalcin-dev/go-sse-ai-chat/pkg/buildinfo.version=1.2.0
+//	  -X github.com/yasin-yalcin-dev/go-sse-ai-chat/pkg/buildinfo.buildTime=2025-04-12T00:00:00Z" ./cmd/api
+//
+// Values left empty fall back to the metadata the Go toolchain embeds; the
+// build time then falls back to the time of the VCS commit.
+var (
+	version   string
+	revision  string
+	buildTime string
+)
+
+// Info describes the running binary
+type Info struct {
+	Version   string `json:"version"`
+	Revision  string `json:"revision"`
+	Dirty     bool   `json:"dirty"`
+	BuildTime string `json:"build_time"`
+	GoVersion string `json:"go_version"`
+}
+
+var (
+	info     Info
+	infoOnce sync.Once
+)
+
+// Get returns the build metadata of the running binary
+func Get() Info {
+	infoOnce.Do(func() {
+		info = Info{
+			Version:   "dev",
+			GoVersion: runtime.Version(),
+		}
+
+		if build, ok := debug.ReadBuildInfo(); ok {
+			if v := build.Main.Version; v != "" && v != "(devel)" {
+				info.Version = strings.TrimPrefix(v, "v")
+			}
+			for _, setting := range build.Settings {
+				switch setting.Key {
+				case "vcs.revision":
+					info.Revision = setting.Value
+				case "vcs.time":
+					info.BuildTime = setting.Value
+				case "vcs.modified":
+					info.Dirty = setting.Value == "true"
+				}
+			}
+		}
+
+		// Build-time values take precedence over embedded metadata
+		if version != "" {
+			info.Version = version
+		}
+		if revision != "" {
+			info.Revision = revision
+		}
+		if buildTime != "" {
+			info.BuildTime = buildTime
+		}
+	})
+
+	return info
+}
+
+// ShortRevision returns the first 12 characters of the VCS revision
+func (i Info) ShortRevision() string {
+	if len(i.Revision) > 12 {
+		return i.Revision[:12]
+	}
+	return i.Revision
+}
+
+// String formats the metadata on one line, as printed by --version
+func (i Info) String() string {
+	revision := i.ShortRevision()
+	if revision == "" {
+		revision = "unknown"
+	}
+	if i.Dirty {
+		revision += "-dirty"
+	}
+
+	built := i.BuildTime
+	if built == "" {
+		built = "unknown"
+	}
+
+	return fmt.Sprintf("%s (revision %s, built %s, %s)", i.Version, revision, built, i.GoVersion)
+}
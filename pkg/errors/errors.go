@@ -40,6 +40,8 @@ const (
 	CodeValidationError      = "VALIDATION_ERROR"
 	CodeDatabaseError        = "DATABASE_ERROR"
 	CodeExternalServiceError = "EXTERNAL_SERVICE_ERROR"
+	CodeQuotaExceeded        = "QUOTA_EXCEEDED"
+	CodeRateLimited          = "RATE_LIMITED"
 )
 
 // New creates a new error with a message
@@ -126,7 +128,7 @@ func (e *AppError) GetStatusCode() int {
 		return http.StatusBadRequest
 	case CodeUnauthorized:
 		return http.StatusUnauthorized
-	case CodeForbidden:
+	case CodeForbidden, CodeQuotaExceeded:
 		return http.StatusForbidden
 	case CodeNotFound:
 		return http.StatusNotFound
@@ -136,6 +138,8 @@ func (e *AppError) GetStatusCode() int {
 		return http.StatusServiceUnavailable
 	case CodeTimeout:
 		return http.StatusGatewayTimeout
+	case CodeRateLimited:
+		return http.StatusTooManyRequests
 	default:
 		return http.StatusInternalServerError
 	}
@@ -291,6 +295,20 @@ func NewExternalServiceError(message string, err error) *AppError {
 		WithStatusCode(http.StatusBadGateway)
 }
 
+// NewQuotaExceededError creates an error for requests that exceed a tenant quota
+func NewQuotaExceededError(message string, err error) *AppError {
+	return NewAppError(message, err).
+		WithCode(CodeQuotaExceeded).
+		WithStatusCode(http.StatusForbidden)
+}
+
+// NewRateLimitError creates an error for requests rejected by a rate limit
+func NewRateLimitError(message string, err error) *AppError {
+	return NewAppError(message, err).
+		WithCode(CodeRateLimited).
+		WithStatusCode(http.StatusTooManyRequests)
+}
+
 // Is reports whether any error in err's chain matches target.
 func Is(err, target error) bool {
 	return errors.Is(err, target)
@@ -0,0 +1,77 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package grpcjson
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// Name is the content subtype of the codec. Calls select it with the content
+// type application/grpc+json, or in Go with grpc.CallContentSubtype(Name).
+const Name = "json"
+
+// ContentType is the content type of calls encoded with the codec
+const ContentType = "application/grpc+" + Name
+
+// Supported reports whether a call with the given content type is encoded
+// with the codec. Content type parameters are ignored.
+func Supported(contentType string) bool {
+	if i := strings.IndexByte(contentType, ';'); i >= 0 {
+		contentType = contentType[:i]
+	}
+	return strings.EqualFold(strings.TrimSpace(contentType), ContentType)
+}
+
+// Codec encodes protobuf messages in their JSON mapping, using the field
+// names of the .proto file. It is an alternative to the default protobuf
+// codec for clients without generated code; unknown fields are ignored.
+// Importing the package registers the codec with gRPC.
+type Codec struct{}
+
+var (
+	marshalOptions   = protojson.MarshalOptions{UseProtoNames: true}
+	unmarshalOptions = protojson.UnmarshalOptions{DiscardUnknown: true}
+)
+
+func init() {
+	encoding.RegisterCodec(Codec{})
+}
+
+// Marshal implements encoding.Codec
+func (Codec) Marshal(v interface{}) ([]byte, error) {
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("grpcjson: cannot marshal %T: not a protobuf message", v)
+	}
+	return marshalOptions.Marshal(msg)
+}
+
+// Unmarshal implements encoding.Codec. Empty data decodes to the zero message.
+func (Codec) Unmarshal(data []byte, v interface{}) error {
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("grpcjson: cannot unmarshal into %T: not a protobuf message", v)
+	}
+	if len(data) == 0 {
+		proto.Reset(msg)
+		return nil
+	}
+	return unmarshalOptions.Unmarshal(data, msg)
+}
+
+// Name implements encoding.Codec
+func (Codec) Name() string {
+	return Name
+}
@@ -0,0 +1,82 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package logger
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Field names used to correlate log lines
+const (
+	FieldRequestID    = "request_id"
+	FieldTenantID     = "tenant_id"
+	FieldChatID       = "chat_id"
+	FieldClientID     = "client_id"
+	FieldGenerationID = "generation_id"
+	FieldJobID        = "job_id"
+	FieldJobType      = "job_type"
+)
+
+// fieldsKey is the context key of the log fields
+type fieldsKey struct{}
+
+// WithContext returns a copy of ctx carrying the given key-value pairs.
+// Loggers obtained with FromContext add them to every log line; a key that
+// is already present is replaced.
+func WithContext(ctx context.Context, keysAndValues ...interface{}) context.Context {
+	current, _ := ctx.Value(fieldsKey{}).([]interface{})
+	fields := make([]interface{}, 0, len(current)+len(keysAndValues))
+	fields = append(fields, current...)
+
+	for i := 0; i+1 < len(keysAndValues); i += 2 {
+		fields = setField(fields, keysAndValues[i], keysAndValues[i+1])
+	}
+
+	return context.WithValue(ctx, fieldsKey{}, fields)
+}
+
+// setField replaces the value of key in fields, or appends the pair
+func setField(fields []interface{}, key, value interface{}) []interface{} {
+	for i := 0; i+1 < len(fields); i += 2 {
+		if fields[i] == key {
+			fields[i+1] = value
+			return fields
+		}
+	}
+	return append(fields, key, value)
+}
+
+// FromContext returns a logger with the fields stored in ctx and the IDs of
+// the active trace span, if any
+func FromContext(ctx context.Context) *Logger {
+	fields, _ := ctx.Value(fieldsKey{}).([]interface{})
+
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+		fields = append(fields[:len(fields):len(fields)],
+			"trace_id", spanContext.TraceID().String(),
+			"span_id", spanContext.SpanID().String())
+	}
+
+	return With(fields...)
+}
+
+// RequestID returns the request ID stored in ctx, or an empty string
+func RequestID(ctx context.Context) string {
+	fields, _ := ctx.Value(fieldsKey{}).([]interface{})
+	for i := 0; i+1 < len(fields); i += 2 {
+		if fields[i] == FieldRequestID {
+			id, _ := fields[i+1].(string)
+			return id
+		}
+	}
+	return ""
+}
@@ -10,6 +10,7 @@
 package logger
 
 import (
+	"github.com/yasin-yalcin-dev/go-sse-ai-chat/pkg/buildinfo"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
@@ -22,25 +23,13 @@ type Logger struct {
 var (
 	// Global logger instance
 	log *Logger
+
+	// Level of the global logger, which can change while it is in use
+	level zap.AtomicLevel
 )
 
 // Initialize configures the logger at the start of the project
-func Initialize(level string, isDevelopment bool) {
-	// Set log level
-	var zapLevel zapcore.Level
-	switch level {
-	case "debug":
-		zapLevel = zapcore.DebugLevel
-	case "info":
-		zapLevel = zapcore.InfoLevel
-	case "warn":
-		zapLevel = zapcore.WarnLevel
-	case "error":
-		zapLevel = zapcore.ErrorLevel
-	default:
-		zapLevel = zapcore.InfoLevel
-	}
-
+func Initialize(levelName string, isDevelopment bool) {
 	// Create Zap configuration
 	var config zap.Config
 	if isDevelopment {
@@ -52,7 +41,8 @@ func Initialize(level string, isDevelopment bool) {
 		config = zap.NewProductionConfig()
 		config.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
 	}
-	config.Level.SetLevel(zapLevel)
+	config.Level.SetLevel(parseLevel(levelName))
+	level = config.Level
 
 	// Build the logger
 	zapLogger, err := config.Build()
@@ -60,11 +50,42 @@ func Initialize(level string, isDevelopment bool) {
 		panic("logger initialization failed: " + err.Error())
 	}
 
+	// Tag production logs with the build so that log lines can be matched to a release
+	if !isDevelopment {
+		info := buildinfo.Get()
+		zapLogger = zapLogger.With(zap.String("version", info.Version), zap.String("revision", info.ShortRevision()))
+	}
+
 	log = &Logger{
 		SugaredLogger: zapLogger.Sugar(),
 	}
 }
 
+// SetLevel changes the level of the global logger and of every logger derived from it
+func SetLevel(levelName string) {
+	if log == nil {
+		Initialize(levelName, true)
+		return
+	}
+	level.SetLevel(parseLevel(levelName))
+}
+
+// parseLevel converts a level name to a zap level, defaulting to info
+func parseLevel(levelName string) zapcore.Level {
+	switch levelName {
+	case "debug":
+		return zapcore.DebugLevel
+	case "info":
+		return zapcore.InfoLevel
+	case "warn":
+		return zapcore.WarnLevel
+	case "error":
+		return zapcore.ErrorLevel
+	default:
+		return zapcore.InfoLevel
+	}
+}
+
 // NewWithOptions creates a new logger with the given options
 func NewWithOptions(opts ...zap.Option) *Logger {
 	if log == nil {